@@ -0,0 +1,316 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"strconv"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/knr1997/assets-management-apiserver/internal/api/requests"
+	"github.com/knr1997/assets-management-apiserver/internal/api/responses"
+	"github.com/knr1997/assets-management-apiserver/internal/store"
+)
+
+type accessoryKey string
+
+const accessoryCtx accessoryKey = "accessory"
+
+func getAccessoryFromCtx(r *http.Request) *store.Accessory {
+	accessory, _ := r.Context().Value(accessoryCtx).(*store.Accessory)
+	return accessory
+}
+
+func (app *application) accessoryContextMiddleware(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		idParam := chi.URLParam(r, "accessoryID")
+		id, err := strconv.ParseInt(idParam, 10, 64)
+		if err != nil {
+			app.internalServerError(w, r, err)
+			return
+		}
+
+		ctx := r.Context()
+
+		accessory, err := app.store.Accessory.GetByID(ctx, id)
+		if err != nil {
+			switch {
+			case errors.Is(err, store.ErrNotFound):
+				app.notFoundResponse(w, r, err)
+			default:
+				app.internalServerError(w, r, err)
+			}
+			return
+		}
+
+		ctx = context.WithValue(ctx, accessoryCtx, accessory)
+		next.ServeHTTP(w, r.WithContext(ctx))
+	})
+}
+
+func (app *application) getAllAccessoryHandler(w http.ResponseWriter, r *http.Request) {
+	spec, ok := app.parseQuerySpec(w, r)
+	if !ok {
+		return
+	}
+
+	writeListPage(app, w, r, spec, app.store.Accessory.List, responses.NewAccessoriesResponse)
+}
+
+func (app *application) createAccessoryHandler(w http.ResponseWriter, r *http.Request) {
+	var payload requests.CreateAccessoryPayload
+	if err := readJSON(w, r, &payload); err != nil {
+		app.badRequestResponse(w, r, err)
+		return
+	}
+
+	if err := Validate.Struct(payload); err != nil {
+		app.badRequestResponse(w, r, err)
+		return
+	}
+
+	accessory := &store.Accessory{
+		Name:           payload.Name,
+		CategoryID:     payload.CategoryID,
+		ManufacturerID: payload.ManufacturerID,
+		SupplierID:     payload.SupplierID,
+		ModelNumber:    payload.ModelNumber,
+		PurchaseDate:   payload.PurchaseDate,
+		UnitCost:       payload.UnitCost,
+		Notes:          payload.Notes,
+	}
+
+	ctx := r.Context()
+
+	if err := app.store.Accessory.Create(ctx, accessory); err != nil {
+		app.internalServerError(w, r, err)
+		return
+	}
+
+	accessory, err := app.store.Accessory.GetByID(ctx, accessory.ID)
+	if err != nil {
+		app.internalServerError(w, r, err)
+		return
+	}
+
+	if err := app.jsonResponse(w, http.StatusCreated, responses.NewAccessoryResponse(accessory)); err != nil {
+		app.internalServerError(w, r, err)
+		return
+	}
+}
+
+func (app *application) getAccessoryHandler(w http.ResponseWriter, r *http.Request) {
+	accessory := getAccessoryFromCtx(r)
+
+	if err := app.jsonResponse(w, http.StatusOK, responses.NewAccessoryResponse(accessory)); err != nil {
+		app.internalServerError(w, r, err)
+		return
+	}
+}
+
+func (app *application) updateAccessoryHandler(w http.ResponseWriter, r *http.Request) {
+	accessory := getAccessoryFromCtx(r)
+
+	var payload requests.UpdateAccessoryPayload
+	if err := readJSON(w, r, &payload); err != nil {
+		app.badRequestResponse(w, r, err)
+		return
+	}
+
+	if err := Validate.Struct(payload); err != nil {
+		app.badRequestResponse(w, r, err)
+		return
+	}
+
+	if payload.Name != nil {
+		accessory.Name = *payload.Name
+	}
+	if payload.CategoryID != nil {
+		accessory.CategoryID = *payload.CategoryID
+	}
+	if payload.ManufacturerID != nil {
+		accessory.ManufacturerID = payload.ManufacturerID
+	}
+	if payload.SupplierID != nil {
+		accessory.SupplierID = payload.SupplierID
+	}
+	if payload.ModelNumber != nil {
+		accessory.ModelNumber = *payload.ModelNumber
+	}
+	if payload.PurchaseDate != nil {
+		accessory.PurchaseDate = payload.PurchaseDate
+	}
+	if payload.UnitCost != nil {
+		accessory.UnitCost = *payload.UnitCost
+	}
+	if payload.Notes != nil {
+		accessory.Notes = *payload.Notes
+	}
+
+	ctx := r.Context()
+
+	if err := app.store.Accessory.Update(ctx, accessory); err != nil {
+		switch {
+		case errors.Is(err, store.ErrNotFound):
+			app.notFoundResponse(w, r, err)
+		default:
+			app.internalServerError(w, r, err)
+		}
+		return
+	}
+
+	accessory, err := app.store.Accessory.GetByID(ctx, accessory.ID)
+	if err != nil {
+		app.internalServerError(w, r, err)
+		return
+	}
+
+	if err := app.jsonResponse(w, http.StatusOK, responses.NewAccessoryResponse(accessory)); err != nil {
+		app.internalServerError(w, r, err)
+		return
+	}
+}
+
+func (app *application) deleteAccessoryHandler(w http.ResponseWriter, r *http.Request) {
+	accessory := getAccessoryFromCtx(r)
+
+	if err := app.store.Accessory.Delete(r.Context(), accessory.ID); err != nil {
+		switch {
+		case errors.Is(err, store.ErrNotFound):
+			app.notFoundResponse(w, r, err)
+		default:
+			app.internalServerError(w, r, err)
+		}
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// setAccessoryStockHandler sets how many of the accessory are kept at a
+// location.
+func (app *application) setAccessoryStockHandler(w http.ResponseWriter, r *http.Request) {
+	accessory := getAccessoryFromCtx(r)
+
+	var payload requests.SetAccessoryStockPayload
+	if err := readJSON(w, r, &payload); err != nil {
+		app.badRequestResponse(w, r, err)
+		return
+	}
+
+	if err := Validate.Struct(payload); err != nil {
+		app.badRequestResponse(w, r, err)
+		return
+	}
+
+	ctx := r.Context()
+
+	if err := app.store.Accessory.SetQuantity(ctx, accessory.ID, payload.Location, payload.Quantity); err != nil {
+		switch {
+		case errors.Is(err, store.ErrStockInUse):
+			app.conflictResponse(w, r, fmt.Errorf("more than %d are checked out at %s", payload.Quantity, payload.Location))
+		default:
+			app.internalServerError(w, r, err)
+		}
+		return
+	}
+
+	accessory, err := app.store.Accessory.GetByID(ctx, accessory.ID)
+	if err != nil {
+		app.internalServerError(w, r, err)
+		return
+	}
+
+	if err := app.jsonResponse(w, http.StatusOK, responses.NewAccessoryResponse(accessory)); err != nil {
+		app.internalServerError(w, r, err)
+		return
+	}
+}
+
+func (app *application) checkoutAccessoryHandler(w http.ResponseWriter, r *http.Request) {
+	accessory := getAccessoryFromCtx(r)
+
+	var payload requests.CheckoutAccessoryPayload
+	if err := readJSON(w, r, &payload); err != nil {
+		app.badRequestResponse(w, r, err)
+		return
+	}
+
+	if err := Validate.Struct(payload); err != nil {
+		app.badRequestResponse(w, r, err)
+		return
+	}
+
+	loan := &store.AccessoryLoan{
+		AccessoryID:         accessory.ID,
+		UserID:              payload.UserID,
+		Location:            payload.Location,
+		Quantity:            max(payload.Quantity, 1),
+		ExpectedCheckinDate: payload.ExpectedCheckinDate,
+		Notes:               payload.Notes,
+	}
+	if user := getUserFromContext(r); user != nil {
+		loan.CheckedOutByID = &user.ID
+	}
+
+	ctx := r.Context()
+
+	if err := app.store.Accessory.Checkout(ctx, loan); err != nil {
+		switch {
+		case errors.Is(err, store.ErrInsufficientStock):
+			app.conflictResponse(w, r, fmt.Errorf("not enough %s available at %s", accessory.Name, payload.Location))
+		default:
+			app.internalServerError(w, r, err)
+		}
+		return
+	}
+
+	accessory, err := app.store.Accessory.GetByID(ctx, accessory.ID)
+	if err != nil {
+		app.internalServerError(w, r, err)
+		return
+	}
+
+	if err := app.jsonResponse(w, http.StatusCreated, responses.NewAccessoryResponse(accessory)); err != nil {
+		app.internalServerError(w, r, err)
+		return
+	}
+}
+
+func (app *application) checkinAccessoryHandler(w http.ResponseWriter, r *http.Request) {
+	accessory := getAccessoryFromCtx(r)
+
+	loanID, err := strconv.ParseInt(chi.URLParam(r, "loanID"), 10, 64)
+	if err != nil {
+		app.internalServerError(w, r, err)
+		return
+	}
+
+	if err := app.store.Accessory.Checkin(r.Context(), accessory.ID, loanID); err != nil {
+		switch {
+		case errors.Is(err, store.ErrNotFound):
+			app.notFoundResponse(w, r, err)
+		default:
+			app.internalServerError(w, r, err)
+		}
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+func (app *application) getAccessoryLoansHandler(w http.ResponseWriter, r *http.Request) {
+	accessory := getAccessoryFromCtx(r)
+
+	spec, ok := app.parseQuerySpec(w, r)
+	if !ok {
+		return
+	}
+
+	list := func(ctx context.Context, spec store.QuerySpec) (*store.Pagination, error) {
+		return app.store.Accessory.Loans(ctx, accessory.ID, spec)
+	}
+
+	writeListPage(app, w, r, spec, list, responses.NewAccessoryLoansResponse)
+}
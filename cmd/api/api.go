@@ -13,6 +13,8 @@ import (
 	"github.com/go-chi/chi/v5/middleware"
 	"github.com/go-chi/cors"
 	"github.com/knr1997/assets-management-apiserver/internal/auth"
+	"github.com/knr1997/assets-management-apiserver/internal/blob"
+	"github.com/knr1997/assets-management-apiserver/internal/secret"
 	"github.com/knr1997/assets-management-apiserver/internal/store"
 	httpSwagger "github.com/swaggo/http-swagger"
 	"go.uber.org/zap"
@@ -23,6 +25,9 @@ type application struct {
 	store         store.Storage
 	logger        *zap.SugaredLogger
 	authenticator auth.Authenticator
+	secrets       *secret.Box
+	blobs         blob.Store
+	events        *eventHub
 }
 
 type config struct {
@@ -32,6 +37,38 @@ type config struct {
 	apiURL      string
 	frontendURL string
 	auth        authConfig
+	secretKey   string
+	scheduler   schedulerConfig
+	attachments attachmentConfig
+	events      eventConfig
+	webhooks    webhookConfig
+}
+
+type schedulerConfig struct {
+	interval time.Duration
+}
+
+type attachmentConfig struct {
+	backend string // fs or s3
+	dir     string
+	s3      blob.S3Config
+	maxSize int64
+	urlTTL  time.Duration
+	// signs download URLs, kept apart from the key sealing stored secrets
+	signingKey string
+}
+
+type eventConfig struct {
+	interval time.Duration
+	// published events are kept this long, for streams catching up and
+	// webhook redeliveries
+	retention time.Duration
+}
+
+type webhookConfig struct {
+	interval    time.Duration
+	timeout     time.Duration
+	maxAttempts int
 }
 
 type authConfig struct {
@@ -65,7 +102,7 @@ func (app *application) mount() http.Handler {
 		AllowedOrigins:   []string{"http://localhost:5173"},
 		AllowedMethods:   []string{"GET", "POST", "PUT", "PATCH", "DELETE", "OPTIONS"},
 		AllowedHeaders:   []string{"Accept", "Authorization", "Content-Type", "X-CSRF-Token"},
-		ExposedHeaders:   []string{"Link"},
+		ExposedHeaders:   []string{"Link", "Content-Disposition"},
 		AllowCredentials: true,
 		MaxAge:           300, // Maximum value not ignored by browsers
 	}))
@@ -75,7 +112,7 @@ func (app *application) mount() http.Handler {
 	r.Use(middleware.RealIP)    // import for rate limiting and analytics and tracing
 	r.Use(middleware.Logger)
 	r.Use(middleware.Recoverer) // recover from crashes
-	r.Use(middleware.Timeout(60 * time.Second))
+	r.Use(timeoutExceptStreams(60 * time.Second))
 
 	// Swagger UI
 	r.Get("/swagger/*", httpSwagger.WrapHandler)
@@ -102,13 +139,13 @@ func (app *application) mount() http.Handler {
 
 	r.Route("/api/departments", func(r chi.Router) {
 		r.Get("/", app.getAlldepartmentHandler)
-		r.Post("/", app.createdepartmentHandler)
+		r.With(app.OptionalAuthTokenMiddleware).Post("/", app.createdepartmentHandler)
 
 		r.Route("/{departmentID}", func(r chi.Router) {
 			r.Use(app.departmentContextMiddleware)
 			r.Get("/", app.getdepartmentHandler)
 
-			r.Patch("/", app.updatedepartmentHandler)
+			r.With(app.OptionalAuthTokenMiddleware).Patch("/", app.updatedepartmentHandler)
 			r.Delete("/", app.deletedepartmentHandler)
 		})
 	})
@@ -123,6 +160,21 @@ func (app *application) mount() http.Handler {
 
 			r.Patch("/", app.updateSupplierHandler)
 			r.Delete("/", app.deleteSupplierHandler)
+
+			r.Route("/attachments", app.attachmentRoutes)
+
+			r.Route("/contacts", func(r chi.Router) {
+				r.Use(app.AuthTokenMiddleware)
+				r.Get("/", app.getAllContactHandler)
+				r.Post("/", app.createContactHandler)
+
+				r.Route("/{contactID}", func(r chi.Router) {
+					r.Use(app.contactContextMiddleware)
+					r.Get("/", app.getContactHandler)
+					r.Patch("/", app.updateContactHandler)
+					r.Delete("/", app.deleteContactHandler)
+				})
+			})
 		})
 	})
 
@@ -136,6 +188,15 @@ func (app *application) mount() http.Handler {
 
 			r.Patch("/", app.updateModelHandler)
 			r.Delete("/", app.deleteModelHandler)
+
+			r.Get("/custom-fields", app.getModelCustomFieldsHandler)
+			r.Get("/availability", app.getModelAvailabilityHandler)
+
+			r.Get("/image", app.getModelImageHandler)
+			r.With(app.AuthTokenMiddleware).Post("/image", app.uploadModelImageHandler)
+			r.With(app.AuthTokenMiddleware).Delete("/image", app.deleteModelImageHandler)
+
+			r.Route("/attachments", app.attachmentRoutes)
 		})
 	})
 
@@ -149,6 +210,33 @@ func (app *application) mount() http.Handler {
 
 			r.Patch("/", app.updateManufacturerHandler)
 			r.Delete("/", app.deleteManufacturerHandler)
+
+			r.Route("/contacts", func(r chi.Router) {
+				r.Use(app.AuthTokenMiddleware)
+				r.Get("/", app.getAllContactHandler)
+				r.Post("/", app.createContactHandler)
+
+				r.Route("/{contactID}", func(r chi.Router) {
+					r.Use(app.contactContextMiddleware)
+					r.Get("/", app.getContactHandler)
+					r.Patch("/", app.updateContactHandler)
+					r.Delete("/", app.deleteContactHandler)
+				})
+			})
+		})
+	})
+
+	r.Route("/api/custom-field-sets", func(r chi.Router) {
+		r.Use(app.AuthTokenMiddleware)
+		r.Get("/", app.getAllCustomFieldSetHandler)
+		r.Post("/", app.createCustomFieldSetHandler)
+
+		r.Route("/{fieldSetID}", func(r chi.Router) {
+			r.Use(app.customFieldSetContextMiddleware)
+			r.Get("/", app.getCustomFieldSetHandler)
+
+			r.Patch("/", app.updateCustomFieldSetHandler)
+			r.Delete("/", app.deleteCustomFieldSetHandler)
 		})
 	})
 
@@ -158,18 +246,333 @@ func (app *application) mount() http.Handler {
 
 		r.Route("/{assetID}", func(r chi.Router) {
 			r.Use(app.assetContextMiddleware)
-			r.Get("/", app.getAssetHandler)
+			r.With(app.OptionalAuthTokenMiddleware).Get("/", app.getAssetHandler)
 
 			r.Patch("/", app.updateAssetHandler)
 			r.Delete("/", app.deleteAssetHandler)
 
 			r.Post("/checkout", app.checkoutAssetHandler)
 			r.Post("/checkin", app.checkinAssetHandler)
+
+			r.Get("/procurement", app.getAssetProcurementHandler)
+
+			r.Get("/relations", app.getAssetRelationsHandler)
+			r.With(app.AuthTokenMiddleware).Post("/relations", app.createAssetRelationHandler)
+			r.With(app.AuthTokenMiddleware).Delete("/relations/{relationID}", app.deleteAssetRelationHandler)
+			r.Get("/subtree", app.getAssetSubtreeHandler)
+			r.Get("/dependents", app.getAssetDependentsHandler)
+
+			r.Route("/attachments", app.attachmentRoutes)
+		})
+	})
+
+	r.Route("/api/maintenances", func(r chi.Router) {
+		r.Use(app.AuthTokenMiddleware)
+		r.Get("/", app.getAllMaintenanceHandler)
+		r.Post("/", app.createMaintenanceHandler)
+
+		r.Route("/{maintenanceID}", func(r chi.Router) {
+			r.Use(app.maintenanceContextMiddleware)
+			r.Get("/", app.getMaintenanceHandler)
+
+			r.Patch("/", app.updateMaintenanceHandler)
+			r.Delete("/", app.deleteMaintenanceHandler)
+
+			r.Route("/attachments", app.attachmentRoutes)
 		})
 	})
 
+	r.Route("/api/maintenance-plans", func(r chi.Router) {
+		r.Use(app.AuthTokenMiddleware)
+		r.Get("/", app.getAllMaintenancePlanHandler)
+		r.Post("/", app.createMaintenancePlanHandler)
+		r.Get("/due", app.getMaintenanceDueHandler)
+
+		r.Route("/{planID}", func(r chi.Router) {
+			r.Use(app.maintenancePlanContextMiddleware)
+			r.Get("/", app.getMaintenancePlanHandler)
+
+			r.Patch("/", app.updateMaintenancePlanHandler)
+			r.Delete("/", app.deleteMaintenancePlanHandler)
+		})
+	})
+
+	r.Route("/api/notifications", func(r chi.Router) {
+		r.Use(app.AuthTokenMiddleware)
+		r.Get("/", app.getAllNotificationHandler)
+		r.Post("/read", app.readAllNotificationHandler)
+		r.Post("/{notificationID}/read", app.readNotificationHandler)
+	})
+
+	r.Route("/api/purchase-orders", func(r chi.Router) {
+		r.Use(app.AuthTokenMiddleware)
+		r.Get("/", app.getAllPurchaseOrderHandler)
+		r.Post("/", app.createPurchaseOrderHandler)
+
+		r.Route("/{purchaseOrderID}", func(r chi.Router) {
+			r.Use(app.purchaseOrderContextMiddleware)
+			r.Get("/", app.getPurchaseOrderHandler)
+
+			r.Patch("/", app.updatePurchaseOrderHandler)
+			r.Delete("/", app.deletePurchaseOrderHandler)
+
+			r.Post("/receive", app.receivePurchaseOrderHandler)
+			r.Post("/invoices", app.createInvoiceHandler)
+
+			r.Route("/invoices/{invoiceID}", func(r chi.Router) {
+				r.Use(app.invoiceContextMiddleware)
+				r.Delete("/", app.deleteInvoiceHandler)
+
+				r.Route("/attachments", app.attachmentRoutes)
+			})
+		})
+	})
+
+	r.Route("/api/warranties", func(r chi.Router) {
+		r.Use(app.AuthTokenMiddleware)
+		r.Get("/", app.getAllWarrantyHandler)
+		r.Post("/", app.createWarrantyHandler)
+
+		r.Route("/{warrantyID}", func(r chi.Router) {
+			r.Use(app.warrantyContextMiddleware)
+			r.Get("/", app.getWarrantyHandler)
+
+			r.Patch("/", app.updateWarrantyHandler)
+			r.Delete("/", app.deleteWarrantyHandler)
+		})
+	})
+
+	r.Route("/api/consumables", func(r chi.Router) {
+		r.Use(app.AuthTokenMiddleware)
+		r.Get("/", app.getAllConsumableHandler)
+		r.Post("/", app.createConsumableHandler)
+		r.Get("/low-stock", app.getLowStockHandler)
+
+		r.Route("/{consumableID}", func(r chi.Router) {
+			r.Use(app.consumableContextMiddleware)
+			r.Get("/", app.getConsumableHandler)
+
+			r.Patch("/", app.updateConsumableHandler)
+			r.Delete("/", app.deleteConsumableHandler)
+
+			r.Put("/stock", app.setConsumableStockHandler)
+			r.Post("/issue", app.issueConsumableHandler)
+			r.Post("/receive", app.receiveConsumableHandler)
+			r.Get("/movements", app.getConsumableMovementsHandler)
+		})
+	})
+
+	r.Route("/api/licenses", func(r chi.Router) {
+		r.Use(app.AuthTokenMiddleware)
+		r.Get("/", app.getAllLicenseHandler)
+		r.Post("/", app.createLicenseHandler)
+
+		r.Route("/{licenseID}", func(r chi.Router) {
+			r.Use(app.licenseContextMiddleware)
+			r.Get("/", app.getLicenseHandler)
+
+			r.Patch("/", app.updateLicenseHandler)
+			r.Delete("/", app.deleteLicenseHandler)
+
+			r.Get("/seats", app.getLicenseSeatsHandler)
+			r.Post("/checkout", app.checkoutLicenseHandler)
+			r.Post("/seats/{seatID}/checkin", app.checkinLicenseHandler)
+		})
+	})
+
+	r.Route("/api/accessories", func(r chi.Router) {
+		r.Use(app.AuthTokenMiddleware)
+		r.Get("/", app.getAllAccessoryHandler)
+		r.Post("/", app.createAccessoryHandler)
+
+		r.Route("/{accessoryID}", func(r chi.Router) {
+			r.Use(app.accessoryContextMiddleware)
+			r.Get("/", app.getAccessoryHandler)
+
+			r.Patch("/", app.updateAccessoryHandler)
+			r.Delete("/", app.deleteAccessoryHandler)
+
+			r.Put("/stock", app.setAccessoryStockHandler)
+			r.Get("/loans", app.getAccessoryLoansHandler)
+			r.Post("/checkout", app.checkoutAccessoryHandler)
+			r.Post("/loans/{loanID}/checkin", app.checkinAccessoryHandler)
+		})
+	})
+
+	r.Route("/api/components", func(r chi.Router) {
+		r.Use(app.AuthTokenMiddleware)
+		r.Get("/", app.getAllComponentHandler)
+		r.Post("/", app.createComponentHandler)
+
+		r.Route("/{componentID}", func(r chi.Router) {
+			r.Use(app.componentContextMiddleware)
+			r.Get("/", app.getComponentHandler)
+
+			r.Patch("/", app.updateComponentHandler)
+			r.Delete("/", app.deleteComponentHandler)
+
+			r.Get("/installs", app.getComponentInstallsHandler)
+			r.Post("/install", app.installComponentHandler)
+			r.Post("/installs/{installID}/remove", app.removeComponentHandler)
+		})
+	})
+
+	r.Route("/api/kits", func(r chi.Router) {
+		r.Use(app.AuthTokenMiddleware)
+		r.Get("/", app.getAllKitHandler)
+		r.Post("/", app.createKitHandler)
+
+		r.Route("/{kitID}", func(r chi.Router) {
+			r.Use(app.kitContextMiddleware)
+			r.Get("/", app.getKitHandler)
+
+			r.Patch("/", app.updateKitHandler)
+			r.Delete("/", app.deleteKitHandler)
+
+			r.Post("/checkout", app.checkoutKitHandler)
+		})
+	})
+
+	r.Route("/api/reservations", func(r chi.Router) {
+		r.Use(app.AuthTokenMiddleware)
+		r.Get("/", app.getAllReservationHandler)
+		r.Post("/", app.createReservationHandler)
+
+		r.Route("/{reservationID}", func(r chi.Router) {
+			r.Use(app.reservationContextMiddleware)
+			r.Get("/", app.getReservationHandler)
+
+			r.Post("/pickup", app.pickupReservationHandler)
+			r.Post("/cancel", app.cancelReservationHandler)
+		})
+	})
+
+	r.Route("/api/approval-steps", func(r chi.Router) {
+		r.Use(app.AuthTokenMiddleware)
+		r.Get("/", app.getAllApprovalStepHandler)
+		r.With(app.RequireRoleMiddleware(approvalAdminRole)).Post("/", app.createApprovalStepHandler)
+
+		r.Route("/{stepID}", func(r chi.Router) {
+			r.Use(app.RequireRoleMiddleware(approvalAdminRole))
+			r.Use(app.approvalStepContextMiddleware)
+			r.Patch("/", app.updateApprovalStepHandler)
+			r.Delete("/", app.deleteApprovalStepHandler)
+		})
+	})
+
+	r.Route("/api/asset-requests", func(r chi.Router) {
+		r.Use(app.AuthTokenMiddleware)
+		r.Get("/", app.getAllAssetRequestHandler)
+		r.Post("/", app.createAssetRequestHandler)
+		r.Get("/catalog", app.getRequestCatalogHandler)
+
+		r.Route("/{requestID}", func(r chi.Router) {
+			r.Use(app.assetRequestContextMiddleware)
+			r.Get("/", app.getAssetRequestHandler)
+
+			r.Post("/approve", app.approveAssetRequestHandler)
+			r.Post("/reject", app.rejectAssetRequestHandler)
+			r.Post("/cancel", app.cancelAssetRequestHandler)
+			r.Post("/fulfill", app.fulfillAssetRequestHandler)
+		})
+	})
+
+	r.Route("/api/offboardings", func(r chi.Router) {
+		r.Use(app.AuthTokenMiddleware)
+		r.Get("/", app.getAllOffboardingHandler)
+
+		r.Route("/{offboardingID}", func(r chi.Router) {
+			r.Use(app.offboardingContextMiddleware)
+			r.Get("/", app.getOffboardingHandler)
+			r.Get("/checklist", app.getOffboardingChecklistHandler)
+
+			r.Post("/cancel", app.cancelOffboardingHandler)
+			r.Post("/items/{itemID}/recover", app.recoverOffboardingItemHandler)
+			r.Post("/items/{itemID}/write-off", app.writeOffOffboardingItemHandler)
+		})
+	})
+
+	r.Route("/api/disposals", func(r chi.Router) {
+		r.Use(app.AuthTokenMiddleware)
+		r.Get("/", app.getAllDisposalHandler)
+		r.Post("/", app.createDisposalHandler)
+
+		r.Route("/{disposalID}", func(r chi.Router) {
+			r.Use(app.disposalContextMiddleware)
+			r.Get("/", app.getDisposalHandler)
+
+			r.Post("/approve", app.approveDisposalHandler)
+			r.Post("/reject", app.rejectDisposalHandler)
+			r.Post("/data-wipe", app.confirmDataWipeHandler)
+			r.Post("/certificates", app.uploadDisposalCertificateHandler)
+			r.Get("/certificates/{certificateID}", app.getDisposalCertificateHandler)
+			r.Post("/complete", app.completeDisposalHandler)
+			r.Post("/cancel", app.cancelDisposalHandler)
+		})
+	})
+
+	r.Route("/api/reports", func(r chi.Router) {
+		r.Use(app.AuthTokenMiddleware)
+		r.Get("/maintenance-costs", app.getMaintenanceCostsHandler)
+		r.Get("/warranty-expiring", app.getWarrantyExpiringHandler)
+		r.Get("/consumable-consumption", app.getConsumptionHandler)
+		r.Get("/license-compliance", app.getLicenseComplianceHandler)
+		r.Get("/pending-acceptances", app.getPendingAcceptancesHandler)
+		r.Get("/disposals", app.getDisposalRegisterHandler)
+		r.Get("/disposals/summary", app.getDisposalSummaryHandler)
+		r.Get("/end-of-support", app.getEndOfSupportHandler)
+	})
+
+	r.Route("/api/webhooks", func(r chi.Router) {
+		r.Use(app.AuthTokenMiddleware)
+		r.Use(app.RequireRoleMiddleware(webhookAdminRole))
+		r.Get("/", app.getAllWebhookHandler)
+		r.Post("/", app.createWebhookHandler)
+		r.Get("/event-types", app.getEventTypesHandler)
+
+		r.Route("/{webhookID}", func(r chi.Router) {
+			r.Use(app.webhookContextMiddleware)
+			r.Get("/", app.getWebhookHandler)
+			r.Patch("/", app.updateWebhookHandler)
+			r.Delete("/", app.deleteWebhookHandler)
+
+			r.Get("/deliveries", app.getWebhookDeliveriesHandler)
+			r.Get("/deliveries/{deliveryID}", app.getWebhookDeliveryHandler)
+			r.Post("/deliveries/{deliveryID}/redeliver", app.redeliverWebhookHandler)
+		})
+	})
+
+	r.Route("/api/events", func(r chi.Router) {
+		r.With(app.AuthTokenMiddleware).Post("/stream-token", app.createEventStreamTokenHandler)
+		r.With(app.eventStreamAuthMiddleware).Get("/stream", app.eventStreamHandler)
+	})
+
+	r.Route("/api/search", func(r chi.Router) {
+		r.Use(app.AuthTokenMiddleware)
+		r.Get("/", app.searchHandler)
+	})
+
+	r.Route("/api/loans", func(r chi.Router) {
+		r.Use(app.AuthTokenMiddleware)
+		r.Get("/", app.getAllAssetLoanHandler)
+	})
+
+	r.Route("/api/audit-logs", func(r chi.Router) {
+		r.Use(app.AuthTokenMiddleware)
+		r.Get("/", app.getAllAuditLogHandler)
+	})
+
 	r.Route("/asset-assignments", func(r chi.Router) {
-		r.Post("/", app.CreateAssetAssignmentHandler)
+		r.Use(app.AuthTokenMiddleware)
+		r.With(app.RequireRoleMiddleware(assignmentAdminRole)).Post("/", app.CreateAssetAssignmentHandler)
+
+		r.Route("/{assignmentID}", func(r chi.Router) {
+			r.Use(app.assetAssignmentContextMiddleware)
+			r.Get("/", app.getAssetAssignmentHandler)
+
+			r.Post("/accept", app.acceptAssetAssignmentHandler)
+			r.Get("/receipt", app.getAssetAssignmentReceiptHandler)
+		})
 	})
 
 	r.Route("/api/profile", func(r chi.Router) {
@@ -180,6 +583,9 @@ func (app *application) mount() http.Handler {
 	r.Route("/api/me", func(r chi.Router) {
 		r.Use(app.AuthTokenMiddleware)
 		r.Get("/", app.meDetailsHandler)
+		r.Get("/items", app.getHeldItemsHandler)
+		r.Get("/asset-requests", app.getMyAssetRequestsHandler)
+		r.Get("/approvals", app.getMyApprovalsHandler)
 	})
 
 	r.Route("/api/users", func(r chi.Router) {
@@ -187,12 +593,18 @@ func (app *application) mount() http.Handler {
 		r.Get("/", app.getAllUserHandler)
 		// r.Post("/", app.createAssetHandler)
 
+		// outside the subroute: its middleware replaces the signed in user
+		r.Post("/{userID}/offboard", app.offboardUserHandler)
+		r.With(app.RequireRoleMiddleware(userAdminRole)).Put("/{userID}/department", app.setUserDepartmentHandler)
+
 		r.Route("/{userID}", func(r chi.Router) {
 			r.Use(app.userContextMiddleware)
 			// r.Get("/", app.getAssetHandler)
 
 			r.Patch("/", app.updateUserHandler)
 			// r.Delete("/", app.deleteAssetHandler)
+
+			r.Get("/items", app.getHeldItemsHandler)
 		})
 	})
 
@@ -202,6 +614,9 @@ func (app *application) mount() http.Handler {
 		r.Post("/token", app.createTokenHandler)
 	})
 
+	// signed download links, checked by the handler
+	r.Get("/api/files/{attachmentID}", app.downloadAttachmentHandler)
+
 	return r
 }
 
@@ -213,6 +628,11 @@ func (app *application) run(mux http.Handler) error {
 		ReadTimeout:  time.Second * 10,
 		IdleTimeout:  time.Minute,
 	}
+	srv.RegisterOnShutdown(app.events.close)
+
+	jobsCtx, stopJobs := context.WithCancel(context.Background())
+	defer stopJobs()
+	waitJobs := app.startJobs(jobsCtx, app.jobs())
 
 	shutdown := make(chan error)
 
@@ -227,7 +647,11 @@ func (app *application) run(mux http.Handler) error {
 
 		app.logger.Infow("signal caught", "signal", s.String())
 
-		shutdown <- srv.Shutdown(ctx)
+		stopJobs()
+		err := srv.Shutdown(ctx)
+		waitJobs()
+
+		shutdown <- err
 	}()
 
 	app.logger.Infow("server has started", "addr", app.config.addr, "env", app.config.env)
@@ -4,12 +4,15 @@ import (
 	"context"
 	"encoding/json"
 	"errors"
+	"fmt"
 	"net/http"
 	"strconv"
+	"time"
 
 	"github.com/go-chi/chi/v5"
 	"github.com/knr1997/assets-management-apiserver/internal/api/requests"
 	"github.com/knr1997/assets-management-apiserver/internal/api/responses"
+	"github.com/knr1997/assets-management-apiserver/internal/secret"
 	"github.com/knr1997/assets-management-apiserver/internal/store"
 )
 
@@ -67,16 +70,44 @@ func (app *application) createAssetHandler(w http.ResponseWriter, r *http.Reques
 		SerialNumber: payload.SerialNumber,
 		Description:  payload.Description,
 		ModelID:      payload.ModelID,
+
+		DepreciationMethod: store.DepreciationMethod(payload.DepreciationMethod),
+	}
+	if payload.UsefulLifeYears != nil {
+		asset.UsefulLifeYears = *payload.UsefulLifeYears
 	}
 
 	ctx := r.Context()
 
+	model, err := app.store.Model.GetByID(ctx, payload.ModelID)
+	if err != nil {
+		switch {
+		case errors.Is(err, store.ErrNotFound):
+			app.badRequestResponse(w, r, errors.New("model not found"))
+		default:
+			app.internalServerError(w, r, err)
+		}
+		return
+	}
+	asset.InheritModelDefaults(model)
+
+	if err := app.resolveCustomFields(ctx, asset, payload.CustomFields); err != nil {
+		app.customFieldErrorResponse(w, r, err)
+		return
+	}
+
 	if err := app.store.Asset.Create(ctx, asset); err != nil {
+		app.customFieldErrorResponse(w, r, err)
+		return
+	}
+
+	asset, err = app.store.Asset.GetByID(ctx, asset.ID)
+	if err != nil {
 		app.internalServerError(w, r, err)
 		return
 	}
 
-	if err := app.jsonResponse(w, http.StatusCreated, asset); err != nil {
+	if err := app.jsonResponse(w, http.StatusCreated, responses.NewAssetResponse(asset)); err != nil {
 		app.internalServerError(w, r, err)
 		return
 	}
@@ -119,14 +150,32 @@ func (app *application) updateAssetHandler(w http.ResponseWriter, r *http.Reques
 	if payload.Description != nil {
 		asset.Description = *payload.Description
 	}
+	if payload.UsefulLifeYears != nil {
+		asset.UsefulLifeYears = *payload.UsefulLifeYears
+	}
+	if payload.DepreciationMethod != nil {
+		asset.DepreciationMethod = store.DepreciationMethod(*payload.DepreciationMethod)
+	}
 
 	ctx := r.Context()
 
+	if err := app.resolveCustomFields(ctx, asset, payload.CustomFields); err != nil {
+		app.customFieldErrorResponse(w, r, err)
+		return
+	}
+
 	if err := app.updateAsset(ctx, asset); err != nil {
+		app.customFieldErrorResponse(w, r, err)
+		return
+	}
+
+	asset, err := app.store.Asset.GetByID(ctx, asset.ID)
+	if err != nil {
 		app.internalServerError(w, r, err)
+		return
 	}
 
-	if err := app.jsonResponse(w, http.StatusOK, asset); err != nil {
+	if err := app.jsonResponse(w, http.StatusOK, responses.NewAssetResponse(asset)); err != nil {
 		app.internalServerError(w, r, err)
 	}
 }
@@ -135,6 +184,10 @@ func (app *application) getAssetHandler(w http.ResponseWriter, r *http.Request)
 	asset := getAssetFromCtx(r)
 
 	response := responses.NewAssetResponse(asset)
+	if err := app.revealCustomFields(r, asset, &response); err != nil {
+		app.internalServerError(w, r, err)
+		return
+	}
 
 	if err := app.jsonResponse(w, http.StatusOK, response); err != nil {
 		app.internalServerError(w, r, err)
@@ -142,19 +195,44 @@ func (app *application) getAssetHandler(w http.ResponseWriter, r *http.Request)
 	}
 }
 
+var assetExportColumns = []exportColumn[store.Asset]{
+	{"id", func(a *store.Asset) any { return a.ID }},
+	{"name", func(a *store.Asset) any { return a.Name }},
+	{"tag", func(a *store.Asset) any { return a.Tag }},
+	{"serialNumber", func(a *store.Asset) any { return a.SerialNumber }},
+	{"description", func(a *store.Asset) any { return a.Description }},
+	{"status", func(a *store.Asset) any { return string(a.Status) }},
+	{"modelId", func(a *store.Asset) any { return a.ModelID }},
+	{"model", func(a *store.Asset) any { return a.Model.Name }},
+	{"location", func(a *store.Asset) any { return a.Location }},
+	{"purchaseDate", func(a *store.Asset) any { return a.PurchaseDate }},
+	{"purchaseCost", func(a *store.Asset) any { return a.PurchaseCost }},
+	{"purchaseCurrency", func(a *store.Asset) any { return a.PurchaseCurrency }},
+	{"usefulLifeYears", func(a *store.Asset) any { return a.UsefulLifeYears }},
+	{"depreciationMethod", func(a *store.Asset) any { return string(a.DepreciationMethod) }},
+	{"salvageValue", func(a *store.Asset) any { return a.SalvageValue }},
+	{"customFields", func(a *store.Asset) any { return customFieldsExportValue(a.CustomFields) }},
+	{"createdAt", func(a *store.Asset) any { return a.CreatedAt }},
+	{"updatedAt", func(a *store.Asset) any { return a.UpdatedAt }},
+}
+
 func (app *application) getAllAssetHandler(w http.ResponseWriter, r *http.Request) {
-	ctx := r.Context()
+	spec, ok := app.parseQuerySpec(w, r)
+	if !ok {
+		return
+	}
 
-	assets, err := app.store.Asset.GetAll(ctx)
+	format, ok, err := requestedExportFormat(r)
 	if err != nil {
-		app.internalServerError(w, r, err)
+		app.badRequestResponse(w, r, err)
+		return
+	}
+	if ok {
+		streamExport(app, w, r, "assets", format, spec, assetExportColumns, app.store.Asset.Stream)
 		return
 	}
 
-	response := responses.NewAssetsResponse(assets)
-
-	w.Header().Set("Content-Type", "application/json")
-	json.NewEncoder(w).Encode(response)
+	writeListPage(app, w, r, spec, app.store.Asset.List, responses.NewAssetsResponse)
 }
 
 func (app *application) deleteAssetHandler(w http.ResponseWriter, r *http.Request) {
@@ -202,26 +280,68 @@ func (app *application) checkoutAssetHandler(w http.ResponseWriter, r *http.Requ
 
 	ctx := r.Context()
 
-	assetLoan := &store.AssetLoan{
+	// the model's loan period sets the due date when none is given
+	due := payload.ExpectedCheckinDate
+	if due == nil && asset.Model.DefaultLoanDays > 0 {
+		d := payload.CheckoutDate.AddDate(0, 0, asset.Model.DefaultLoanDays)
+		due = &d
+	}
+
+	loans := []store.AssetLoan{{
 		AssetName:           payload.AssetName,
-		AssetID:             payload.AssetID,
+		AssetID:             asset.ID,
 		UserID:              payload.UserID,
 		CheckoutDate:        payload.CheckoutDate,
-		ExpectedCheckinDate: payload.ExpectedCheckinDate,
+		ExpectedCheckinDate: due,
 		Status:              store.AssetPending,
 		Notes:               payload.Notes,
+	}}
+
+	if payload.Cascade {
+		contained, err := app.store.AssetRelation.Walk(ctx, asset.ID, []store.AssetRelationType{store.RelationContains}, false)
+		if err != nil {
+			app.internalServerError(w, r, err)
+			return
+		}
+
+		for _, relation := range contained {
+			child := relation.ToAsset
+			if child.Status != store.AssetAvailable {
+				continue
+			}
+
+			loans = append(loans, store.AssetLoan{
+				AssetName:           child.Name,
+				AssetID:             child.ID,
+				UserID:              payload.UserID,
+				CheckoutDate:        payload.CheckoutDate,
+				ExpectedCheckinDate: due,
+				Status:              store.AssetPending,
+				Notes:               payload.Notes,
+			})
+		}
 	}
 
-	if err := app.store.AssetLoan.Create(ctx, assetLoan); err != nil {
-		app.internalServerError(w, r, err)
+	if err := app.store.AssetLoan.Checkout(ctx, loans); err != nil {
+		switch {
+		case errors.Is(err, store.ErrAssetNotAvailable):
+			app.conflictResponse(w, r, errors.New("an asset was checked out meanwhile, try again"))
+		case errors.Is(err, store.ErrAssetReserved):
+			app.conflictResponse(w, r, errors.New("an asset is reserved by another user while it would be out"))
+		default:
+			app.internalServerError(w, r, err)
+		}
+		return
 	}
 
-	if err := app.store.Asset.UpdateStatus(ctx, asset.ID, store.AssetAssigned); err != nil {
-		app.internalServerError(w, r, err)
+	assetIDs := make([]int64, len(loans))
+	for i, loan := range loans {
+		assetIDs[i] = loan.AssetID
 	}
 
-	app.jsonResponse(w, http.StatusCreated, map[string]string{
+	app.jsonResponse(w, http.StatusCreated, map[string]any{
 		"status": "asset assigned successfully",
+		"assets": assetIDs,
 	})
 }
 
@@ -241,8 +361,29 @@ func (app *application) checkinAssetHandler(w http.ResponseWriter, r *http.Reque
 
 	ctx := r.Context()
 
-	if err := app.store.Asset.UpdateStatus(ctx, asset.ID, store.AssetStatus(payload.Status)); err != nil {
-		app.internalServerError(w, r, err)
+	var contents []int64
+	if payload.Cascade {
+		contained, err := app.store.AssetRelation.Walk(ctx, asset.ID, []store.AssetRelationType{store.RelationContains}, false)
+		if err != nil {
+			app.internalServerError(w, r, err)
+			return
+		}
+
+		for _, relation := range contained {
+			if relation.ToAsset.Status == store.AssetAssigned {
+				contents = append(contents, relation.ToAssetID)
+			}
+		}
+	}
+
+	if err := app.store.AssetLoan.Checkin(ctx, asset.ID, contents, store.AssetStatus(payload.Status), payload.CheckinDate); err != nil {
+		switch {
+		case errors.Is(err, store.ErrNotFound):
+			app.notFoundResponse(w, r, err)
+		default:
+			app.internalServerError(w, r, err)
+		}
+		return
 	}
 
 	// if err := app.store.AssetLoan.UpdateStatus(ctx, asset.ID, store.AssetReadyToDeploy); err != nil {
@@ -253,3 +394,46 @@ func (app *application) checkinAssetHandler(w http.ResponseWriter, r *http.Reque
 		"status": "asset checkin successfully",
 	})
 }
+
+// customFieldsExportValue renders custom fields as a JSON object with
+// encrypted values left out.
+func customFieldsExportValue(values store.CustomFieldValues) string {
+	visible := make(map[string]any, len(values))
+	for k, v := range values {
+		if s, ok := v.(string); ok && secret.IsSealed(s) {
+			continue
+		}
+		visible[k] = v
+	}
+
+	b, _ := json.Marshal(visible)
+	return string(b)
+}
+
+const maxEndOfSupportDays = 730
+
+// getEndOfSupportHandler lists the assets in service whose model is out of
+// vendor support, or will be within ?days.
+func (app *application) getEndOfSupportHandler(w http.ResponseWriter, r *http.Request) {
+	spec, ok := app.parseQuerySpec(w, r)
+	if !ok {
+		return
+	}
+
+	days := 0
+	if param := r.URL.Query().Get("days"); param != "" {
+		n, err := strconv.Atoi(param)
+		if err != nil || n < 0 || n > maxEndOfSupportDays {
+			app.badRequestResponse(w, r, fmt.Errorf("days must be between 0 and %d", maxEndOfSupportDays))
+			return
+		}
+		days = n
+	}
+	cutoff := time.Now().AddDate(0, 0, days)
+
+	list := func(ctx context.Context, spec store.QuerySpec) (*store.Pagination, error) {
+		return app.store.Asset.ListPastEndOfSupport(ctx, spec, cutoff)
+	}
+
+	writeListPage(app, w, r, spec, list, responses.NewAssetsResponse)
+}
@@ -1,11 +1,86 @@
 package main
 
 import (
+	"bytes"
+	"context"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"image"
+	"image/png"
+	"net"
 	"net/http"
+	"strconv"
+	"strings"
+	"time"
 
+	"github.com/go-chi/chi/v5"
+	"github.com/knr1997/assets-management-apiserver/internal/api/requests"
+	"github.com/knr1997/assets-management-apiserver/internal/api/responses"
+	"github.com/knr1997/assets-management-apiserver/internal/pdf"
 	"github.com/knr1997/assets-management-apiserver/internal/store"
 )
 
+const (
+	maxSignatureBytes  = 256 << 10
+	maxSignatureWidth  = 2000
+	maxSignatureHeight = 1000
+
+	// assignmentAdminRole assigns assets and sees every assignment, other
+	// users see their own
+	assignmentAdminRole = "admin"
+)
+
+type assetAssignmentKey string
+
+const assetAssignmentCtx assetAssignmentKey = "assetAssignment"
+
+func getAssetAssignmentFromCtx(r *http.Request) *store.AssetAssignment {
+	assignment, _ := r.Context().Value(assetAssignmentCtx).(*store.AssetAssignment)
+	return assignment
+}
+
+// assetAssignmentContextMiddleware loads the assignment in the URL for the
+// user it is assigned to or an admin.
+func (app *application) assetAssignmentContextMiddleware(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		idParam := chi.URLParam(r, "assignmentID")
+		id, err := strconv.ParseInt(idParam, 10, 64)
+		if err != nil {
+			app.internalServerError(w, r, err)
+			return
+		}
+
+		ctx := r.Context()
+
+		assignment, err := app.store.AssetAssignment.GetByID(ctx, id)
+		if err != nil {
+			switch {
+			case errors.Is(err, store.ErrNotFound):
+				app.notFoundResponse(w, r, err)
+			default:
+				app.internalServerError(w, r, err)
+			}
+			return
+		}
+
+		if user := getUserFromContext(r); assignment.UserID != user.ID {
+			admin, err := app.hasRole(ctx, user, assignmentAdminRole)
+			if err != nil {
+				app.internalServerError(w, r, err)
+				return
+			}
+			if !admin {
+				app.forbiddenResponse(w, r)
+				return
+			}
+		}
+
+		ctx = context.WithValue(ctx, assetAssignmentCtx, assignment)
+		next.ServeHTTP(w, r.WithContext(ctx))
+	})
+}
+
 type CreateAssetAssignmentPayload struct {
 	AssetID int64 `json:"assetID" validate:"required"`
 	UserID  int64 `json:"userID" validate:"required"`
@@ -24,8 +99,9 @@ func (app *application) CreateAssetAssignmentHandler(w http.ResponseWriter, r *h
 	}
 
 	asset_assignment := &store.AssetAssignment{
-		AssetID: payload.AssetID,
-		UserID:  payload.UserID,
+		AssetID:    payload.AssetID,
+		UserID:     payload.UserID,
+		AssignedAt: time.Now(),
 	}
 
 	ctx := r.Context()
@@ -45,8 +121,234 @@ func (app *application) CreateAssetAssignmentHandler(w http.ResponseWriter, r *h
 
 	app.updateAsset(ctx, asset)
 
+	notification := store.Notification{
+		UserID:  payload.UserID,
+		Kind:    "assignment_acceptance",
+		Title:   "Please confirm your new asset",
+		Body:    fmt.Sprintf("%s was assigned to you. Confirm you received it and accept its usage policy.", asset.Name),
+		AssetID: &asset.ID,
+	}
+	if err := app.store.Notification.Create(ctx, []store.Notification{notification}); err != nil {
+		app.logger.Errorw("assignment notification failed", "assignment", asset_assignment.ID, "error", err.Error())
+	}
+
 	if err := app.jsonResponse(w, http.StatusCreated, asset_assignment); err != nil {
 		app.internalServerError(w, r, err)
 		return
 	}
 }
+
+func (app *application) getAssetAssignmentHandler(w http.ResponseWriter, r *http.Request) {
+	assignment := getAssetAssignmentFromCtx(r)
+
+	if err := app.jsonResponse(w, http.StatusOK, responses.NewAssetAssignmentResponse(assignment)); err != nil {
+		app.internalServerError(w, r, err)
+		return
+	}
+}
+
+// acceptAssetAssignmentHandler lets the user an asset was assigned to
+// confirm they received it and accept the usage policy of its category. A
+// PDF receipt of the handover is kept with the assignment.
+func (app *application) acceptAssetAssignmentHandler(w http.ResponseWriter, r *http.Request) {
+	user := getUserFromContext(r)
+	assignment := getAssetAssignmentFromCtx(r)
+
+	if assignment.UserID != user.ID {
+		app.forbiddenResponse(w, r)
+		return
+	}
+
+	var payload requests.AcceptAssetAssignmentPayload
+	if err := readJSON(w, r, &payload); err != nil {
+		app.badRequestResponse(w, r, err)
+		return
+	}
+
+	if err := Validate.Struct(payload); err != nil {
+		app.badRequestResponse(w, r, err)
+		return
+	}
+
+	eula := assignment.Asset.Model.Category.EULA
+	if eula != "" && !payload.AcceptEULA {
+		app.badRequestResponse(w, r, errors.New("the usage policy must be accepted"))
+		return
+	}
+
+	switch {
+	case assignment.AcceptanceStatus != store.AcceptancePending:
+		app.conflictResponse(w, r, store.ErrAlreadyAccepted)
+		return
+	case assignment.ReturnedAt != nil:
+		app.conflictResponse(w, r, errors.New("the asset was already returned"))
+		return
+	}
+
+	var signature []byte
+	var signatureImage image.Image
+	if payload.Signature != "" {
+		var err error
+		signature, signatureImage, err = decodeSignature(payload.Signature)
+		if err != nil {
+			app.badRequestResponse(w, r, err)
+			return
+		}
+	}
+
+	acceptance := &store.AssignmentAcceptance{
+		AcceptedAt: time.Now(),
+		Name:       payload.Name,
+		Signature:  signature,
+		IP:         clientIP(r),
+		EULA:       eula,
+	}
+
+	content, err := handoverReceipt(assignment, acceptance, signatureImage)
+	if err != nil {
+		app.internalServerError(w, r, err)
+		return
+	}
+
+	receipt := store.NewAttachment(store.AttachmentOwnerAssignment, assignment.ID,
+		fmt.Sprintf("handover-receipt-%d.pdf", assignment.ID), "application/pdf", content)
+	receipt.UploadedByID = &user.ID
+
+	ctx := r.Context()
+
+	if err := app.store.AssetAssignment.Accept(ctx, assignment, acceptance, receipt); err != nil {
+		switch {
+		case errors.Is(err, store.ErrAlreadyAccepted):
+			app.conflictResponse(w, r, err)
+		default:
+			app.internalServerError(w, r, err)
+		}
+		return
+	}
+
+	assignment, err = app.store.AssetAssignment.GetByID(ctx, assignment.ID)
+	if err != nil {
+		app.internalServerError(w, r, err)
+		return
+	}
+
+	if err := app.jsonResponse(w, http.StatusOK, responses.NewAssetAssignmentResponse(assignment)); err != nil {
+		app.internalServerError(w, r, err)
+		return
+	}
+}
+
+// getAssetAssignmentReceiptHandler downloads the handover receipt of an
+// accepted assignment.
+func (app *application) getAssetAssignmentReceiptHandler(w http.ResponseWriter, r *http.Request) {
+	assignment := getAssetAssignmentFromCtx(r)
+
+	if assignment.ReceiptID == nil {
+		app.notFoundResponse(w, r, errors.New("the assignment was not accepted yet"))
+		return
+	}
+
+	receipt, err := app.store.Attachment.GetByID(r.Context(), *assignment.ReceiptID)
+	if err != nil {
+		switch {
+		case errors.Is(err, store.ErrNotFound):
+			app.notFoundResponse(w, r, err)
+		default:
+			app.internalServerError(w, r, err)
+		}
+		return
+	}
+
+	app.writeAttachment(w, r, receipt)
+}
+
+// getPendingAcceptancesHandler lists the assets users hold but have not
+// confirmed receiving.
+func (app *application) getPendingAcceptancesHandler(w http.ResponseWriter, r *http.Request) {
+	spec, ok := app.parseQuerySpec(w, r)
+	if !ok {
+		return
+	}
+
+	writeListPage(app, w, r, spec, app.store.AssetAssignment.ListAwaitingAcceptance, responses.NewAssetAssignmentsResponse)
+}
+
+// decodeSignature reads a drawn signature sent as a PNG data URL.
+func decodeSignature(dataURL string) ([]byte, image.Image, error) {
+	data, err := base64.StdEncoding.DecodeString(strings.TrimPrefix(dataURL, "data:image/png;base64,"))
+	if err != nil {
+		return nil, nil, errors.New("signature is not valid base64")
+	}
+	if len(data) > maxSignatureBytes {
+		return nil, nil, fmt.Errorf("signature must be at most %d KB", maxSignatureBytes>>10)
+	}
+
+	config, err := png.DecodeConfig(bytes.NewReader(data))
+	if err != nil {
+		return nil, nil, errors.New("signature is not a PNG image")
+	}
+	if config.Width > maxSignatureWidth || config.Height > maxSignatureHeight {
+		return nil, nil, fmt.Errorf("signature must be at most %dx%d pixels", maxSignatureWidth, maxSignatureHeight)
+	}
+
+	img, err := png.Decode(bytes.NewReader(data))
+	if err != nil {
+		return nil, nil, errors.New("signature is not a PNG image")
+	}
+
+	return data, img, nil
+}
+
+// clientIP is the caller's address without the port. RealIP has already
+// taken it from the proxy headers when there are any.
+func clientIP(r *http.Request) string {
+	host, _, err := net.SplitHostPort(r.RemoteAddr)
+	if err != nil {
+		return r.RemoteAddr
+	}
+	return host
+}
+
+func handoverReceipt(assignment *store.AssetAssignment, acceptance *store.AssignmentAcceptance, signature image.Image) ([]byte, error) {
+	asset := &assignment.Asset
+
+	doc := pdf.New()
+	doc.Title("Asset handover receipt")
+	doc.Field("Receipt", fmt.Sprintf("Assignment #%d", assignment.ID))
+
+	doc.Heading("Asset")
+	doc.Field("Name", asset.Name)
+	doc.Field("Asset tag", asset.Tag)
+	doc.Field("Serial number", asset.SerialNumber)
+	doc.Field("Model", asset.Model.Name)
+	doc.Field("Category", asset.Model.Category.Name)
+
+	doc.Heading("Handed to")
+	doc.Field("User", assignment.User.Username)
+	doc.Field("Email", assignment.User.Email)
+	doc.Field("Assigned", assignment.AssignedAt.Format(time.RFC1123))
+
+	doc.Heading("Acknowledgement")
+	doc.Text("I confirm I received the asset above in working order and will return it when asked to.")
+	if acceptance.EULA != "" {
+		doc.Text("I have read and accept the usage policy below.")
+	}
+	doc.Gap()
+	doc.Field("Name", acceptance.Name)
+	doc.Field("Accepted", acceptance.AcceptedAt.Format(time.RFC1123))
+	doc.Field("IP address", acceptance.IP)
+
+	if signature != nil {
+		doc.Gap()
+		if err := doc.Image(signature, 180); err != nil {
+			return nil, err
+		}
+	}
+
+	if acceptance.EULA != "" {
+		doc.Heading("Usage policy")
+		doc.Text(acceptance.EULA)
+	}
+
+	return doc.Bytes(), nil
+}
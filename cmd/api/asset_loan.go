@@ -0,0 +1,41 @@
+package main
+
+import (
+	"net/http"
+
+	"github.com/knr1997/assets-management-apiserver/internal/api/responses"
+	"github.com/knr1997/assets-management-apiserver/internal/store"
+)
+
+var assetLoanExportColumns = []exportColumn[store.AssetLoan]{
+	{"id", func(l *store.AssetLoan) any { return l.ID }},
+	{"assetId", func(l *store.AssetLoan) any { return l.AssetID }},
+	{"assetTag", func(l *store.AssetLoan) any { return l.Asset.Tag }},
+	{"assetName", func(l *store.AssetLoan) any { return l.AssetName }},
+	{"userId", func(l *store.AssetLoan) any { return l.UserID }},
+	{"username", func(l *store.AssetLoan) any { return l.User.Username }},
+	{"checkoutDate", func(l *store.AssetLoan) any { return l.CheckoutDate }},
+	{"expectedCheckinDate", func(l *store.AssetLoan) any { return l.ExpectedCheckinDate }},
+	{"actualReturnDate", func(l *store.AssetLoan) any { return l.ActualReturnDate }},
+	{"status", func(l *store.AssetLoan) any { return string(l.Status) }},
+	{"notes", func(l *store.AssetLoan) any { return l.Notes }},
+}
+
+func (app *application) getAllAssetLoanHandler(w http.ResponseWriter, r *http.Request) {
+	spec, ok := app.parseQuerySpec(w, r)
+	if !ok {
+		return
+	}
+
+	format, ok, err := requestedExportFormat(r)
+	if err != nil {
+		app.badRequestResponse(w, r, err)
+		return
+	}
+	if ok {
+		streamExport(app, w, r, "loans", format, spec, assetLoanExportColumns, app.store.AssetLoan.Stream)
+		return
+	}
+
+	writeListPage(app, w, r, spec, app.store.AssetLoan.List, responses.NewAssetLoansResponse)
+}
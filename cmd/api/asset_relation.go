@@ -0,0 +1,185 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"slices"
+	"strconv"
+	"strings"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/knr1997/assets-management-apiserver/internal/api/requests"
+	"github.com/knr1997/assets-management-apiserver/internal/api/responses"
+	"github.com/knr1997/assets-management-apiserver/internal/store"
+)
+
+// parseRelationTypes reads the comma separated relation types of the type
+// query parameter.
+func parseRelationTypes(r *http.Request, fallback store.AssetRelationType) ([]store.AssetRelationType, error) {
+	param := r.URL.Query().Get("type")
+	if param == "" {
+		return []store.AssetRelationType{fallback}, nil
+	}
+
+	var types []store.AssetRelationType
+	for _, v := range strings.Split(param, ",") {
+		t := store.AssetRelationType(strings.ToUpper(strings.TrimSpace(v)))
+		if !slices.Contains(store.AssetRelationTypes, t) {
+			return nil, fmt.Errorf("unknown relation type %q", v)
+		}
+		types = append(types, t)
+	}
+
+	return types, nil
+}
+
+func (app *application) getAssetRelationsHandler(w http.ResponseWriter, r *http.Request) {
+	asset := getAssetFromCtx(r)
+
+	relations, err := app.store.AssetRelation.GetByAsset(r.Context(), asset.ID)
+	if err != nil {
+		app.internalServerError(w, r, err)
+		return
+	}
+
+	if err := app.jsonResponse(w, http.StatusOK, responses.NewAssetRelationsResponse(relations)); err != nil {
+		app.internalServerError(w, r, err)
+		return
+	}
+}
+
+func (app *application) createAssetRelationHandler(w http.ResponseWriter, r *http.Request) {
+	asset := getAssetFromCtx(r)
+
+	var payload requests.CreateAssetRelationPayload
+	if err := readJSON(w, r, &payload); err != nil {
+		app.badRequestResponse(w, r, err)
+		return
+	}
+
+	if err := Validate.Struct(payload); err != nil {
+		app.badRequestResponse(w, r, err)
+		return
+	}
+
+	if payload.ToAssetID == asset.ID {
+		app.badRequestResponse(w, r, errors.New("an asset cannot be related to itself"))
+		return
+	}
+
+	ctx := r.Context()
+
+	if _, err := app.store.Asset.GetByID(ctx, payload.ToAssetID); err != nil {
+		switch {
+		case errors.Is(err, store.ErrNotFound):
+			app.badRequestResponse(w, r, fmt.Errorf("asset %d does not exist", payload.ToAssetID))
+		default:
+			app.internalServerError(w, r, err)
+		}
+		return
+	}
+
+	relation := &store.AssetRelation{
+		FromAssetID: asset.ID,
+		ToAssetID:   payload.ToAssetID,
+		Type:        store.AssetRelationType(payload.Type),
+		Notes:       payload.Notes,
+	}
+	if user := getUserFromContext(r); user != nil {
+		relation.CreatedByID = &user.ID
+	}
+
+	if err := app.store.AssetRelation.Create(ctx, relation); err != nil {
+		switch {
+		case errors.Is(err, store.ErrRelationCycle):
+			app.conflictResponse(w, r, fmt.Errorf("asset %d already %s asset %d, directly or through others", payload.ToAssetID, strings.ToLower(payload.Type), asset.ID))
+		case errors.Is(err, store.ErrConflict):
+			app.conflictResponse(w, r, errors.New("the relation exists or the asset is already contained in another asset"))
+		default:
+			app.internalServerError(w, r, err)
+		}
+		return
+	}
+
+	relation, err := app.store.AssetRelation.GetByID(ctx, asset.ID, relation.ID)
+	if err != nil {
+		app.internalServerError(w, r, err)
+		return
+	}
+
+	if err := app.jsonResponse(w, http.StatusCreated, responses.NewAssetRelationResponse(relation)); err != nil {
+		app.internalServerError(w, r, err)
+		return
+	}
+}
+
+func (app *application) deleteAssetRelationHandler(w http.ResponseWriter, r *http.Request) {
+	asset := getAssetFromCtx(r)
+
+	relationID, err := strconv.ParseInt(chi.URLParam(r, "relationID"), 10, 64)
+	if err != nil {
+		app.internalServerError(w, r, err)
+		return
+	}
+
+	ctx := r.Context()
+
+	relation, err := app.store.AssetRelation.GetByID(ctx, asset.ID, relationID)
+	if err != nil {
+		switch {
+		case errors.Is(err, store.ErrNotFound):
+			app.notFoundResponse(w, r, err)
+		default:
+			app.internalServerError(w, r, err)
+		}
+		return
+	}
+
+	if err := app.store.AssetRelation.Delete(ctx, relation.ID); err != nil {
+		switch {
+		case errors.Is(err, store.ErrNotFound):
+			app.notFoundResponse(w, r, err)
+		default:
+			app.internalServerError(w, r, err)
+		}
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// writeAssetTree walks the relations of the requested types from the asset
+// and writes them as a tree.
+func (app *application) writeAssetTree(w http.ResponseWriter, r *http.Request, fallback store.AssetRelationType, reverse bool) {
+	asset := getAssetFromCtx(r)
+
+	types, err := parseRelationTypes(r, fallback)
+	if err != nil {
+		app.badRequestResponse(w, r, err)
+		return
+	}
+
+	relations, err := app.store.AssetRelation.Walk(r.Context(), asset.ID, types, reverse)
+	if err != nil {
+		app.internalServerError(w, r, err)
+		return
+	}
+
+	if err := app.jsonResponse(w, http.StatusOK, responses.NewAssetTreeResponse(asset, relations, reverse)); err != nil {
+		app.internalServerError(w, r, err)
+		return
+	}
+}
+
+// getAssetSubtreeHandler returns what the asset contains, or with ?type=
+// whatever it reaches through the given relation types.
+func (app *application) getAssetSubtreeHandler(w http.ResponseWriter, r *http.Request) {
+	app.writeAssetTree(w, r, store.RelationContains, false)
+}
+
+// getAssetDependentsHandler returns the assets that depend on the asset,
+// directly or through others.
+func (app *application) getAssetDependentsHandler(w http.ResponseWriter, r *http.Request) {
+	app.writeAssetTree(w, r, store.RelationDependsOn, true)
+}
@@ -0,0 +1,543 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"strconv"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/knr1997/assets-management-apiserver/internal/api/requests"
+	"github.com/knr1997/assets-management-apiserver/internal/api/responses"
+	"github.com/knr1997/assets-management-apiserver/internal/store"
+)
+
+// configures the approval chain and the department managers it runs through
+const approvalAdminRole = "admin"
+
+type approvalStepKey string
+
+const approvalStepCtx approvalStepKey = "approvalStep"
+
+type assetRequestKey string
+
+const assetRequestCtx assetRequestKey = "assetRequest"
+
+func getApprovalStepFromCtx(r *http.Request) *store.ApprovalStep {
+	step, _ := r.Context().Value(approvalStepCtx).(*store.ApprovalStep)
+	return step
+}
+
+func getAssetRequestFromCtx(r *http.Request) *store.AssetRequest {
+	request, _ := r.Context().Value(assetRequestCtx).(*store.AssetRequest)
+	return request
+}
+
+func (app *application) approvalStepContextMiddleware(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		idParam := chi.URLParam(r, "stepID")
+		id, err := strconv.ParseInt(idParam, 10, 64)
+		if err != nil {
+			app.internalServerError(w, r, err)
+			return
+		}
+
+		ctx := r.Context()
+
+		step, err := app.store.ApprovalStep.GetByID(ctx, id)
+		if err != nil {
+			switch {
+			case errors.Is(err, store.ErrNotFound):
+				app.notFoundResponse(w, r, err)
+			default:
+				app.internalServerError(w, r, err)
+			}
+			return
+		}
+
+		ctx = context.WithValue(ctx, approvalStepCtx, step)
+		next.ServeHTTP(w, r.WithContext(ctx))
+	})
+}
+
+func (app *application) assetRequestContextMiddleware(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		idParam := chi.URLParam(r, "requestID")
+		id, err := strconv.ParseInt(idParam, 10, 64)
+		if err != nil {
+			app.internalServerError(w, r, err)
+			return
+		}
+
+		ctx := r.Context()
+
+		request, err := app.store.AssetRequest.GetByID(ctx, id)
+		if err != nil {
+			switch {
+			case errors.Is(err, store.ErrNotFound):
+				app.notFoundResponse(w, r, err)
+			default:
+				app.internalServerError(w, r, err)
+			}
+			return
+		}
+
+		ctx = context.WithValue(ctx, assetRequestCtx, request)
+		next.ServeHTTP(w, r.WithContext(ctx))
+	})
+}
+
+func (app *application) getAllApprovalStepHandler(w http.ResponseWriter, r *http.Request) {
+	steps, err := app.store.ApprovalStep.GetAll(r.Context())
+	if err != nil {
+		app.internalServerError(w, r, err)
+		return
+	}
+
+	if err := app.jsonResponse(w, http.StatusOK, responses.NewApprovalStepsResponse(steps)); err != nil {
+		app.internalServerError(w, r, err)
+		return
+	}
+}
+
+func (app *application) createApprovalStepHandler(w http.ResponseWriter, r *http.Request) {
+	var payload requests.CreateApprovalStepPayload
+	if err := readJSON(w, r, &payload); err != nil {
+		app.badRequestResponse(w, r, err)
+		return
+	}
+
+	if err := Validate.Struct(payload); err != nil {
+		app.badRequestResponse(w, r, err)
+		return
+	}
+
+	step := &store.ApprovalStep{
+		Position: payload.Position,
+		Name:     payload.Name,
+		Approver: store.ApproverKind(payload.Approver),
+		RoleID:   payload.RoleID,
+		MinCost:  payload.MinCost,
+	}
+
+	ctx := r.Context()
+
+	if err := app.store.ApprovalStep.Create(ctx, step); err != nil {
+		switch {
+		case errors.Is(err, store.ErrConflict):
+			app.conflictResponse(w, r, fmt.Errorf("position %d is already taken", step.Position))
+		default:
+			app.internalServerError(w, r, err)
+		}
+		return
+	}
+
+	step, err := app.store.ApprovalStep.GetByID(ctx, step.ID)
+	if err != nil {
+		app.internalServerError(w, r, err)
+		return
+	}
+
+	if err := app.jsonResponse(w, http.StatusCreated, responses.NewApprovalStepResponse(step)); err != nil {
+		app.internalServerError(w, r, err)
+		return
+	}
+}
+
+func (app *application) updateApprovalStepHandler(w http.ResponseWriter, r *http.Request) {
+	step := getApprovalStepFromCtx(r)
+
+	var payload requests.UpdateApprovalStepPayload
+	if err := readJSON(w, r, &payload); err != nil {
+		app.badRequestResponse(w, r, err)
+		return
+	}
+
+	if err := Validate.Struct(payload); err != nil {
+		app.badRequestResponse(w, r, err)
+		return
+	}
+
+	if payload.Position != nil {
+		step.Position = *payload.Position
+	}
+	if payload.Name != nil {
+		step.Name = *payload.Name
+	}
+	if payload.Approver != nil {
+		step.Approver = store.ApproverKind(*payload.Approver)
+	}
+	if payload.RoleID != nil {
+		step.RoleID = payload.RoleID
+	}
+	if payload.MinCost != nil {
+		step.MinCost = *payload.MinCost
+	}
+
+	switch {
+	case step.Approver == store.ApproverDepartmentManager:
+		step.RoleID = nil
+	case step.RoleID == nil:
+		app.badRequestResponse(w, r, errors.New("roleId is required for ROLE steps"))
+		return
+	}
+
+	ctx := r.Context()
+
+	if err := app.store.ApprovalStep.Update(ctx, step); err != nil {
+		switch {
+		case errors.Is(err, store.ErrNotFound):
+			app.notFoundResponse(w, r, err)
+		case errors.Is(err, store.ErrConflict):
+			app.conflictResponse(w, r, fmt.Errorf("position %d is already taken", step.Position))
+		default:
+			app.internalServerError(w, r, err)
+		}
+		return
+	}
+
+	step, err := app.store.ApprovalStep.GetByID(ctx, step.ID)
+	if err != nil {
+		app.internalServerError(w, r, err)
+		return
+	}
+
+	if err := app.jsonResponse(w, http.StatusOK, responses.NewApprovalStepResponse(step)); err != nil {
+		app.internalServerError(w, r, err)
+		return
+	}
+}
+
+func (app *application) deleteApprovalStepHandler(w http.ResponseWriter, r *http.Request) {
+	step := getApprovalStepFromCtx(r)
+
+	if err := app.store.ApprovalStep.Delete(r.Context(), step.ID); err != nil {
+		switch {
+		case errors.Is(err, store.ErrNotFound):
+			app.notFoundResponse(w, r, err)
+		default:
+			app.internalServerError(w, r, err)
+		}
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// getRequestCatalogHandler lists the models users can request.
+func (app *application) getRequestCatalogHandler(w http.ResponseWriter, r *http.Request) {
+	spec, ok := app.parseQuerySpec(w, r)
+	if !ok {
+		return
+	}
+
+	writeListPage(app, w, r, spec, app.store.Model.ListRequestable, responses.NewModelsResponse)
+}
+
+func (app *application) getAllAssetRequestHandler(w http.ResponseWriter, r *http.Request) {
+	spec, ok := app.parseQuerySpec(w, r)
+	if !ok {
+		return
+	}
+
+	writeListPage(app, w, r, spec, app.store.AssetRequest.List, responses.NewAssetRequestsResponse)
+}
+
+// getMyAssetRequestsHandler lists the requests the user submitted.
+func (app *application) getMyAssetRequestsHandler(w http.ResponseWriter, r *http.Request) {
+	user := getUserFromContext(r)
+
+	spec, ok := app.parseQuerySpec(w, r)
+	if !ok {
+		return
+	}
+
+	list := func(ctx context.Context, spec store.QuerySpec) (*store.Pagination, error) {
+		return app.store.AssetRequest.ListByRequester(ctx, user.ID, spec)
+	}
+
+	writeListPage(app, w, r, spec, list, responses.NewAssetRequestsResponse)
+}
+
+// getMyApprovalsHandler lists the requests waiting for the user's decision.
+func (app *application) getMyApprovalsHandler(w http.ResponseWriter, r *http.Request) {
+	user := getUserFromContext(r)
+
+	spec, ok := app.parseQuerySpec(w, r)
+	if !ok {
+		return
+	}
+
+	list := func(ctx context.Context, spec store.QuerySpec) (*store.Pagination, error) {
+		return app.store.AssetRequest.ListAwaiting(ctx, user, spec)
+	}
+
+	writeListPage(app, w, r, spec, list, responses.NewAssetRequestsResponse)
+}
+
+func (app *application) createAssetRequestHandler(w http.ResponseWriter, r *http.Request) {
+	user := getUserFromContext(r)
+
+	var payload requests.CreateAssetRequestPayload
+	if err := readJSON(w, r, &payload); err != nil {
+		app.badRequestResponse(w, r, err)
+		return
+	}
+
+	if err := Validate.Struct(payload); err != nil {
+		app.badRequestResponse(w, r, err)
+		return
+	}
+
+	ctx := r.Context()
+
+	model, err := app.store.Model.GetByID(ctx, payload.ModelID)
+	if err != nil {
+		switch {
+		case errors.Is(err, store.ErrNotFound):
+			app.badRequestResponse(w, r, fmt.Errorf("model %d does not exist", payload.ModelID))
+		default:
+			app.internalServerError(w, r, err)
+		}
+		return
+	}
+	if !model.Requestable {
+		app.badRequestResponse(w, r, fmt.Errorf("%s cannot be requested", model.Name))
+		return
+	}
+
+	request := &store.AssetRequest{
+		RequesterID:   user.ID,
+		ModelID:       model.ID,
+		Justification: payload.Justification,
+		EstimatedCost: model.RequestCost,
+	}
+
+	if err := app.store.AssetRequest.Create(ctx, request); err != nil {
+		app.internalServerError(w, r, err)
+		return
+	}
+
+	request, err = app.store.AssetRequest.GetByID(ctx, request.ID)
+	if err != nil {
+		app.internalServerError(w, r, err)
+		return
+	}
+
+	app.notifyAssetRequest(ctx, request)
+
+	if err := app.jsonResponse(w, http.StatusCreated, responses.NewAssetRequestResponse(request)); err != nil {
+		app.internalServerError(w, r, err)
+		return
+	}
+}
+
+func (app *application) getAssetRequestHandler(w http.ResponseWriter, r *http.Request) {
+	request := getAssetRequestFromCtx(r)
+
+	if err := app.jsonResponse(w, http.StatusOK, responses.NewAssetRequestResponse(request)); err != nil {
+		app.internalServerError(w, r, err)
+		return
+	}
+}
+
+func (app *application) approveAssetRequestHandler(w http.ResponseWriter, r *http.Request) {
+	app.decideAssetRequest(w, r, store.DecisionApproved)
+}
+
+func (app *application) rejectAssetRequestHandler(w http.ResponseWriter, r *http.Request) {
+	app.decideAssetRequest(w, r, store.DecisionRejected)
+}
+
+// decideAssetRequest records the user's decision on the current step of the
+// request's approval chain. The comment is optional.
+func (app *application) decideAssetRequest(w http.ResponseWriter, r *http.Request, decision store.ApprovalDecision) {
+	user := getUserFromContext(r)
+	request := getAssetRequestFromCtx(r)
+
+	var payload requests.DecideAssetRequestPayload
+	if err := readJSON(w, r, &payload); err != nil && !errors.Is(err, io.EOF) {
+		app.badRequestResponse(w, r, err)
+		return
+	}
+
+	if err := Validate.Struct(payload); err != nil {
+		app.badRequestResponse(w, r, err)
+		return
+	}
+
+	ctx := r.Context()
+
+	if err := app.store.AssetRequest.Decide(ctx, request.ID, user, decision, payload.Comment); err != nil {
+		switch {
+		case errors.Is(err, store.ErrNotFound):
+			app.notFoundResponse(w, r, err)
+		case errors.Is(err, store.ErrNotApprover):
+			app.forbiddenResponse(w, r)
+		case errors.Is(err, store.ErrRequestNotPending):
+			app.conflictResponse(w, r, fmt.Errorf("request is %s", request.Status))
+		default:
+			app.internalServerError(w, r, err)
+		}
+		return
+	}
+
+	request, err := app.store.AssetRequest.GetByID(ctx, request.ID)
+	if err != nil {
+		app.internalServerError(w, r, err)
+		return
+	}
+
+	app.notifyAssetRequest(ctx, request)
+
+	if err := app.jsonResponse(w, http.StatusOK, responses.NewAssetRequestResponse(request)); err != nil {
+		app.internalServerError(w, r, err)
+		return
+	}
+}
+
+// cancelAssetRequestHandler lets the requester withdraw a request that was
+// not fulfilled yet.
+func (app *application) cancelAssetRequestHandler(w http.ResponseWriter, r *http.Request) {
+	user := getUserFromContext(r)
+	request := getAssetRequestFromCtx(r)
+
+	if request.RequesterID != user.ID {
+		app.forbiddenResponse(w, r)
+		return
+	}
+
+	ctx := r.Context()
+
+	if err := app.store.AssetRequest.Cancel(ctx, request.ID); err != nil {
+		switch {
+		case errors.Is(err, store.ErrRequestNotPending):
+			app.conflictResponse(w, r, fmt.Errorf("request is %s", request.Status))
+		default:
+			app.internalServerError(w, r, err)
+		}
+		return
+	}
+
+	request, err := app.store.AssetRequest.GetByID(ctx, request.ID)
+	if err != nil {
+		app.internalServerError(w, r, err)
+		return
+	}
+
+	if err := app.jsonResponse(w, http.StatusOK, responses.NewAssetRequestResponse(request)); err != nil {
+		app.internalServerError(w, r, err)
+		return
+	}
+}
+
+// fulfillAssetRequestHandler assigns an asset of the requested model to the
+// requester of an approved request.
+func (app *application) fulfillAssetRequestHandler(w http.ResponseWriter, r *http.Request) {
+	user := getUserFromContext(r)
+	request := getAssetRequestFromCtx(r)
+
+	var payload requests.FulfillAssetRequestPayload
+	if err := readJSON(w, r, &payload); err != nil && !errors.Is(err, io.EOF) {
+		app.badRequestResponse(w, r, err)
+		return
+	}
+
+	ctx := r.Context()
+
+	if err := app.store.AssetRequest.Fulfill(ctx, request.ID, payload.AssetID, user.ID); err != nil {
+		switch {
+		case errors.Is(err, store.ErrNotFound):
+			app.notFoundResponse(w, r, err)
+		case errors.Is(err, store.ErrRequestNotApproved):
+			app.conflictResponse(w, r, fmt.Errorf("request is %s", request.Status))
+		case errors.Is(err, store.ErrAssetNotAvailable) && payload.AssetID != nil:
+			app.conflictResponse(w, r, fmt.Errorf("asset %d is not an available %s", *payload.AssetID, request.Model.Name))
+		case errors.Is(err, store.ErrAssetNotAvailable):
+			app.conflictResponse(w, r, fmt.Errorf("no %s is available", request.Model.Name))
+		default:
+			app.internalServerError(w, r, err)
+		}
+		return
+	}
+
+	request, err := app.store.AssetRequest.GetByID(ctx, request.ID)
+	if err != nil {
+		app.internalServerError(w, r, err)
+		return
+	}
+
+	app.notifyAssetRequest(ctx, request)
+
+	if err := app.jsonResponse(w, http.StatusOK, responses.NewAssetRequestResponse(request)); err != nil {
+		app.internalServerError(w, r, err)
+		return
+	}
+}
+
+// notifyAssetRequest tells the approvers of the current step that a request
+// waits for them, or the requester how it ended. The request was already
+// saved, so failures are only logged.
+func (app *application) notifyAssetRequest(ctx context.Context, request *store.AssetRequest) {
+	var notifications []store.Notification
+
+	if current := request.CurrentApproval(); current != nil {
+		var userIDs []int64
+		switch {
+		case current.ApproverID != nil:
+			userIDs = []int64{*current.ApproverID}
+		case current.Role != nil:
+			ids, err := app.store.Users.GetIDsByRole(ctx, current.Role.Name)
+			if err != nil {
+				app.logger.Errorw("asset request notification failed", "request", request.ID, "error", err.Error())
+				return
+			}
+			userIDs = ids
+		}
+
+		for _, id := range userIDs {
+			if id == request.RequesterID {
+				continue
+			}
+			notifications = append(notifications, store.Notification{
+				UserID: id,
+				Kind:   "asset_request_approval",
+				Title:  "Asset request awaiting approval",
+				Body:   fmt.Sprintf("%s requested a %s: %s", request.Requester.Username, request.Model.Name, request.Justification),
+			})
+		}
+	} else {
+		var kind, title, body string
+		switch request.Status {
+		case store.RequestApproved:
+			kind, title = "asset_request_approved", "Asset request approved"
+			body = fmt.Sprintf("Your request for a %s was approved and will be handed out soon.", request.Model.Name)
+		case store.RequestRejected:
+			kind, title = "asset_request_rejected", "Asset request rejected"
+			body = fmt.Sprintf("Your request for a %s was rejected.", request.Model.Name)
+		case store.RequestFulfilled:
+			kind, title = "asset_request_fulfilled", "Asset request fulfilled"
+			body = fmt.Sprintf("%s was assigned to you for your request.", request.Asset.Name)
+		default:
+			return
+		}
+
+		notifications = append(notifications, store.Notification{
+			UserID:  request.RequesterID,
+			Kind:    kind,
+			Title:   title,
+			Body:    body,
+			AssetID: request.AssetID,
+		})
+	}
+
+	if len(notifications) == 0 {
+		return
+	}
+
+	if err := app.store.Notification.Create(ctx, notifications); err != nil {
+		app.logger.Errorw("asset request notification failed", "request", request.ID, "error", err.Error())
+	}
+}
@@ -0,0 +1,406 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"mime/multipart"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/google/uuid"
+	"github.com/knr1997/assets-management-apiserver/internal/api/responses"
+	"github.com/knr1997/assets-management-apiserver/internal/blob"
+	"github.com/knr1997/assets-management-apiserver/internal/store"
+)
+
+// attachmentTypes are the sniffed content types accepted as attachments.
+var attachmentTypes = map[string]bool{
+	"application/pdf":           true,
+	"image/png":                 true,
+	"image/jpeg":                true,
+	"image/gif":                 true,
+	"image/webp":                true,
+	"text/plain; charset=utf-8": true,
+}
+
+type attachmentKey string
+
+const attachmentCtx attachmentKey = "attachment"
+
+func getAttachmentFromCtx(r *http.Request) *store.Attachment {
+	attachment, _ := r.Context().Value(attachmentCtx).(*store.Attachment)
+	return attachment
+}
+
+// attachmentOwner returns the record the attachment routes are mounted under.
+func attachmentOwner(r *http.Request) (string, int64) {
+	if asset := getAssetFromCtx(r); asset != nil {
+		return store.AttachmentOwnerAsset, asset.ID
+	}
+	if model := getModelFromCtx(r); model != nil {
+		return store.AttachmentOwnerModel, model.ID
+	}
+	if supplier := getsupplierFromCtx(r); supplier != nil {
+		return store.AttachmentOwnerSupplier, supplier.ID
+	}
+	if invoice := getInvoiceFromCtx(r); invoice != nil {
+		return store.AttachmentOwnerInvoice, invoice.ID
+	}
+
+	maintenance := getMaintenanceFromCtx(r)
+	return store.AttachmentOwnerMaintenance, maintenance.ID
+}
+
+func (app *application) attachmentRoutes(r chi.Router) {
+	r.Use(app.AuthTokenMiddleware)
+	r.Get("/", app.getAllAttachmentHandler)
+	r.Post("/", app.uploadAttachmentHandler)
+
+	r.Route("/{attachmentID}", func(r chi.Router) {
+		r.Use(app.attachmentContextMiddleware)
+		r.Get("/", app.getAttachmentHandler)
+		r.Delete("/", app.deleteAttachmentHandler)
+	})
+}
+
+func (app *application) attachmentContextMiddleware(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		idParam := chi.URLParam(r, "attachmentID")
+		id, err := strconv.ParseInt(idParam, 10, 64)
+		if err != nil {
+			app.internalServerError(w, r, err)
+			return
+		}
+
+		ctx := r.Context()
+
+		attachment, err := app.store.Attachment.GetByID(ctx, id)
+		if err != nil {
+			switch {
+			case errors.Is(err, store.ErrNotFound):
+				app.notFoundResponse(w, r, err)
+			default:
+				app.internalServerError(w, r, err)
+			}
+			return
+		}
+
+		ownerType, ownerID := attachmentOwner(r)
+		if attachment.OwnerType != ownerType || attachment.OwnerID != ownerID {
+			app.notFoundResponse(w, r, store.ErrNotFound)
+			return
+		}
+
+		ctx = context.WithValue(ctx, attachmentCtx, attachment)
+		next.ServeHTTP(w, r.WithContext(ctx))
+	})
+}
+
+func (app *application) getAllAttachmentHandler(w http.ResponseWriter, r *http.Request) {
+	ownerType, ownerID := attachmentOwner(r)
+
+	attachments, err := app.store.Attachment.ListByOwner(r.Context(), ownerType, ownerID)
+	if err != nil {
+		app.internalServerError(w, r, err)
+		return
+	}
+
+	response := responses.NewAttachmentsResponse(attachments)
+	for i := range response {
+		response[i].URL, response[i].URLExpiresAt = app.signedAttachmentURL(response[i].ID)
+	}
+
+	if err := app.jsonResponse(w, http.StatusOK, response); err != nil {
+		app.internalServerError(w, r, err)
+		return
+	}
+}
+
+func (app *application) getAttachmentHandler(w http.ResponseWriter, r *http.Request) {
+	attachment := getAttachmentFromCtx(r)
+
+	response := responses.NewAttachmentResponse(attachment)
+	response.URL, response.URLExpiresAt = app.signedAttachmentURL(attachment.ID)
+
+	if err := app.jsonResponse(w, http.StatusOK, response); err != nil {
+		app.internalServerError(w, r, err)
+		return
+	}
+}
+
+// uploadAttachmentHandler stores the "file" field of a multipart form for
+// the record the route is mounted under.
+func (app *application) uploadAttachmentHandler(w http.ResponseWriter, r *http.Request) {
+	user := getUserFromContext(r)
+	ownerType, ownerID := attachmentOwner(r)
+
+	upload, err := app.readUpload(w, r, attachmentTypes)
+	if err != nil {
+		app.badRequestResponse(w, r, err)
+		return
+	}
+	defer upload.close()
+
+	ctx := r.Context()
+
+	attachment, err := app.putUpload(ctx, ownerType, ownerID, upload, user.ID)
+	if err != nil {
+		app.internalServerError(w, r, err)
+		return
+	}
+
+	if err := app.store.Attachment.Create(ctx, attachment); err != nil {
+		app.removeBlobs(ctx, attachment.StorageKey)
+		app.internalServerError(w, r, err)
+		return
+	}
+
+	response := responses.NewAttachmentResponse(attachment)
+	response.URL, response.URLExpiresAt = app.signedAttachmentURL(attachment.ID)
+
+	if err := app.jsonResponse(w, http.StatusCreated, response); err != nil {
+		app.internalServerError(w, r, err)
+		return
+	}
+}
+
+func (app *application) deleteAttachmentHandler(w http.ResponseWriter, r *http.Request) {
+	attachment := getAttachmentFromCtx(r)
+
+	ctx := r.Context()
+
+	if err := app.store.Attachment.Delete(ctx, attachment.ID); err != nil {
+		switch {
+		case errors.Is(err, store.ErrNotFound):
+			app.notFoundResponse(w, r, err)
+		default:
+			app.internalServerError(w, r, err)
+		}
+		return
+	}
+
+	app.removeBlobs(ctx, attachment.StorageKey)
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// downloadAttachmentHandler serves an attachment to whoever holds a signed
+// URL for it, so links work in browsers and mail without a token.
+func (app *application) downloadAttachmentHandler(w http.ResponseWriter, r *http.Request) {
+	id, err := strconv.ParseInt(chi.URLParam(r, "attachmentID"), 10, 64)
+	if err != nil {
+		app.notFoundResponse(w, r, err)
+		return
+	}
+
+	query := r.URL.Query()
+	expires, err := strconv.ParseInt(query.Get("expires"), 10, 64)
+	if err != nil || !hmac.Equal([]byte(query.Get("signature")), []byte(app.attachmentSignature(id, expires))) {
+		app.forbiddenResponse(w, r)
+		return
+	}
+	if time.Now().Unix() > expires {
+		app.forbiddenResponse(w, r)
+		return
+	}
+
+	attachment, err := app.store.Attachment.GetByID(r.Context(), id)
+	if err != nil {
+		switch {
+		case errors.Is(err, store.ErrNotFound):
+			app.notFoundResponse(w, r, err)
+		default:
+			app.internalServerError(w, r, err)
+		}
+		return
+	}
+
+	app.writeAttachment(w, r, attachment)
+}
+
+// deleteAttachments removes the attachments of a deleted record with their
+// blobs.
+func (app *application) deleteAttachments(ctx context.Context, ownerType string, ownerID int64) {
+	keys, err := app.store.Attachment.DeleteByOwner(ctx, ownerType, ownerID)
+	if err != nil {
+		app.logger.Errorw("deleting attachments failed", "owner", ownerType, "id", ownerID, "error", err.Error())
+		return
+	}
+
+	app.removeBlobs(ctx, keys...)
+}
+
+// removeBlobs deletes blobs no attachment refers to anymore. Failures are
+// only logged, an orphaned blob does no harm.
+func (app *application) removeBlobs(ctx context.Context, keys ...string) {
+	for _, key := range keys {
+		if key == "" {
+			continue
+		}
+		if err := app.blobs.Delete(ctx, key); err != nil {
+			app.logger.Errorw("deleting blob failed", "key", key, "error", err.Error())
+		}
+	}
+}
+
+// writeAttachment streams an attachment from the blob store, or from the
+// database for inline ones.
+func (app *application) writeAttachment(w http.ResponseWriter, r *http.Request, attachment *store.Attachment) {
+	var content io.Reader
+	if attachment.StorageKey == "" {
+		content = bytes.NewReader(attachment.Content)
+	} else {
+		rc, err := app.blobs.Get(r.Context(), attachment.StorageKey)
+		if err != nil {
+			switch {
+			case errors.Is(err, blob.ErrNotFound):
+				app.notFoundResponse(w, r, err)
+			default:
+				app.internalServerError(w, r, err)
+			}
+			return
+		}
+		defer rc.Close()
+		content = rc
+	}
+
+	w.Header().Set("Content-Type", attachment.ContentType)
+	w.Header().Set("Content-Disposition", fmt.Sprintf("attachment; filename=%q", attachment.FileName))
+	w.Header().Set("Content-Length", strconv.FormatInt(attachment.Size, 10))
+	w.Header().Set("X-Content-Type-Options", "nosniff")
+	w.WriteHeader(http.StatusOK)
+
+	if _, err := io.Copy(w, content); err != nil {
+		app.logger.Errorw("attachment download failed", "attachment", attachment.ID, "error", err.Error())
+	}
+}
+
+type upload struct {
+	file        multipart.File
+	form        *multipart.Form
+	fileName    string
+	contentType string
+	size        int64
+	checksum    string
+}
+
+// close releases the upload along with the temporary files of its form. The
+// server only cleans up the form of the request it handed out, not of the
+// copies middleware made with WithContext.
+func (u *upload) close() {
+	u.file.Close()
+	u.form.RemoveAll()
+}
+
+// readUpload reads the "file" field of a multipart form, which must be one
+// of the allowed types by its content and within the size limit.
+func (app *application) readUpload(w http.ResponseWriter, r *http.Request, allowed map[string]bool) (*upload, error) {
+	maxSize := app.config.attachments.maxSize
+
+	r.Body = http.MaxBytesReader(w, r.Body, maxSize+1<<20)
+	if err := r.ParseMultipartForm(1 << 20); err != nil {
+		var tooLarge *http.MaxBytesError
+		if errors.As(err, &tooLarge) {
+			return nil, fmt.Errorf("file must be at most %d MB", maxSize>>20)
+		}
+		return nil, err
+	}
+
+	file, header, err := r.FormFile("file")
+	if err != nil {
+		r.MultipartForm.RemoveAll()
+		return nil, err
+	}
+
+	u := &upload{
+		file:     file,
+		form:     r.MultipartForm,
+		fileName: header.Filename,
+		size:     header.Size,
+	}
+
+	if header.Size > maxSize {
+		u.close()
+		return nil, fmt.Errorf("file must be at most %d MB", maxSize>>20)
+	}
+
+	head := make([]byte, 512)
+	n, err := io.ReadFull(file, head)
+	if err != nil && !errors.Is(err, io.ErrUnexpectedEOF) && !errors.Is(err, io.EOF) {
+		u.close()
+		return nil, err
+	}
+
+	contentType := http.DetectContentType(head[:n])
+	if !allowed[contentType] {
+		u.close()
+		return nil, fmt.Errorf("files of type %s cannot be attached", contentType)
+	}
+
+	hash := sha256.New()
+	if _, err := file.Seek(0, io.SeekStart); err != nil {
+		u.close()
+		return nil, err
+	}
+	if _, err := io.Copy(hash, file); err != nil {
+		u.close()
+		return nil, err
+	}
+	if _, err := file.Seek(0, io.SeekStart); err != nil {
+		u.close()
+		return nil, err
+	}
+
+	u.contentType = contentType
+	u.checksum = hex.EncodeToString(hash.Sum(nil))
+
+	return u, nil
+}
+
+// putUpload writes an upload to the blob store, returning the attachment to
+// record for it.
+func (app *application) putUpload(ctx context.Context, ownerType string, ownerID int64, upload *upload, uploadedBy int64) (*store.Attachment, error) {
+	key := fmt.Sprintf("%s/%d/%s", ownerType, ownerID, uuid.NewString())
+
+	if err := app.blobs.Put(ctx, key, upload.file, upload.size, upload.contentType); err != nil {
+		return nil, err
+	}
+
+	return &store.Attachment{
+		OwnerType:    ownerType,
+		OwnerID:      ownerID,
+		FileName:     upload.fileName,
+		ContentType:  upload.contentType,
+		Size:         upload.size,
+		Checksum:     upload.checksum,
+		StorageKey:   key,
+		UploadedByID: &uploadedBy,
+	}, nil
+}
+
+// signedAttachmentURL returns a download URL for an attachment that works
+// without a token until it expires.
+func (app *application) signedAttachmentURL(id int64) (string, *time.Time) {
+	expiresAt := time.Now().Add(app.config.attachments.urlTTL).Truncate(time.Second)
+	expires := expiresAt.Unix()
+
+	url := fmt.Sprintf("%s/api/files/%d?expires=%d&signature=%s",
+		app.config.apiURL, id, expires, app.attachmentSignature(id, expires))
+
+	return url, &expiresAt
+}
+
+func (app *application) attachmentSignature(id, expires int64) string {
+	mac := hmac.New(sha256.New, []byte(app.config.attachments.signingKey))
+	fmt.Fprintf(mac, "attachment:%d:%d", id, expires)
+	return base64.RawURLEncoding.EncodeToString(mac.Sum(nil))
+}
@@ -0,0 +1,48 @@
+package main
+
+import (
+	"net/http"
+
+	"github.com/knr1997/assets-management-apiserver/internal/api/responses"
+	"github.com/knr1997/assets-management-apiserver/internal/store"
+)
+
+var auditLogExportColumns = []exportColumn[store.AuditLog]{
+	{"id", func(l *store.AuditLog) any { return l.ID }},
+	{"tableName", func(l *store.AuditLog) any { return l.TableName }},
+	{"recordId", func(l *store.AuditLog) any { return l.RecordID }},
+	{"operation", func(l *store.AuditLog) any { return l.Operation }},
+	{"oldValue", func(l *store.AuditLog) any { return deref(l.OldValue) }},
+	{"newValue", func(l *store.AuditLog) any { return deref(l.NewValue) }},
+	{"diff", func(l *store.AuditLog) any { return deref(l.Diff) }},
+	{"changedAt", func(l *store.AuditLog) any { return l.ChangedAt }},
+	{"changedBy", func(l *store.AuditLog) any { return l.ChangedBy }},
+	{"ipAddress", func(l *store.AuditLog) any { return deref(l.IPAddress) }},
+	{"requestId", func(l *store.AuditLog) any { return deref(l.RequestID) }},
+}
+
+func (app *application) getAllAuditLogHandler(w http.ResponseWriter, r *http.Request) {
+	spec, ok := app.parseQuerySpec(w, r)
+	if !ok {
+		return
+	}
+
+	format, ok, err := requestedExportFormat(r)
+	if err != nil {
+		app.badRequestResponse(w, r, err)
+		return
+	}
+	if ok {
+		streamExport(app, w, r, "audit-logs", format, spec, auditLogExportColumns, app.store.AuditLog.Stream)
+		return
+	}
+
+	writeListPage(app, w, r, spec, app.store.AuditLog.List, responses.NewAuditLogsResponse)
+}
+
+func deref(s *string) string {
+	if s == nil {
+		return ""
+	}
+	return *s
+}
@@ -47,6 +47,7 @@ func (app *application) registerUserHandler(w http.ResponseWriter, r *http.Reque
 	user := &store.User{
 		Username: payload.Username,
 		Email:    payload.Email,
+		IsActive: true,
 		Role: store.Role{
 			Name: "user",
 		},
@@ -131,6 +132,11 @@ func (app *application) createTokenHandler(w http.ResponseWriter, r *http.Reques
 		return
 	}
 
+	if !user.IsActive {
+		app.unauthorizedErrorResponse(w, r, errUserInactive)
+		return
+	}
+
 	claims := jwt.MapClaims{
 		"sub": user.ID,
 		"exp": time.Now().Add(app.config.auth.token.exp).Unix(),
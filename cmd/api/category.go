@@ -2,14 +2,12 @@ package main
 
 import (
 	"context"
-	"encoding/json"
 	"errors"
 	"net/http"
 	"strconv"
 
 	"github.com/go-chi/chi/v5"
 	"github.com/knr1997/assets-management-apiserver/internal/store"
-	"github.com/knr1997/assets-management-apiserver/internal/utils"
 )
 
 type categoryKey string
@@ -19,6 +17,7 @@ const categoryCtx categoryKey = "category"
 type CreateCategoryPayload struct {
 	Name        string `json:"name" validate:"required,max=100"`
 	Description string `json:"description"`
+	EULA        string `json:"eula" validate:"max=20000"`
 }
 
 func (app *application) createCategoryHandler(w http.ResponseWriter, r *http.Request) {
@@ -36,6 +35,7 @@ func (app *application) createCategoryHandler(w http.ResponseWriter, r *http.Req
 	post := &store.Category{
 		Name:        payload.Name,
 		Description: payload.Description,
+		EULA:        payload.EULA,
 	}
 
 	ctx := r.Context()
@@ -54,6 +54,7 @@ func (app *application) createCategoryHandler(w http.ResponseWriter, r *http.Req
 type UpdateCategoryPayload struct {
 	Name        *string `json:"name" validate:"omitempty,max=100"`
 	Description *string `json:"description"`
+	EULA        *string `json:"eula" validate:"omitempty,max=20000"`
 }
 
 func (app *application) updateCategoryHandler(w http.ResponseWriter, r *http.Request) {
@@ -76,6 +77,9 @@ func (app *application) updateCategoryHandler(w http.ResponseWriter, r *http.Req
 	if payload.Description != nil {
 		category.Description = *payload.Description
 	}
+	if payload.EULA != nil {
+		category.EULA = *payload.EULA
+	}
 
 	ctx := r.Context()
 
@@ -103,6 +107,7 @@ type CategoryResponse struct {
 	ID          int64  `json:"id"`
 	Name        string `json:"name"`
 	Description string `json:"description"`
+	EULA        string `json:"eula"`
 }
 
 func ToCategoryResponse(a *store.Category) CategoryResponse {
@@ -110,6 +115,7 @@ func ToCategoryResponse(a *store.Category) CategoryResponse {
 		ID:          a.ID,
 		Name:        a.Name,
 		Description: a.Description,
+		EULA:        a.EULA,
 	}
 }
 
@@ -123,51 +129,13 @@ func ToCategoryResponseList(categories []store.Category) []CategoryResponse {
 	return responses
 }
 
-func (app *application) getAllCategoryHandler(w http.ResponseWriter, r *http.Request) {
-	ctx := r.Context()
-
-	categories, err := app.store.Category.GetAll(ctx)
-	if err != nil {
-		app.internalServerError(w, r, err)
-		return
-	}
-
-	response := ToCategoryResponseList(categories)
-
-	w.Header().Set("Content-Type", "application/json")
-	json.NewEncoder(w).Encode(response)
-}
-
 func (app *application) getPaginatedCategoryHandler(w http.ResponseWriter, r *http.Request) {
-	p := utils.ParsePagination(r)
-
-	result, err := app.store.Category.List(store.Pagination{
-		Limit: p.Limit,
-		Page:  p.Page,
-	})
-	if err != nil {
-		app.internalServerError(w, r, err)
-		return
-	}
-
-	// Type assert rows
-	categories, ok := result.Rows.([]*store.Category)
+	spec, ok := app.parseQuerySpec(w, r)
 	if !ok {
-		app.internalServerError(w, r, errors.New("invalid category type"))
 		return
 	}
 
-	// Convert to response DTO
-	responses := make([]CategoryResponse, 0, len(categories))
-	for _, c := range categories {
-		responses = append(responses, ToCategoryResponse(c))
-	}
-
-	// Replace rows with DTO
-	result.Rows = responses
-
-	w.Header().Set("Content-Type", "application/json")
-	json.NewEncoder(w).Encode(result)
+	writeListPage(app, w, r, spec, app.store.Category.List, ToCategoryResponseList)
 }
 
 func (app *application) deleteCategoryHandler(w http.ResponseWriter, r *http.Request) {
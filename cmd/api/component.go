@@ -0,0 +1,300 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"strconv"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/knr1997/assets-management-apiserver/internal/api/requests"
+	"github.com/knr1997/assets-management-apiserver/internal/api/responses"
+	"github.com/knr1997/assets-management-apiserver/internal/store"
+)
+
+type componentKey string
+
+const componentCtx componentKey = "component"
+
+func getComponentFromCtx(r *http.Request) *store.Component {
+	component, _ := r.Context().Value(componentCtx).(*store.Component)
+	return component
+}
+
+func (app *application) componentContextMiddleware(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		idParam := chi.URLParam(r, "componentID")
+		id, err := strconv.ParseInt(idParam, 10, 64)
+		if err != nil {
+			app.internalServerError(w, r, err)
+			return
+		}
+
+		ctx := r.Context()
+
+		component, err := app.store.Component.GetByID(ctx, id)
+		if err != nil {
+			switch {
+			case errors.Is(err, store.ErrNotFound):
+				app.notFoundResponse(w, r, err)
+			default:
+				app.internalServerError(w, r, err)
+			}
+			return
+		}
+
+		ctx = context.WithValue(ctx, componentCtx, component)
+		next.ServeHTTP(w, r.WithContext(ctx))
+	})
+}
+
+func (app *application) getAllComponentHandler(w http.ResponseWriter, r *http.Request) {
+	spec, ok := app.parseQuerySpec(w, r)
+	if !ok {
+		return
+	}
+
+	writeListPage(app, w, r, spec, app.store.Component.List, responses.NewComponentsResponse)
+}
+
+func (app *application) createComponentHandler(w http.ResponseWriter, r *http.Request) {
+	var payload requests.CreateComponentPayload
+	if err := readJSON(w, r, &payload); err != nil {
+		app.badRequestResponse(w, r, err)
+		return
+	}
+
+	if err := Validate.Struct(payload); err != nil {
+		app.badRequestResponse(w, r, err)
+		return
+	}
+
+	component := &store.Component{
+		Name:           payload.Name,
+		CategoryID:     payload.CategoryID,
+		ManufacturerID: payload.ManufacturerID,
+		SupplierID:     payload.SupplierID,
+		PartNumber:     payload.PartNumber,
+		SerialNumber:   payload.SerialNumber,
+		Quantity:       payload.Quantity,
+		UnitCost:       payload.UnitCost,
+		PurchaseDate:   payload.PurchaseDate,
+		Location:       payload.Location,
+		Notes:          payload.Notes,
+	}
+
+	ctx := r.Context()
+
+	if err := app.store.Component.Create(ctx, component); err != nil {
+		app.internalServerError(w, r, err)
+		return
+	}
+
+	component, err := app.store.Component.GetByID(ctx, component.ID)
+	if err != nil {
+		app.internalServerError(w, r, err)
+		return
+	}
+
+	if err := app.jsonResponse(w, http.StatusCreated, responses.NewComponentResponse(component)); err != nil {
+		app.internalServerError(w, r, err)
+		return
+	}
+}
+
+func (app *application) getComponentHandler(w http.ResponseWriter, r *http.Request) {
+	component := getComponentFromCtx(r)
+
+	if err := app.jsonResponse(w, http.StatusOK, responses.NewComponentResponse(component)); err != nil {
+		app.internalServerError(w, r, err)
+		return
+	}
+}
+
+func (app *application) updateComponentHandler(w http.ResponseWriter, r *http.Request) {
+	component := getComponentFromCtx(r)
+
+	var payload requests.UpdateComponentPayload
+	if err := readJSON(w, r, &payload); err != nil {
+		app.badRequestResponse(w, r, err)
+		return
+	}
+
+	if err := Validate.Struct(payload); err != nil {
+		app.badRequestResponse(w, r, err)
+		return
+	}
+
+	if payload.Name != nil {
+		component.Name = *payload.Name
+	}
+	if payload.CategoryID != nil {
+		component.CategoryID = *payload.CategoryID
+	}
+	if payload.ManufacturerID != nil {
+		component.ManufacturerID = payload.ManufacturerID
+	}
+	if payload.SupplierID != nil {
+		component.SupplierID = payload.SupplierID
+	}
+	if payload.PartNumber != nil {
+		component.PartNumber = *payload.PartNumber
+	}
+	if payload.SerialNumber != nil {
+		component.SerialNumber = *payload.SerialNumber
+	}
+	if payload.Quantity != nil {
+		component.Quantity = *payload.Quantity
+	}
+	if payload.UnitCost != nil {
+		component.UnitCost = *payload.UnitCost
+	}
+	if payload.PurchaseDate != nil {
+		component.PurchaseDate = payload.PurchaseDate
+	}
+	if payload.Location != nil {
+		component.Location = *payload.Location
+	}
+	if payload.Notes != nil {
+		component.Notes = *payload.Notes
+	}
+
+	ctx := r.Context()
+
+	if err := app.store.Component.Update(ctx, component); err != nil {
+		switch {
+		case errors.Is(err, store.ErrStockInUse):
+			app.conflictResponse(w, r, fmt.Errorf("more than %d are installed, remove some before reducing the quantity", component.Quantity))
+		default:
+			app.internalServerError(w, r, err)
+		}
+		return
+	}
+
+	component, err := app.store.Component.GetByID(ctx, component.ID)
+	if err != nil {
+		app.internalServerError(w, r, err)
+		return
+	}
+
+	if err := app.jsonResponse(w, http.StatusOK, responses.NewComponentResponse(component)); err != nil {
+		app.internalServerError(w, r, err)
+		return
+	}
+}
+
+func (app *application) deleteComponentHandler(w http.ResponseWriter, r *http.Request) {
+	component := getComponentFromCtx(r)
+
+	if err := app.store.Component.Delete(r.Context(), component.ID); err != nil {
+		switch {
+		case errors.Is(err, store.ErrNotFound):
+			app.notFoundResponse(w, r, err)
+		case errors.Is(err, store.ErrStockInUse):
+			app.conflictResponse(w, r, fmt.Errorf("%s is still installed in assets", component.Name))
+		default:
+			app.internalServerError(w, r, err)
+		}
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+func (app *application) installComponentHandler(w http.ResponseWriter, r *http.Request) {
+	component := getComponentFromCtx(r)
+
+	var payload requests.InstallComponentPayload
+	if err := readJSON(w, r, &payload); err != nil {
+		app.badRequestResponse(w, r, err)
+		return
+	}
+
+	if err := Validate.Struct(payload); err != nil {
+		app.badRequestResponse(w, r, err)
+		return
+	}
+
+	ctx := r.Context()
+
+	asset, err := app.store.Asset.GetByID(ctx, payload.AssetID)
+	if err != nil {
+		switch {
+		case errors.Is(err, store.ErrNotFound):
+			app.badRequestResponse(w, r, fmt.Errorf("asset %d does not exist", payload.AssetID))
+		default:
+			app.internalServerError(w, r, err)
+		}
+		return
+	}
+
+	user := getUserFromContext(r)
+
+	install := &store.ComponentInstall{
+		AssetID:       asset.ID,
+		Quantity:      max(payload.Quantity, 1),
+		InstalledByID: &user.ID,
+		Notes:         payload.Notes,
+	}
+
+	if err := app.store.Component.Install(ctx, component, install, user.ID); err != nil {
+		switch {
+		case errors.Is(err, store.ErrInsufficientStock):
+			app.conflictResponse(w, r, fmt.Errorf("only %d of %s are available", component.Available, component.Name))
+		default:
+			app.internalServerError(w, r, err)
+		}
+		return
+	}
+
+	asset, err = app.store.Asset.GetByID(ctx, asset.ID)
+	if err != nil {
+		app.internalServerError(w, r, err)
+		return
+	}
+
+	if err := app.jsonResponse(w, http.StatusCreated, responses.NewAssetResponse(asset)); err != nil {
+		app.internalServerError(w, r, err)
+		return
+	}
+}
+
+func (app *application) removeComponentHandler(w http.ResponseWriter, r *http.Request) {
+	component := getComponentFromCtx(r)
+
+	installID, err := strconv.ParseInt(chi.URLParam(r, "installID"), 10, 64)
+	if err != nil {
+		app.internalServerError(w, r, err)
+		return
+	}
+
+	user := getUserFromContext(r)
+
+	if err := app.store.Component.Remove(r.Context(), component, installID, user.ID); err != nil {
+		switch {
+		case errors.Is(err, store.ErrNotFound):
+			app.notFoundResponse(w, r, err)
+		default:
+			app.internalServerError(w, r, err)
+		}
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+func (app *application) getComponentInstallsHandler(w http.ResponseWriter, r *http.Request) {
+	component := getComponentFromCtx(r)
+
+	spec, ok := app.parseQuerySpec(w, r)
+	if !ok {
+		return
+	}
+
+	list := func(ctx context.Context, spec store.QuerySpec) (*store.Pagination, error) {
+		return app.store.Component.Installs(ctx, component.ID, spec)
+	}
+
+	writeListPage(app, w, r, spec, list, responses.NewComponentInstallsResponse)
+}
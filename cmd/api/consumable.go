@@ -0,0 +1,430 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/knr1997/assets-management-apiserver/internal/api/requests"
+	"github.com/knr1997/assets-management-apiserver/internal/api/responses"
+	"github.com/knr1997/assets-management-apiserver/internal/store"
+)
+
+type consumableKey string
+
+const consumableCtx consumableKey = "consumable"
+
+const (
+	// role whose members are told about low stock
+	lowStockAlertRole = "admin"
+
+	// months covered by the consumption report when no range is given
+	defaultConsumptionMonths = 12
+)
+
+func getConsumableFromCtx(r *http.Request) *store.Consumable {
+	consumable, _ := r.Context().Value(consumableCtx).(*store.Consumable)
+	return consumable
+}
+
+func (app *application) consumableContextMiddleware(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		idParam := chi.URLParam(r, "consumableID")
+		id, err := strconv.ParseInt(idParam, 10, 64)
+		if err != nil {
+			app.internalServerError(w, r, err)
+			return
+		}
+
+		ctx := r.Context()
+
+		consumable, err := app.store.Consumable.GetByID(ctx, id)
+		if err != nil {
+			switch {
+			case errors.Is(err, store.ErrNotFound):
+				app.notFoundResponse(w, r, err)
+			default:
+				app.internalServerError(w, r, err)
+			}
+			return
+		}
+
+		ctx = context.WithValue(ctx, consumableCtx, consumable)
+		next.ServeHTTP(w, r.WithContext(ctx))
+	})
+}
+
+func (app *application) getAllConsumableHandler(w http.ResponseWriter, r *http.Request) {
+	spec, ok := app.parseQuerySpec(w, r)
+	if !ok {
+		return
+	}
+
+	writeListPage(app, w, r, spec, app.store.Consumable.List, responses.NewConsumablesResponse)
+}
+
+func (app *application) createConsumableHandler(w http.ResponseWriter, r *http.Request) {
+	var payload requests.CreateConsumablePayload
+	if err := readJSON(w, r, &payload); err != nil {
+		app.badRequestResponse(w, r, err)
+		return
+	}
+
+	if err := Validate.Struct(payload); err != nil {
+		app.badRequestResponse(w, r, err)
+		return
+	}
+
+	consumable := &store.Consumable{
+		Name:           payload.Name,
+		CategoryID:     payload.CategoryID,
+		ManufacturerID: payload.ManufacturerID,
+		ItemNumber:     payload.ItemNumber,
+		UnitCost:       payload.UnitCost,
+		Notes:          payload.Notes,
+	}
+
+	ctx := r.Context()
+
+	if err := app.store.Consumable.Create(ctx, consumable); err != nil {
+		app.internalServerError(w, r, err)
+		return
+	}
+
+	consumable, err := app.store.Consumable.GetByID(ctx, consumable.ID)
+	if err != nil {
+		app.internalServerError(w, r, err)
+		return
+	}
+
+	if err := app.jsonResponse(w, http.StatusCreated, responses.NewConsumableResponse(consumable)); err != nil {
+		app.internalServerError(w, r, err)
+		return
+	}
+}
+
+func (app *application) getConsumableHandler(w http.ResponseWriter, r *http.Request) {
+	consumable := getConsumableFromCtx(r)
+
+	if err := app.jsonResponse(w, http.StatusOK, responses.NewConsumableResponse(consumable)); err != nil {
+		app.internalServerError(w, r, err)
+		return
+	}
+}
+
+func (app *application) updateConsumableHandler(w http.ResponseWriter, r *http.Request) {
+	consumable := getConsumableFromCtx(r)
+
+	var payload requests.UpdateConsumablePayload
+	if err := readJSON(w, r, &payload); err != nil {
+		app.badRequestResponse(w, r, err)
+		return
+	}
+
+	if err := Validate.Struct(payload); err != nil {
+		app.badRequestResponse(w, r, err)
+		return
+	}
+
+	if payload.Name != nil {
+		consumable.Name = *payload.Name
+	}
+	if payload.CategoryID != nil {
+		consumable.CategoryID = *payload.CategoryID
+	}
+	if payload.ManufacturerID != nil {
+		consumable.ManufacturerID = payload.ManufacturerID
+	}
+	if payload.ItemNumber != nil {
+		consumable.ItemNumber = *payload.ItemNumber
+	}
+	if payload.UnitCost != nil {
+		consumable.UnitCost = *payload.UnitCost
+	}
+	if payload.Notes != nil {
+		consumable.Notes = *payload.Notes
+	}
+
+	ctx := r.Context()
+
+	if err := app.store.Consumable.Update(ctx, consumable); err != nil {
+		switch {
+		case errors.Is(err, store.ErrNotFound):
+			app.notFoundResponse(w, r, err)
+		default:
+			app.internalServerError(w, r, err)
+		}
+		return
+	}
+
+	consumable, err := app.store.Consumable.GetByID(ctx, consumable.ID)
+	if err != nil {
+		app.internalServerError(w, r, err)
+		return
+	}
+
+	if err := app.jsonResponse(w, http.StatusOK, responses.NewConsumableResponse(consumable)); err != nil {
+		app.internalServerError(w, r, err)
+		return
+	}
+}
+
+func (app *application) deleteConsumableHandler(w http.ResponseWriter, r *http.Request) {
+	consumable := getConsumableFromCtx(r)
+
+	if err := app.store.Consumable.Delete(r.Context(), consumable.ID); err != nil {
+		switch {
+		case errors.Is(err, store.ErrNotFound):
+			app.notFoundResponse(w, r, err)
+		default:
+			app.internalServerError(w, r, err)
+		}
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// setConsumableStockHandler sets the minimum quantity kept at a location.
+func (app *application) setConsumableStockHandler(w http.ResponseWriter, r *http.Request) {
+	consumable := getConsumableFromCtx(r)
+
+	var payload requests.SetConsumableStockPayload
+	if err := readJSON(w, r, &payload); err != nil {
+		app.badRequestResponse(w, r, err)
+		return
+	}
+
+	if err := Validate.Struct(payload); err != nil {
+		app.badRequestResponse(w, r, err)
+		return
+	}
+
+	ctx := r.Context()
+
+	if err := app.store.Consumable.SetMinQuantity(ctx, consumable.ID, payload.Location, payload.MinQuantity); err != nil {
+		app.internalServerError(w, r, err)
+		return
+	}
+
+	app.alertLowStockNow(ctx)
+
+	consumable, err := app.store.Consumable.GetByID(ctx, consumable.ID)
+	if err != nil {
+		app.internalServerError(w, r, err)
+		return
+	}
+
+	if err := app.jsonResponse(w, http.StatusOK, responses.NewConsumableResponse(consumable)); err != nil {
+		app.internalServerError(w, r, err)
+		return
+	}
+}
+
+func (app *application) issueConsumableHandler(w http.ResponseWriter, r *http.Request) {
+	consumable := getConsumableFromCtx(r)
+
+	var payload requests.IssueConsumablePayload
+	if err := readJSON(w, r, &payload); err != nil {
+		app.badRequestResponse(w, r, err)
+		return
+	}
+
+	if err := Validate.Struct(payload); err != nil {
+		app.badRequestResponse(w, r, err)
+		return
+	}
+
+	movement := &store.ConsumableMovement{
+		ConsumableID: consumable.ID,
+		Location:     payload.Location,
+		Quantity:     payload.Quantity,
+		UserID:       payload.UserID,
+		DepartmentID: payload.DepartmentID,
+		UnitCost:     consumable.UnitCost,
+		Notes:        payload.Notes,
+	}
+	if user := getUserFromContext(r); user != nil {
+		movement.PerformedByID = &user.ID
+	}
+
+	ctx := r.Context()
+
+	stock, err := app.store.Consumable.Issue(ctx, movement)
+	if err != nil {
+		switch {
+		case errors.Is(err, store.ErrInsufficientStock):
+			app.conflictResponse(w, r, fmt.Errorf("not enough %s at %s to issue %d", consumable.Name, payload.Location, payload.Quantity))
+		default:
+			app.internalServerError(w, r, err)
+		}
+		return
+	}
+
+	if stock.Low() {
+		app.alertLowStockNow(ctx)
+	}
+
+	if err := app.jsonResponse(w, http.StatusCreated, responses.NewConsumableStockResponse(stock)); err != nil {
+		app.internalServerError(w, r, err)
+		return
+	}
+}
+
+func (app *application) receiveConsumableHandler(w http.ResponseWriter, r *http.Request) {
+	consumable := getConsumableFromCtx(r)
+
+	var payload requests.ReceiveConsumablePayload
+	if err := readJSON(w, r, &payload); err != nil {
+		app.badRequestResponse(w, r, err)
+		return
+	}
+
+	if err := Validate.Struct(payload); err != nil {
+		app.badRequestResponse(w, r, err)
+		return
+	}
+
+	movement := &store.ConsumableMovement{
+		ConsumableID: consumable.ID,
+		Location:     payload.Location,
+		Quantity:     payload.Quantity,
+		SupplierID:   payload.SupplierID,
+		UnitCost:     payload.UnitCost,
+		Notes:        payload.Notes,
+	}
+	if user := getUserFromContext(r); user != nil {
+		movement.PerformedByID = &user.ID
+	}
+
+	stock, err := app.store.Consumable.Receive(r.Context(), movement)
+	if err != nil {
+		app.internalServerError(w, r, err)
+		return
+	}
+
+	if err := app.jsonResponse(w, http.StatusCreated, responses.NewConsumableStockResponse(stock)); err != nil {
+		app.internalServerError(w, r, err)
+		return
+	}
+}
+
+func (app *application) getConsumableMovementsHandler(w http.ResponseWriter, r *http.Request) {
+	consumable := getConsumableFromCtx(r)
+
+	spec, ok := app.parseQuerySpec(w, r)
+	if !ok {
+		return
+	}
+
+	list := func(ctx context.Context, spec store.QuerySpec) (*store.Pagination, error) {
+		return app.store.Consumable.Movements(ctx, consumable.ID, spec)
+	}
+
+	writeListPage(app, w, r, spec, list, responses.NewConsumableMovementsResponse)
+}
+
+func (app *application) getLowStockHandler(w http.ResponseWriter, r *http.Request) {
+	stocks, err := app.store.Consumable.GetLow(r.Context())
+	if err != nil {
+		app.internalServerError(w, r, err)
+		return
+	}
+
+	if err := app.jsonResponse(w, http.StatusOK, responses.NewConsumableStocksResponse(stocks)); err != nil {
+		app.internalServerError(w, r, err)
+	}
+}
+
+// getConsumptionHandler reports what each department used per month. from and
+// to are months (2006-01), both inclusive; the last twelve months by default.
+func (app *application) getConsumptionHandler(w http.ResponseWriter, r *http.Request) {
+	query := r.URL.Query()
+
+	now := time.Now()
+	to := time.Date(now.Year(), now.Month(), 1, 0, 0, 0, 0, time.UTC)
+	from := to.AddDate(0, 1-defaultConsumptionMonths, 0)
+
+	for name, month := range map[string]*time.Time{"from": &from, "to": &to} {
+		param := query.Get(name)
+		if param == "" {
+			continue
+		}
+
+		t, err := time.Parse("2006-01", param)
+		if err != nil {
+			app.badRequestResponse(w, r, fmt.Errorf("%s must be a month like 2006-01", name))
+			return
+		}
+		*month = t
+	}
+
+	if to.Before(from) {
+		app.badRequestResponse(w, r, errors.New("to must not be before from"))
+		return
+	}
+
+	var departmentID *int64
+	if param := query.Get("departmentId"); param != "" {
+		v, err := strconv.ParseInt(param, 10, 64)
+		if err != nil {
+			app.badRequestResponse(w, r, fmt.Errorf("invalid departmentId %q", param))
+			return
+		}
+		departmentID = &v
+	}
+
+	usage, err := app.store.Consumable.Usage(r.Context(), from, to.AddDate(0, 1, 0), departmentID)
+	if err != nil {
+		app.internalServerError(w, r, err)
+		return
+	}
+
+	if err := app.jsonResponse(w, http.StatusOK, responses.NewConsumableUsageResponse(usage)); err != nil {
+		app.internalServerError(w, r, err)
+	}
+}
+
+// alertLowStockNow sends low-stock alerts right after stock changed instead of
+// waiting for the scheduler. Failures are left for the next scheduled run.
+func (app *application) alertLowStockNow(ctx context.Context) {
+	if err := app.alertLowStock(ctx); err != nil {
+		app.logger.Errorw("low stock alert failed", "error", err.Error())
+	}
+}
+
+// alertLowStock tells the admins once about every stock entry dropping to its
+// minimum. The alert is re-armed when the stock is replenished.
+func (app *application) alertLowStock(ctx context.Context) error {
+	stocks, err := app.store.Consumable.GetUnnotifiedLow(ctx)
+	if err != nil || len(stocks) == 0 {
+		return err
+	}
+
+	admins, err := app.store.Users.GetIDsByRole(ctx, lowStockAlertRole)
+	if err != nil {
+		return err
+	}
+
+	for _, stock := range stocks {
+		notifications := make([]store.Notification, len(admins))
+		for i, userID := range admins {
+			notifications[i] = store.Notification{
+				UserID: userID,
+				Kind:   "consumable_low_stock",
+				Title:  fmt.Sprintf("%s is running low at %s", stock.Consumable.Name, stock.Location),
+				Body:   fmt.Sprintf("%d %s left at %s, the minimum is %d.", stock.Quantity, stock.Consumable.Name, stock.Location, stock.MinQuantity),
+			}
+		}
+
+		if _, err := app.store.Consumable.MarkLowNotified(ctx, stock.ID, notifications); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
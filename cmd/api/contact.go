@@ -0,0 +1,218 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"strconv"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/knr1997/assets-management-apiserver/internal/api/requests"
+	"github.com/knr1997/assets-management-apiserver/internal/api/responses"
+	"github.com/knr1997/assets-management-apiserver/internal/store"
+)
+
+type contactKey string
+
+const contactCtx contactKey = "contact"
+
+func getContactFromCtx(r *http.Request) *store.Contact {
+	contact, _ := r.Context().Value(contactCtx).(*store.Contact)
+	return contact
+}
+
+// contactOwner returns the supplier or manufacturer the contact routes are
+// mounted under.
+func contactOwner(r *http.Request) (string, int64) {
+	if supplier := getsupplierFromCtx(r); supplier != nil {
+		return store.ContactOwnerSupplier, supplier.ID
+	}
+
+	manufacturer := getManufacturerFromCtx(r)
+	return store.ContactOwnerManufacturer, manufacturer.ID
+}
+
+func (app *application) contactContextMiddleware(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		idParam := chi.URLParam(r, "contactID")
+		id, err := strconv.ParseInt(idParam, 10, 64)
+		if err != nil {
+			app.internalServerError(w, r, err)
+			return
+		}
+
+		ctx := r.Context()
+		ownerType, ownerID := contactOwner(r)
+
+		contact, err := app.store.Contact.GetByID(ctx, ownerType, ownerID, id)
+		if err != nil {
+			switch {
+			case errors.Is(err, store.ErrNotFound):
+				app.notFoundResponse(w, r, err)
+			default:
+				app.internalServerError(w, r, err)
+			}
+			return
+		}
+
+		ctx = context.WithValue(ctx, contactCtx, contact)
+		next.ServeHTTP(w, r.WithContext(ctx))
+	})
+}
+
+func (app *application) getAllContactHandler(w http.ResponseWriter, r *http.Request) {
+	ownerType, ownerID := contactOwner(r)
+
+	contacts, err := app.store.Contact.GetAll(r.Context(), ownerType, ownerID)
+	if err != nil {
+		app.internalServerError(w, r, err)
+		return
+	}
+
+	if err := app.jsonResponse(w, http.StatusOK, responses.NewContactsResponse(contacts)); err != nil {
+		app.internalServerError(w, r, err)
+		return
+	}
+}
+
+func (app *application) createContactHandler(w http.ResponseWriter, r *http.Request) {
+	var payload requests.CreateContactPayload
+	if err := readJSON(w, r, &payload); err != nil {
+		app.badRequestResponse(w, r, err)
+		return
+	}
+
+	if err := Validate.Struct(payload); err != nil {
+		app.badRequestResponse(w, r, err)
+		return
+	}
+
+	ownerType, ownerID := contactOwner(r)
+
+	contact := &store.Contact{
+		OwnerType: ownerType,
+		OwnerID:   ownerID,
+		Name:      payload.Name,
+		Email:     payload.Email,
+		Phone:     payload.Phone,
+		Role:      payload.Role,
+		Notes:     payload.Notes,
+	}
+
+	if err := app.store.Contact.Create(r.Context(), contact); err != nil {
+		app.internalServerError(w, r, err)
+		return
+	}
+
+	if err := app.jsonResponse(w, http.StatusCreated, responses.NewContactResponse(contact)); err != nil {
+		app.internalServerError(w, r, err)
+		return
+	}
+}
+
+func (app *application) getContactHandler(w http.ResponseWriter, r *http.Request) {
+	contact := getContactFromCtx(r)
+
+	if err := app.jsonResponse(w, http.StatusOK, responses.NewContactResponse(contact)); err != nil {
+		app.internalServerError(w, r, err)
+		return
+	}
+}
+
+func (app *application) updateContactHandler(w http.ResponseWriter, r *http.Request) {
+	contact := getContactFromCtx(r)
+
+	var payload requests.UpdateContactPayload
+	if err := readJSON(w, r, &payload); err != nil {
+		app.badRequestResponse(w, r, err)
+		return
+	}
+
+	if err := Validate.Struct(payload); err != nil {
+		app.badRequestResponse(w, r, err)
+		return
+	}
+
+	if payload.Name != nil {
+		contact.Name = *payload.Name
+	}
+	if payload.Email != nil {
+		contact.Email = *payload.Email
+	}
+	if payload.Phone != nil {
+		contact.Phone = *payload.Phone
+	}
+	if payload.Role != nil {
+		contact.Role = *payload.Role
+	}
+	if payload.Notes != nil {
+		contact.Notes = *payload.Notes
+	}
+
+	ctx := r.Context()
+
+	if err := app.store.Contact.Update(ctx, contact); err != nil {
+		switch {
+		case errors.Is(err, store.ErrNotFound):
+			app.notFoundResponse(w, r, err)
+		default:
+			app.internalServerError(w, r, err)
+		}
+		return
+	}
+
+	ownerType, ownerID := contactOwner(r)
+
+	updated, err := app.store.Contact.GetByID(ctx, ownerType, ownerID, contact.ID)
+	if err != nil {
+		app.internalServerError(w, r, err)
+		return
+	}
+
+	if err := app.jsonResponse(w, http.StatusOK, responses.NewContactResponse(updated)); err != nil {
+		app.internalServerError(w, r, err)
+		return
+	}
+}
+
+func (app *application) deleteContactHandler(w http.ResponseWriter, r *http.Request) {
+	contact := getContactFromCtx(r)
+
+	if err := app.store.Contact.Delete(r.Context(), contact.OwnerType, contact.OwnerID, contact.ID); err != nil {
+		switch {
+		case errors.Is(err, store.ErrNotFound):
+			app.notFoundResponse(w, r, err)
+		default:
+			app.internalServerError(w, r, err)
+		}
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// applyVendorDetails copies the fields present in payload onto d.
+func applyVendorDetails(d *store.VendorDetails, payload requests.VendorDetailsPayload) {
+	set := func(dst *string, src *string) {
+		if src != nil {
+			*dst = *src
+		}
+	}
+
+	set(&d.Website, payload.Website)
+	set(&d.Phone, payload.Phone)
+	set(&d.SupportURL, payload.SupportURL)
+	set(&d.SupportPhone, payload.SupportPhone)
+	set(&d.SupportEmail, payload.SupportEmail)
+	set(&d.AccountNumber, payload.AccountNumber)
+	set(&d.Notes, payload.Notes)
+
+	if a := payload.Address; a != nil {
+		set(&d.Address.Line1, a.Line1)
+		set(&d.Address.Line2, a.Line2)
+		set(&d.Address.City, a.City)
+		set(&d.Address.Region, a.Region)
+		set(&d.Address.PostalCode, a.PostalCode)
+		set(&d.Address.Country, a.Country)
+	}
+}
@@ -0,0 +1,323 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"strconv"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/knr1997/assets-management-apiserver/internal/api/requests"
+	"github.com/knr1997/assets-management-apiserver/internal/api/responses"
+	"github.com/knr1997/assets-management-apiserver/internal/secret"
+	"github.com/knr1997/assets-management-apiserver/internal/store"
+)
+
+type customFieldSetKey string
+
+const customFieldSetCtx customFieldSetKey = "customFieldSet"
+
+// customFieldRevealRole may read encrypted custom field values in plain text.
+const customFieldRevealRole = "admin"
+
+func getCustomFieldSetFromCtx(r *http.Request) *store.CustomFieldSet {
+	set, _ := r.Context().Value(customFieldSetCtx).(*store.CustomFieldSet)
+	return set
+}
+
+func (app *application) customFieldSetContextMiddleware(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		idParam := chi.URLParam(r, "fieldSetID")
+		id, err := strconv.ParseInt(idParam, 10, 64)
+		if err != nil {
+			app.internalServerError(w, r, err)
+			return
+		}
+
+		ctx := r.Context()
+
+		set, err := app.store.CustomField.GetByID(ctx, id)
+		if err != nil {
+			switch {
+			case errors.Is(err, store.ErrNotFound):
+				app.notFoundResponse(w, r, err)
+			default:
+				app.internalServerError(w, r, err)
+			}
+			return
+		}
+
+		ctx = context.WithValue(ctx, customFieldSetCtx, set)
+		next.ServeHTTP(w, r.WithContext(ctx))
+	})
+}
+
+func toCustomFields(payload []requests.CustomFieldPayload) []store.CustomField {
+	fields := make([]store.CustomField, len(payload))
+
+	for i, f := range payload {
+		fields[i] = store.CustomField{
+			Key:      f.Key,
+			Label:    f.Label,
+			Type:     store.CustomFieldType(f.Type),
+			Required: f.Required,
+			Unique:   f.Unique,
+			Options:  f.Options,
+			Pattern:  f.Pattern,
+			Position: i,
+		}
+	}
+
+	return fields
+}
+
+func (app *application) createCustomFieldSetHandler(w http.ResponseWriter, r *http.Request) {
+	var payload requests.CreateCustomFieldSetPayload
+	if err := readJSON(w, r, &payload); err != nil {
+		app.badRequestResponse(w, r, err)
+		return
+	}
+
+	if err := Validate.Struct(payload); err != nil {
+		app.badRequestResponse(w, r, err)
+		return
+	}
+
+	set := &store.CustomFieldSet{
+		Name:       payload.Name,
+		CategoryID: payload.CategoryID,
+		ModelID:    payload.ModelID,
+		Fields:     toCustomFields(payload.Fields),
+	}
+
+	if err := set.Validate(); err != nil {
+		app.badRequestResponse(w, r, err)
+		return
+	}
+
+	ctx := r.Context()
+
+	if err := app.store.CustomField.Create(ctx, set); err != nil {
+		app.customFieldErrorResponse(w, r, err)
+		return
+	}
+
+	if err := app.jsonResponse(w, http.StatusCreated, responses.NewCustomFieldSetResponse(set)); err != nil {
+		app.internalServerError(w, r, err)
+		return
+	}
+}
+
+func (app *application) updateCustomFieldSetHandler(w http.ResponseWriter, r *http.Request) {
+	set := getCustomFieldSetFromCtx(r)
+
+	var payload requests.UpdateCustomFieldSetPayload
+	if err := readJSON(w, r, &payload); err != nil {
+		app.badRequestResponse(w, r, err)
+		return
+	}
+
+	if err := Validate.Struct(payload); err != nil {
+		app.badRequestResponse(w, r, err)
+		return
+	}
+
+	if payload.Name != nil {
+		set.Name = *payload.Name
+	}
+	if payload.CategoryID != nil {
+		set.CategoryID = payload.CategoryID
+		set.ModelID = nil
+	}
+	if payload.ModelID != nil {
+		set.ModelID = payload.ModelID
+		set.CategoryID = nil
+	}
+	if payload.Fields != nil {
+		set.Fields = toCustomFields(*payload.Fields)
+	}
+
+	if err := set.Validate(); err != nil {
+		app.badRequestResponse(w, r, err)
+		return
+	}
+
+	ctx := r.Context()
+
+	if err := app.store.CustomField.Update(ctx, set); err != nil {
+		app.customFieldErrorResponse(w, r, err)
+		return
+	}
+
+	if err := app.jsonResponse(w, http.StatusOK, responses.NewCustomFieldSetResponse(set)); err != nil {
+		app.internalServerError(w, r, err)
+	}
+}
+
+func (app *application) getCustomFieldSetHandler(w http.ResponseWriter, r *http.Request) {
+	set := getCustomFieldSetFromCtx(r)
+
+	if err := app.jsonResponse(w, http.StatusOK, responses.NewCustomFieldSetResponse(set)); err != nil {
+		app.internalServerError(w, r, err)
+		return
+	}
+}
+
+func (app *application) getAllCustomFieldSetHandler(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+
+	sets, err := app.store.CustomField.GetAll(ctx)
+	if err != nil {
+		app.internalServerError(w, r, err)
+		return
+	}
+
+	if err := app.jsonResponse(w, http.StatusOK, responses.NewCustomFieldSetsResponse(sets)); err != nil {
+		app.internalServerError(w, r, err)
+	}
+}
+
+func (app *application) deleteCustomFieldSetHandler(w http.ResponseWriter, r *http.Request) {
+	set := getCustomFieldSetFromCtx(r)
+
+	ctx := r.Context()
+
+	if err := app.store.CustomField.Delete(ctx, set.ID); err != nil {
+		switch {
+		case errors.Is(err, store.ErrNotFound):
+			app.notFoundResponse(w, r, err)
+		default:
+			app.internalServerError(w, r, err)
+		}
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+func (app *application) getModelCustomFieldsHandler(w http.ResponseWriter, r *http.Request) {
+	model := getModelFromCtx(r)
+
+	ctx := r.Context()
+
+	fields, err := app.store.CustomField.GetForModel(ctx, model.ID)
+	if err != nil {
+		app.internalServerError(w, r, err)
+		return
+	}
+
+	if err := app.jsonResponse(w, http.StatusOK, responses.NewCustomFieldsResponse(fields)); err != nil {
+		app.internalServerError(w, r, err)
+	}
+}
+
+// resolveCustomFields validates input against the fields defined for the
+// asset's model and merges it into the asset's current values. Encrypted
+// values are sealed before they are stored.
+func (app *application) resolveCustomFields(ctx context.Context, asset *store.Asset, input map[string]any) error {
+	fields, err := app.store.CustomField.GetForModel(ctx, asset.ModelID)
+	if err != nil {
+		return err
+	}
+
+	defs := make(map[string]*store.CustomField, len(fields))
+	for i := range fields {
+		defs[fields[i].Key] = &fields[i]
+	}
+
+	values := store.CustomFieldValues{}
+	for k, v := range asset.CustomFields {
+		values[k] = v
+	}
+
+	for key, raw := range input {
+		def, ok := defs[key]
+		if !ok {
+			return fmt.Errorf("%w: %s is not defined for this model", store.ErrInvalidCustomField, key)
+		}
+
+		if raw == nil || raw == "" {
+			delete(values, key)
+			continue
+		}
+
+		value, err := def.Normalize(raw)
+		if err != nil {
+			return err
+		}
+
+		if def.Type == store.CustomFieldEncrypted {
+			value, err = app.secrets.Seal(value.(string))
+			if err != nil {
+				return err
+			}
+		}
+
+		if def.Unique {
+			taken, err := app.store.CustomField.IsValueTaken(ctx, key, value, asset.ID)
+			if err != nil {
+				return err
+			}
+			if taken {
+				return fmt.Errorf("%w: %s", store.ErrCustomFieldTaken, key)
+			}
+		}
+
+		values[key] = value
+	}
+
+	// values for fields that no longer apply, e.g. after the model changed
+	for key := range values {
+		if _, ok := defs[key]; !ok {
+			delete(values, key)
+		}
+	}
+
+	for _, def := range fields {
+		if _, ok := values[def.Key]; def.Required && !ok {
+			return fmt.Errorf("%w: %s is required", store.ErrInvalidCustomField, def.Key)
+		}
+	}
+
+	asset.CustomFields = values
+
+	return nil
+}
+
+// revealCustomFields opens the encrypted values of a single asset response
+// for users holding customFieldRevealRole; everyone else keeps the mask.
+func (app *application) revealCustomFields(r *http.Request, asset *store.Asset, response *responses.AssetResponse) error {
+	ok, err := app.hasRole(r.Context(), getUserFromContext(r), customFieldRevealRole)
+	if err != nil || !ok {
+		return err
+	}
+
+	for k, v := range asset.CustomFields {
+		s, ok := v.(string)
+		if !ok || !secret.IsSealed(s) {
+			continue
+		}
+
+		plain, err := app.secrets.Open(s)
+		if err != nil {
+			app.logger.Warnw("could not open custom field", "asset", asset.ID, "field", k, "error", err.Error())
+			continue
+		}
+
+		response.CustomFields[k] = plain
+	}
+
+	return nil
+}
+
+func (app *application) customFieldErrorResponse(w http.ResponseWriter, r *http.Request, err error) {
+	switch {
+	case errors.Is(err, store.ErrInvalidCustomField):
+		app.badRequestResponse(w, r, err)
+	case errors.Is(err, store.ErrCustomFieldTaken):
+		app.conflictResponse(w, r, err)
+	default:
+		app.internalServerError(w, r, err)
+	}
+}
@@ -2,7 +2,6 @@ package main
 
 import (
 	"context"
-	"encoding/json"
 	"errors"
 	"net/http"
 	"strconv"
@@ -47,9 +46,16 @@ func (app *application) departmentContextMiddleware(next http.Handler) http.Hand
 	})
 }
 
+// canSetManager reports whether the user may set a department's manager,
+// who approves the asset requests of the department.
+func (app *application) canSetManager(r *http.Request) (bool, error) {
+	return app.hasRole(r.Context(), getUserFromContext(r), approvalAdminRole)
+}
+
 type CreatedepartmentPayload struct {
-	Name  string `json:"name" validate:"required,max=100"`
-	Notes string `json:"description"`
+	Name      string `json:"name" validate:"required,max=100"`
+	Notes     string `json:"description"`
+	ManagerID *int64 `json:"managerId"`
 }
 
 func (app *application) createdepartmentHandler(w http.ResponseWriter, r *http.Request) {
@@ -64,9 +70,22 @@ func (app *application) createdepartmentHandler(w http.ResponseWriter, r *http.R
 		return
 	}
 
+	if payload.ManagerID != nil {
+		allowed, err := app.canSetManager(r)
+		if err != nil {
+			app.internalServerError(w, r, err)
+			return
+		}
+		if !allowed {
+			app.forbiddenResponse(w, r)
+			return
+		}
+	}
+
 	post := &store.Department{
-		Name:  payload.Name,
-		Notes: payload.Notes,
+		Name:      payload.Name,
+		Notes:     payload.Notes,
+		ManagerID: payload.ManagerID,
 	}
 
 	ctx := r.Context()
@@ -83,8 +102,9 @@ func (app *application) createdepartmentHandler(w http.ResponseWriter, r *http.R
 }
 
 type UpdatedepartmentPayload struct {
-	Name  *string `json:"name" validate:"omitempty,max=100"`
-	Notes *string `json:"notes"`
+	Name      *string `json:"name" validate:"omitempty,max=100"`
+	Notes     *string `json:"notes"`
+	ManagerID *int64  `json:"managerId"`
 }
 
 func (app *application) updatedepartment(ctx context.Context, department *store.Department) error {
@@ -115,6 +135,18 @@ func (app *application) updatedepartmentHandler(w http.ResponseWriter, r *http.R
 	if payload.Notes != nil {
 		department.Notes = *payload.Notes
 	}
+	if payload.ManagerID != nil {
+		allowed, err := app.canSetManager(r)
+		if err != nil {
+			app.internalServerError(w, r, err)
+			return
+		}
+		if !allowed {
+			app.forbiddenResponse(w, r)
+			return
+		}
+		department.ManagerID = payload.ManagerID
+	}
 
 	ctx := r.Context()
 
@@ -139,16 +171,18 @@ func (app *application) getdepartmentHandler(w http.ResponseWriter, r *http.Requ
 }
 
 type departmentResponse struct {
-	ID    int64  `json:"id"`
-	Name  string `json:"name"`
-	Notes string `json:"notes"`
+	ID        int64  `json:"id"`
+	Name      string `json:"name"`
+	Notes     string `json:"notes"`
+	ManagerID *int64 `json:"managerId"`
 }
 
 func TodepartmentResponse(a *store.Department) departmentResponse {
 	return departmentResponse{
-		ID:    a.ID,
-		Name:  a.Name,
-		Notes: a.Notes,
+		ID:        a.ID,
+		Name:      a.Name,
+		Notes:     a.Notes,
+		ManagerID: a.ManagerID,
 	}
 }
 
@@ -163,18 +197,12 @@ func TodepartmentResponseList(categories []store.Department) []departmentRespons
 }
 
 func (app *application) getAlldepartmentHandler(w http.ResponseWriter, r *http.Request) {
-	ctx := r.Context()
-
-	categories, err := app.store.Department.GetAll(ctx)
-	if err != nil {
-		app.internalServerError(w, r, err)
+	spec, ok := app.parseQuerySpec(w, r)
+	if !ok {
 		return
 	}
 
-	response := TodepartmentResponseList(categories)
-
-	w.Header().Set("Content-Type", "application/json")
-	json.NewEncoder(w).Encode(response)
+	writeListPage(app, w, r, spec, app.store.Department.List, TodepartmentResponseList)
 }
 
 func (app *application) deletedepartmentHandler(w http.ResponseWriter, r *http.Request) {
@@ -0,0 +1,498 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/knr1997/assets-management-apiserver/internal/api/requests"
+	"github.com/knr1997/assets-management-apiserver/internal/api/responses"
+	"github.com/knr1997/assets-management-apiserver/internal/store"
+)
+
+// certificateTypes are the content types accepted as certificates.
+var certificateTypes = map[string]bool{
+	"application/pdf": true,
+	"image/png":       true,
+	"image/jpeg":      true,
+}
+
+type disposalKey string
+
+const disposalCtx disposalKey = "disposal"
+
+func getDisposalFromCtx(r *http.Request) *store.Disposal {
+	disposal, _ := r.Context().Value(disposalCtx).(*store.Disposal)
+	return disposal
+}
+
+func (app *application) disposalContextMiddleware(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		idParam := chi.URLParam(r, "disposalID")
+		id, err := strconv.ParseInt(idParam, 10, 64)
+		if err != nil {
+			app.internalServerError(w, r, err)
+			return
+		}
+
+		ctx := r.Context()
+
+		disposal, err := app.store.Disposal.GetByID(ctx, id)
+		if err != nil {
+			switch {
+			case errors.Is(err, store.ErrNotFound):
+				app.notFoundResponse(w, r, err)
+			default:
+				app.internalServerError(w, r, err)
+			}
+			return
+		}
+
+		ctx = context.WithValue(ctx, disposalCtx, disposal)
+		next.ServeHTTP(w, r.WithContext(ctx))
+	})
+}
+
+func (app *application) getAllDisposalHandler(w http.ResponseWriter, r *http.Request) {
+	spec, ok := app.parseQuerySpec(w, r)
+	if !ok {
+		return
+	}
+
+	writeListPage(app, w, r, spec, app.store.Disposal.List, responses.NewDisposalsResponse)
+}
+
+func (app *application) getDisposalHandler(w http.ResponseWriter, r *http.Request) {
+	disposal := getDisposalFromCtx(r)
+
+	if err := app.jsonResponse(w, http.StatusOK, responses.NewDisposalResponse(disposal)); err != nil {
+		app.internalServerError(w, r, err)
+		return
+	}
+}
+
+// createDisposalHandler asks for an asset to be disposed of, which the
+// disposal approvers are told about.
+func (app *application) createDisposalHandler(w http.ResponseWriter, r *http.Request) {
+	user := getUserFromContext(r)
+
+	var payload requests.CreateDisposalPayload
+	if err := readJSON(w, r, &payload); err != nil {
+		app.badRequestResponse(w, r, err)
+		return
+	}
+
+	if err := Validate.Struct(payload); err != nil {
+		app.badRequestResponse(w, r, err)
+		return
+	}
+
+	disposal := &store.Disposal{
+		AssetID:       payload.AssetID,
+		Method:        store.DisposalMethod(payload.Method),
+		Reason:        payload.Reason,
+		RequestedByID: &user.ID,
+	}
+
+	ctx := r.Context()
+
+	if err := app.store.Disposal.Create(ctx, disposal); err != nil {
+		switch {
+		case errors.Is(err, store.ErrNotFound):
+			app.notFoundResponse(w, r, err)
+		case errors.Is(err, store.ErrAssetNotAvailable):
+			app.conflictResponse(w, r, errors.New("asset is checked out, only a write-off can dispose of it"))
+		case errors.Is(err, store.ErrConflict):
+			app.conflictResponse(w, r, errors.New("asset already has a disposal"))
+		default:
+			app.internalServerError(w, r, err)
+		}
+		return
+	}
+
+	disposal, err := app.store.Disposal.GetByID(ctx, disposal.ID)
+	if err != nil {
+		app.internalServerError(w, r, err)
+		return
+	}
+
+	app.notifyDisposal(ctx, disposal)
+
+	if err := app.jsonResponse(w, http.StatusCreated, responses.NewDisposalResponse(disposal)); err != nil {
+		app.internalServerError(w, r, err)
+		return
+	}
+}
+
+func (app *application) approveDisposalHandler(w http.ResponseWriter, r *http.Request) {
+	app.decideDisposal(w, r, store.DecisionApproved)
+}
+
+func (app *application) rejectDisposalHandler(w http.ResponseWriter, r *http.Request) {
+	app.decideDisposal(w, r, store.DecisionRejected)
+}
+
+func (app *application) decideDisposal(w http.ResponseWriter, r *http.Request, decision store.ApprovalDecision) {
+	user := getUserFromContext(r)
+	disposal := getDisposalFromCtx(r)
+
+	var payload requests.DecideDisposalPayload
+	if err := readJSON(w, r, &payload); err != nil && !errors.Is(err, io.EOF) {
+		app.badRequestResponse(w, r, err)
+		return
+	}
+
+	if err := Validate.Struct(payload); err != nil {
+		app.badRequestResponse(w, r, err)
+		return
+	}
+
+	ctx := r.Context()
+
+	if err := app.store.Disposal.Decide(ctx, disposal.ID, user, decision, payload.Comment); err != nil {
+		switch {
+		case errors.Is(err, store.ErrNotFound):
+			app.notFoundResponse(w, r, err)
+		case errors.Is(err, store.ErrNotApprover):
+			app.forbiddenResponse(w, r)
+		case errors.Is(err, store.ErrDisposalNotPending):
+			app.conflictResponse(w, r, fmt.Errorf("disposal is %s", disposal.Status))
+		default:
+			app.internalServerError(w, r, err)
+		}
+		return
+	}
+
+	disposal, err := app.store.Disposal.GetByID(ctx, disposal.ID)
+	if err != nil {
+		app.internalServerError(w, r, err)
+		return
+	}
+
+	app.notifyDisposal(ctx, disposal)
+
+	if err := app.jsonResponse(w, http.StatusOK, responses.NewDisposalResponse(disposal)); err != nil {
+		app.internalServerError(w, r, err)
+		return
+	}
+}
+
+// confirmDataWipeHandler records that the asset's storage was wiped before it
+// left.
+func (app *application) confirmDataWipeHandler(w http.ResponseWriter, r *http.Request) {
+	user := getUserFromContext(r)
+	disposal := getDisposalFromCtx(r)
+
+	var payload requests.ConfirmDataWipePayload
+	if err := readJSON(w, r, &payload); err != nil {
+		app.badRequestResponse(w, r, err)
+		return
+	}
+
+	if err := Validate.Struct(payload); err != nil {
+		app.badRequestResponse(w, r, err)
+		return
+	}
+
+	ctx := r.Context()
+
+	if err := app.store.Disposal.ConfirmDataWipe(ctx, disposal.ID, user.ID, payload.Method); err != nil {
+		switch {
+		case errors.Is(err, store.ErrDisposalClosed):
+			app.conflictResponse(w, r, fmt.Errorf("disposal is %s", disposal.Status))
+		default:
+			app.internalServerError(w, r, err)
+		}
+		return
+	}
+
+	disposal, err := app.store.Disposal.GetByID(ctx, disposal.ID)
+	if err != nil {
+		app.internalServerError(w, r, err)
+		return
+	}
+
+	if err := app.jsonResponse(w, http.StatusOK, responses.NewDisposalResponse(disposal)); err != nil {
+		app.internalServerError(w, r, err)
+		return
+	}
+}
+
+// uploadDisposalCertificateHandler keeps a recycling or destruction
+// certificate, sent as the "file" field of a multipart form.
+func (app *application) uploadDisposalCertificateHandler(w http.ResponseWriter, r *http.Request) {
+	user := getUserFromContext(r)
+	disposal := getDisposalFromCtx(r)
+
+	upload, err := app.readUpload(w, r, certificateTypes)
+	if err != nil {
+		app.badRequestResponse(w, r, err)
+		return
+	}
+	defer upload.close()
+
+	ctx := r.Context()
+
+	certificate, err := app.putUpload(ctx, store.AttachmentOwnerDisposal, disposal.ID, upload, user.ID)
+	if err != nil {
+		app.internalServerError(w, r, err)
+		return
+	}
+
+	if err := app.store.Disposal.AddCertificate(ctx, disposal.ID, certificate); err != nil {
+		app.removeBlobs(ctx, certificate.StorageKey)
+		switch {
+		case errors.Is(err, store.ErrNotFound):
+			app.notFoundResponse(w, r, err)
+		case errors.Is(err, store.ErrDisposalClosed):
+			app.conflictResponse(w, r, fmt.Errorf("disposal is %s", disposal.Status))
+		default:
+			app.internalServerError(w, r, err)
+		}
+		return
+	}
+
+	disposal, err = app.store.Disposal.GetByID(ctx, disposal.ID)
+	if err != nil {
+		app.internalServerError(w, r, err)
+		return
+	}
+
+	if err := app.jsonResponse(w, http.StatusCreated, responses.NewDisposalResponse(disposal)); err != nil {
+		app.internalServerError(w, r, err)
+		return
+	}
+}
+
+func (app *application) getDisposalCertificateHandler(w http.ResponseWriter, r *http.Request) {
+	disposal := getDisposalFromCtx(r)
+
+	id, err := strconv.ParseInt(chi.URLParam(r, "certificateID"), 10, 64)
+	if err != nil {
+		app.internalServerError(w, r, err)
+		return
+	}
+
+	certificate, err := app.store.Attachment.GetByID(r.Context(), id)
+	if err != nil {
+		switch {
+		case errors.Is(err, store.ErrNotFound):
+			app.notFoundResponse(w, r, err)
+		default:
+			app.internalServerError(w, r, err)
+		}
+		return
+	}
+	if certificate.OwnerType != store.AttachmentOwnerDisposal || certificate.OwnerID != disposal.ID {
+		app.notFoundResponse(w, r, store.ErrNotFound)
+		return
+	}
+
+	app.writeAttachment(w, r, certificate)
+}
+
+// completeDisposalHandler books an approved disposal once the asset is gone,
+// working out the gain or loss against its book value.
+func (app *application) completeDisposalHandler(w http.ResponseWriter, r *http.Request) {
+	user := getUserFromContext(r)
+	disposal := getDisposalFromCtx(r)
+
+	var payload requests.CompleteDisposalPayload
+	if err := readJSON(w, r, &payload); err != nil && !errors.Is(err, io.EOF) {
+		app.badRequestResponse(w, r, err)
+		return
+	}
+
+	if err := Validate.Struct(payload); err != nil {
+		app.badRequestResponse(w, r, err)
+		return
+	}
+
+	if payload.Proceeds > 0 && disposal.Method != store.DisposalSale {
+		app.badRequestResponse(w, r, errors.New("only a sale has proceeds"))
+		return
+	}
+
+	disposedAt := time.Now()
+	if payload.DisposedAt != nil {
+		if payload.DisposedAt.After(disposedAt) {
+			app.badRequestResponse(w, r, errors.New("disposedAt cannot be in the future"))
+			return
+		}
+		disposedAt = *payload.DisposedAt
+	}
+
+	completion := &store.Disposal{
+		ID:            disposal.ID,
+		DisposedAt:    &disposedAt,
+		Proceeds:      payload.Proceeds,
+		Recipient:     payload.Recipient,
+		Reference:     payload.Reference,
+		CompletedByID: &user.ID,
+	}
+
+	ctx := r.Context()
+
+	if err := app.store.Disposal.Complete(ctx, completion); err != nil {
+		switch {
+		case errors.Is(err, store.ErrNotFound):
+			app.notFoundResponse(w, r, err)
+		case errors.Is(err, store.ErrDisposalNotApproved):
+			app.conflictResponse(w, r, fmt.Errorf("disposal is %s", disposal.Status))
+		case errors.Is(err, store.ErrDataWipeRequired),
+			errors.Is(err, store.ErrCertificateRequired),
+			errors.Is(err, store.ErrAssetNotAvailable):
+			app.conflictResponse(w, r, err)
+		default:
+			app.internalServerError(w, r, err)
+		}
+		return
+	}
+
+	disposal, err := app.store.Disposal.GetByID(ctx, disposal.ID)
+	if err != nil {
+		app.internalServerError(w, r, err)
+		return
+	}
+
+	if err := app.jsonResponse(w, http.StatusOK, responses.NewDisposalResponse(disposal)); err != nil {
+		app.internalServerError(w, r, err)
+		return
+	}
+}
+
+func (app *application) cancelDisposalHandler(w http.ResponseWriter, r *http.Request) {
+	disposal := getDisposalFromCtx(r)
+
+	ctx := r.Context()
+
+	if err := app.store.Disposal.Cancel(ctx, disposal.ID); err != nil {
+		switch {
+		case errors.Is(err, store.ErrDisposalClosed):
+			app.conflictResponse(w, r, fmt.Errorf("disposal is %s", disposal.Status))
+		default:
+			app.internalServerError(w, r, err)
+		}
+		return
+	}
+
+	disposal, err := app.store.Disposal.GetByID(ctx, disposal.ID)
+	if err != nil {
+		app.internalServerError(w, r, err)
+		return
+	}
+
+	if err := app.jsonResponse(w, http.StatusOK, responses.NewDisposalResponse(disposal)); err != nil {
+		app.internalServerError(w, r, err)
+		return
+	}
+}
+
+// getDisposalRegisterHandler lists the completed disposals, filtered and
+// sorted like any other list.
+func (app *application) getDisposalRegisterHandler(w http.ResponseWriter, r *http.Request) {
+	spec, ok := app.parseQuerySpec(w, r)
+	if !ok {
+		return
+	}
+
+	writeListPage(app, w, r, spec, app.store.Disposal.ListRegister, responses.NewDisposalsResponse)
+}
+
+// getDisposalSummaryHandler totals the disposal register by method. from and
+// to are dates (2006-01-02), both inclusive and both optional.
+func (app *application) getDisposalSummaryHandler(w http.ResponseWriter, r *http.Request) {
+	query := r.URL.Query()
+
+	var from, to *time.Time
+	for name, day := range map[string]**time.Time{"from": &from, "to": &to} {
+		param := query.Get(name)
+		if param == "" {
+			continue
+		}
+
+		t, err := time.Parse(time.DateOnly, param)
+		if err != nil {
+			app.badRequestResponse(w, r, fmt.Errorf("%s must be a date like 2006-01-02", name))
+			return
+		}
+		*day = &t
+	}
+
+	if from != nil && to != nil && to.Before(*from) {
+		app.badRequestResponse(w, r, errors.New("to must not be before from"))
+		return
+	}
+	if to != nil {
+		end := to.AddDate(0, 0, 1)
+		to = &end
+	}
+
+	summaries, err := app.store.Disposal.Summary(r.Context(), from, to)
+	if err != nil {
+		app.internalServerError(w, r, err)
+		return
+	}
+
+	if err := app.jsonResponse(w, http.StatusOK, responses.NewDisposalSummariesResponse(summaries)); err != nil {
+		app.internalServerError(w, r, err)
+	}
+}
+
+// notifyDisposal tells the approvers about a new disposal and the requester
+// about the decision.
+func (app *application) notifyDisposal(ctx context.Context, disposal *store.Disposal) {
+	var notifications []store.Notification
+
+	switch disposal.Status {
+	case store.DisposalPending:
+		ids, err := app.store.Users.GetIDsByRole(ctx, store.DisposalApproverRole)
+		if err != nil {
+			app.logger.Errorw("disposal notification failed", "disposal", disposal.ID, "error", err.Error())
+			return
+		}
+
+		for _, id := range ids {
+			if disposal.RequestedByID != nil && id == *disposal.RequestedByID {
+				continue
+			}
+			notifications = append(notifications, store.Notification{
+				UserID:  id,
+				Kind:    "disposal_approval",
+				Title:   "Disposal awaiting approval",
+				Body:    fmt.Sprintf("%s (%s) is to be disposed of by %s: %s", disposal.Asset.Name, disposal.Asset.Tag, disposal.Method, disposal.Reason),
+				AssetID: &disposal.AssetID,
+			})
+		}
+	case store.DisposalApproved, store.DisposalRejected:
+		if disposal.RequestedByID == nil {
+			return
+		}
+
+		kind, title := "disposal_approved", "Disposal approved"
+		if disposal.Status == store.DisposalRejected {
+			kind, title = "disposal_rejected", "Disposal rejected"
+		}
+		notifications = append(notifications, store.Notification{
+			UserID:  *disposal.RequestedByID,
+			Kind:    kind,
+			Title:   title,
+			Body:    fmt.Sprintf("The disposal of %s (%s) was %s.", disposal.Asset.Name, disposal.Asset.Tag, strings.ToLower(string(disposal.Status))),
+			AssetID: &disposal.AssetID,
+		})
+	}
+
+	if len(notifications) == 0 {
+		return
+	}
+
+	if err := app.store.Notification.Create(ctx, notifications); err != nil {
+		app.logger.Errorw("disposal notification failed", "disposal", disposal.ID, "error", err.Error())
+	}
+}
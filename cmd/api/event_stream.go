@@ -0,0 +1,405 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"slices"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/golang-jwt/jwt/v5"
+	"github.com/jackc/pgx/v5"
+	"github.com/knr1997/assets-management-apiserver/internal/store"
+)
+
+const (
+	eventStreamPath = "/api/events/stream"
+	// the most events a reconnecting client is sent to catch up
+	eventStreamHistory = 1000
+	// events created this long before the last one a client saw are sent
+	// again when it reconnects, in case one committed after that
+	eventStreamLookback  = time.Minute
+	eventStreamBuffer    = 64
+	eventStreamHeartbeat = 20 * time.Second
+	// waited before listening again after the connection failed
+	eventListenRetry = 5 * time.Second
+	// sees every topic, audit included
+	eventStreamAdminRole = "admin"
+	// a browser EventSource cannot send an Authorization header, so it opens
+	// the stream with a token of this scope in the URL instead
+	eventStreamTokenScope = "event-stream"
+	eventStreamTokenTTL   = time.Minute
+)
+
+var streamTopics = []string{"asset", "loan", "user", "audit"}
+
+// eventTopics groups the event types into the topics a stream can be limited
+// to.
+var eventTopics = map[string]string{
+	store.EventAssetCreated:       "asset",
+	store.EventAssetStatusChanged: "asset",
+	store.EventAssetCheckedOut:    "loan",
+	store.EventAssetCheckedIn:     "loan",
+	store.EventUserDeactivated:    "user",
+	store.EventAuditLogged:        "audit",
+}
+
+// eventHub hands the events this replica is notified of to its open streams.
+type eventHub struct {
+	mu      sync.Mutex
+	streams map[chan *store.OutboxEvent]struct{}
+	closed  bool
+}
+
+func newEventHub() *eventHub {
+	return &eventHub{streams: make(map[chan *store.OutboxEvent]struct{})}
+}
+
+// subscribe opens a stream. Its channel is closed by the returned func, when
+// the stream falls behind, or when the server shuts down.
+func (h *eventHub) subscribe() (<-chan *store.OutboxEvent, func()) {
+	ch := make(chan *store.OutboxEvent, eventStreamBuffer)
+
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	if h.closed {
+		close(ch)
+		return ch, func() {}
+	}
+	h.streams[ch] = struct{}{}
+
+	return ch, func() {
+		h.mu.Lock()
+		defer h.mu.Unlock()
+		h.drop(ch)
+	}
+}
+
+func (h *eventHub) drop(ch chan *store.OutboxEvent) {
+	if _, ok := h.streams[ch]; ok {
+		delete(h.streams, ch)
+		close(ch)
+	}
+}
+
+func (h *eventHub) listening() bool {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	return len(h.streams) > 0
+}
+
+// broadcast never blocks: a stream that cannot keep up is dropped, and its
+// client catches up from the history when it reconnects.
+func (h *eventHub) broadcast(event *store.OutboxEvent) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	for ch := range h.streams {
+		select {
+		case ch <- event:
+		default:
+			h.drop(ch)
+		}
+	}
+}
+
+// close ends every stream, which would otherwise keep the server from
+// shutting down.
+func (h *eventHub) close() {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	h.closed = true
+	for ch := range h.streams {
+		h.drop(ch)
+	}
+}
+
+// listenEvents relays the events published on any replica to the streams of
+// this one, until ctx is done or the connection fails.
+func (app *application) listenEvents(ctx context.Context) error {
+	conn, err := pgx.Connect(ctx, app.config.db.addr)
+	if err != nil {
+		return err
+	}
+	defer conn.Close(context.Background())
+
+	if _, err := conn.Exec(ctx, "LISTEN "+pgx.Identifier{store.OutboxChannel}.Sanitize()); err != nil {
+		return err
+	}
+
+	for {
+		notification, err := conn.WaitForNotification(ctx)
+		if err != nil {
+			return err
+		}
+
+		if !app.events.listening() {
+			continue
+		}
+
+		id, err := strconv.ParseInt(notification.Payload, 10, 64)
+		if err != nil {
+			continue
+		}
+
+		event, err := app.store.Outbox.GetByID(ctx, id)
+		if err != nil {
+			if errors.Is(err, store.ErrNotFound) {
+				continue
+			}
+			return err
+		}
+
+		app.events.broadcast(event)
+	}
+}
+
+// streamFilter picks the events a stream's user may see out of the topics
+// they asked for. Users other than admins see asset changes, and loans and
+// user events only about themselves.
+type streamFilter struct {
+	userID int64
+	admin  bool
+	topics []string
+}
+
+func (app *application) newStreamFilter(r *http.Request, user *store.User) (*streamFilter, error) {
+	filter := &streamFilter{userID: user.ID}
+
+	if param := r.URL.Query().Get("topics"); param != "" {
+		for _, topic := range strings.Split(param, ",") {
+			topic = strings.TrimSpace(topic)
+			if !slices.Contains(streamTopics, topic) {
+				return nil, fmt.Errorf("unknown topic %q", topic)
+			}
+			filter.topics = append(filter.topics, topic)
+		}
+	}
+
+	admin, err := app.hasRole(r.Context(), user, eventStreamAdminRole)
+	if err != nil {
+		return nil, err
+	}
+	filter.admin = admin
+
+	return filter, nil
+}
+
+func (f *streamFilter) allows(event *store.OutboxEvent) bool {
+	topic := eventTopics[event.Type]
+	if len(f.topics) > 0 && !slices.Contains(f.topics, topic) {
+		return false
+	}
+	if f.admin {
+		return true
+	}
+
+	switch topic {
+	case "asset":
+		return true
+	case "loan", "user":
+		var subject struct {
+			UserID int64 `json:"userId"`
+		}
+		return json.Unmarshal([]byte(event.Payload), &subject) == nil && subject.UserID == f.userID
+	default:
+		return false
+	}
+}
+
+type eventStreamToken struct {
+	Token     string    `json:"token"`
+	ExpiresAt time.Time `json:"expiresAt"`
+}
+
+// createEventStreamTokenHandler issues a token that opens the event stream
+// as ?token= for a short while. It is good for nothing else, so the URL it
+// ends up in does not give the user's session away.
+func (app *application) createEventStreamTokenHandler(w http.ResponseWriter, r *http.Request) {
+	user := getUserFromContext(r)
+
+	now := time.Now()
+	expiresAt := now.Add(eventStreamTokenTTL).Truncate(time.Second)
+
+	claims := jwt.MapClaims{
+		"sub":   user.ID,
+		"exp":   expiresAt.Unix(),
+		"iat":   now.Unix(),
+		"nbf":   now.Unix(),
+		"iss":   app.config.auth.token.iss,
+		"aud":   app.config.auth.token.iss,
+		"scope": eventStreamTokenScope,
+	}
+
+	token, err := app.authenticator.GenerateToken(claims)
+	if err != nil {
+		app.internalServerError(w, r, err)
+		return
+	}
+
+	if err := app.jsonResponse(w, http.StatusCreated, eventStreamToken{token, expiresAt}); err != nil {
+		app.internalServerError(w, r, err)
+	}
+}
+
+// eventStreamAuthMiddleware identifies the user of a stream by its ?token=,
+// or like AuthTokenMiddleware by the Authorization header.
+func (app *application) eventStreamAuthMiddleware(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		token := r.URL.Query().Get("token")
+		if token == "" {
+			app.AuthTokenMiddleware(next).ServeHTTP(w, r)
+			return
+		}
+
+		user, err := app.tokenUser(r.Context(), token, eventStreamTokenScope)
+		if err != nil {
+			app.unauthorizedErrorResponse(w, r, err)
+			return
+		}
+
+		ctx := context.WithValue(r.Context(), userCtx, user)
+		next.ServeHTTP(w, r.WithContext(ctx))
+	})
+}
+
+// eventStreamHandler streams the changes the user may see as Server-Sent
+// Events, optionally limited by ?topics=asset,loan,user,audit. A client
+// reconnecting with Last-Event-ID, or ?lastEventId= for a new EventSource, is
+// first sent what it missed, which can repeat events it already has; clients
+// drop ids they have seen. When it missed more than the history holds it gets
+// a reset event and should reload.
+func (app *application) eventStreamHandler(w http.ResponseWriter, r *http.Request) {
+	user := getUserFromContext(r)
+
+	filter, err := app.newStreamFilter(r, user)
+	if err != nil {
+		app.badRequestResponse(w, r, err)
+		return
+	}
+
+	var lastID int64
+	last := r.Header.Get("Last-Event-ID")
+	if last == "" {
+		last = r.URL.Query().Get("lastEventId")
+	}
+	if last != "" {
+		lastID, err = strconv.ParseInt(last, 10, 64)
+		if err != nil {
+			app.badRequestResponse(w, r, errors.New("invalid Last-Event-ID"))
+			return
+		}
+	}
+
+	rc := http.NewResponseController(w)
+
+	// the stream outlives the server's write timeout
+	if err := rc.SetWriteDeadline(time.Time{}); err != nil {
+		app.internalServerError(w, r, err)
+		return
+	}
+
+	ctx := r.Context()
+
+	// subscribed before the history is read so nothing falls in between;
+	// events in both are sent once
+	live, unsubscribe := app.events.subscribe()
+	defer unsubscribe()
+
+	var (
+		history []store.OutboxEvent
+		resetID int64
+	)
+	if lastID > 0 {
+		var ok bool
+		history, ok, err = app.store.Outbox.Resume(ctx, lastID, eventStreamLookback, eventStreamHistory+1)
+		if err != nil {
+			app.internalServerError(w, r, err)
+			return
+		}
+
+		// events after a pruned one may be gone as well
+		if !ok || len(history) > eventStreamHistory {
+			history = nil
+			resetID, err = app.store.Outbox.LatestID(ctx)
+			if err != nil {
+				app.internalServerError(w, r, err)
+				return
+			}
+		}
+	}
+
+	w.Header().Set("Content-Type", "text/event-stream")
+	w.Header().Set("Cache-Control", "no-cache")
+	w.Header().Set("X-Accel-Buffering", "no")
+	w.WriteHeader(http.StatusOK)
+
+	fmt.Fprintf(w, "retry: %d\n\n", eventListenRetry.Milliseconds())
+
+	if resetID > 0 {
+		// resumes from here after the client reloaded
+		fmt.Fprintf(w, "id: %d\nevent: reset\ndata: {}\n\n", resetID)
+	}
+
+	sent := make(map[int64]bool, len(history))
+	for i := range history {
+		sent[history[i].ID] = true
+		if !filter.allows(&history[i]) {
+			continue
+		}
+		if err := writeStreamEvent(w, &history[i]); err != nil {
+			return
+		}
+	}
+
+	if err := rc.Flush(); err != nil {
+		return
+	}
+
+	heartbeat := time.NewTicker(eventStreamHeartbeat)
+	defer heartbeat.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-heartbeat.C:
+			if _, err := io.WriteString(w, ": ping\n\n"); err != nil {
+				return
+			}
+		case event, ok := <-live:
+			if !ok {
+				// fell behind or shutting down; the client reconnects
+				return
+			}
+			if sent[event.ID] || !filter.allows(event) {
+				continue
+			}
+			if err := writeStreamEvent(w, event); err != nil {
+				return
+			}
+		}
+
+		if err := rc.Flush(); err != nil {
+			return
+		}
+	}
+}
+
+func writeStreamEvent(w io.Writer, event *store.OutboxEvent) error {
+	data, err := json.Marshal(newEventEnvelope(event))
+	if err != nil {
+		return err
+	}
+
+	_, err = fmt.Fprintf(w, "id: %d\nevent: %s\ndata: %s\n\n", event.ID, event.Type, data)
+	return err
+}
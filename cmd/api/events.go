@@ -0,0 +1,150 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"slices"
+	"time"
+
+	"github.com/knr1997/assets-management-apiserver/internal/store"
+)
+
+const eventBatchSize = 100
+
+// eventEnvelope is how events are sent out, to webhooks and event streams.
+type eventEnvelope struct {
+	ID        int64           `json:"id"`
+	Type      string          `json:"type"`
+	CreatedAt time.Time       `json:"createdAt"`
+	Data      json.RawMessage `json:"data"`
+}
+
+func newEventEnvelope(event *store.OutboxEvent) eventEnvelope {
+	return eventEnvelope{
+		ID:        event.ID,
+		Type:      event.Type,
+		CreatedAt: event.CreatedAt,
+		Data:      json.RawMessage(event.Payload),
+	}
+}
+
+// subscriber handles the domain events of the types it lists. An event can
+// reach a subscriber more than once, so handlers must be idempotent.
+type subscriber struct {
+	name   string
+	events []string
+	handle func(context.Context, *store.OutboxEvent) error
+}
+
+// Search needs no subscriber: its vectors are generated columns Postgres
+// keeps in step with every change.
+func (app *application) subscribers() []subscriber {
+	return []subscriber{
+		{"webhooks", store.EventTypes, app.queueWebhookDeliveries},
+		{"notifications", []string{store.EventAssetCheckedOut, store.EventAssetCheckedIn}, app.notifyAssetEvent},
+	}
+}
+
+// dispatchEvents hands the events committed to the outbox to the subscribers,
+// at least once each. Replicas dispatch side by side without taking the same
+// events.
+func (app *application) dispatchEvents(ctx context.Context) error {
+	subscribers := app.subscribers()
+
+	handle := func(event *store.OutboxEvent) error {
+		var errs []error
+		for _, s := range subscribers {
+			if !slices.Contains(s.events, event.Type) {
+				continue
+			}
+
+			if err := s.handle(ctx, event); err != nil {
+				app.logger.Errorw("event subscriber failed", "subscriber", s.name, "event", event.ID, "type", event.Type, "error", err.Error())
+				errs = append(errs, fmt.Errorf("%s: %w", s.name, err))
+			}
+		}
+		return errors.Join(errs...)
+	}
+
+	for {
+		n, err := app.store.Outbox.Dispatch(ctx, eventBatchSize, handle)
+		if err != nil || n < eventBatchSize {
+			return err
+		}
+	}
+}
+
+// pruneEvents deletes the events published longer ago than the retention,
+// which keeps events for good when it is not set.
+func (app *application) pruneEvents(ctx context.Context) error {
+	if app.config.events.retention <= 0 {
+		return nil
+	}
+	before := time.Now().Add(-app.config.events.retention)
+
+	for {
+		n, err := app.store.Outbox.Prune(ctx, before, eventBatchSize)
+		if err != nil || n < eventBatchSize {
+			return err
+		}
+	}
+}
+
+// notifyAssetEvent tells users about the assets checked out to them and
+// checked back in.
+func (app *application) notifyAssetEvent(ctx context.Context, event *store.OutboxEvent) error {
+	var (
+		userID, assetID int64
+		kind, title     string
+		body            func(*store.Asset) string
+	)
+
+	switch event.Type {
+	case store.EventAssetCheckedOut:
+		var e store.AssetCheckedOut
+		if err := event.Decode(&e); err != nil {
+			return err
+		}
+
+		userID, assetID = e.UserID, e.AssetID
+		kind, title = "asset_checked_out", "Asset checked out to you"
+		body = func(a *store.Asset) string {
+			if e.ExpectedCheckinDate == nil {
+				return fmt.Sprintf("%s (%s) was checked out to you.", a.Name, a.Tag)
+			}
+			return fmt.Sprintf("%s (%s) was checked out to you, due back on %s.", a.Name, a.Tag, e.ExpectedCheckinDate.Format(time.DateOnly))
+		}
+	case store.EventAssetCheckedIn:
+		var e store.AssetCheckedIn
+		if err := event.Decode(&e); err != nil {
+			return err
+		}
+
+		userID, assetID = e.UserID, e.AssetID
+		kind, title = "asset_checked_in", "Asset returned"
+		body = func(a *store.Asset) string {
+			return fmt.Sprintf("%s (%s) was checked back in.", a.Name, a.Tag)
+		}
+	default:
+		return nil
+	}
+
+	asset, err := app.store.Asset.GetByID(ctx, assetID)
+	if err != nil {
+		if errors.Is(err, store.ErrNotFound) {
+			// deleted since
+			return nil
+		}
+		return err
+	}
+
+	return app.store.Notification.CreateForEvent(ctx, event.ID, []store.Notification{{
+		UserID:  userID,
+		Kind:    kind,
+		Title:   title,
+		Body:    body(asset),
+		AssetID: &asset.ID,
+	}})
+}
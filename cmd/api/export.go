@@ -0,0 +1,158 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/knr1997/assets-management-apiserver/internal/export"
+	"github.com/knr1997/assets-management-apiserver/internal/store"
+)
+
+const (
+	// exports can run far past the server write timeout, so the deadline is
+	// pushed forward for every batch of rows written.
+	exportWriteWindow = time.Minute
+	// bounds an export as a whole, in place of the request timeout
+	exportTimeout = 30 * time.Minute
+)
+
+type exportColumn[T any] struct {
+	Name  string
+	Value func(*T) any
+}
+
+// requestedExportFormat reports whether the request asks for an export,
+// either through ?format= or the Accept header.
+func requestedExportFormat(r *http.Request) (export.Format, bool, error) {
+	if f := r.URL.Query().Get("format"); f != "" && f != "json" {
+		format, err := export.ParseFormat(f)
+		if err != nil {
+			return "", false, err
+		}
+		return format, true, nil
+	}
+
+	format, ok := export.FormatFromAccept(r.Header.Get("Accept"))
+	return format, ok, nil
+}
+
+// selectExportColumns narrows the available columns down to the ones listed in
+// ?columns=, keeping the order requested by the caller.
+func selectExportColumns[T any](r *http.Request, available []exportColumn[T]) ([]exportColumn[T], error) {
+	param := r.URL.Query().Get("columns")
+	if param == "" {
+		return available, nil
+	}
+
+	byName := make(map[string]exportColumn[T], len(available))
+	for _, c := range available {
+		byName[c.Name] = c
+	}
+
+	var selected []exportColumn[T]
+	for _, name := range strings.Split(param, ",") {
+		name = strings.TrimSpace(name)
+		c, ok := byName[name]
+		if !ok {
+			return nil, fmt.Errorf("unknown export column %q", name)
+		}
+		selected = append(selected, c)
+	}
+
+	return selected, nil
+}
+
+func streamExport[T any](
+	app *application,
+	w http.ResponseWriter,
+	r *http.Request,
+	resource string,
+	format export.Format,
+	spec store.QuerySpec,
+	available []exportColumn[T],
+	stream func(context.Context, store.QuerySpec, func(*T) error) error,
+) {
+	columns, err := selectExportColumns(r, available)
+	if err != nil {
+		app.badRequestResponse(w, r, err)
+		return
+	}
+
+	names := make([]string, len(columns))
+	for i, c := range columns {
+		names[i] = c.Name
+	}
+
+	rc := http.NewResponseController(w)
+
+	// nothing is written until the query has produced its first row, so
+	// invalid filters still get a regular JSON error response
+	var writer export.Writer
+	start := func() error {
+		if writer != nil {
+			return nil
+		}
+
+		filename := fmt.Sprintf("%s-%s.%s", resource, time.Now().Format("20060102-150405"), format.Extension())
+		w.Header().Set("Content-Type", format.ContentType())
+		w.Header().Set("Content-Disposition", fmt.Sprintf("attachment; filename=%q", filename))
+		rc.SetWriteDeadline(time.Now().Add(exportWriteWindow))
+
+		var err error
+		writer, err = export.NewWriter(format, w)
+		if err != nil {
+			return err
+		}
+
+		return writer.WriteHeader(names)
+	}
+
+	ctx, cancel := context.WithTimeout(r.Context(), exportTimeout)
+	defer cancel()
+
+	rows := 0
+	err = stream(ctx, spec, func(item *T) error {
+		if err := start(); err != nil {
+			return err
+		}
+
+		values := make([]any, len(columns))
+		for i, c := range columns {
+			values[i] = c.Value(item)
+		}
+
+		rows++
+		if rows%1000 == 0 {
+			rc.SetWriteDeadline(time.Now().Add(exportWriteWindow))
+		}
+
+		return writer.WriteRow(values)
+	})
+	if err == nil {
+		err = start()
+	}
+	if err != nil {
+		if writer == nil {
+			switch {
+			case errors.Is(err, store.ErrInvalidQuery):
+				app.badRequestResponse(w, r, err)
+			default:
+				app.internalServerError(w, r, err)
+			}
+			return
+		}
+
+		// headers are already on the wire, the best we can do is log and cut
+		// the response short
+		app.logger.Errorw("export failed", "resource", resource, "rows", rows, "error", err.Error())
+		return
+	}
+
+	if err := writer.Close(); err != nil {
+		app.logger.Errorw("export failed", "resource", resource, "rows", rows, "error", err.Error())
+	}
+}
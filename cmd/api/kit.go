@@ -0,0 +1,272 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"strconv"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/knr1997/assets-management-apiserver/internal/api/requests"
+	"github.com/knr1997/assets-management-apiserver/internal/api/responses"
+	"github.com/knr1997/assets-management-apiserver/internal/store"
+)
+
+type kitKey string
+
+const kitCtx kitKey = "kit"
+
+func getKitFromCtx(r *http.Request) *store.Kit {
+	kit, _ := r.Context().Value(kitCtx).(*store.Kit)
+	return kit
+}
+
+func (app *application) kitContextMiddleware(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		idParam := chi.URLParam(r, "kitID")
+		id, err := strconv.ParseInt(idParam, 10, 64)
+		if err != nil {
+			app.internalServerError(w, r, err)
+			return
+		}
+
+		ctx := r.Context()
+
+		kit, err := app.store.Kit.GetByID(ctx, id)
+		if err != nil {
+			switch {
+			case errors.Is(err, store.ErrNotFound):
+				app.notFoundResponse(w, r, err)
+			default:
+				app.internalServerError(w, r, err)
+			}
+			return
+		}
+
+		ctx = context.WithValue(ctx, kitCtx, kit)
+		next.ServeHTTP(w, r.WithContext(ctx))
+	})
+}
+
+func kitItemsFromPayload(items []requests.KitItemPayload) []store.KitItem {
+	kitItems := make([]store.KitItem, len(items))
+
+	for i, item := range items {
+		kitItems[i] = store.KitItem{
+			ModelID:      item.ModelID,
+			AccessoryID:  item.AccessoryID,
+			ConsumableID: item.ConsumableID,
+			Quantity:     max(item.Quantity, 1),
+		}
+	}
+
+	return kitItems
+}
+
+func (app *application) getAllKitHandler(w http.ResponseWriter, r *http.Request) {
+	spec, ok := app.parseQuerySpec(w, r)
+	if !ok {
+		return
+	}
+
+	writeListPage(app, w, r, spec, app.store.Kit.List, responses.NewKitsResponse)
+}
+
+func (app *application) createKitHandler(w http.ResponseWriter, r *http.Request) {
+	var payload requests.CreateKitPayload
+	if err := readJSON(w, r, &payload); err != nil {
+		app.badRequestResponse(w, r, err)
+		return
+	}
+
+	if err := Validate.Struct(payload); err != nil {
+		app.badRequestResponse(w, r, err)
+		return
+	}
+
+	kit := &store.Kit{
+		Name:        payload.Name,
+		Description: payload.Description,
+		Items:       kitItemsFromPayload(payload.Items),
+	}
+
+	ctx := r.Context()
+
+	if err := app.store.Kit.Create(ctx, kit); err != nil {
+		switch {
+		case errors.Is(err, store.ErrConflict):
+			app.conflictResponse(w, r, fmt.Errorf("a kit named %s already exists", kit.Name))
+		default:
+			app.internalServerError(w, r, err)
+		}
+		return
+	}
+
+	kit, err := app.store.Kit.GetByID(ctx, kit.ID)
+	if err != nil {
+		app.internalServerError(w, r, err)
+		return
+	}
+
+	if err := app.jsonResponse(w, http.StatusCreated, responses.NewKitResponse(kit)); err != nil {
+		app.internalServerError(w, r, err)
+		return
+	}
+}
+
+func (app *application) getKitHandler(w http.ResponseWriter, r *http.Request) {
+	kit := getKitFromCtx(r)
+
+	if err := app.jsonResponse(w, http.StatusOK, responses.NewKitResponse(kit)); err != nil {
+		app.internalServerError(w, r, err)
+		return
+	}
+}
+
+func (app *application) updateKitHandler(w http.ResponseWriter, r *http.Request) {
+	kit := getKitFromCtx(r)
+
+	var payload requests.UpdateKitPayload
+	if err := readJSON(w, r, &payload); err != nil {
+		app.badRequestResponse(w, r, err)
+		return
+	}
+
+	if err := Validate.Struct(payload); err != nil {
+		app.badRequestResponse(w, r, err)
+		return
+	}
+
+	if payload.Name != nil {
+		kit.Name = *payload.Name
+	}
+	if payload.Description != nil {
+		kit.Description = *payload.Description
+	}
+	if payload.Items != nil {
+		kit.Items = kitItemsFromPayload(payload.Items)
+	}
+
+	ctx := r.Context()
+
+	if err := app.store.Kit.Update(ctx, kit, payload.Items != nil); err != nil {
+		switch {
+		case errors.Is(err, store.ErrNotFound):
+			app.notFoundResponse(w, r, err)
+		case errors.Is(err, store.ErrConflict):
+			app.conflictResponse(w, r, fmt.Errorf("a kit named %s already exists", kit.Name))
+		default:
+			app.internalServerError(w, r, err)
+		}
+		return
+	}
+
+	kit, err := app.store.Kit.GetByID(ctx, kit.ID)
+	if err != nil {
+		app.internalServerError(w, r, err)
+		return
+	}
+
+	if err := app.jsonResponse(w, http.StatusOK, responses.NewKitResponse(kit)); err != nil {
+		app.internalServerError(w, r, err)
+		return
+	}
+}
+
+func (app *application) deleteKitHandler(w http.ResponseWriter, r *http.Request) {
+	kit := getKitFromCtx(r)
+
+	if err := app.store.Kit.Delete(r.Context(), kit.ID); err != nil {
+		switch {
+		case errors.Is(err, store.ErrNotFound):
+			app.notFoundResponse(w, r, err)
+		default:
+			app.internalServerError(w, r, err)
+		}
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// checkoutKitHandler hands every item of the kit to a user. Nothing is handed
+// out when one of the items is short.
+func (app *application) checkoutKitHandler(w http.ResponseWriter, r *http.Request) {
+	kit := getKitFromCtx(r)
+
+	var payload requests.CheckoutKitPayload
+	if err := readJSON(w, r, &payload); err != nil {
+		app.badRequestResponse(w, r, err)
+		return
+	}
+
+	if err := Validate.Struct(payload); err != nil {
+		app.badRequestResponse(w, r, err)
+		return
+	}
+
+	if payload.Location == "" {
+		for _, item := range kit.Items {
+			if item.ModelID == nil {
+				app.badRequestResponse(w, r, errors.New("location is required for kits with accessories or consumables"))
+				return
+			}
+		}
+	}
+
+	ctx := r.Context()
+
+	user, err := app.store.Users.GetByID(ctx, payload.UserID)
+	if err != nil {
+		switch {
+		case errors.Is(err, store.ErrNotFound):
+			app.badRequestResponse(w, r, fmt.Errorf("user %d does not exist", payload.UserID))
+		default:
+			app.internalServerError(w, r, err)
+		}
+		return
+	}
+	if !user.IsActive {
+		app.badRequestResponse(w, r, fmt.Errorf("user %d is not active", user.ID))
+		return
+	}
+
+	checkout := &store.KitCheckout{
+		UserID:              user.ID,
+		Location:            payload.Location,
+		ExpectedCheckinDate: payload.ExpectedCheckinDate,
+		Notes:               payload.Notes,
+	}
+	if payload.CheckoutDate != nil {
+		checkout.CheckoutDate = *payload.CheckoutDate
+	}
+	if performer := getUserFromContext(r); performer != nil {
+		checkout.CheckedOutByID = &performer.ID
+	}
+
+	if err := app.store.Kit.Checkout(ctx, kit, checkout); err != nil {
+		switch {
+		case errors.Is(err, store.ErrInsufficientStock):
+			app.conflictResponse(w, r, fmt.Errorf("%s cannot be checked out: %w", kit.Name, err))
+		default:
+			app.internalServerError(w, r, err)
+		}
+		return
+	}
+
+	for i := range checkout.AssetLoans {
+		checkout.AssetLoans[i].User = *user
+	}
+	for i := range checkout.AccessoryLoans {
+		checkout.AccessoryLoans[i].User = *user
+	}
+	for i := range checkout.ConsumableMovements {
+		checkout.ConsumableMovements[i].User = user
+	}
+
+	if err := app.jsonResponse(w, http.StatusCreated, responses.NewKitCheckoutResponse(checkout)); err != nil {
+		app.internalServerError(w, r, err)
+		return
+	}
+}
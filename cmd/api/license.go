@@ -0,0 +1,393 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/knr1997/assets-management-apiserver/internal/api/requests"
+	"github.com/knr1997/assets-management-apiserver/internal/api/responses"
+	"github.com/knr1997/assets-management-apiserver/internal/store"
+)
+
+type licenseKey string
+
+const licenseCtx licenseKey = "license"
+
+// role whose members are told about expiring licenses
+const licenseAlertRole = "admin"
+
+func getLicenseFromCtx(r *http.Request) *store.License {
+	license, _ := r.Context().Value(licenseCtx).(*store.License)
+	return license
+}
+
+func (app *application) licenseContextMiddleware(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		idParam := chi.URLParam(r, "licenseID")
+		id, err := strconv.ParseInt(idParam, 10, 64)
+		if err != nil {
+			app.internalServerError(w, r, err)
+			return
+		}
+
+		ctx := r.Context()
+
+		license, err := app.store.License.GetByID(ctx, id)
+		if err != nil {
+			switch {
+			case errors.Is(err, store.ErrNotFound):
+				app.notFoundResponse(w, r, err)
+			default:
+				app.internalServerError(w, r, err)
+			}
+			return
+		}
+
+		ctx = context.WithValue(ctx, licenseCtx, license)
+		next.ServeHTTP(w, r.WithContext(ctx))
+	})
+}
+
+func (app *application) getAllLicenseHandler(w http.ResponseWriter, r *http.Request) {
+	spec, ok := app.parseQuerySpec(w, r)
+	if !ok {
+		return
+	}
+
+	writeListPage(app, w, r, spec, app.store.License.List, responses.NewLicensesResponse)
+}
+
+// sealProductKey encrypts a product key before it is stored.
+func (app *application) sealProductKey(key string) (string, error) {
+	if key == "" {
+		return "", nil
+	}
+
+	return app.secrets.Seal(key)
+}
+
+func (app *application) createLicenseHandler(w http.ResponseWriter, r *http.Request) {
+	var payload requests.CreateLicensePayload
+	if err := readJSON(w, r, &payload); err != nil {
+		app.badRequestResponse(w, r, err)
+		return
+	}
+
+	if err := Validate.Struct(payload); err != nil {
+		app.badRequestResponse(w, r, err)
+		return
+	}
+
+	productKey, err := app.sealProductKey(payload.ProductKey)
+	if err != nil {
+		app.internalServerError(w, r, err)
+		return
+	}
+
+	license := &store.License{
+		Name:           payload.Name,
+		ProductKey:     productKey,
+		LicensedTo:     payload.LicensedTo,
+		ManufacturerID: payload.ManufacturerID,
+		SupplierID:     payload.SupplierID,
+		Seats:          payload.Seats,
+		PurchaseDate:   payload.PurchaseDate,
+		PurchaseCost:   payload.PurchaseCost,
+		ExpiresAt:      payload.ExpiresAt,
+		Notes:          payload.Notes,
+	}
+
+	ctx := r.Context()
+
+	if err := app.store.License.Create(ctx, license); err != nil {
+		app.internalServerError(w, r, err)
+		return
+	}
+
+	license, err = app.store.License.GetByID(ctx, license.ID)
+	if err != nil {
+		app.internalServerError(w, r, err)
+		return
+	}
+
+	if err := app.jsonResponse(w, http.StatusCreated, responses.NewLicenseResponse(license)); err != nil {
+		app.internalServerError(w, r, err)
+		return
+	}
+}
+
+func (app *application) getLicenseHandler(w http.ResponseWriter, r *http.Request) {
+	license := getLicenseFromCtx(r)
+
+	response := responses.NewLicenseResponse(license)
+
+	if license.ProductKey != "" {
+		key, err := app.secrets.Open(license.ProductKey)
+		if err != nil {
+			app.logger.Warnw("could not open product key", "license", license.ID, "error", err.Error())
+		} else {
+			response.ProductKey = key
+		}
+	}
+
+	if err := app.jsonResponse(w, http.StatusOK, response); err != nil {
+		app.internalServerError(w, r, err)
+		return
+	}
+}
+
+func (app *application) updateLicenseHandler(w http.ResponseWriter, r *http.Request) {
+	license := getLicenseFromCtx(r)
+
+	var payload requests.UpdateLicensePayload
+	if err := readJSON(w, r, &payload); err != nil {
+		app.badRequestResponse(w, r, err)
+		return
+	}
+
+	if err := Validate.Struct(payload); err != nil {
+		app.badRequestResponse(w, r, err)
+		return
+	}
+
+	if payload.Name != nil {
+		license.Name = *payload.Name
+	}
+	if payload.ProductKey != nil {
+		productKey, err := app.sealProductKey(*payload.ProductKey)
+		if err != nil {
+			app.internalServerError(w, r, err)
+			return
+		}
+		license.ProductKey = productKey
+	}
+	if payload.LicensedTo != nil {
+		license.LicensedTo = *payload.LicensedTo
+	}
+	if payload.ManufacturerID != nil {
+		license.ManufacturerID = payload.ManufacturerID
+	}
+	if payload.SupplierID != nil {
+		license.SupplierID = payload.SupplierID
+	}
+	if payload.Seats != nil {
+		license.Seats = *payload.Seats
+	}
+	if payload.PurchaseDate != nil {
+		license.PurchaseDate = payload.PurchaseDate
+	}
+	if payload.PurchaseCost != nil {
+		license.PurchaseCost = *payload.PurchaseCost
+	}
+	if payload.ExpiresAt != nil && (license.ExpiresAt == nil || !payload.ExpiresAt.Equal(*license.ExpiresAt)) {
+		license.ExpiresAt = payload.ExpiresAt
+		// a renewed license may expire again later
+		license.ExpiryNotifiedAt = nil
+	}
+	if payload.Notes != nil {
+		license.Notes = *payload.Notes
+	}
+
+	ctx := r.Context()
+
+	if err := app.store.License.Update(ctx, license); err != nil {
+		switch {
+		case errors.Is(err, store.ErrNotFound):
+			app.notFoundResponse(w, r, err)
+		case errors.Is(err, store.ErrSeatsInUse):
+			app.conflictResponse(w, r, fmt.Errorf("%d seats are checked out, check some in before reducing seats", license.SeatsUsed))
+		default:
+			app.internalServerError(w, r, err)
+		}
+		return
+	}
+
+	license, err := app.store.License.GetByID(ctx, license.ID)
+	if err != nil {
+		app.internalServerError(w, r, err)
+		return
+	}
+
+	if err := app.jsonResponse(w, http.StatusOK, responses.NewLicenseResponse(license)); err != nil {
+		app.internalServerError(w, r, err)
+		return
+	}
+}
+
+func (app *application) deleteLicenseHandler(w http.ResponseWriter, r *http.Request) {
+	license := getLicenseFromCtx(r)
+
+	if err := app.store.License.Delete(r.Context(), license.ID); err != nil {
+		switch {
+		case errors.Is(err, store.ErrNotFound):
+			app.notFoundResponse(w, r, err)
+		default:
+			app.internalServerError(w, r, err)
+		}
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+func (app *application) getLicenseSeatsHandler(w http.ResponseWriter, r *http.Request) {
+	license := getLicenseFromCtx(r)
+
+	spec, ok := app.parseQuerySpec(w, r)
+	if !ok {
+		return
+	}
+
+	list := func(ctx context.Context, spec store.QuerySpec) (*store.Pagination, error) {
+		return app.store.License.Seats(ctx, license.ID, spec)
+	}
+
+	writeListPage(app, w, r, spec, list, responses.NewLicenseSeatsResponse)
+}
+
+func (app *application) checkoutLicenseHandler(w http.ResponseWriter, r *http.Request) {
+	license := getLicenseFromCtx(r)
+
+	var payload requests.CheckoutLicensePayload
+	if err := readJSON(w, r, &payload); err != nil {
+		app.badRequestResponse(w, r, err)
+		return
+	}
+
+	if err := Validate.Struct(payload); err != nil {
+		app.badRequestResponse(w, r, err)
+		return
+	}
+
+	ctx := r.Context()
+
+	if payload.UserID != nil {
+		user, err := app.store.Users.GetByID(ctx, *payload.UserID)
+		if err != nil {
+			switch {
+			case errors.Is(err, store.ErrNotFound):
+				app.badRequestResponse(w, r, fmt.Errorf("user %d does not exist", *payload.UserID))
+			default:
+				app.internalServerError(w, r, err)
+			}
+			return
+		}
+		if !user.IsActive {
+			app.badRequestResponse(w, r, fmt.Errorf("user %d is not active", user.ID))
+			return
+		}
+	}
+
+	seat := &store.LicenseSeat{
+		LicenseID: license.ID,
+		UserID:    payload.UserID,
+		AssetID:   payload.AssetID,
+		Notes:     payload.Notes,
+	}
+	if user := getUserFromContext(r); user != nil {
+		seat.CheckedOutByID = &user.ID
+	}
+
+	if err := app.store.License.Checkout(ctx, seat); err != nil {
+		switch {
+		case errors.Is(err, store.ErrNoSeatsAvailable):
+			app.conflictResponse(w, r, fmt.Errorf("all %d seats of %s are checked out", license.Seats, license.Name))
+		case errors.Is(err, store.ErrConflict):
+			app.conflictResponse(w, r, errors.New("a seat of this license is already checked out to them"))
+		default:
+			app.internalServerError(w, r, err)
+		}
+		return
+	}
+
+	if err := app.jsonResponse(w, http.StatusCreated, responses.NewLicenseSeatResponse(seat)); err != nil {
+		app.internalServerError(w, r, err)
+		return
+	}
+}
+
+func (app *application) checkinLicenseHandler(w http.ResponseWriter, r *http.Request) {
+	license := getLicenseFromCtx(r)
+
+	seatID, err := strconv.ParseInt(chi.URLParam(r, "seatID"), 10, 64)
+	if err != nil {
+		app.internalServerError(w, r, err)
+		return
+	}
+
+	if err := app.store.License.Checkin(r.Context(), license.ID, seatID); err != nil {
+		switch {
+		case errors.Is(err, store.ErrNotFound):
+			app.notFoundResponse(w, r, err)
+		default:
+			app.internalServerError(w, r, err)
+		}
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+func (app *application) getLicenseComplianceHandler(w http.ResponseWriter, r *http.Request) {
+	licenses, err := app.store.License.GetAll(r.Context())
+	if err != nil {
+		app.internalServerError(w, r, err)
+		return
+	}
+
+	if err := app.jsonResponse(w, http.StatusOK, responses.NewLicenseComplianceResponse(licenses)); err != nil {
+		app.internalServerError(w, r, err)
+	}
+}
+
+// reclaimLicenseSeats returns the seats held by deactivated or deleted users
+// to the pool.
+func (app *application) reclaimLicenseSeats(ctx context.Context) error {
+	seats, err := app.store.License.ReclaimInactive(ctx)
+	if err != nil {
+		return err
+	}
+
+	for _, seat := range seats {
+		app.logger.Infow("license seat reclaimed", "license", seat.LicenseID, "seat", seat.ID, "user", *seat.UserID)
+	}
+
+	return nil
+}
+
+// alertExpiringLicenses tells the admins once about every license entering
+// its expiring window.
+func (app *application) alertExpiringLicenses(ctx context.Context) error {
+	licenses, err := app.store.License.GetUnnotifiedExpiring(ctx)
+	if err != nil || len(licenses) == 0 {
+		return err
+	}
+
+	admins, err := app.store.Users.GetIDsByRole(ctx, licenseAlertRole)
+	if err != nil {
+		return err
+	}
+
+	for _, license := range licenses {
+		notifications := make([]store.Notification, len(admins))
+		for i, userID := range admins {
+			notifications[i] = store.Notification{
+				UserID: userID,
+				Kind:   "license_expiring",
+				Title:  fmt.Sprintf("License %s is expiring", license.Name),
+				Body:   fmt.Sprintf("%s (%d seats) expires on %s.", license.Name, license.Seats, license.ExpiresAt.Format(time.DateOnly)),
+			}
+		}
+
+		if _, err := app.store.License.MarkNotified(ctx, license.ID, notifications); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
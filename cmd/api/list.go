@@ -0,0 +1,55 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+
+	"github.com/knr1997/assets-management-apiserver/internal/store"
+)
+
+func (app *application) parseQuerySpec(w http.ResponseWriter, r *http.Request) (store.QuerySpec, bool) {
+	spec, err := store.ParseQuerySpec(r.URL.Query())
+	if err != nil {
+		app.badRequestResponse(w, r, err)
+		return spec, false
+	}
+
+	return spec, true
+}
+
+// writeListPage loads a page with list and writes it in the pagination
+// envelope shared by every list endpoint, converting the rows to their
+// response DTOs on the way out.
+func writeListPage[T, R any](
+	app *application,
+	w http.ResponseWriter,
+	r *http.Request,
+	spec store.QuerySpec,
+	list func(context.Context, store.QuerySpec) (*store.Pagination, error),
+	toResponse func([]T) []R,
+) {
+	result, err := list(r.Context(), spec)
+	if err != nil {
+		switch {
+		case errors.Is(err, store.ErrInvalidQuery):
+			app.badRequestResponse(w, r, err)
+		default:
+			app.internalServerError(w, r, err)
+		}
+		return
+	}
+
+	rows, ok := result.Rows.([]T)
+	if !ok {
+		app.internalServerError(w, r, fmt.Errorf("invalid row type %T", result.Rows))
+		return
+	}
+
+	result.Rows = toResponse(rows)
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(result)
+}
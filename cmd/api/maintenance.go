@@ -0,0 +1,325 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/knr1997/assets-management-apiserver/internal/api/requests"
+	"github.com/knr1997/assets-management-apiserver/internal/api/responses"
+	"github.com/knr1997/assets-management-apiserver/internal/store"
+)
+
+type maintenanceKey string
+
+const maintenanceCtx maintenanceKey = "maintenance"
+
+var maintenanceExportColumns = []exportColumn[store.Maintenance]{
+	{"id", func(m *store.Maintenance) any { return m.ID }},
+	{"title", func(m *store.Maintenance) any { return m.Title }},
+	{"assetId", func(m *store.Maintenance) any { return m.AssetID }},
+	{"assetTag", func(m *store.Maintenance) any { return m.Asset.Tag }},
+	{"supplier", func(m *store.Maintenance) any {
+		if m.Supplier == nil {
+			return nil
+		}
+		return m.Supplier.Name
+	}},
+	{"type", func(m *store.Maintenance) any { return string(m.Type) }},
+	{"status", func(m *store.Maintenance) any { return string(m.Status) }},
+	{"startDate", func(m *store.Maintenance) any { return m.StartDate }},
+	{"completionDate", func(m *store.Maintenance) any { return m.CompletionDate }},
+	{"cost", func(m *store.Maintenance) any { return m.Cost }},
+	{"notes", func(m *store.Maintenance) any { return m.Notes }},
+}
+
+func getMaintenanceFromCtx(r *http.Request) *store.Maintenance {
+	maintenance, _ := r.Context().Value(maintenanceCtx).(*store.Maintenance)
+	return maintenance
+}
+
+func (app *application) maintenanceContextMiddleware(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		idParam := chi.URLParam(r, "maintenanceID")
+		id, err := strconv.ParseInt(idParam, 10, 64)
+		if err != nil {
+			app.internalServerError(w, r, err)
+			return
+		}
+
+		ctx := r.Context()
+
+		maintenance, err := app.store.Maintenance.GetByID(ctx, id)
+		if err != nil {
+			switch {
+			case errors.Is(err, store.ErrNotFound):
+				app.notFoundResponse(w, r, err)
+			default:
+				app.internalServerError(w, r, err)
+			}
+			return
+		}
+
+		ctx = context.WithValue(ctx, maintenanceCtx, maintenance)
+		next.ServeHTTP(w, r.WithContext(ctx))
+	})
+}
+
+// setMaintenanceStatus moves a record to status, stamping the start and
+// completion dates when the caller did not supply them. Completed and
+// cancelled records are final.
+func setMaintenanceStatus(m *store.Maintenance, status store.MaintenanceStatus) error {
+	if m.Status == status {
+		return nil
+	}
+
+	if m.ID != 0 && (m.Status == store.MaintenanceCompleted || m.Status == store.MaintenanceCancelled) {
+		return fmt.Errorf("maintenance is already %s", m.Status)
+	}
+
+	now := time.Now()
+
+	switch status {
+	case store.MaintenanceInProgress:
+		if m.StartDate == nil {
+			m.StartDate = &now
+		}
+	case store.MaintenanceCompleted:
+		if m.StartDate == nil {
+			m.StartDate = &now
+		}
+		if m.CompletionDate == nil {
+			m.CompletionDate = &now
+		}
+	}
+
+	m.Status = status
+
+	return nil
+}
+
+func (app *application) createMaintenanceHandler(w http.ResponseWriter, r *http.Request) {
+	var payload requests.CreateMaintenancePayload
+	if err := readJSON(w, r, &payload); err != nil {
+		app.badRequestResponse(w, r, err)
+		return
+	}
+
+	if err := Validate.Struct(payload); err != nil {
+		app.badRequestResponse(w, r, err)
+		return
+	}
+
+	ctx := r.Context()
+
+	asset, err := app.store.Asset.GetByID(ctx, payload.AssetID)
+	if err != nil {
+		switch {
+		case errors.Is(err, store.ErrNotFound):
+			app.badRequestResponse(w, r, errors.New("asset does not exist"))
+		default:
+			app.internalServerError(w, r, err)
+		}
+		return
+	}
+
+	maintenance := &store.Maintenance{
+		Title:          payload.Title,
+		AssetID:        payload.AssetID,
+		SupplierID:     payload.SupplierID,
+		Type:           store.MaintenanceType(payload.Type),
+		Status:         store.MaintenanceScheduled,
+		StartDate:      payload.StartDate,
+		CompletionDate: payload.CompletionDate,
+		Cost:           payload.Cost,
+		Notes:          payload.Notes,
+	}
+
+	if payload.Status != "" {
+		if err := setMaintenanceStatus(maintenance, store.MaintenanceStatus(payload.Status)); err != nil {
+			app.badRequestResponse(w, r, err)
+			return
+		}
+	}
+
+	if maintenance.Status == store.MaintenanceInProgress && asset.Status == store.AssetAssigned {
+		app.conflictResponse(w, r, errors.New("asset is checked out, check it in before starting maintenance"))
+		return
+	}
+
+	if err := app.store.Maintenance.Create(ctx, maintenance); err != nil {
+		app.internalServerError(w, r, err)
+		return
+	}
+
+	maintenance, err = app.store.Maintenance.GetByID(ctx, maintenance.ID)
+	if err != nil {
+		app.internalServerError(w, r, err)
+		return
+	}
+
+	if err := app.jsonResponse(w, http.StatusCreated, responses.NewMaintenanceResponse(maintenance)); err != nil {
+		app.internalServerError(w, r, err)
+		return
+	}
+}
+
+func (app *application) updateMaintenanceHandler(w http.ResponseWriter, r *http.Request) {
+	maintenance := getMaintenanceFromCtx(r)
+
+	var payload requests.UpdateMaintenancePayload
+	if err := readJSON(w, r, &payload); err != nil {
+		app.badRequestResponse(w, r, err)
+		return
+	}
+
+	if err := Validate.Struct(payload); err != nil {
+		app.badRequestResponse(w, r, err)
+		return
+	}
+
+	if payload.Title != nil {
+		maintenance.Title = *payload.Title
+	}
+	if payload.SupplierID != nil {
+		maintenance.SupplierID = payload.SupplierID
+	}
+	if payload.Type != nil {
+		maintenance.Type = store.MaintenanceType(*payload.Type)
+	}
+	if payload.StartDate != nil {
+		maintenance.StartDate = payload.StartDate
+	}
+	if payload.CompletionDate != nil {
+		maintenance.CompletionDate = payload.CompletionDate
+	}
+	if payload.Cost != nil {
+		maintenance.Cost = *payload.Cost
+	}
+	if payload.Notes != nil {
+		maintenance.Notes = *payload.Notes
+	}
+
+	if payload.Status != nil {
+		status := store.MaintenanceStatus(*payload.Status)
+		if err := setMaintenanceStatus(maintenance, status); err != nil {
+			app.conflictResponse(w, r, err)
+			return
+		}
+
+		if status == store.MaintenanceInProgress && maintenance.Asset.Status == store.AssetAssigned {
+			app.conflictResponse(w, r, errors.New("asset is checked out, check it in before starting maintenance"))
+			return
+		}
+	}
+
+	ctx := r.Context()
+
+	if err := app.store.Maintenance.Update(ctx, maintenance); err != nil {
+		app.internalServerError(w, r, err)
+		return
+	}
+
+	maintenance, err := app.store.Maintenance.GetByID(ctx, maintenance.ID)
+	if err != nil {
+		app.internalServerError(w, r, err)
+		return
+	}
+
+	if err := app.jsonResponse(w, http.StatusOK, responses.NewMaintenanceResponse(maintenance)); err != nil {
+		app.internalServerError(w, r, err)
+	}
+}
+
+func (app *application) getMaintenanceHandler(w http.ResponseWriter, r *http.Request) {
+	maintenance := getMaintenanceFromCtx(r)
+
+	if err := app.jsonResponse(w, http.StatusOK, responses.NewMaintenanceResponse(maintenance)); err != nil {
+		app.internalServerError(w, r, err)
+		return
+	}
+}
+
+func (app *application) getAllMaintenanceHandler(w http.ResponseWriter, r *http.Request) {
+	spec, ok := app.parseQuerySpec(w, r)
+	if !ok {
+		return
+	}
+
+	format, ok, err := requestedExportFormat(r)
+	if err != nil {
+		app.badRequestResponse(w, r, err)
+		return
+	}
+	if ok {
+		streamExport(app, w, r, "maintenances", format, spec, maintenanceExportColumns, app.store.Maintenance.Stream)
+		return
+	}
+
+	writeListPage(app, w, r, spec, app.store.Maintenance.List, responses.NewMaintenancesResponse)
+}
+
+func (app *application) deleteMaintenanceHandler(w http.ResponseWriter, r *http.Request) {
+	maintenance := getMaintenanceFromCtx(r)
+
+	ctx := r.Context()
+
+	if err := app.store.Maintenance.Delete(ctx, maintenance); err != nil {
+		switch {
+		case errors.Is(err, store.ErrNotFound):
+			app.notFoundResponse(w, r, err)
+		default:
+			app.internalServerError(w, r, err)
+		}
+		return
+	}
+
+	app.deleteAttachments(ctx, store.AttachmentOwnerMaintenance, maintenance.ID)
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// getMaintenanceCostsHandler reports purchase and maintenance cost totals
+// grouped by asset (the default) or by model, optionally for a single id.
+func (app *application) getMaintenanceCostsHandler(w http.ResponseWriter, r *http.Request) {
+	query := r.URL.Query()
+
+	var id *int64
+	if param := query.Get("id"); param != "" {
+		v, err := strconv.ParseInt(param, 10, 64)
+		if err != nil {
+			app.badRequestResponse(w, r, fmt.Errorf("invalid id %q", param))
+			return
+		}
+		id = &v
+	}
+
+	ctx := r.Context()
+
+	var (
+		costs []store.MaintenanceCost
+		err   error
+	)
+
+	switch query.Get("groupBy") {
+	case "", "asset":
+		costs, err = app.store.Maintenance.CostByAsset(ctx, id)
+	case "model":
+		costs, err = app.store.Maintenance.CostByModel(ctx, id)
+	default:
+		app.badRequestResponse(w, r, errors.New("groupBy must be asset or model"))
+		return
+	}
+	if err != nil {
+		app.internalServerError(w, r, err)
+		return
+	}
+
+	if err := app.jsonResponse(w, http.StatusOK, responses.NewMaintenanceCostsResponse(costs)); err != nil {
+		app.internalServerError(w, r, err)
+	}
+}
@@ -0,0 +1,408 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"slices"
+	"strconv"
+	"time"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/knr1997/assets-management-apiserver/internal/api/requests"
+	"github.com/knr1997/assets-management-apiserver/internal/api/responses"
+	"github.com/knr1997/assets-management-apiserver/internal/store"
+)
+
+type maintenancePlanKey string
+
+const maintenancePlanCtx maintenancePlanKey = "maintenancePlan"
+
+const (
+	defaultDueDays = 30
+	maxDueDays     = 365
+)
+
+// dueWorkOrder is the next work order of a plan for one asset, whether or not
+// it has been generated yet.
+type dueWorkOrder struct {
+	plan    *store.MaintenancePlan
+	target  store.PlanTarget
+	dueDate time.Time
+}
+
+func getMaintenancePlanFromCtx(r *http.Request) *store.MaintenancePlan {
+	plan, _ := r.Context().Value(maintenancePlanCtx).(*store.MaintenancePlan)
+	return plan
+}
+
+func (app *application) maintenancePlanContextMiddleware(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		idParam := chi.URLParam(r, "planID")
+		id, err := strconv.ParseInt(idParam, 10, 64)
+		if err != nil {
+			app.internalServerError(w, r, err)
+			return
+		}
+
+		ctx := r.Context()
+
+		plan, err := app.store.MaintenancePlan.GetByID(ctx, id)
+		if err != nil {
+			switch {
+			case errors.Is(err, store.ErrNotFound):
+				app.notFoundResponse(w, r, err)
+			default:
+				app.internalServerError(w, r, err)
+			}
+			return
+		}
+
+		ctx = context.WithValue(ctx, maintenancePlanCtx, plan)
+		next.ServeHTTP(w, r.WithContext(ctx))
+	})
+}
+
+func (app *application) createMaintenancePlanHandler(w http.ResponseWriter, r *http.Request) {
+	var payload requests.CreateMaintenancePlanPayload
+	if err := readJSON(w, r, &payload); err != nil {
+		app.badRequestResponse(w, r, err)
+		return
+	}
+
+	if err := Validate.Struct(payload); err != nil {
+		app.badRequestResponse(w, r, err)
+		return
+	}
+
+	plan := &store.MaintenancePlan{
+		Name:         payload.Name,
+		ModelID:      payload.ModelID,
+		AssetID:      payload.AssetID,
+		SupplierID:   payload.SupplierID,
+		OwnerID:      payload.OwnerID,
+		Type:         store.MaintenancePreventive,
+		Recurrence:   payload.Recurrence,
+		IntervalDays: payload.IntervalDays,
+		StartDate:    payload.StartDate,
+		LeadDays:     14,
+		Active:       true,
+		Notes:        payload.Notes,
+	}
+
+	if payload.Type != "" {
+		plan.Type = store.MaintenanceType(payload.Type)
+	}
+	if payload.LeadDays != nil {
+		plan.LeadDays = *payload.LeadDays
+	}
+	if payload.Active != nil {
+		plan.Active = *payload.Active
+	}
+
+	if err := plan.Validate(); err != nil {
+		app.badRequestResponse(w, r, err)
+		return
+	}
+
+	ctx := r.Context()
+
+	if err := app.store.MaintenancePlan.Create(ctx, plan); err != nil {
+		app.internalServerError(w, r, err)
+		return
+	}
+
+	plan, err := app.store.MaintenancePlan.GetByID(ctx, plan.ID)
+	if err != nil {
+		app.internalServerError(w, r, err)
+		return
+	}
+
+	if err := app.jsonResponse(w, http.StatusCreated, responses.NewMaintenancePlanResponse(plan)); err != nil {
+		app.internalServerError(w, r, err)
+		return
+	}
+}
+
+func (app *application) updateMaintenancePlanHandler(w http.ResponseWriter, r *http.Request) {
+	plan := getMaintenancePlanFromCtx(r)
+
+	var payload requests.UpdateMaintenancePlanPayload
+	if err := readJSON(w, r, &payload); err != nil {
+		app.badRequestResponse(w, r, err)
+		return
+	}
+
+	if err := Validate.Struct(payload); err != nil {
+		app.badRequestResponse(w, r, err)
+		return
+	}
+
+	if payload.Name != nil {
+		plan.Name = *payload.Name
+	}
+	if payload.ModelID != nil {
+		plan.ModelID = payload.ModelID
+		plan.AssetID = nil
+	}
+	if payload.AssetID != nil {
+		plan.AssetID = payload.AssetID
+		plan.ModelID = nil
+	}
+	if payload.SupplierID != nil {
+		plan.SupplierID = payload.SupplierID
+	}
+	if payload.OwnerID != nil {
+		plan.OwnerID = payload.OwnerID
+	}
+	if payload.Type != nil {
+		plan.Type = store.MaintenanceType(*payload.Type)
+	}
+	// recurrence and interval replace each other
+	if payload.Recurrence != nil {
+		plan.Recurrence = *payload.Recurrence
+		plan.IntervalDays = 0
+	}
+	if payload.IntervalDays != nil {
+		plan.IntervalDays = *payload.IntervalDays
+		if *payload.IntervalDays > 0 {
+			plan.Recurrence = ""
+		}
+	}
+	if payload.StartDate != nil {
+		plan.StartDate = *payload.StartDate
+	}
+	if payload.LeadDays != nil {
+		plan.LeadDays = *payload.LeadDays
+	}
+	if payload.Active != nil {
+		plan.Active = *payload.Active
+	}
+	if payload.Notes != nil {
+		plan.Notes = *payload.Notes
+	}
+
+	if err := plan.Validate(); err != nil {
+		app.badRequestResponse(w, r, err)
+		return
+	}
+
+	ctx := r.Context()
+
+	if err := app.store.MaintenancePlan.Update(ctx, plan); err != nil {
+		app.internalServerError(w, r, err)
+		return
+	}
+
+	plan, err := app.store.MaintenancePlan.GetByID(ctx, plan.ID)
+	if err != nil {
+		app.internalServerError(w, r, err)
+		return
+	}
+
+	if err := app.jsonResponse(w, http.StatusOK, responses.NewMaintenancePlanResponse(plan)); err != nil {
+		app.internalServerError(w, r, err)
+	}
+}
+
+func (app *application) getMaintenancePlanHandler(w http.ResponseWriter, r *http.Request) {
+	plan := getMaintenancePlanFromCtx(r)
+
+	if err := app.jsonResponse(w, http.StatusOK, responses.NewMaintenancePlanResponse(plan)); err != nil {
+		app.internalServerError(w, r, err)
+		return
+	}
+}
+
+func (app *application) getAllMaintenancePlanHandler(w http.ResponseWriter, r *http.Request) {
+	spec, ok := app.parseQuerySpec(w, r)
+	if !ok {
+		return
+	}
+
+	writeListPage(app, w, r, spec, app.store.MaintenancePlan.List, responses.NewMaintenancePlansResponse)
+}
+
+func (app *application) deleteMaintenancePlanHandler(w http.ResponseWriter, r *http.Request) {
+	plan := getMaintenancePlanFromCtx(r)
+
+	ctx := r.Context()
+
+	if err := app.store.MaintenancePlan.Delete(ctx, plan.ID); err != nil {
+		switch {
+		case errors.Is(err, store.ErrNotFound):
+			app.notFoundResponse(w, r, err)
+		default:
+			app.internalServerError(w, r, err)
+		}
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// getMaintenanceDueHandler lists the work orders due within ?days= (30 by
+// default), overdue ones included, grouped by asset location.
+func (app *application) getMaintenanceDueHandler(w http.ResponseWriter, r *http.Request) {
+	days := defaultDueDays
+	if param := r.URL.Query().Get("days"); param != "" {
+		n, err := strconv.Atoi(param)
+		if err != nil || n < 0 || n > maxDueDays {
+			app.badRequestResponse(w, r, fmt.Errorf("days must be between 0 and %d", maxDueDays))
+			return
+		}
+		days = n
+	}
+
+	now := time.Now()
+	until := now.AddDate(0, 0, days)
+
+	due, err := app.upcomingWorkOrders(r.Context(), func(*store.MaintenancePlan) time.Time { return until })
+	if err != nil {
+		app.internalServerError(w, r, err)
+		return
+	}
+
+	groups := []responses.MaintenanceDueGroupResponse{}
+	index := map[string]int{}
+
+	for _, d := range due {
+		i, ok := index[d.target.Location]
+		if !ok {
+			i = len(groups)
+			index[d.target.Location] = i
+			groups = append(groups, responses.MaintenanceDueGroupResponse{Location: d.target.Location})
+		}
+
+		groups[i].Items = append(groups[i].Items, responses.MaintenanceDueResponse{
+			PlanID:   d.plan.ID,
+			PlanName: d.plan.Name,
+			Type:     string(d.plan.Type),
+			Asset: responses.AssetSummary{
+				ID:   d.target.AssetID,
+				Name: d.target.AssetName,
+				Tag:  d.target.AssetTag,
+			},
+			DueDate:       d.dueDate,
+			Overdue:       d.dueDate.Before(now),
+			MaintenanceID: d.target.OpenID,
+		})
+	}
+
+	slices.SortFunc(groups, func(a, b responses.MaintenanceDueGroupResponse) int {
+		switch {
+		case a.Location < b.Location:
+			return -1
+		case a.Location > b.Location:
+			return 1
+		}
+		return 0
+	})
+
+	if err := app.jsonResponse(w, http.StatusOK, groups); err != nil {
+		app.internalServerError(w, r, err)
+	}
+}
+
+// upcomingWorkOrders returns, ordered by due date, the next work order of
+// every active plan and covered asset that falls due before until(plan).
+func (app *application) upcomingWorkOrders(ctx context.Context, until func(*store.MaintenancePlan) time.Time) ([]dueWorkOrder, error) {
+	plans, err := app.store.MaintenancePlan.GetActive(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	var due []dueWorkOrder
+
+	for i := range plans {
+		plan := &plans[i]
+
+		targets, err := app.store.MaintenancePlan.Targets(ctx, plan)
+		if err != nil {
+			return nil, err
+		}
+
+		limit := until(plan)
+		for _, target := range targets {
+			next, ok := plan.NextDue(target)
+			if !ok || next.After(limit) {
+				continue
+			}
+			due = append(due, dueWorkOrder{plan: plan, target: target, dueDate: next})
+		}
+	}
+
+	slices.SortStableFunc(due, func(a, b dueWorkOrder) int {
+		return a.dueDate.Compare(b.dueDate)
+	})
+
+	return due, nil
+}
+
+// generateWorkOrders creates the work orders that have come within the lead
+// time of their plan and notifies the asset holder and the plan owner.
+func (app *application) generateWorkOrders(ctx context.Context) error {
+	now := time.Now()
+
+	due, err := app.upcomingWorkOrders(ctx, func(p *store.MaintenancePlan) time.Time {
+		return now.AddDate(0, 0, p.LeadDays)
+	})
+	if err != nil {
+		return err
+	}
+
+	generated := 0
+
+	for _, d := range due {
+		if d.target.OpenID != nil {
+			continue
+		}
+
+		dueDate := d.dueDate
+		assetID := d.target.AssetID
+
+		order := &store.Maintenance{
+			Title:      d.plan.Name,
+			AssetID:    assetID,
+			SupplierID: d.plan.SupplierID,
+			PlanID:     &d.plan.ID,
+			DueDate:    &dueDate,
+			Type:       d.plan.Type,
+			Status:     store.MaintenanceScheduled,
+			Notes:      d.plan.Notes,
+		}
+
+		var recipients []int64
+		for _, id := range []*int64{d.target.HolderID, d.plan.OwnerID} {
+			if id != nil && !slices.Contains(recipients, *id) {
+				recipients = append(recipients, *id)
+			}
+		}
+
+		notifications := make([]store.Notification, len(recipients))
+		for i, userID := range recipients {
+			notifications[i] = store.Notification{
+				UserID:  userID,
+				Kind:    "maintenance_due",
+				Title:   fmt.Sprintf("%s is due for %s", d.target.AssetTag, d.plan.Name),
+				Body:    fmt.Sprintf("%s (%s) is due for maintenance on %s.", d.target.AssetName, d.target.AssetTag, dueDate.Format(time.DateOnly)),
+				AssetID: &assetID,
+			}
+		}
+
+		created, err := app.store.MaintenancePlan.CreateWorkOrder(ctx, order, notifications)
+		if err != nil {
+			return err
+		}
+		if created {
+			generated++
+		}
+	}
+
+	if generated > 0 {
+		app.logger.Infow("generated maintenance work orders", "count", generated)
+	}
+
+	return nil
+}
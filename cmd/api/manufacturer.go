@@ -2,7 +2,6 @@ package main
 
 import (
 	"context"
-	"encoding/json"
 	"errors"
 	"net/http"
 	"strconv"
@@ -57,7 +56,7 @@ func (app *application) manufacturerContextMiddleware(next http.Handler) http.Ha
 //	@Accept			json
 //	@Produce		json
 //	@Param			payload	body		CreateManufacturerPayload	true	"Manufacturer payload"
-//	@Success		201		{object}	store.Manufacturer
+//	@Success		201		{object}	responses.ManufacturerResponse
 //	@Failure		400		{object}	error
 //	@Failure		401		{object}	error
 //	@Failure		500		{object}	error
@@ -76,18 +75,25 @@ func (app *application) createManufacturerHandler(w http.ResponseWriter, r *http
 	}
 
 	manufacturer := &store.Manufacturer{
-		Name:  payload.Name,
-		Email: payload.Email,
+		Name:              payload.Name,
+		Email:             payload.Email,
+		WarrantyLookupURL: payload.WarrantyLookupURL,
 	}
+	applyVendorDetails(&manufacturer.VendorDetails, payload.VendorDetailsPayload)
 
 	ctx := r.Context()
 
 	if err := app.store.Manufacturer.Create(ctx, manufacturer); err != nil {
-		app.internalServerError(w, r, err)
+		switch {
+		case errors.Is(err, store.ErrConflict):
+			app.conflictResponse(w, r, err)
+		default:
+			app.internalServerError(w, r, err)
+		}
 		return
 	}
 
-	if err := app.jsonResponse(w, http.StatusCreated, manufacturer); err != nil {
+	if err := app.jsonResponse(w, http.StatusCreated, responses.NewManufacturerResponse(manufacturer)); err != nil {
 		app.internalServerError(w, r, err)
 		return
 	}
@@ -110,7 +116,7 @@ func (app *application) updateManufacturer(ctx context.Context, manufacturer *st
 //	@Produce		json
 //	@Param			id		path		int					true	"Manufacturer ID"
 //	@Param			payload	body		UpdateManufacturerPayload	true	"Manufacturer payload"
-//	@Success		200		{object}	store.Manufacturer
+//	@Success		200		{object}	responses.ManufacturerResponse
 //	@Failure		400		{object}	error
 //	@Failure		401		{object}	error
 //	@Failure		404		{object}	error
@@ -138,14 +144,24 @@ func (app *application) updateManufacturerHandler(w http.ResponseWriter, r *http
 	if payload.Email != nil {
 		manufacturer.Email = *payload.Email
 	}
+	if payload.WarrantyLookupURL != nil {
+		manufacturer.WarrantyLookupURL = *payload.WarrantyLookupURL
+	}
+	applyVendorDetails(&manufacturer.VendorDetails, payload.VendorDetailsPayload)
 
 	ctx := r.Context()
 
 	if err := app.updateManufacturer(ctx, manufacturer); err != nil {
-		app.internalServerError(w, r, err)
+		switch {
+		case errors.Is(err, store.ErrConflict):
+			app.conflictResponse(w, r, err)
+		default:
+			app.internalServerError(w, r, err)
+		}
+		return
 	}
 
-	if err := app.jsonResponse(w, http.StatusOK, manufacturer); err != nil {
+	if err := app.jsonResponse(w, http.StatusOK, responses.NewManufacturerResponse(manufacturer)); err != nil {
 		app.internalServerError(w, r, err)
 	}
 }
@@ -157,16 +173,17 @@ func (app *application) updateManufacturerHandler(w http.ResponseWriter, r *http
 //	@Tags			manufacturers
 //	@Accept			json
 //	@Produce		json
-//	@Param			id	path		int	true	"Manufacturer ID"
-//	@Success		200	{object}	store.Manufacturer
-//	@Failure		404	{object}	error
-//	@Failure		500	{object}	error
+//	@Param			id		path		int		true	"Manufacturer ID"
+//	@Param			serial	query		string	false	"Serial number to build the warranty lookup URL for"
+//	@Success		200		{object}	responses.ManufacturerDetailResponse
+//	@Failure		404		{object}	error
+//	@Failure		500		{object}	error
 //	@Security		ApiKeyAuth
 //	@Router			/manufacturers/{id} [get]
 func (app *application) getManufacturerHandler(w http.ResponseWriter, r *http.Request) {
 	manufacturer := getManufacturerFromCtx(r)
 
-	response := responses.NewManufacturerResponse(manufacturer)
+	response := responses.NewManufacturerDetailResponse(manufacturer, r.URL.Query().Get("serial"))
 
 	if err := app.jsonResponse(w, http.StatusOK, response); err != nil {
 		app.internalServerError(w, r, err)
@@ -175,18 +192,12 @@ func (app *application) getManufacturerHandler(w http.ResponseWriter, r *http.Re
 }
 
 func (app *application) getAllManufacturerHandler(w http.ResponseWriter, r *http.Request) {
-	ctx := r.Context()
-
-	Manufacturers, err := app.store.Manufacturer.GetAll(ctx)
-	if err != nil {
-		app.internalServerError(w, r, err)
+	spec, ok := app.parseQuerySpec(w, r)
+	if !ok {
 		return
 	}
 
-	response := responses.NewManufacturersResponse(Manufacturers)
-
-	w.Header().Set("Content-Type", "application/json")
-	json.NewEncoder(w).Encode(response)
+	writeListPage(app, w, r, spec, app.store.Manufacturer.List, responses.NewManufacturersResponse)
 }
 
 // DeleteManufacturer godoc
@@ -203,7 +214,7 @@ func (app *application) getAllManufacturerHandler(w http.ResponseWriter, r *http
 //	@Security		ApiKeyAuth
 //	@Router			/manufacturers/{id} [delete]
 func (app *application) deleteManufacturerHandler(w http.ResponseWriter, r *http.Request) {
-	idParam := chi.URLParam(r, "ManufacturerID")
+	idParam := chi.URLParam(r, "manufacturerID")
 	id, err := strconv.ParseInt(idParam, 10, 64)
 	if err != nil {
 		app.internalServerError(w, r, err)
@@ -2,11 +2,13 @@ package main
 
 import (
 	"context"
+	"errors"
 	"fmt"
 	"net"
 	"net/http"
 	"strconv"
 	"strings"
+	"time"
 
 	"github.com/go-chi/chi/v5/middleware"
 	"github.com/golang-jwt/jwt/v5"
@@ -15,46 +17,128 @@ import (
 
 func (app *application) AuthTokenMiddleware(next http.Handler) http.Handler {
 	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
-		authHeader := r.Header.Get("Authorization")
-		if authHeader == "" {
+		if r.Header.Get("Authorization") == "" {
 			app.unauthorizedErrorResponse(w, r, fmt.Errorf("authorization header is missing"))
 			return
 		}
 
-		parts := strings.Split(authHeader, " ")
-		if len(parts) != 2 || parts[0] != "Bearer" {
-			app.unauthorizedErrorResponse(w, r, fmt.Errorf("authorization header is malformed"))
-			return
-		}
-
-		token := parts[1]
-		jwtToken, err := app.authenticator.ValidateToken(token)
+		user, err := app.authenticate(r)
 		if err != nil {
 			app.unauthorizedErrorResponse(w, r, err)
 			return
 		}
 
-		claims, _ := jwtToken.Claims.(jwt.MapClaims)
+		ctx := context.WithValue(r.Context(), userCtx, user)
+		next.ServeHTTP(w, r.WithContext(ctx))
+	})
+}
 
-		userID, err := strconv.ParseInt(fmt.Sprintf("%.f", claims["sub"]), 10, 64)
-		if err != nil {
-			app.unauthorizedErrorResponse(w, r, err)
+// OptionalAuthTokenMiddleware identifies the user when a token is sent, for
+// routes that are public but show more to some users. A token that is sent
+// must still be valid.
+func (app *application) OptionalAuthTokenMiddleware(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("Authorization") == "" {
+			next.ServeHTTP(w, r)
 			return
 		}
 
-		ctx := r.Context()
-
-		user, err := app.getUser(ctx, userID)
+		user, err := app.authenticate(r)
 		if err != nil {
 			app.unauthorizedErrorResponse(w, r, err)
 			return
 		}
 
-		ctx = context.WithValue(ctx, userCtx, user)
+		ctx := context.WithValue(r.Context(), userCtx, user)
 		next.ServeHTTP(w, r.WithContext(ctx))
 	})
 }
 
+var (
+	errUserInactive    = errors.New("user is not active")
+	errTokenWrongScope = errors.New("token is not valid here")
+)
+
+// authenticate returns the user of the bearer token in the Authorization
+// header. Deactivated users are refused even with a token still valid.
+func (app *application) authenticate(r *http.Request) (*store.User, error) {
+	parts := strings.Split(r.Header.Get("Authorization"), " ")
+	if len(parts) != 2 || parts[0] != "Bearer" {
+		return nil, fmt.Errorf("authorization header is malformed")
+	}
+
+	return app.tokenUser(r.Context(), parts[1], "")
+}
+
+// tokenUser returns the user of token, which must have been issued for scope.
+// Sign-in tokens have none; a token limited to a scope, such as one for the
+// event stream, is only good for it.
+func (app *application) tokenUser(ctx context.Context, token, scope string) (*store.User, error) {
+	jwtToken, err := app.authenticator.ValidateToken(token)
+	if err != nil {
+		return nil, err
+	}
+
+	claims, _ := jwtToken.Claims.(jwt.MapClaims)
+
+	if tokenScope, _ := claims["scope"].(string); tokenScope != scope {
+		return nil, errTokenWrongScope
+	}
+
+	userID, err := strconv.ParseInt(fmt.Sprintf("%.f", claims["sub"]), 10, 64)
+	if err != nil {
+		return nil, err
+	}
+
+	user, err := app.getUser(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+	if !user.IsActive {
+		return nil, errUserInactive
+	}
+
+	return user, nil
+}
+
+// RequireRoleMiddleware lets only users holding the named role through. It
+// goes after AuthTokenMiddleware.
+func (app *application) RequireRoleMiddleware(roleName string) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			ok, err := app.hasRole(r.Context(), getUserFromContext(r), roleName)
+			if err != nil {
+				app.internalServerError(w, r, err)
+				return
+			}
+			if !ok {
+				app.forbiddenResponse(w, r)
+				return
+			}
+
+			next.ServeHTTP(w, r)
+		})
+	}
+}
+
+// hasRole reports whether user holds the named role. Nobody does when the
+// role does not exist, or when there is no user.
+func (app *application) hasRole(ctx context.Context, user *store.User, roleName string) (bool, error) {
+	if user == nil {
+		return false, nil
+	}
+
+	role, err := app.store.Roles.GetByName(ctx, roleName)
+	if err != nil {
+		if errors.Is(err, store.ErrNotFound) {
+			return false, nil
+		}
+		return false, err
+	}
+
+	return user.RoleID == role.ID, nil
+}
+
 func (app *application) checkRolePrecedence(ctx context.Context, user *store.User, roleName string) (bool, error) {
 	role, err := app.store.Roles.GetByName(ctx, roleName)
 	if err != nil {
@@ -101,3 +185,25 @@ func (app *application) AuditContextMiddleware(next http.Handler) http.Handler {
 		next.ServeHTTP(w, r.WithContext(ctx))
 	})
 }
+
+// timeoutExceptStreams is middleware.Timeout for every request but the event
+// stream, which stays open for as long as its client listens, and exports,
+// which run under exportTimeout instead. An export cut off by the shorter
+// timeout would reach the client as a truncated file with status 200.
+func timeoutExceptStreams(timeout time.Duration) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		timed := middleware.Timeout(timeout)(next)
+
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			if r.URL.Path == eventStreamPath {
+				next.ServeHTTP(w, r)
+				return
+			}
+			if _, ok, err := requestedExportFormat(r); ok && err == nil {
+				next.ServeHTTP(w, r)
+				return
+			}
+			timed.ServeHTTP(w, r)
+		})
+	}
+}
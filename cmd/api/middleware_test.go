@@ -0,0 +1,82 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/golang-jwt/jwt/v5"
+	"github.com/knr1997/assets-management-apiserver/internal/auth"
+)
+
+func TestTimeoutExceptStreams(t *testing.T) {
+	tests := []struct {
+		name   string
+		target string
+		accept string
+		timed  bool
+	}{
+		{"list", "/api/assets", "", true},
+		{"json format", "/api/assets?format=json", "", true},
+		{"unknown format", "/api/assets?format=pdf", "", true},
+		{"csv format", "/api/assets?format=csv", "", false},
+		{"xlsx accept", "/api/assets", "application/vnd.openxmlformats-officedocument.spreadsheetml.sheet", false},
+		{"event stream", eventStreamPath, "", false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var timed bool
+			handler := timeoutExceptStreams(time.Minute)(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				_, timed = r.Context().Deadline()
+			}))
+
+			r := httptest.NewRequest(http.MethodGet, tt.target, nil)
+			if tt.accept != "" {
+				r.Header.Set("Accept", tt.accept)
+			}
+			handler.ServeHTTP(httptest.NewRecorder(), r)
+
+			if timed != tt.timed {
+				t.Errorf("deadline set = %v, want %v", timed, tt.timed)
+			}
+		})
+	}
+}
+
+func TestTokenUserScope(t *testing.T) {
+	app := &application{authenticator: auth.NewJWTAuthenticator("secret", "test", "test")}
+
+	token := func(scope string) string {
+		claims := jwt.MapClaims{"sub": 1, "exp": time.Now().Add(time.Minute).Unix(), "iss": "test", "aud": "test"}
+		if scope != "" {
+			claims["scope"] = scope
+		}
+		token, err := app.authenticator.GenerateToken(claims)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return token
+	}
+
+	tests := []struct {
+		name       string
+		tokenScope string
+		scope      string
+	}{
+		{"stream token as sign in", eventStreamTokenScope, ""},
+		{"sign in token in stream URL", "", eventStreamTokenScope},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := app.tokenUser(context.Background(), token(tt.tokenScope), tt.scope)
+			if !errors.Is(err, errTokenWrongScope) {
+				t.Errorf("tokenUser() error = %v, want %v", err, errTokenWrongScope)
+			}
+		})
+	}
+}
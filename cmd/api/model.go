@@ -2,7 +2,6 @@ package main
 
 import (
 	"context"
-	"encoding/json"
 	"errors"
 	"net/http"
 	"strconv"
@@ -66,6 +65,17 @@ func (app *application) createModelHandler(w http.ResponseWriter, r *http.Reques
 		CategoryID:     payload.CategoryID,
 		ManufacturerID: payload.ManufacturerID,
 		ModelNumber:    payload.ModelNumber,
+		Requestable:    payload.Requestable,
+		RequestCost:    payload.RequestCost,
+
+		EOLDate:                   payload.EOLDate,
+		EndOfSupportDate:          payload.EndOfSupportDate,
+		DefaultUsefulLifeYears:    payload.DefaultUsefulLifeYears,
+		DefaultDepreciationMethod: store.DepreciationStraightLine,
+		DefaultLoanDays:           payload.DefaultLoanDays,
+	}
+	if payload.DefaultDepreciationMethod != "" {
+		model.DefaultDepreciationMethod = store.DepreciationMethod(payload.DefaultDepreciationMethod)
 	}
 
 	ctx := r.Context()
@@ -107,6 +117,27 @@ func (app *application) updateModelHandler(w http.ResponseWriter, r *http.Reques
 	if payload.Name != nil {
 		Model.Name = *payload.Name
 	}
+	if payload.Requestable != nil {
+		Model.Requestable = *payload.Requestable
+	}
+	if payload.RequestCost != nil {
+		Model.RequestCost = *payload.RequestCost
+	}
+	if payload.EOLDate != nil {
+		Model.EOLDate = payload.EOLDate
+	}
+	if payload.EndOfSupportDate != nil {
+		Model.EndOfSupportDate = payload.EndOfSupportDate
+	}
+	if payload.DefaultUsefulLifeYears != nil {
+		Model.DefaultUsefulLifeYears = *payload.DefaultUsefulLifeYears
+	}
+	if payload.DefaultDepreciationMethod != nil {
+		Model.DefaultDepreciationMethod = store.DepreciationMethod(*payload.DefaultDepreciationMethod)
+	}
+	if payload.DefaultLoanDays != nil {
+		Model.DefaultLoanDays = *payload.DefaultLoanDays
+	}
 
 	ctx := r.Context()
 
@@ -130,19 +161,42 @@ func (app *application) getModelHandler(w http.ResponseWriter, r *http.Request)
 	}
 }
 
+var modelExportColumns = []exportColumn[store.Model]{
+	{"id", func(m *store.Model) any { return m.ID }},
+	{"name", func(m *store.Model) any { return m.Name }},
+	{"modelNumber", func(m *store.Model) any { return m.ModelNumber }},
+	{"categoryId", func(m *store.Model) any { return m.CategoryID }},
+	{"category", func(m *store.Model) any { return m.Category.Name }},
+	{"manufacturerId", func(m *store.Model) any { return m.ManufacturerID }},
+	{"manufacturer", func(m *store.Model) any { return m.Manufacturer.Name }},
+	{"requestable", func(m *store.Model) any { return m.Requestable }},
+	{"requestCost", func(m *store.Model) any { return m.RequestCost }},
+	{"eolDate", func(m *store.Model) any { return m.EOLDate }},
+	{"endOfSupportDate", func(m *store.Model) any { return m.EndOfSupportDate }},
+	{"defaultUsefulLifeYears", func(m *store.Model) any { return m.DefaultUsefulLifeYears }},
+	{"defaultDepreciationMethod", func(m *store.Model) any { return string(m.DefaultDepreciationMethod) }},
+	{"defaultLoanDays", func(m *store.Model) any { return m.DefaultLoanDays }},
+	{"createdAt", func(m *store.Model) any { return m.CreatedAt }},
+	{"updatedAt", func(m *store.Model) any { return m.UpdatedAt }},
+}
+
 func (app *application) getAllModelHandler(w http.ResponseWriter, r *http.Request) {
-	ctx := r.Context()
+	spec, ok := app.parseQuerySpec(w, r)
+	if !ok {
+		return
+	}
 
-	Models, err := app.store.Model.GetAll(ctx)
+	format, ok, err := requestedExportFormat(r)
 	if err != nil {
-		app.internalServerError(w, r, err)
+		app.badRequestResponse(w, r, err)
+		return
+	}
+	if ok {
+		streamExport(app, w, r, "models", format, spec, modelExportColumns, app.store.Model.Stream)
 		return
 	}
 
-	response := responses.NewModelsResponse(Models)
-
-	w.Header().Set("Content-Type", "application/json")
-	json.NewEncoder(w).Encode(response)
+	writeListPage(app, w, r, spec, app.store.Model.List, responses.NewModelsResponse)
 }
 
 func (app *application) deleteModelHandler(w http.ResponseWriter, r *http.Request) {
@@ -165,5 +219,106 @@ func (app *application) deleteModelHandler(w http.ResponseWriter, r *http.Reques
 		return
 	}
 
+	app.deleteAttachments(ctx, store.AttachmentOwnerModel, id)
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// imageTypes are the sniffed content types accepted as model pictures.
+var imageTypes = map[string]bool{
+	"image/png":  true,
+	"image/jpeg": true,
+	"image/gif":  true,
+	"image/webp": true,
+}
+
+// uploadModelImageHandler sets the model's picture from the "file" field of
+// a multipart form, replacing the one it had.
+func (app *application) uploadModelImageHandler(w http.ResponseWriter, r *http.Request) {
+	user := getUserFromContext(r)
+	model := getModelFromCtx(r)
+
+	upload, err := app.readUpload(w, r, imageTypes)
+	if err != nil {
+		app.badRequestResponse(w, r, err)
+		return
+	}
+	defer upload.close()
+
+	ctx := r.Context()
+
+	image, err := app.putUpload(ctx, store.AttachmentOwnerModel, model.ID, upload, user.ID)
+	if err != nil {
+		app.internalServerError(w, r, err)
+		return
+	}
+
+	replaced, err := app.store.Model.SetImage(ctx, model.ID, image)
+	if err != nil {
+		app.removeBlobs(ctx, image.StorageKey)
+		switch {
+		case errors.Is(err, store.ErrNotFound):
+			app.notFoundResponse(w, r, err)
+		default:
+			app.internalServerError(w, r, err)
+		}
+		return
+	}
+	app.removeBlobs(ctx, replaced)
+
+	model, err = app.store.Model.GetByID(ctx, model.ID)
+	if err != nil {
+		app.internalServerError(w, r, err)
+		return
+	}
+
+	if err := app.jsonResponse(w, http.StatusOK, responses.NewModelResponse(model)); err != nil {
+		app.internalServerError(w, r, err)
+		return
+	}
+}
+
+// getModelImageHandler serves the model's picture, so it can be used as an
+// image source directly.
+func (app *application) getModelImageHandler(w http.ResponseWriter, r *http.Request) {
+	model := getModelFromCtx(r)
+
+	if model.ImageID == nil {
+		app.notFoundResponse(w, r, errors.New("the model has no image"))
+		return
+	}
+
+	image, err := app.store.Attachment.GetByID(r.Context(), *model.ImageID)
+	if err != nil {
+		switch {
+		case errors.Is(err, store.ErrNotFound):
+			app.notFoundResponse(w, r, err)
+		default:
+			app.internalServerError(w, r, err)
+		}
+		return
+	}
+
+	w.Header().Set("Cache-Control", "public, max-age=3600")
+	app.writeAttachment(w, r, image)
+}
+
+func (app *application) deleteModelImageHandler(w http.ResponseWriter, r *http.Request) {
+	model := getModelFromCtx(r)
+
+	ctx := r.Context()
+
+	removed, err := app.store.Model.RemoveImage(ctx, model.ID)
+	if err != nil {
+		switch {
+		case errors.Is(err, store.ErrNotFound):
+			app.notFoundResponse(w, r, err)
+		default:
+			app.internalServerError(w, r, err)
+		}
+		return
+	}
+	app.removeBlobs(ctx, removed)
+
 	w.WriteHeader(http.StatusNoContent)
 }
@@ -0,0 +1,276 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/knr1997/assets-management-apiserver/internal/api/requests"
+	"github.com/knr1997/assets-management-apiserver/internal/api/responses"
+	"github.com/knr1997/assets-management-apiserver/internal/pdf"
+	"github.com/knr1997/assets-management-apiserver/internal/store"
+)
+
+type offboardingKey string
+
+const offboardingCtx offboardingKey = "offboarding"
+
+func getOffboardingFromCtx(r *http.Request) *store.Offboarding {
+	offboarding, _ := r.Context().Value(offboardingCtx).(*store.Offboarding)
+	return offboarding
+}
+
+func (app *application) offboardingContextMiddleware(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		idParam := chi.URLParam(r, "offboardingID")
+		id, err := strconv.ParseInt(idParam, 10, 64)
+		if err != nil {
+			app.internalServerError(w, r, err)
+			return
+		}
+
+		ctx := r.Context()
+
+		offboarding, err := app.store.Offboarding.GetByID(ctx, id)
+		if err != nil {
+			switch {
+			case errors.Is(err, store.ErrNotFound):
+				app.notFoundResponse(w, r, err)
+			default:
+				app.internalServerError(w, r, err)
+			}
+			return
+		}
+
+		ctx = context.WithValue(ctx, offboardingCtx, offboarding)
+		next.ServeHTTP(w, r.WithContext(ctx))
+	})
+}
+
+// offboardUserHandler opens the offboarding case of a leaving user, listing
+// everything they still hold.
+func (app *application) offboardUserHandler(w http.ResponseWriter, r *http.Request) {
+	performer := getUserFromContext(r)
+
+	userID, err := strconv.ParseInt(chi.URLParam(r, "userID"), 10, 64)
+	if err != nil {
+		app.internalServerError(w, r, err)
+		return
+	}
+
+	var payload requests.CreateOffboardingPayload
+	if err := readJSON(w, r, &payload); err != nil && !errors.Is(err, io.EOF) {
+		app.badRequestResponse(w, r, err)
+		return
+	}
+
+	if err := Validate.Struct(payload); err != nil {
+		app.badRequestResponse(w, r, err)
+		return
+	}
+
+	ctx := r.Context()
+
+	user, err := app.store.Users.GetByID(ctx, userID)
+	if err != nil {
+		switch {
+		case errors.Is(err, store.ErrNotFound):
+			app.notFoundResponse(w, r, err)
+		default:
+			app.internalServerError(w, r, err)
+		}
+		return
+	}
+
+	offboarding := &store.Offboarding{
+		UserID:     user.ID,
+		LastDay:    payload.LastDay,
+		OpenedByID: &performer.ID,
+		Notes:      payload.Notes,
+	}
+
+	if err := app.store.Offboarding.Open(ctx, offboarding); err != nil {
+		switch {
+		case errors.Is(err, store.ErrConflict):
+			app.conflictResponse(w, r, fmt.Errorf("user %s already has an open offboarding", user.Username))
+		default:
+			app.internalServerError(w, r, err)
+		}
+		return
+	}
+
+	offboarding, err = app.store.Offboarding.GetByID(ctx, offboarding.ID)
+	if err != nil {
+		app.internalServerError(w, r, err)
+		return
+	}
+
+	if err := app.jsonResponse(w, http.StatusCreated, responses.NewOffboardingResponse(offboarding)); err != nil {
+		app.internalServerError(w, r, err)
+		return
+	}
+}
+
+func (app *application) getAllOffboardingHandler(w http.ResponseWriter, r *http.Request) {
+	spec, ok := app.parseQuerySpec(w, r)
+	if !ok {
+		return
+	}
+
+	writeListPage(app, w, r, spec, app.store.Offboarding.List, responses.NewOffboardingsResponse)
+}
+
+func (app *application) getOffboardingHandler(w http.ResponseWriter, r *http.Request) {
+	offboarding := getOffboardingFromCtx(r)
+
+	if err := app.jsonResponse(w, http.StatusOK, responses.NewOffboardingResponse(offboarding)); err != nil {
+		app.internalServerError(w, r, err)
+		return
+	}
+}
+
+func (app *application) recoverOffboardingItemHandler(w http.ResponseWriter, r *http.Request) {
+	app.resolveOffboardingItem(w, r, store.ItemRecovered)
+}
+
+func (app *application) writeOffOffboardingItemHandler(w http.ResponseWriter, r *http.Request) {
+	app.resolveOffboardingItem(w, r, store.ItemWrittenOff)
+}
+
+// resolveOffboardingItem marks an item as recovered or written off, which
+// completes the case once it was the last one pending.
+func (app *application) resolveOffboardingItem(w http.ResponseWriter, r *http.Request, status store.OffboardingItemStatus) {
+	user := getUserFromContext(r)
+	offboarding := getOffboardingFromCtx(r)
+
+	itemID, err := strconv.ParseInt(chi.URLParam(r, "itemID"), 10, 64)
+	if err != nil {
+		app.internalServerError(w, r, err)
+		return
+	}
+
+	var payload requests.ResolveOffboardingItemPayload
+	if err := readJSON(w, r, &payload); err != nil && !errors.Is(err, io.EOF) {
+		app.badRequestResponse(w, r, err)
+		return
+	}
+
+	if err := Validate.Struct(payload); err != nil {
+		app.badRequestResponse(w, r, err)
+		return
+	}
+
+	ctx := r.Context()
+
+	if err := app.store.Offboarding.Resolve(ctx, offboarding.ID, itemID, status, user.ID, payload.Notes); err != nil {
+		switch {
+		case errors.Is(err, store.ErrNotFound):
+			app.notFoundResponse(w, r, err)
+		case errors.Is(err, store.ErrOffboardingClosed):
+			app.conflictResponse(w, r, fmt.Errorf("offboarding is %s", offboarding.Status))
+		case errors.Is(err, store.ErrItemResolved):
+			app.conflictResponse(w, r, err)
+		default:
+			app.internalServerError(w, r, err)
+		}
+		return
+	}
+
+	offboarding, err = app.store.Offboarding.GetByID(ctx, offboarding.ID)
+	if err != nil {
+		app.internalServerError(w, r, err)
+		return
+	}
+
+	if err := app.jsonResponse(w, http.StatusOK, responses.NewOffboardingResponse(offboarding)); err != nil {
+		app.internalServerError(w, r, err)
+		return
+	}
+}
+
+func (app *application) cancelOffboardingHandler(w http.ResponseWriter, r *http.Request) {
+	offboarding := getOffboardingFromCtx(r)
+
+	ctx := r.Context()
+
+	if err := app.store.Offboarding.Cancel(ctx, offboarding.ID); err != nil {
+		switch {
+		case errors.Is(err, store.ErrOffboardingClosed):
+			app.conflictResponse(w, r, fmt.Errorf("offboarding is %s", offboarding.Status))
+		default:
+			app.internalServerError(w, r, err)
+		}
+		return
+	}
+
+	offboarding, err := app.store.Offboarding.GetByID(ctx, offboarding.ID)
+	if err != nil {
+		app.internalServerError(w, r, err)
+		return
+	}
+
+	if err := app.jsonResponse(w, http.StatusOK, responses.NewOffboardingResponse(offboarding)); err != nil {
+		app.internalServerError(w, r, err)
+		return
+	}
+}
+
+// getOffboardingChecklistHandler renders the case as a printable checklist.
+func (app *application) getOffboardingChecklistHandler(w http.ResponseWriter, r *http.Request) {
+	offboarding := getOffboardingFromCtx(r)
+
+	content := offboardingChecklist(offboarding)
+
+	w.Header().Set("Content-Type", "application/pdf")
+	w.Header().Set("Content-Disposition", fmt.Sprintf("attachment; filename=%q", fmt.Sprintf("offboarding-%d.pdf", offboarding.ID)))
+	w.Header().Set("Content-Length", strconv.Itoa(len(content)))
+	w.WriteHeader(http.StatusOK)
+	w.Write(content)
+}
+
+func offboardingChecklist(offboarding *store.Offboarding) []byte {
+	doc := pdf.New()
+	doc.Title("Offboarding checklist")
+	doc.Field("Case", fmt.Sprintf("#%d", offboarding.ID))
+	doc.Field("Status", string(offboarding.Status))
+	doc.Field("Opened", offboarding.CreatedAt.Format(time.RFC1123))
+
+	doc.Heading("Employee")
+	doc.Field("User", offboarding.User.Username)
+	doc.Field("Email", offboarding.User.Email)
+	if offboarding.LastDay != nil {
+		doc.Field("Last day", offboarding.LastDay.Format("2006-01-02"))
+	}
+
+	doc.Heading(fmt.Sprintf("Items (%d of %d pending)", offboarding.Pending(), len(offboarding.Items)))
+	if len(offboarding.Items) == 0 {
+		doc.Text("The user held nothing.")
+	}
+	for _, item := range offboarding.Items {
+		text := item.Description
+		if item.Status != store.ItemPending {
+			text += " - " + string(item.Status)
+			if item.ResolvedAt != nil {
+				text += " " + item.ResolvedAt.Format("2006-01-02")
+			}
+		}
+		doc.Checkbox(item.Status != store.ItemPending, text)
+	}
+
+	if offboarding.Notes != "" {
+		doc.Heading("Notes")
+		doc.Text(offboarding.Notes)
+	}
+
+	doc.Gap()
+	doc.Gap()
+	doc.Field("Checked by", "")
+	doc.Field("Date", "")
+
+	return doc.Bytes()
+}
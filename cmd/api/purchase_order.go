@@ -0,0 +1,468 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/knr1997/assets-management-apiserver/internal/api/requests"
+	"github.com/knr1997/assets-management-apiserver/internal/api/responses"
+	"github.com/knr1997/assets-management-apiserver/internal/store"
+)
+
+type purchaseOrderKey string
+
+const (
+	purchaseOrderCtx purchaseOrderKey = "purchaseOrder"
+	invoiceCtx       purchaseOrderKey = "invoice"
+)
+
+var errPurchaseOrderReceived = errors.New("purchase order has received items")
+
+func getPurchaseOrderFromCtx(r *http.Request) *store.PurchaseOrder {
+	order, _ := r.Context().Value(purchaseOrderCtx).(*store.PurchaseOrder)
+	return order
+}
+
+func (app *application) purchaseOrderContextMiddleware(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		idParam := chi.URLParam(r, "purchaseOrderID")
+		id, err := strconv.ParseInt(idParam, 10, 64)
+		if err != nil {
+			app.internalServerError(w, r, err)
+			return
+		}
+
+		ctx := r.Context()
+
+		order, err := app.store.PurchaseOrder.GetByID(ctx, id)
+		if err != nil {
+			switch {
+			case errors.Is(err, store.ErrNotFound):
+				app.notFoundResponse(w, r, err)
+			default:
+				app.internalServerError(w, r, err)
+			}
+			return
+		}
+
+		ctx = context.WithValue(ctx, purchaseOrderCtx, order)
+		next.ServeHTTP(w, r.WithContext(ctx))
+	})
+}
+
+func getInvoiceFromCtx(r *http.Request) *store.Invoice {
+	invoice, _ := r.Context().Value(invoiceCtx).(*store.Invoice)
+	return invoice
+}
+
+// invoiceContextMiddleware looks the invoice up among those of the purchase
+// order in the context.
+func (app *application) invoiceContextMiddleware(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		order := getPurchaseOrderFromCtx(r)
+
+		idParam := chi.URLParam(r, "invoiceID")
+		id, err := strconv.ParseInt(idParam, 10, 64)
+		if err != nil {
+			app.badRequestResponse(w, r, err)
+			return
+		}
+
+		for i := range order.Invoices {
+			if order.Invoices[i].ID == id {
+				ctx := context.WithValue(r.Context(), invoiceCtx, &order.Invoices[i])
+				next.ServeHTTP(w, r.WithContext(ctx))
+				return
+			}
+		}
+
+		app.notFoundResponse(w, r, store.ErrNotFound)
+	})
+}
+
+func toPurchaseOrderLines(payload []requests.PurchaseOrderLinePayload) []store.PurchaseOrderLine {
+	lines := make([]store.PurchaseOrderLine, len(payload))
+
+	for i, l := range payload {
+		lines[i] = store.PurchaseOrderLine{
+			ModelID:     l.ModelID,
+			Description: l.Description,
+			Quantity:    l.Quantity,
+			UnitCost:    l.UnitCost,
+			Currency:    l.Currency,
+		}
+	}
+
+	return lines
+}
+
+func hasReceivedItems(order *store.PurchaseOrder) bool {
+	for _, line := range order.Lines {
+		if line.ReceivedQuantity > 0 {
+			return true
+		}
+	}
+	return false
+}
+
+func (app *application) createPurchaseOrderHandler(w http.ResponseWriter, r *http.Request) {
+	var payload requests.CreatePurchaseOrderPayload
+	if err := readJSON(w, r, &payload); err != nil {
+		app.badRequestResponse(w, r, err)
+		return
+	}
+
+	if err := Validate.Struct(payload); err != nil {
+		app.badRequestResponse(w, r, err)
+		return
+	}
+
+	order := &store.PurchaseOrder{
+		Number:     payload.Number,
+		SupplierID: payload.SupplierID,
+		Status:     store.PurchaseOrderDraft,
+		OrderDate:  payload.OrderDate,
+		Notes:      payload.Notes,
+		Lines:      toPurchaseOrderLines(payload.Lines),
+	}
+
+	ctx := r.Context()
+
+	if err := app.store.PurchaseOrder.Create(ctx, order); err != nil {
+		switch {
+		case errors.Is(err, store.ErrConflict):
+			app.conflictResponse(w, r, errors.New("purchase order number already in use"))
+		default:
+			app.internalServerError(w, r, err)
+		}
+		return
+	}
+
+	order, err := app.store.PurchaseOrder.GetByID(ctx, order.ID)
+	if err != nil {
+		app.internalServerError(w, r, err)
+		return
+	}
+
+	if err := app.jsonResponse(w, http.StatusCreated, responses.NewPurchaseOrderResponse(order)); err != nil {
+		app.internalServerError(w, r, err)
+		return
+	}
+}
+
+func (app *application) updatePurchaseOrderHandler(w http.ResponseWriter, r *http.Request) {
+	order := getPurchaseOrderFromCtx(r)
+
+	var payload requests.UpdatePurchaseOrderPayload
+	if err := readJSON(w, r, &payload); err != nil {
+		app.badRequestResponse(w, r, err)
+		return
+	}
+
+	if err := Validate.Struct(payload); err != nil {
+		app.badRequestResponse(w, r, err)
+		return
+	}
+
+	received := hasReceivedItems(order)
+
+	if payload.Number != nil {
+		order.Number = *payload.Number
+	}
+	if payload.SupplierID != nil {
+		order.SupplierID = *payload.SupplierID
+	}
+	if payload.OrderDate != nil {
+		order.OrderDate = payload.OrderDate
+	}
+	if payload.Notes != nil {
+		order.Notes = *payload.Notes
+	}
+
+	// receiving owns the status once items came in, and the lines are what
+	// the received assets were booked against
+	if payload.Status != nil {
+		if received {
+			app.conflictResponse(w, r, errPurchaseOrderReceived)
+			return
+		}
+
+		order.Status = store.PurchaseOrderStatus(*payload.Status)
+		if order.Status == store.PurchaseOrderOrdered && order.OrderDate == nil {
+			now := time.Now()
+			order.OrderDate = &now
+		}
+	}
+	if payload.Lines != nil {
+		if received {
+			app.conflictResponse(w, r, errPurchaseOrderReceived)
+			return
+		}
+		order.Lines = toPurchaseOrderLines(*payload.Lines)
+	}
+
+	ctx := r.Context()
+
+	if err := app.store.PurchaseOrder.Update(ctx, order, payload.Lines != nil); err != nil {
+		switch {
+		case errors.Is(err, store.ErrConflict):
+			app.conflictResponse(w, r, errors.New("purchase order number already in use"))
+		default:
+			app.internalServerError(w, r, err)
+		}
+		return
+	}
+
+	order, err := app.store.PurchaseOrder.GetByID(ctx, order.ID)
+	if err != nil {
+		app.internalServerError(w, r, err)
+		return
+	}
+
+	if err := app.jsonResponse(w, http.StatusOK, responses.NewPurchaseOrderResponse(order)); err != nil {
+		app.internalServerError(w, r, err)
+	}
+}
+
+func (app *application) getPurchaseOrderHandler(w http.ResponseWriter, r *http.Request) {
+	order := getPurchaseOrderFromCtx(r)
+
+	if err := app.jsonResponse(w, http.StatusOK, responses.NewPurchaseOrderResponse(order)); err != nil {
+		app.internalServerError(w, r, err)
+		return
+	}
+}
+
+func (app *application) getAllPurchaseOrderHandler(w http.ResponseWriter, r *http.Request) {
+	spec, ok := app.parseQuerySpec(w, r)
+	if !ok {
+		return
+	}
+
+	writeListPage(app, w, r, spec, app.store.PurchaseOrder.List, responses.NewPurchaseOrdersResponse)
+}
+
+func (app *application) deletePurchaseOrderHandler(w http.ResponseWriter, r *http.Request) {
+	order := getPurchaseOrderFromCtx(r)
+
+	if hasReceivedItems(order) {
+		app.conflictResponse(w, r, errPurchaseOrderReceived)
+		return
+	}
+
+	ctx := r.Context()
+
+	if err := app.store.PurchaseOrder.Delete(ctx, order.ID); err != nil {
+		switch {
+		case errors.Is(err, store.ErrNotFound):
+			app.notFoundResponse(w, r, err)
+		default:
+			app.internalServerError(w, r, err)
+		}
+		return
+	}
+
+	for _, invoice := range order.Invoices {
+		app.deleteAttachments(ctx, store.AttachmentOwnerInvoice, invoice.ID)
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// receivePurchaseOrderHandler creates the delivered assets in bulk, each one
+// carrying the unit cost of its line and the date it was received.
+func (app *application) receivePurchaseOrderHandler(w http.ResponseWriter, r *http.Request) {
+	order := getPurchaseOrderFromCtx(r)
+
+	var payload requests.ReceivePurchaseOrderPayload
+	if err := readJSON(w, r, &payload); err != nil {
+		app.badRequestResponse(w, r, err)
+		return
+	}
+
+	if err := Validate.Struct(payload); err != nil {
+		app.badRequestResponse(w, r, err)
+		return
+	}
+
+	if order.Status != store.PurchaseOrderOrdered && order.Status != store.PurchaseOrderPartiallyReceived {
+		app.conflictResponse(w, r, errors.New("only ordered purchase orders can be received"))
+		return
+	}
+
+	if payload.InvoiceID != nil && !hasInvoice(order, *payload.InvoiceID) {
+		app.badRequestResponse(w, r, errors.New("invoice does not belong to this purchase order"))
+		return
+	}
+
+	lines := make(map[int64]*store.PurchaseOrderLine, len(order.Lines))
+	for i := range order.Lines {
+		lines[order.Lines[i].ID] = &order.Lines[i]
+	}
+
+	ctx := r.Context()
+
+	receipt := make([]store.ReceiptLine, len(payload.Lines))
+	var assets []*store.Asset
+
+	for i, l := range payload.Lines {
+		line, ok := lines[l.LineID]
+		if !ok {
+			app.badRequestResponse(w, r, errors.New("line does not belong to this purchase order"))
+			return
+		}
+
+		receipt[i].LineID = line.ID
+
+		for _, a := range l.Assets {
+			name := a.Name
+			if name == "" {
+				name = line.Model.Name
+			}
+
+			asset := &store.Asset{
+				Name:                name,
+				Tag:                 a.Tag,
+				SerialNumber:        a.SerialNumber,
+				Description:         line.Description,
+				ModelID:             line.ModelID,
+				Status:              store.AssetAvailable,
+				PurchaseDate:        payload.ReceivedDate,
+				PurchaseCost:        line.UnitCost,
+				PurchaseCurrency:    line.Currency,
+				Location:            a.Location,
+				PurchaseOrderID:     &order.ID,
+				PurchaseOrderLineID: &line.ID,
+				InvoiceID:           payload.InvoiceID,
+			}
+			asset.InheritModelDefaults(&line.Model)
+
+			if err := app.resolveCustomFields(ctx, asset, a.CustomFields); err != nil {
+				app.customFieldErrorResponse(w, r, err)
+				return
+			}
+
+			receipt[i].Assets = append(receipt[i].Assets, asset)
+			assets = append(assets, asset)
+		}
+	}
+
+	if err := app.store.PurchaseOrder.Receive(ctx, order, receipt); err != nil {
+		switch {
+		case errors.Is(err, store.ErrInvalidReceipt):
+			app.badRequestResponse(w, r, err)
+		case errors.Is(err, store.ErrConflict):
+			app.conflictResponse(w, r, errors.New("an asset with that tag or serial number already exists"))
+		default:
+			app.internalServerError(w, r, err)
+		}
+		return
+	}
+
+	order, err := app.store.PurchaseOrder.GetByID(ctx, order.ID)
+	if err != nil {
+		app.internalServerError(w, r, err)
+		return
+	}
+
+	response := responses.ReceiptResponse{
+		PurchaseOrder: responses.NewPurchaseOrderResponse(order),
+		Assets:        make([]responses.AssetResponse, len(assets)),
+	}
+	for i, asset := range assets {
+		asset.Model = lines[*asset.PurchaseOrderLineID].Model
+		response.Assets[i] = responses.NewAssetResponse(asset)
+	}
+
+	if err := app.jsonResponse(w, http.StatusCreated, response); err != nil {
+		app.internalServerError(w, r, err)
+	}
+}
+
+func hasInvoice(order *store.PurchaseOrder, invoiceID int64) bool {
+	for _, invoice := range order.Invoices {
+		if invoice.ID == invoiceID {
+			return true
+		}
+	}
+	return false
+}
+
+func (app *application) createInvoiceHandler(w http.ResponseWriter, r *http.Request) {
+	order := getPurchaseOrderFromCtx(r)
+
+	var payload requests.CreateInvoicePayload
+	if err := readJSON(w, r, &payload); err != nil {
+		app.badRequestResponse(w, r, err)
+		return
+	}
+
+	if err := Validate.Struct(payload); err != nil {
+		app.badRequestResponse(w, r, err)
+		return
+	}
+
+	invoice := &store.Invoice{
+		PurchaseOrderID: order.ID,
+		Number:          payload.Number,
+		InvoiceDate:     payload.InvoiceDate,
+		Amount:          payload.Amount,
+		Currency:        payload.Currency,
+		DocumentURL:     payload.DocumentURL,
+		Notes:           payload.Notes,
+	}
+
+	if err := app.store.PurchaseOrder.CreateInvoice(r.Context(), invoice); err != nil {
+		app.internalServerError(w, r, err)
+		return
+	}
+
+	if err := app.jsonResponse(w, http.StatusCreated, responses.NewInvoiceResponse(invoice)); err != nil {
+		app.internalServerError(w, r, err)
+	}
+}
+
+func (app *application) deleteInvoiceHandler(w http.ResponseWriter, r *http.Request) {
+	order := getPurchaseOrderFromCtx(r)
+	invoice := getInvoiceFromCtx(r)
+
+	ctx := r.Context()
+
+	if err := app.store.PurchaseOrder.DeleteInvoice(ctx, order.ID, invoice.ID); err != nil {
+		switch {
+		case errors.Is(err, store.ErrNotFound):
+			app.notFoundResponse(w, r, err)
+		default:
+			app.internalServerError(w, r, err)
+		}
+		return
+	}
+
+	app.deleteAttachments(ctx, store.AttachmentOwnerInvoice, invoice.ID)
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// getAssetProcurementHandler traces an asset back to its purchase order and
+// invoice.
+func (app *application) getAssetProcurementHandler(w http.ResponseWriter, r *http.Request) {
+	asset := getAssetFromCtx(r)
+
+	var order *store.PurchaseOrder
+	if asset.PurchaseOrderID != nil {
+		var err error
+		order, err = app.store.PurchaseOrder.GetByID(r.Context(), *asset.PurchaseOrderID)
+		if err != nil && !errors.Is(err, store.ErrNotFound) {
+			app.internalServerError(w, r, err)
+			return
+		}
+	}
+
+	if err := app.jsonResponse(w, http.StatusOK, responses.NewProcurementResponse(asset, order)); err != nil {
+		app.internalServerError(w, r, err)
+	}
+}
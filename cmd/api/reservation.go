@@ -0,0 +1,301 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/knr1997/assets-management-apiserver/internal/api/requests"
+	"github.com/knr1997/assets-management-apiserver/internal/api/responses"
+	"github.com/knr1997/assets-management-apiserver/internal/store"
+)
+
+type reservationKey string
+
+const reservationCtx reservationKey = "reservation"
+
+const (
+	defaultAvailabilityDays = 14
+	maxAvailabilityDays     = 92
+)
+
+func getReservationFromCtx(r *http.Request) *store.Reservation {
+	reservation, _ := r.Context().Value(reservationCtx).(*store.Reservation)
+	return reservation
+}
+
+func (app *application) reservationContextMiddleware(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		idParam := chi.URLParam(r, "reservationID")
+		id, err := strconv.ParseInt(idParam, 10, 64)
+		if err != nil {
+			app.internalServerError(w, r, err)
+			return
+		}
+
+		ctx := r.Context()
+
+		reservation, err := app.store.Reservation.GetByID(ctx, id)
+		if err != nil {
+			switch {
+			case errors.Is(err, store.ErrNotFound):
+				app.notFoundResponse(w, r, err)
+			default:
+				app.internalServerError(w, r, err)
+			}
+			return
+		}
+
+		ctx = context.WithValue(ctx, reservationCtx, reservation)
+		next.ServeHTTP(w, r.WithContext(ctx))
+	})
+}
+
+func (app *application) getAllReservationHandler(w http.ResponseWriter, r *http.Request) {
+	spec, ok := app.parseQuerySpec(w, r)
+	if !ok {
+		return
+	}
+
+	writeListPage(app, w, r, spec, app.store.Reservation.List, responses.NewReservationsResponse)
+}
+
+func (app *application) createReservationHandler(w http.ResponseWriter, r *http.Request) {
+	var payload requests.CreateReservationPayload
+	if err := readJSON(w, r, &payload); err != nil {
+		app.badRequestResponse(w, r, err)
+		return
+	}
+
+	if err := Validate.Struct(payload); err != nil {
+		app.badRequestResponse(w, r, err)
+		return
+	}
+
+	if !payload.EndsAt.After(time.Now()) {
+		app.badRequestResponse(w, r, errors.New("endsAt must be in the future"))
+		return
+	}
+
+	ctx := r.Context()
+
+	user, err := app.store.Users.GetByID(ctx, payload.UserID)
+	if err != nil {
+		switch {
+		case errors.Is(err, store.ErrNotFound):
+			app.badRequestResponse(w, r, fmt.Errorf("user %d does not exist", payload.UserID))
+		default:
+			app.internalServerError(w, r, err)
+		}
+		return
+	}
+	if !user.IsActive {
+		app.badRequestResponse(w, r, fmt.Errorf("user %d is not active", user.ID))
+		return
+	}
+
+	reservation := &store.Reservation{
+		UserID:   user.ID,
+		StartsAt: payload.StartsAt,
+		EndsAt:   payload.EndsAt,
+		Notes:    payload.Notes,
+	}
+	if performer := getUserFromContext(r); performer != nil {
+		reservation.ReservedByID = &performer.ID
+	}
+
+	switch {
+	case payload.AssetID != nil:
+		if _, err := app.store.Asset.GetByID(ctx, *payload.AssetID); err != nil {
+			switch {
+			case errors.Is(err, store.ErrNotFound):
+				app.badRequestResponse(w, r, fmt.Errorf("asset %d does not exist", *payload.AssetID))
+			default:
+				app.internalServerError(w, r, err)
+			}
+			return
+		}
+		reservation.AssetID = *payload.AssetID
+	default:
+		if _, err := app.store.Model.GetByID(ctx, *payload.ModelID); err != nil {
+			switch {
+			case errors.Is(err, store.ErrNotFound):
+				app.badRequestResponse(w, r, fmt.Errorf("model %d does not exist", *payload.ModelID))
+			default:
+				app.internalServerError(w, r, err)
+			}
+			return
+		}
+		reservation.ModelID = *payload.ModelID
+	}
+
+	if err := app.store.Reservation.Create(ctx, reservation); err != nil {
+		switch {
+		case errors.Is(err, store.ErrAssetNotAvailable):
+			app.conflictResponse(w, r, errors.New("no asset is free for the whole window"))
+		case errors.Is(err, store.ErrConflict):
+			app.conflictResponse(w, r, errors.New("the asset was booked for an overlapping window meanwhile, try again"))
+		default:
+			app.internalServerError(w, r, err)
+		}
+		return
+	}
+
+	reservation, err = app.store.Reservation.GetByID(ctx, reservation.ID)
+	if err != nil {
+		app.internalServerError(w, r, err)
+		return
+	}
+
+	if err := app.jsonResponse(w, http.StatusCreated, responses.NewReservationResponse(reservation)); err != nil {
+		app.internalServerError(w, r, err)
+		return
+	}
+}
+
+func (app *application) getReservationHandler(w http.ResponseWriter, r *http.Request) {
+	reservation := getReservationFromCtx(r)
+
+	if err := app.jsonResponse(w, http.StatusOK, responses.NewReservationResponse(reservation)); err != nil {
+		app.internalServerError(w, r, err)
+		return
+	}
+}
+
+func (app *application) cancelReservationHandler(w http.ResponseWriter, r *http.Request) {
+	reservation := getReservationFromCtx(r)
+
+	ctx := r.Context()
+
+	if err := app.store.Reservation.Cancel(ctx, reservation.ID); err != nil {
+		switch {
+		case errors.Is(err, store.ErrReservationNotBooked):
+			app.conflictResponse(w, r, fmt.Errorf("reservation is %s", reservation.Status))
+		default:
+			app.internalServerError(w, r, err)
+		}
+		return
+	}
+
+	reservation, err := app.store.Reservation.GetByID(ctx, reservation.ID)
+	if err != nil {
+		app.internalServerError(w, r, err)
+		return
+	}
+
+	if err := app.jsonResponse(w, http.StatusOK, responses.NewReservationResponse(reservation)); err != nil {
+		app.internalServerError(w, r, err)
+		return
+	}
+}
+
+// pickupReservationHandler checks the reserved asset out to the user until
+// the reservation ends.
+func (app *application) pickupReservationHandler(w http.ResponseWriter, r *http.Request) {
+	reservation := getReservationFromCtx(r)
+
+	ctx := r.Context()
+
+	if err := app.store.Reservation.Pickup(ctx, reservation.ID); err != nil {
+		switch {
+		case errors.Is(err, store.ErrNotFound):
+			app.notFoundResponse(w, r, err)
+		case errors.Is(err, store.ErrReservationNotBooked):
+			app.conflictResponse(w, r, fmt.Errorf("reservation is %s", reservation.Status))
+		case errors.Is(err, store.ErrReservationNotDue):
+			app.conflictResponse(w, r, fmt.Errorf("reservation can be picked up from %s", reservation.StartsAt.Add(-store.ReservationPickupGrace).Format(time.RFC3339)))
+		case errors.Is(err, store.ErrAssetNotAvailable):
+			app.conflictResponse(w, r, fmt.Errorf("%s has not been returned yet", reservation.Asset.Name))
+		case errors.Is(err, store.ErrAssetReserved):
+			app.conflictResponse(w, r, fmt.Errorf("%s is still booked by another user, pick it up from %s", reservation.Asset.Name, reservation.StartsAt.Format(time.RFC3339)))
+		default:
+			app.internalServerError(w, r, err)
+		}
+		return
+	}
+
+	reservation, err := app.store.Reservation.GetByID(ctx, reservation.ID)
+	if err != nil {
+		app.internalServerError(w, r, err)
+		return
+	}
+
+	if err := app.jsonResponse(w, http.StatusOK, responses.NewReservationResponse(reservation)); err != nil {
+		app.internalServerError(w, r, err)
+		return
+	}
+}
+
+// getModelAvailabilityHandler returns the bookings and loans of the model's
+// assets between from and to (dates, both inclusive), two weeks from today
+// by default.
+func (app *application) getModelAvailabilityHandler(w http.ResponseWriter, r *http.Request) {
+	model := getModelFromCtx(r)
+	query := r.URL.Query()
+
+	now := time.Now().UTC()
+	from := time.Date(now.Year(), now.Month(), now.Day(), 0, 0, 0, 0, time.UTC)
+	to := from.AddDate(0, 0, defaultAvailabilityDays-1)
+
+	for name, day := range map[string]*time.Time{"from": &from, "to": &to} {
+		param := query.Get(name)
+		if param == "" {
+			continue
+		}
+
+		t, err := time.Parse(time.DateOnly, param)
+		if err != nil {
+			app.badRequestResponse(w, r, fmt.Errorf("%s must be a date like 2006-01-02", name))
+			return
+		}
+		*day = t
+	}
+
+	if to.Before(from) {
+		app.badRequestResponse(w, r, errors.New("to must not be before from"))
+		return
+	}
+	if to.Sub(from) >= maxAvailabilityDays*24*time.Hour {
+		app.badRequestResponse(w, r, fmt.Errorf("at most %d days can be requested", maxAvailabilityDays))
+		return
+	}
+
+	availability, err := app.store.Reservation.Availability(r.Context(), model.ID, from, to.AddDate(0, 0, 1))
+	if err != nil {
+		app.internalServerError(w, r, err)
+		return
+	}
+
+	if err := app.jsonResponse(w, http.StatusOK, responses.NewModelAvailabilityResponse(model, availability)); err != nil {
+		app.internalServerError(w, r, err)
+		return
+	}
+}
+
+// expireReservations expires the reservations nobody picked up and tells
+// the users they were for.
+func (app *application) expireReservations(ctx context.Context) error {
+	reservations, err := app.store.Reservation.ExpireNoShows(ctx)
+	if err != nil || len(reservations) == 0 {
+		return err
+	}
+
+	notifications := make([]store.Notification, len(reservations))
+	for i, reservation := range reservations {
+		app.logger.Infow("reservation expired", "reservation", reservation.ID, "asset", reservation.AssetID, "user", reservation.UserID)
+
+		notifications[i] = store.Notification{
+			UserID:  reservation.UserID,
+			Kind:    "reservation_expired",
+			Title:   "Reservation expired",
+			Body:    fmt.Sprintf("Your reservation starting %s was not picked up and has expired.", reservation.StartsAt.Format(time.DateTime)),
+			AssetID: &reservation.AssetID,
+		}
+	}
+
+	return app.store.Notification.Create(ctx, notifications)
+}
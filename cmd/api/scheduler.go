@@ -0,0 +1,79 @@
+package main
+
+import (
+	"context"
+	"sync"
+	"time"
+)
+
+// job is background work the server repeats on an interval for as long as it
+// runs.
+type job struct {
+	name     string
+	interval time.Duration
+	run      func(context.Context) error
+}
+
+func (app *application) jobs() []job {
+	return []job{
+		{"maintenance-work-orders", app.config.scheduler.interval, app.generateWorkOrders},
+		{"warranty-expiry-alerts", app.config.scheduler.interval, app.alertExpiringWarranties},
+		{"consumable-low-stock-alerts", app.config.scheduler.interval, app.alertLowStock},
+		{"license-expiry-alerts", app.config.scheduler.interval, app.alertExpiringLicenses},
+		{"license-seat-reclaim", app.config.scheduler.interval, app.reclaimLicenseSeats},
+		{"reservation-no-shows", app.config.scheduler.interval, app.expireReservations},
+		{"event-dispatch", app.config.events.interval, app.dispatchEvents},
+		{"event-pruning", app.config.scheduler.interval, app.pruneEvents},
+		{"webhook-deliveries", app.config.webhooks.interval, app.deliverWebhooks},
+		{"event-stream-listener", eventListenRetry, app.listenEvents},
+	}
+}
+
+// startJobs runs every job once right away and then on its interval until
+// ctx is cancelled. Jobs without an interval are disabled. The returned func
+// waits for running jobs to finish.
+func (app *application) startJobs(ctx context.Context, jobs []job) (wait func()) {
+	var wg sync.WaitGroup
+
+	for _, j := range jobs {
+		if j.interval <= 0 {
+			app.logger.Infow("job disabled", "job", j.name)
+			continue
+		}
+
+		wg.Add(1)
+
+		go func() {
+			defer wg.Done()
+
+			ticker := time.NewTicker(j.interval)
+			defer ticker.Stop()
+
+			for {
+				app.runJob(ctx, j)
+
+				select {
+				case <-ctx.Done():
+					return
+				case <-ticker.C:
+				}
+			}
+		}()
+	}
+
+	return wg.Wait
+}
+
+func (app *application) runJob(ctx context.Context, j job) {
+	defer func() {
+		if err := recover(); err != nil {
+			app.logger.Errorw("job panicked", "job", j.name, "error", err)
+		}
+	}()
+
+	start := time.Now()
+
+	if err := j.run(ctx); err != nil && ctx.Err() == nil {
+		app.logger.Errorw("job failed", "job", j.name, "duration", time.Since(start).String(), "error", err.Error())
+	}
+}
@@ -0,0 +1,62 @@
+package main
+
+import (
+	"errors"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/knr1997/assets-management-apiserver/internal/api/responses"
+)
+
+const (
+	defaultSearchLimit = 5
+	maxSearchLimit     = 25
+)
+
+// searchHandler godoc
+//
+//	@Summary		Searches assets, models, manufacturers and users
+//	@Description	Ranks full text and fuzzy matches for q, grouped by resource type. The highlight field is HTML escaped, with matches wrapped in <mark> tags.
+//	@Tags			search
+//	@Produce		json
+//	@Param			q		query		string	true	"Search terms"
+//	@Param			limit	query		int		false	"Hits per resource type"
+//	@Success		200		{object}	responses.SearchResponse
+//	@Failure		400		{object}	error
+//	@Failure		500		{object}	error
+//	@Security		ApiKeyAuth
+//	@Router			/search [get]
+func (app *application) searchHandler(w http.ResponseWriter, r *http.Request) {
+	q := strings.TrimSpace(r.URL.Query().Get("q"))
+	if q == "" {
+		app.badRequestResponse(w, r, errors.New("q is required"))
+		return
+	}
+	if len(q) > 200 {
+		app.badRequestResponse(w, r, errors.New("q must be at most 200 characters"))
+		return
+	}
+
+	limit := defaultSearchLimit
+	if l := r.URL.Query().Get("limit"); l != "" {
+		v, err := strconv.Atoi(l)
+		if err != nil || v <= 0 {
+			app.badRequestResponse(w, r, errors.New("limit must be a positive integer"))
+			return
+		}
+		limit = min(v, maxSearchLimit)
+	}
+
+	ctx := r.Context()
+
+	results, err := app.store.Search.Search(ctx, q, limit)
+	if err != nil {
+		app.internalServerError(w, r, err)
+		return
+	}
+
+	if err := app.jsonResponse(w, http.StatusOK, responses.NewSearchResponse(q, results)); err != nil {
+		app.internalServerError(w, r, err)
+	}
+}
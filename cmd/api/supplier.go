@@ -2,12 +2,13 @@ package main
 
 import (
 	"context"
-	"encoding/json"
 	"errors"
 	"net/http"
 	"strconv"
 
 	"github.com/go-chi/chi/v5"
+	"github.com/knr1997/assets-management-apiserver/internal/api/requests"
+	"github.com/knr1997/assets-management-apiserver/internal/api/responses"
 	"github.com/knr1997/assets-management-apiserver/internal/store"
 )
 
@@ -47,13 +48,8 @@ func (app *application) supplierContextMiddleware(next http.Handler) http.Handle
 	})
 }
 
-type CreatesupplierPayload struct {
-	Name string `json:"name" validate:"required,max=100"`
-	// Notes string `json:"description"`
-}
-
 func (app *application) createSupplierHandler(w http.ResponseWriter, r *http.Request) {
-	var payload CreatesupplierPayload
+	var payload requests.CreateSupplierPayload
 	if err := readJSON(w, r, &payload); err != nil {
 		app.badRequestResponse(w, r, err)
 		return
@@ -64,28 +60,29 @@ func (app *application) createSupplierHandler(w http.ResponseWriter, r *http.Req
 		return
 	}
 
-	post := &store.Supplier{
+	supplier := &store.Supplier{
 		Name: payload.Name,
 	}
+	applyVendorDetails(&supplier.VendorDetails, payload.VendorDetailsPayload)
 
 	ctx := r.Context()
 
-	if err := app.store.Supplier.Create(ctx, post); err != nil {
-		app.internalServerError(w, r, err)
+	if err := app.store.Supplier.Create(ctx, supplier); err != nil {
+		switch {
+		case errors.Is(err, store.ErrConflict):
+			app.conflictResponse(w, r, err)
+		default:
+			app.internalServerError(w, r, err)
+		}
 		return
 	}
 
-	if err := app.jsonResponse(w, http.StatusCreated, post); err != nil {
+	if err := app.jsonResponse(w, http.StatusCreated, responses.NewSupplierResponse(supplier)); err != nil {
 		app.internalServerError(w, r, err)
 		return
 	}
 }
 
-type UpdatesupplierPayload struct {
-	Name  *string `json:"name" validate:"omitempty,max=100"`
-	Notes *string `json:"notes"`
-}
-
 func (app *application) updateSupplier(ctx context.Context, supplier *store.Supplier) error {
 	if err := app.store.Supplier.Update(ctx, supplier); err != nil {
 		return err
@@ -97,7 +94,7 @@ func (app *application) updateSupplier(ctx context.Context, supplier *store.Supp
 func (app *application) updateSupplierHandler(w http.ResponseWriter, r *http.Request) {
 	supplier := getsupplierFromCtx(r)
 
-	var payload UpdatesupplierPayload
+	var payload requests.UpdateSupplierPayload
 	if err := readJSON(w, r, &payload); err != nil {
 		app.badRequestResponse(w, r, err)
 		return
@@ -111,65 +108,51 @@ func (app *application) updateSupplierHandler(w http.ResponseWriter, r *http.Req
 	if payload.Name != nil {
 		supplier.Name = *payload.Name
 	}
+	applyVendorDetails(&supplier.VendorDetails, payload.VendorDetailsPayload)
 
 	ctx := r.Context()
 
 	if err := app.updateSupplier(ctx, supplier); err != nil {
-		app.internalServerError(w, r, err)
+		switch {
+		case errors.Is(err, store.ErrConflict):
+			app.conflictResponse(w, r, err)
+		default:
+			app.internalServerError(w, r, err)
+		}
+		return
 	}
 
-	if err := app.jsonResponse(w, http.StatusOK, supplier); err != nil {
+	if err := app.jsonResponse(w, http.StatusOK, responses.NewSupplierResponse(supplier)); err != nil {
 		app.internalServerError(w, r, err)
 	}
 }
 
+// getSupplierHandler returns the supplier with its contacts, the assets
+// bought from it and what was spent on orders, repairs and contracts.
 func (app *application) getSupplierHandler(w http.ResponseWriter, r *http.Request) {
 	supplier := getsupplierFromCtx(r)
 
-	response := TosupplierResponse(supplier)
-
-	if err := app.jsonResponse(w, http.StatusOK, response); err != nil {
+	stats, err := app.store.Supplier.Stats(r.Context(), supplier.ID)
+	if err != nil {
 		app.internalServerError(w, r, err)
 		return
 	}
-}
 
-type supplierResponse struct {
-	ID    int64  `json:"id"`
-	Name  string `json:"name"`
-	Notes string `json:"notes"`
-}
+	response := responses.NewSupplierDetailResponse(supplier, stats)
 
-func TosupplierResponse(a *store.Supplier) supplierResponse {
-	return supplierResponse{
-		ID:   a.ID,
-		Name: a.Name,
-	}
-}
-
-func TosupplierResponseList(categories []store.Supplier) []supplierResponse {
-	responses := make([]supplierResponse, 0, len(categories))
-
-	for i := range categories {
-		responses = append(responses, TosupplierResponse(&categories[i]))
+	if err := app.jsonResponse(w, http.StatusOK, response); err != nil {
+		app.internalServerError(w, r, err)
+		return
 	}
-
-	return responses
 }
 
 func (app *application) getAllSupplierHandler(w http.ResponseWriter, r *http.Request) {
-	ctx := r.Context()
-
-	categories, err := app.store.Supplier.GetAll(ctx)
-	if err != nil {
-		app.internalServerError(w, r, err)
+	spec, ok := app.parseQuerySpec(w, r)
+	if !ok {
 		return
 	}
 
-	response := TosupplierResponseList(categories)
-
-	w.Header().Set("Content-Type", "application/json")
-	json.NewEncoder(w).Encode(response)
+	writeListPage(app, w, r, spec, app.store.Supplier.List, responses.NewSuppliersResponse)
 }
 
 func (app *application) deleteSupplierHandler(w http.ResponseWriter, r *http.Request) {
@@ -192,5 +175,7 @@ func (app *application) deleteSupplierHandler(w http.ResponseWriter, r *http.Req
 		return
 	}
 
+	app.deleteAttachments(ctx, store.AttachmentOwnerSupplier, id)
+
 	w.WriteHeader(http.StatusNoContent)
 }
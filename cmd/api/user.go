@@ -2,7 +2,6 @@ package main
 
 import (
 	"context"
-	"encoding/json"
 	"errors"
 	"net/http"
 	"strconv"
@@ -21,11 +20,20 @@ func getUserFromContext(r *http.Request) *store.User {
 	return user
 }
 
+// userAdminRole may move users between departments
+const userAdminRole = "admin"
+
 type UpdateUserPayload struct {
 	Username *string `json:"username"`
 	Email    *string `json:"email"`
 }
 
+// SetUserDepartmentPayload moves a user to a department, a null
+// DepartmentID takes them out of theirs.
+type SetUserDepartmentPayload struct {
+	DepartmentID *int64 `json:"departmentId"`
+}
+
 func (app *application) updateUserHandler(w http.ResponseWriter, r *http.Request) {
 	user := getUserFromContext(r)
 
@@ -51,6 +59,58 @@ func (app *application) updateUserHandler(w http.ResponseWriter, r *http.Request
 
 	if err := app.updateUser(ctx, user); err != nil {
 		app.internalServerError(w, r, err)
+		return
+	}
+
+	if err := app.jsonResponse(w, http.StatusOK, user); err != nil {
+		app.internalServerError(w, r, err)
+	}
+}
+
+// setUserDepartmentHandler assigns the user in the URL to a department. It
+// sits outside the user subroute, whose middleware replaces the signed in
+// user the role is checked on.
+func (app *application) setUserDepartmentHandler(w http.ResponseWriter, r *http.Request) {
+	userID, err := strconv.ParseInt(chi.URLParam(r, "userID"), 10, 64)
+	if err != nil {
+		app.internalServerError(w, r, err)
+		return
+	}
+
+	var payload SetUserDepartmentPayload
+	if err := readJSON(w, r, &payload); err != nil {
+		app.badRequestResponse(w, r, err)
+		return
+	}
+
+	ctx := r.Context()
+
+	if payload.DepartmentID != nil {
+		if _, err := app.store.Department.GetByID(ctx, *payload.DepartmentID); err != nil {
+			switch {
+			case errors.Is(err, store.ErrNotFound):
+				app.badRequestResponse(w, r, errors.New("department not found"))
+			default:
+				app.internalServerError(w, r, err)
+			}
+			return
+		}
+	}
+
+	if err := app.store.Users.SetDepartment(ctx, userID, payload.DepartmentID); err != nil {
+		switch {
+		case errors.Is(err, store.ErrNotFound):
+			app.notFoundResponse(w, r, err)
+		default:
+			app.internalServerError(w, r, err)
+		}
+		return
+	}
+
+	user, err := app.store.Users.GetByID(ctx, userID)
+	if err != nil {
+		app.internalServerError(w, r, err)
+		return
 	}
 
 	if err := app.jsonResponse(w, http.StatusOK, user); err != nil {
@@ -74,20 +134,63 @@ func (app *application) meDetailsHandler(w http.ResponseWriter, r *http.Request)
 	app.jsonResponse(w, http.StatusOK, response)
 }
 
-func (app *application) getAllUserHandler(w http.ResponseWriter, r *http.Request) {
+// getHeldItemsHandler lists the assets, accessories and license seats checked
+// out to the user in context.
+func (app *application) getHeldItemsHandler(w http.ResponseWriter, r *http.Request) {
+	user := getUserFromContext(r)
 	ctx := r.Context()
 
-	users, err := app.store.Users.GetAll(ctx)
+	assets, err := app.store.AssetLoan.HeldBy(ctx, user.ID)
+	if err != nil {
+		app.internalServerError(w, r, err)
+		return
+	}
+
+	accessories, err := app.store.Accessory.HeldBy(ctx, user.ID)
+	if err != nil {
+		app.internalServerError(w, r, err)
+		return
+	}
+
+	licenses, err := app.store.License.HeldBy(ctx, user.ID)
 	if err != nil {
 		app.internalServerError(w, r, err)
 		return
 	}
 
-	response := responses.NewUsersResponse(users)
+	response := responses.NewHeldItemsResponse(assets, accessories, licenses)
+
+	if err := app.jsonResponse(w, http.StatusOK, response); err != nil {
+		app.internalServerError(w, r, err)
+	}
+}
+
+var userExportColumns = []exportColumn[store.User]{
+	{"id", func(u *store.User) any { return u.ID }},
+	{"username", func(u *store.User) any { return u.Username }},
+	{"email", func(u *store.User) any { return u.Email }},
+	{"isActive", func(u *store.User) any { return u.IsActive }},
+	{"roleId", func(u *store.User) any { return u.RoleID }},
+	{"createdAt", func(u *store.User) any { return u.CreatedAt }},
+}
+
+func (app *application) getAllUserHandler(w http.ResponseWriter, r *http.Request) {
+	spec, ok := app.parseQuerySpec(w, r)
+	if !ok {
+		return
+	}
+
+	format, ok, err := requestedExportFormat(r)
+	if err != nil {
+		app.badRequestResponse(w, r, err)
+		return
+	}
+	if ok {
+		streamExport(app, w, r, "users", format, spec, userExportColumns, app.store.Users.Stream)
+		return
+	}
 
-	// app.jsonResponse(w, http.StatusOK, resp)
-	w.Header().Set("Content-Type", "application/json")
-	json.NewEncoder(w).Encode(response)
+	writeListPage(app, w, r, spec, app.store.Users.List, responses.NewUsersResponse)
 }
 
 func (app *application) userContextMiddleware(next http.Handler) http.Handler {
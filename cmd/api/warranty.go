@@ -0,0 +1,284 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"slices"
+	"strconv"
+	"time"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/knr1997/assets-management-apiserver/internal/api/requests"
+	"github.com/knr1997/assets-management-apiserver/internal/api/responses"
+	"github.com/knr1997/assets-management-apiserver/internal/store"
+)
+
+type warrantyKey string
+
+const warrantyCtx warrantyKey = "warranty"
+
+const (
+	defaultWarrantyExpiringDays = 90
+	maxWarrantyExpiringDays     = 3650
+
+	// role whose members are told about expiring coverage
+	warrantyAlertRole = "admin"
+)
+
+func getWarrantyFromCtx(r *http.Request) *store.Warranty {
+	warranty, _ := r.Context().Value(warrantyCtx).(*store.Warranty)
+	return warranty
+}
+
+func (app *application) warrantyContextMiddleware(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		idParam := chi.URLParam(r, "warrantyID")
+		id, err := strconv.ParseInt(idParam, 10, 64)
+		if err != nil {
+			app.internalServerError(w, r, err)
+			return
+		}
+
+		ctx := r.Context()
+
+		warranty, err := app.store.Warranty.GetByID(ctx, id)
+		if err != nil {
+			switch {
+			case errors.Is(err, store.ErrNotFound):
+				app.notFoundResponse(w, r, err)
+			default:
+				app.internalServerError(w, r, err)
+			}
+			return
+		}
+
+		ctx = context.WithValue(ctx, warrantyCtx, warranty)
+		next.ServeHTTP(w, r.WithContext(ctx))
+	})
+}
+
+func (app *application) createWarrantyHandler(w http.ResponseWriter, r *http.Request) {
+	var payload requests.CreateWarrantyPayload
+	if err := readJSON(w, r, &payload); err != nil {
+		app.badRequestResponse(w, r, err)
+		return
+	}
+
+	if err := Validate.Struct(payload); err != nil {
+		app.badRequestResponse(w, r, err)
+		return
+	}
+
+	warranty := &store.Warranty{
+		Kind:           store.WarrantyManufacturer,
+		AssetID:        payload.AssetID,
+		SupplierID:     payload.SupplierID,
+		ManufacturerID: payload.ManufacturerID,
+		ContractNumber: payload.ContractNumber,
+		StartDate:      payload.StartDate,
+		EndDate:        payload.EndDate,
+		SLATerms:       payload.SLATerms,
+		Cost:           payload.Cost,
+		Notes:          payload.Notes,
+	}
+
+	if payload.Kind != "" {
+		warranty.Kind = store.WarrantyKind(payload.Kind)
+	}
+
+	ctx := r.Context()
+
+	if err := app.store.Warranty.Create(ctx, warranty); err != nil {
+		app.internalServerError(w, r, err)
+		return
+	}
+
+	warranty, err := app.store.Warranty.GetByID(ctx, warranty.ID)
+	if err != nil {
+		app.internalServerError(w, r, err)
+		return
+	}
+
+	if err := app.jsonResponse(w, http.StatusCreated, responses.NewWarrantyResponse(warranty)); err != nil {
+		app.internalServerError(w, r, err)
+		return
+	}
+}
+
+func (app *application) updateWarrantyHandler(w http.ResponseWriter, r *http.Request) {
+	warranty := getWarrantyFromCtx(r)
+
+	var payload requests.UpdateWarrantyPayload
+	if err := readJSON(w, r, &payload); err != nil {
+		app.badRequestResponse(w, r, err)
+		return
+	}
+
+	if err := Validate.Struct(payload); err != nil {
+		app.badRequestResponse(w, r, err)
+		return
+	}
+
+	if payload.Kind != nil {
+		warranty.Kind = store.WarrantyKind(*payload.Kind)
+	}
+	if payload.SupplierID != nil {
+		warranty.SupplierID = payload.SupplierID
+	}
+	if payload.ManufacturerID != nil {
+		warranty.ManufacturerID = payload.ManufacturerID
+	}
+	if payload.ContractNumber != nil {
+		warranty.ContractNumber = *payload.ContractNumber
+	}
+	if payload.StartDate != nil {
+		warranty.StartDate = *payload.StartDate
+	}
+	if payload.EndDate != nil && !payload.EndDate.Equal(warranty.EndDate) {
+		warranty.EndDate = *payload.EndDate
+		// a renewed contract gets its own expiry alert
+		warranty.ExpiryNotifiedAt = nil
+	}
+	if payload.SLATerms != nil {
+		warranty.SLATerms = *payload.SLATerms
+	}
+	if payload.Cost != nil {
+		warranty.Cost = *payload.Cost
+	}
+	if payload.Notes != nil {
+		warranty.Notes = *payload.Notes
+	}
+
+	if !warranty.EndDate.After(warranty.StartDate) {
+		app.badRequestResponse(w, r, errors.New("endDate must be after startDate"))
+		return
+	}
+
+	ctx := r.Context()
+
+	if err := app.store.Warranty.Update(ctx, warranty); err != nil {
+		app.internalServerError(w, r, err)
+		return
+	}
+
+	warranty, err := app.store.Warranty.GetByID(ctx, warranty.ID)
+	if err != nil {
+		app.internalServerError(w, r, err)
+		return
+	}
+
+	if err := app.jsonResponse(w, http.StatusOK, responses.NewWarrantyResponse(warranty)); err != nil {
+		app.internalServerError(w, r, err)
+	}
+}
+
+func (app *application) getWarrantyHandler(w http.ResponseWriter, r *http.Request) {
+	warranty := getWarrantyFromCtx(r)
+
+	if err := app.jsonResponse(w, http.StatusOK, responses.NewWarrantyResponse(warranty)); err != nil {
+		app.internalServerError(w, r, err)
+		return
+	}
+}
+
+func (app *application) getAllWarrantyHandler(w http.ResponseWriter, r *http.Request) {
+	spec, ok := app.parseQuerySpec(w, r)
+	if !ok {
+		return
+	}
+
+	writeListPage(app, w, r, spec, app.store.Warranty.List, responses.NewWarrantiesResponse)
+}
+
+func (app *application) deleteWarrantyHandler(w http.ResponseWriter, r *http.Request) {
+	warranty := getWarrantyFromCtx(r)
+
+	ctx := r.Context()
+
+	if err := app.store.Warranty.Delete(ctx, warranty.ID); err != nil {
+		switch {
+		case errors.Is(err, store.ErrNotFound):
+			app.notFoundResponse(w, r, err)
+		default:
+			app.internalServerError(w, r, err)
+		}
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// getWarrantyExpiringHandler lists the warranties and support contracts that
+// run out within ?days= (90 by default), soonest first.
+func (app *application) getWarrantyExpiringHandler(w http.ResponseWriter, r *http.Request) {
+	days := defaultWarrantyExpiringDays
+	if param := r.URL.Query().Get("days"); param != "" {
+		n, err := strconv.Atoi(param)
+		if err != nil || n < 1 || n > maxWarrantyExpiringDays {
+			app.badRequestResponse(w, r, fmt.Errorf("days must be between 1 and %d", maxWarrantyExpiringDays))
+			return
+		}
+		days = n
+	}
+
+	warranties, err := app.store.Warranty.GetExpiring(r.Context(), time.Now().AddDate(0, 0, days))
+	if err != nil {
+		app.internalServerError(w, r, err)
+		return
+	}
+
+	if err := app.jsonResponse(w, http.StatusOK, responses.NewWarrantiesResponse(warranties)); err != nil {
+		app.internalServerError(w, r, err)
+	}
+}
+
+// alertExpiringWarranties tells the asset holder and the admins once about
+// every warranty entering its expiring window.
+func (app *application) alertExpiringWarranties(ctx context.Context) error {
+	warranties, err := app.store.Warranty.GetUnnotifiedExpiring(ctx)
+	if err != nil || len(warranties) == 0 {
+		return err
+	}
+
+	admins, err := app.store.Users.GetIDsByRole(ctx, warrantyAlertRole)
+	if err != nil {
+		return err
+	}
+
+	for _, warranty := range warranties {
+		recipients := slices.Clone(admins)
+
+		holder, err := app.store.AssetLoan.CurrentHolder(ctx, warranty.AssetID)
+		if err != nil {
+			return err
+		}
+		if holder != nil && !slices.Contains(recipients, *holder) {
+			recipients = append(recipients, *holder)
+		}
+
+		label := "Warranty"
+		if warranty.Kind == store.WarrantySupport {
+			label = "Support contract"
+		}
+
+		assetID := warranty.AssetID
+		notifications := make([]store.Notification, len(recipients))
+		for i, userID := range recipients {
+			notifications[i] = store.Notification{
+				UserID:  userID,
+				Kind:    "warranty_expiring",
+				Title:   fmt.Sprintf("%s for %s is expiring", label, warranty.Asset.Tag),
+				Body:    fmt.Sprintf("%s %s for %s (%s) ends on %s.", label, warranty.ContractNumber, warranty.Asset.Name, warranty.Asset.Tag, warranty.EndDate.Format(time.DateOnly)),
+				AssetID: &assetID,
+			}
+		}
+
+		if _, err := app.store.Warranty.MarkNotified(ctx, warranty.ID, notifications); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
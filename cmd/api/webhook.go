@@ -0,0 +1,512 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	mrand "math/rand/v2"
+	"net"
+	"net/http"
+	"net/netip"
+	"slices"
+	"strconv"
+	"strings"
+	"sync"
+	"syscall"
+	"time"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/knr1997/assets-management-apiserver/internal/api/requests"
+	"github.com/knr1997/assets-management-apiserver/internal/api/responses"
+	"github.com/knr1997/assets-management-apiserver/internal/store"
+)
+
+const (
+	webhookBatchSize = 50
+	// the first retry waits this long, doubling up to webhookRetryMax
+	webhookRetryBase     = 30 * time.Second
+	webhookRetryMax      = 6 * time.Hour
+	webhookResponseLimit = 1000
+	// manages webhooks, which see every event
+	webhookAdminRole = "admin"
+)
+
+var errWebhookTarget = errors.New("webhook target is not a public address")
+
+// sharedAddressSpace is the carrier-grade NAT range, private in all but name.
+var sharedAddressSpace = netip.MustParsePrefix("100.64.0.0/10")
+
+// publicTarget refuses connections to loopback, private, link-local and other
+// non-public addresses. It runs on the resolved address at dial time, so a
+// name that resolves to an internal address later is refused as well.
+func publicTarget(network, address string, _ syscall.RawConn) error {
+	host, _, err := net.SplitHostPort(address)
+	if err != nil {
+		return err
+	}
+
+	ip, err := netip.ParseAddr(host)
+	if err != nil {
+		return err
+	}
+	ip = ip.Unmap()
+
+	if !ip.IsGlobalUnicast() || ip.IsPrivate() || sharedAddressSpace.Contains(ip) {
+		return fmt.Errorf("%w: %s", errWebhookTarget, ip)
+	}
+
+	return nil
+}
+
+// webhookTransport delivers webhooks directly, never through a proxy, to
+// public addresses only.
+var webhookTransport = func() *http.Transport {
+	t := http.DefaultTransport.(*http.Transport).Clone()
+	t.Proxy = nil
+	t.DialContext = (&net.Dialer{
+		Timeout:   30 * time.Second,
+		KeepAlive: 30 * time.Second,
+		Control:   publicTarget,
+	}).DialContext
+	return t
+}()
+
+type webhookKey string
+
+const webhookCtx webhookKey = "webhook"
+
+func getWebhookFromCtx(r *http.Request) *store.Webhook {
+	webhook, _ := r.Context().Value(webhookCtx).(*store.Webhook)
+	return webhook
+}
+
+func (app *application) webhookContextMiddleware(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		idParam := chi.URLParam(r, "webhookID")
+		id, err := strconv.ParseInt(idParam, 10, 64)
+		if err != nil {
+			app.internalServerError(w, r, err)
+			return
+		}
+
+		ctx := r.Context()
+
+		webhook, err := app.store.Webhook.GetByID(ctx, id)
+		if err != nil {
+			switch {
+			case errors.Is(err, store.ErrNotFound):
+				app.notFoundResponse(w, r, err)
+			default:
+				app.internalServerError(w, r, err)
+			}
+			return
+		}
+
+		ctx = context.WithValue(ctx, webhookCtx, webhook)
+		next.ServeHTTP(w, r.WithContext(ctx))
+	})
+}
+
+func checkEventTypes(events []string) error {
+	for _, event := range events {
+		if !slices.Contains(store.EventTypes, event) {
+			return fmt.Errorf("unknown event type %q", event)
+		}
+	}
+	return nil
+}
+
+func (app *application) getEventTypesHandler(w http.ResponseWriter, r *http.Request) {
+	if err := app.jsonResponse(w, http.StatusOK, store.EventTypes); err != nil {
+		app.internalServerError(w, r, err)
+	}
+}
+
+func (app *application) getAllWebhookHandler(w http.ResponseWriter, r *http.Request) {
+	spec, ok := app.parseQuerySpec(w, r)
+	if !ok {
+		return
+	}
+
+	writeListPage(app, w, r, spec, app.store.Webhook.List, responses.NewWebhooksResponse)
+}
+
+// createWebhookHandler subscribes a URL to events. The signing secret is
+// shown in the response only.
+func (app *application) createWebhookHandler(w http.ResponseWriter, r *http.Request) {
+	user := getUserFromContext(r)
+
+	var payload requests.CreateWebhookPayload
+	if err := readJSON(w, r, &payload); err != nil {
+		app.badRequestResponse(w, r, err)
+		return
+	}
+
+	if err := Validate.Struct(payload); err != nil {
+		app.badRequestResponse(w, r, err)
+		return
+	}
+
+	if err := checkEventTypes(payload.Events); err != nil {
+		app.badRequestResponse(w, r, err)
+		return
+	}
+
+	secret := payload.Secret
+	if secret == "" {
+		secret = rand.Text()
+	}
+
+	sealed, err := app.secrets.Seal(secret)
+	if err != nil {
+		app.internalServerError(w, r, err)
+		return
+	}
+
+	webhook := &store.Webhook{
+		Name:        payload.Name,
+		URL:         payload.URL,
+		Secret:      sealed,
+		Events:      payload.Events,
+		Active:      true,
+		CreatedByID: &user.ID,
+	}
+	if payload.Active != nil {
+		webhook.Active = *payload.Active
+	}
+
+	ctx := r.Context()
+
+	if err := app.store.Webhook.Create(ctx, webhook); err != nil {
+		app.internalServerError(w, r, err)
+		return
+	}
+
+	webhook, err = app.store.Webhook.GetByID(ctx, webhook.ID)
+	if err != nil {
+		app.internalServerError(w, r, err)
+		return
+	}
+
+	response := responses.NewWebhookResponse(webhook)
+	response.Secret = secret
+
+	if err := app.jsonResponse(w, http.StatusCreated, response); err != nil {
+		app.internalServerError(w, r, err)
+		return
+	}
+}
+
+func (app *application) getWebhookHandler(w http.ResponseWriter, r *http.Request) {
+	webhook := getWebhookFromCtx(r)
+
+	if err := app.jsonResponse(w, http.StatusOK, responses.NewWebhookResponse(webhook)); err != nil {
+		app.internalServerError(w, r, err)
+		return
+	}
+}
+
+func (app *application) updateWebhookHandler(w http.ResponseWriter, r *http.Request) {
+	webhook := getWebhookFromCtx(r)
+
+	var payload requests.UpdateWebhookPayload
+	if err := readJSON(w, r, &payload); err != nil {
+		app.badRequestResponse(w, r, err)
+		return
+	}
+
+	if err := Validate.Struct(payload); err != nil {
+		app.badRequestResponse(w, r, err)
+		return
+	}
+
+	if payload.Name != nil {
+		webhook.Name = *payload.Name
+	}
+	if payload.URL != nil {
+		webhook.URL = *payload.URL
+	}
+	if payload.Events != nil {
+		if err := checkEventTypes(payload.Events); err != nil {
+			app.badRequestResponse(w, r, err)
+			return
+		}
+		webhook.Events = payload.Events
+	}
+	if payload.Secret != nil {
+		sealed, err := app.secrets.Seal(*payload.Secret)
+		if err != nil {
+			app.internalServerError(w, r, err)
+			return
+		}
+		webhook.Secret = sealed
+	}
+	if payload.Active != nil {
+		webhook.Active = *payload.Active
+	}
+
+	ctx := r.Context()
+
+	if err := app.store.Webhook.Update(ctx, webhook); err != nil {
+		switch {
+		case errors.Is(err, store.ErrNotFound):
+			app.notFoundResponse(w, r, err)
+		default:
+			app.internalServerError(w, r, err)
+		}
+		return
+	}
+
+	webhook, err := app.store.Webhook.GetByID(ctx, webhook.ID)
+	if err != nil {
+		app.internalServerError(w, r, err)
+		return
+	}
+
+	response := responses.NewWebhookResponse(webhook)
+	if payload.Secret != nil {
+		response.Secret = *payload.Secret
+	}
+
+	if err := app.jsonResponse(w, http.StatusOK, response); err != nil {
+		app.internalServerError(w, r, err)
+		return
+	}
+}
+
+func (app *application) deleteWebhookHandler(w http.ResponseWriter, r *http.Request) {
+	webhook := getWebhookFromCtx(r)
+
+	if err := app.store.Webhook.Delete(r.Context(), webhook.ID); err != nil {
+		switch {
+		case errors.Is(err, store.ErrNotFound):
+			app.notFoundResponse(w, r, err)
+		default:
+			app.internalServerError(w, r, err)
+		}
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+func (app *application) getWebhookDeliveriesHandler(w http.ResponseWriter, r *http.Request) {
+	webhook := getWebhookFromCtx(r)
+
+	spec, ok := app.parseQuerySpec(w, r)
+	if !ok {
+		return
+	}
+
+	list := func(ctx context.Context, spec store.QuerySpec) (*store.Pagination, error) {
+		return app.store.Webhook.Deliveries(ctx, webhook.ID, spec)
+	}
+
+	writeListPage(app, w, r, spec, list, responses.NewWebhookDeliveriesResponse)
+}
+
+// webhookDelivery looks up the delivery of the webhook in the URL, writing
+// the error response when there is none.
+func (app *application) webhookDelivery(w http.ResponseWriter, r *http.Request) (*store.WebhookDelivery, bool) {
+	webhook := getWebhookFromCtx(r)
+
+	id, err := strconv.ParseInt(chi.URLParam(r, "deliveryID"), 10, 64)
+	if err != nil {
+		app.badRequestResponse(w, r, err)
+		return nil, false
+	}
+
+	delivery, err := app.store.Webhook.GetDelivery(r.Context(), webhook.ID, id)
+	if err != nil {
+		switch {
+		case errors.Is(err, store.ErrNotFound):
+			app.notFoundResponse(w, r, err)
+		default:
+			app.internalServerError(w, r, err)
+		}
+		return nil, false
+	}
+
+	return delivery, true
+}
+
+func (app *application) getWebhookDeliveryHandler(w http.ResponseWriter, r *http.Request) {
+	delivery, ok := app.webhookDelivery(w, r)
+	if !ok {
+		return
+	}
+
+	if err := app.jsonResponse(w, http.StatusOK, responses.NewWebhookDeliveryResponse(delivery)); err != nil {
+		app.internalServerError(w, r, err)
+		return
+	}
+}
+
+// redeliverWebhookHandler queues the event of a delivery again, e.g. once a
+// dead delivery's receiver is fixed.
+func (app *application) redeliverWebhookHandler(w http.ResponseWriter, r *http.Request) {
+	delivery, ok := app.webhookDelivery(w, r)
+	if !ok {
+		return
+	}
+
+	redelivery, err := app.store.Webhook.Redeliver(r.Context(), delivery)
+	if err != nil {
+		app.internalServerError(w, r, err)
+		return
+	}
+	redelivery.Event = delivery.Event
+
+	if err := app.jsonResponse(w, http.StatusAccepted, responses.NewWebhookDeliveryResponse(redelivery)); err != nil {
+		app.internalServerError(w, r, err)
+		return
+	}
+}
+
+// queueWebhookDeliveries is the event subscriber handing events to the
+// webhooks subscribed to them.
+func (app *application) queueWebhookDeliveries(ctx context.Context, event *store.OutboxEvent) error {
+	return app.store.Webhook.QueueDeliveries(ctx, event)
+}
+
+// deliverWebhooks sends the deliveries that are due, retrying failed ones
+// with exponential backoff until they run out of attempts.
+func (app *application) deliverWebhooks(ctx context.Context) error {
+	for {
+		lease := app.config.webhooks.timeout + time.Minute
+
+		deliveries, err := app.store.Webhook.ClaimDue(ctx, webhookBatchSize, lease)
+		if err != nil {
+			return err
+		}
+
+		var wg sync.WaitGroup
+		for i := range deliveries {
+			wg.Go(func() {
+				app.deliverWebhook(ctx, &deliveries[i])
+			})
+		}
+		wg.Wait()
+
+		if len(deliveries) < webhookBatchSize || ctx.Err() != nil {
+			return ctx.Err()
+		}
+	}
+}
+
+func (app *application) deliverWebhook(ctx context.Context, delivery *store.WebhookDelivery) {
+	code, body, err := app.postWebhook(ctx, delivery)
+	if ctx.Err() != nil {
+		// shutting down; the lease runs out and the attempt is made again
+		return
+	}
+
+	now := time.Now()
+	delivery.Attempts++
+	delivery.LastAttemptAt = &now
+	delivery.ResponseCode = nil
+	delivery.ResponseBody = body
+	delivery.Error = ""
+
+	if err != nil {
+		delivery.Error = truncate(err.Error(), 500)
+	} else {
+		delivery.ResponseCode = &code
+	}
+
+	switch {
+	case err == nil && code >= 200 && code < 300:
+		delivery.Status = store.DeliverySucceeded
+		delivery.DeliveredAt = &now
+	case delivery.Attempts >= app.config.webhooks.maxAttempts:
+		delivery.Status = store.DeliveryDead
+	default:
+		delivery.NextAttemptAt = now.Add(webhookBackoff(delivery.Attempts))
+	}
+
+	if err := app.store.Webhook.RecordAttempt(ctx, delivery); err != nil {
+		app.logger.Errorw("recording webhook delivery", "delivery", delivery.ID, "error", err.Error())
+	}
+}
+
+// webhookBackoff is the wait after the given number of failed attempts, with
+// some jitter so retries to one receiver spread out.
+func webhookBackoff(attempts int) time.Duration {
+	wait := webhookRetryMax
+	if attempts < 20 {
+		wait = min(webhookRetryBase<<(attempts-1), webhookRetryMax)
+	}
+	return wait + mrand.N(wait/10+1)
+}
+
+// postWebhook posts a delivery's event, returning the receiver's status code
+// and the start of its response body.
+func (app *application) postWebhook(ctx context.Context, delivery *store.WebhookDelivery) (int, string, error) {
+	secret, err := app.secrets.Open(delivery.Webhook.Secret)
+	if err != nil {
+		return 0, "", err
+	}
+
+	body, err := json.Marshal(newEventEnvelope(&delivery.Event))
+	if err != nil {
+		return 0, "", err
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, delivery.Webhook.URL, bytes.NewReader(body))
+	if err != nil {
+		return 0, "", err
+	}
+
+	timestamp := strconv.FormatInt(time.Now().Unix(), 10)
+
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("User-Agent", "assets-webhooks/"+version)
+	req.Header.Set("X-Webhook-Event", delivery.Event.Type)
+	req.Header.Set("X-Webhook-Delivery", strconv.FormatInt(delivery.ID, 10))
+	req.Header.Set("X-Webhook-Timestamp", timestamp)
+	req.Header.Set("X-Webhook-Signature", webhookSignature(secret, timestamp, body))
+
+	client := &http.Client{
+		Transport: webhookTransport,
+		Timeout:   app.config.webhooks.timeout,
+		// a redirect is reported as the response, never followed
+		CheckRedirect: func(*http.Request, []*http.Request) error {
+			return http.ErrUseLastResponse
+		},
+	}
+
+	resp, err := client.Do(req)
+	if err != nil {
+		return 0, "", err
+	}
+	defer resp.Body.Close()
+
+	b, _ := io.ReadAll(io.LimitReader(resp.Body, webhookResponseLimit))
+	text := strings.ReplaceAll(strings.ToValidUTF8(string(b), ""), "\x00", "")
+
+	return resp.StatusCode, text, nil
+}
+
+// webhookSignature signs the timestamp along with the body, so receivers can
+// reject replayed deliveries: hex HMAC-SHA256 of "<timestamp>.<body>".
+func webhookSignature(secret, timestamp string, body []byte) string {
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write([]byte(timestamp))
+	mac.Write([]byte("."))
+	mac.Write(body)
+	return "sha256=" + hex.EncodeToString(mac.Sum(nil))
+}
+
+func truncate(s string, n int) string {
+	if len(s) <= n {
+		return s
+	}
+	return strings.ToValidUTF8(s[:n], "")
+}
@@ -0,0 +1,60 @@
+package main
+
+import "testing"
+
+func TestWebhookSignature(t *testing.T) {
+	tests := []struct {
+		name      string
+		secret    string
+		timestamp string
+		body      string
+		want      string
+	}{
+		{"signed", "secret", "1700000000", `{"a":1}`, "sha256=49f24e537407743fa4a0242bb63b94b9a47ee99cbbe071ccd8a22550ae411686"},
+		{"empty", "", "0", "", "sha256=b849d5a581847b281957065739df36df2463d1977ea8d6e1e4e6cf33fadc68c3"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := webhookSignature(tt.secret, tt.timestamp, []byte(tt.body)); got != tt.want {
+				t.Errorf("webhookSignature(%q, %q, %q) = %q, want %q", tt.secret, tt.timestamp, tt.body, got, tt.want)
+			}
+		})
+	}
+
+	// the timestamp is signed, so a replayed body with a new one fails
+	if webhookSignature("secret", "1700000001", []byte(`{"a":1}`)) == tests[0].want {
+		t.Error("signature does not cover the timestamp")
+	}
+}
+
+func TestPublicTarget(t *testing.T) {
+	tests := []struct {
+		address string
+		public  bool
+	}{
+		{"93.184.216.34:443", true},
+		{"[2606:2800:220:1:248:1893:25c8:1946]:443", true},
+		{"127.0.0.1:80", false},
+		{"[::1]:80", false},
+		{"10.0.0.5:80", false},
+		{"172.16.0.1:80", false},
+		{"192.168.1.1:80", false},
+		{"169.254.169.254:80", false},
+		{"[fe80::1]:80", false},
+		{"[fc00::1]:80", false},
+		{"[::ffff:127.0.0.1]:80", false},
+		{"100.64.0.1:80", false},
+		{"0.0.0.0:80", false},
+		{"224.0.0.1:80", false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.address, func(t *testing.T) {
+			err := publicTarget("tcp", tt.address, nil)
+			if (err == nil) != tt.public {
+				t.Errorf("publicTarget(%q) = %v, want public %v", tt.address, err, tt.public)
+			}
+		})
+	}
+}
@@ -0,0 +1,38 @@
+package requests
+
+import "time"
+
+type CreateAccessoryPayload struct {
+	Name           string     `json:"name" validate:"required,max=150"`
+	CategoryID     int64      `json:"categoryId" validate:"required"`
+	ManufacturerID *int64     `json:"manufacturerId"`
+	SupplierID     *int64     `json:"supplierId"`
+	ModelNumber    string     `json:"modelNumber" validate:"max=100"`
+	PurchaseDate   *time.Time `json:"purchaseDate"`
+	UnitCost       float64    `json:"unitCost" validate:"gte=0"`
+	Notes          string     `json:"notes" validate:"max=1000"`
+}
+
+type UpdateAccessoryPayload struct {
+	Name           *string    `json:"name" validate:"omitempty,min=1,max=150"`
+	CategoryID     *int64     `json:"categoryId" validate:"omitempty,min=1"`
+	ManufacturerID *int64     `json:"manufacturerId"`
+	SupplierID     *int64     `json:"supplierId"`
+	ModelNumber    *string    `json:"modelNumber" validate:"omitempty,max=100"`
+	PurchaseDate   *time.Time `json:"purchaseDate"`
+	UnitCost       *float64   `json:"unitCost" validate:"omitempty,gte=0"`
+	Notes          *string    `json:"notes" validate:"omitempty,max=1000"`
+}
+
+type SetAccessoryStockPayload struct {
+	Location string `json:"location" validate:"required,max=100"`
+	Quantity int    `json:"quantity" validate:"gte=0"`
+}
+
+type CheckoutAccessoryPayload struct {
+	UserID              int64      `json:"userId" validate:"required"`
+	Location            string     `json:"location" validate:"required,max=100"`
+	Quantity            int        `json:"quantity" validate:"omitempty,min=1"`
+	ExpectedCheckinDate *time.Time `json:"expectedCheckinDate"`
+	Notes               string     `json:"notes" validate:"max=255"`
+}
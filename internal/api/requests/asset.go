@@ -10,6 +10,12 @@ type CreateAssetPayload struct {
 	ModelID      int64  `json:"modelId" validate:"required"`
 	Status       string `json:"status" validate:"required"`
 	Notes        string `json:"notes"`
+
+	// the model's defaults when left out
+	UsefulLifeYears    *int   `json:"usefulLifeYears" validate:"omitempty,gte=0,lte=100"`
+	DepreciationMethod string `json:"depreciationMethod" validate:"omitempty,oneof=STRAIGHT_LINE DECLINING_BALANCE NONE"`
+
+	CustomFields map[string]any `json:"customFields"`
 }
 
 type UpdateAssetPayload struct {
@@ -20,6 +26,12 @@ type UpdateAssetPayload struct {
 	ModelID      *int64  `json:"modelId" validate:"required"`
 	Status       *string `json:"status" validate:"required"`
 	Notes        *string `json:"notes"`
+
+	UsefulLifeYears    *int    `json:"usefulLifeYears" validate:"omitempty,gte=0,lte=100"`
+	DepreciationMethod *string `json:"depreciationMethod" validate:"omitempty,oneof=STRAIGHT_LINE DECLINING_BALANCE NONE"`
+
+	// merged into the existing values, a null removes a field
+	CustomFields map[string]any `json:"customFields"`
 }
 
 type CheckoutAssetPayload struct {
@@ -29,6 +41,9 @@ type CheckoutAssetPayload struct {
 	CheckoutDate        time.Time  `json:"checkoutDate" validate:"required"`
 	ExpectedCheckinDate *time.Time `json:"expectedCheckinDate"`
 	Notes               string     `json:"notes"`
+
+	// also check out the available assets the asset contains
+	Cascade bool `json:"cascade"`
 }
 
 type CheckinAssetPayload struct {
@@ -37,4 +52,7 @@ type CheckinAssetPayload struct {
 	CheckinDate time.Time `json:"checkinDate" validate:"required"`
 	Status      string    `json:"status"`
 	Notes       string    `json:"notes"`
+
+	// also check in the assets the asset contains
+	Cascade bool `json:"cascade"`
 }
@@ -0,0 +1,10 @@
+package requests
+
+// AcceptAssetAssignmentPayload is the user's acknowledgement of an assigned
+// asset: their typed name and optionally a drawn signature as a PNG data
+// URL.
+type AcceptAssetAssignmentPayload struct {
+	Name       string `json:"name" validate:"required,max=150"`
+	Signature  string `json:"signature" validate:"omitempty,startswith=data:image/png;base64"`
+	AcceptEULA bool   `json:"acceptEula"`
+}
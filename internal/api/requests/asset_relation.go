@@ -0,0 +1,7 @@
+package requests
+
+type CreateAssetRelationPayload struct {
+	ToAssetID int64  `json:"toAssetId" validate:"required"`
+	Type      string `json:"type" validate:"required,oneof=CONTAINS CONNECTED_TO DEPENDS_ON"`
+	Notes     string `json:"notes" validate:"max=255"`
+}
@@ -0,0 +1,32 @@
+package requests
+
+type CreateApprovalStepPayload struct {
+	Position int     `json:"position" validate:"required,min=1"`
+	Name     string  `json:"name" validate:"required,max=100"`
+	Approver string  `json:"approver" validate:"required,oneof=DEPARTMENT_MANAGER ROLE"`
+	RoleID   *int64  `json:"roleId" validate:"required_if=Approver ROLE,excluded_unless=Approver ROLE"`
+	MinCost  float64 `json:"minCost" validate:"gte=0"`
+}
+
+type UpdateApprovalStepPayload struct {
+	Position *int     `json:"position" validate:"omitempty,min=1"`
+	Name     *string  `json:"name" validate:"omitempty,min=1,max=100"`
+	Approver *string  `json:"approver" validate:"omitempty,oneof=DEPARTMENT_MANAGER ROLE"`
+	RoleID   *int64   `json:"roleId"`
+	MinCost  *float64 `json:"minCost" validate:"omitempty,gte=0"`
+}
+
+type CreateAssetRequestPayload struct {
+	ModelID       int64  `json:"modelId" validate:"required"`
+	Justification string `json:"justification" validate:"required,max=1000"`
+}
+
+type DecideAssetRequestPayload struct {
+	Comment string `json:"comment" validate:"max=1000"`
+}
+
+// FulfillAssetRequestPayload names the asset to hand out, by default the
+// first available asset of the requested model.
+type FulfillAssetRequestPayload struct {
+	AssetID *int64 `json:"assetId"`
+}
@@ -0,0 +1,37 @@
+package requests
+
+import "time"
+
+type CreateComponentPayload struct {
+	Name           string     `json:"name" validate:"required,max=150"`
+	CategoryID     int64      `json:"categoryId" validate:"required"`
+	ManufacturerID *int64     `json:"manufacturerId"`
+	SupplierID     *int64     `json:"supplierId"`
+	PartNumber     string     `json:"partNumber" validate:"max=100"`
+	SerialNumber   string     `json:"serialNumber" validate:"max=100"`
+	Quantity       int        `json:"quantity" validate:"gte=0"`
+	UnitCost       float64    `json:"unitCost" validate:"gte=0"`
+	PurchaseDate   *time.Time `json:"purchaseDate"`
+	Location       string     `json:"location" validate:"max=100"`
+	Notes          string     `json:"notes" validate:"max=1000"`
+}
+
+type UpdateComponentPayload struct {
+	Name           *string    `json:"name" validate:"omitempty,min=1,max=150"`
+	CategoryID     *int64     `json:"categoryId" validate:"omitempty,min=1"`
+	ManufacturerID *int64     `json:"manufacturerId"`
+	SupplierID     *int64     `json:"supplierId"`
+	PartNumber     *string    `json:"partNumber" validate:"omitempty,max=100"`
+	SerialNumber   *string    `json:"serialNumber" validate:"omitempty,max=100"`
+	Quantity       *int       `json:"quantity" validate:"omitempty,gte=0"`
+	UnitCost       *float64   `json:"unitCost" validate:"omitempty,gte=0"`
+	PurchaseDate   *time.Time `json:"purchaseDate"`
+	Location       *string    `json:"location" validate:"omitempty,max=100"`
+	Notes          *string    `json:"notes" validate:"omitempty,max=1000"`
+}
+
+type InstallComponentPayload struct {
+	AssetID  int64  `json:"assetId" validate:"required"`
+	Quantity int    `json:"quantity" validate:"omitempty,min=1"`
+	Notes    string `json:"notes" validate:"max=255"`
+}
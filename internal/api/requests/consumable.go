@@ -0,0 +1,42 @@
+package requests
+
+type CreateConsumablePayload struct {
+	Name           string  `json:"name" validate:"required,max=150"`
+	CategoryID     int64   `json:"categoryId" validate:"required"`
+	ManufacturerID *int64  `json:"manufacturerId"`
+	ItemNumber     string  `json:"itemNumber" validate:"max=100"`
+	UnitCost       float64 `json:"unitCost" validate:"gte=0"`
+	Notes          string  `json:"notes" validate:"max=1000"`
+}
+
+type UpdateConsumablePayload struct {
+	Name           *string  `json:"name" validate:"omitempty,min=1,max=150"`
+	CategoryID     *int64   `json:"categoryId" validate:"omitempty,min=1"`
+	ManufacturerID *int64   `json:"manufacturerId"`
+	ItemNumber     *string  `json:"itemNumber" validate:"omitempty,max=100"`
+	UnitCost       *float64 `json:"unitCost" validate:"omitempty,gte=0"`
+	Notes          *string  `json:"notes" validate:"omitempty,max=1000"`
+}
+
+type SetConsumableStockPayload struct {
+	Location    string `json:"location" validate:"required,max=100"`
+	MinQuantity int    `json:"minQuantity" validate:"gte=0"`
+}
+
+// IssueConsumablePayload hands stock out to a user, a department or a user
+// on behalf of their department.
+type IssueConsumablePayload struct {
+	Location     string `json:"location" validate:"required,max=100"`
+	Quantity     int    `json:"quantity" validate:"required,min=1"`
+	UserID       *int64 `json:"userId" validate:"required_without=DepartmentID"`
+	DepartmentID *int64 `json:"departmentId" validate:"required_without=UserID"`
+	Notes        string `json:"notes" validate:"max=1000"`
+}
+
+type ReceiveConsumablePayload struct {
+	Location   string  `json:"location" validate:"required,max=100"`
+	Quantity   int     `json:"quantity" validate:"required,min=1"`
+	SupplierID *int64  `json:"supplierId"`
+	UnitCost   float64 `json:"unitCost" validate:"gte=0"`
+	Notes      string  `json:"notes" validate:"max=1000"`
+}
@@ -0,0 +1,25 @@
+package requests
+
+type CustomFieldPayload struct {
+	Key      string   `json:"key" validate:"required,max=64"`
+	Label    string   `json:"label" validate:"required,max=100"`
+	Type     string   `json:"type" validate:"required,oneof=text number date enum regex encrypted"`
+	Required bool     `json:"required"`
+	Unique   bool     `json:"unique"`
+	Options  []string `json:"options"`
+	Pattern  string   `json:"pattern" validate:"max=255"`
+}
+
+type CreateCustomFieldSetPayload struct {
+	Name       string               `json:"name" validate:"required,max=100"`
+	CategoryID *int64               `json:"categoryId"`
+	ModelID    *int64               `json:"modelId"`
+	Fields     []CustomFieldPayload `json:"fields" validate:"dive"`
+}
+
+type UpdateCustomFieldSetPayload struct {
+	Name       *string               `json:"name" validate:"omitempty,max=100"`
+	CategoryID *int64                `json:"categoryId"`
+	ModelID    *int64                `json:"modelId"`
+	Fields     *[]CustomFieldPayload `json:"fields" validate:"omitempty,dive"`
+}
@@ -0,0 +1,24 @@
+package requests
+
+import "time"
+
+type CreateDisposalPayload struct {
+	AssetID int64  `json:"assetId" validate:"required"`
+	Method  string `json:"method" validate:"required,oneof=SALE DONATION RECYCLING DESTRUCTION WRITE_OFF"`
+	Reason  string `json:"reason" validate:"max=500"`
+}
+
+type DecideDisposalPayload struct {
+	Comment string `json:"comment" validate:"max=255"`
+}
+
+type ConfirmDataWipePayload struct {
+	Method string `json:"method" validate:"required,max=100"`
+}
+
+type CompleteDisposalPayload struct {
+	DisposedAt *time.Time `json:"disposedAt"`
+	Proceeds   float64    `json:"proceeds" validate:"gte=0"`
+	Recipient  string     `json:"recipient" validate:"max=150"`
+	Reference  string     `json:"reference" validate:"max=100"`
+}
@@ -0,0 +1,37 @@
+package requests
+
+import "time"
+
+// KitItemPayload is a quantity of exactly one of a model, an accessory or a
+// consumable.
+type KitItemPayload struct {
+	ModelID      *int64 `json:"modelId" validate:"required_without_all=AccessoryID ConsumableID,excluded_with=AccessoryID ConsumableID"`
+	AccessoryID  *int64 `json:"accessoryId" validate:"excluded_with=ConsumableID"`
+	ConsumableID *int64 `json:"consumableId"`
+	Quantity     int    `json:"quantity" validate:"omitempty,min=1"`
+}
+
+type CreateKitPayload struct {
+	Name        string           `json:"name" validate:"required,max=150"`
+	Description string           `json:"description" validate:"max=1000"`
+	Items       []KitItemPayload `json:"items" validate:"required,min=1,dive"`
+}
+
+type UpdateKitPayload struct {
+	Name        *string `json:"name" validate:"omitempty,min=1,max=150"`
+	Description *string `json:"description" validate:"omitempty,max=1000"`
+
+	// replaces the items when set
+	Items []KitItemPayload `json:"items" validate:"omitempty,min=1,dive"`
+}
+
+type CheckoutKitPayload struct {
+	UserID int64 `json:"userId" validate:"required"`
+
+	// where accessories and consumables are taken from
+	Location string `json:"location" validate:"max=100"`
+
+	CheckoutDate        *time.Time `json:"checkoutDate"`
+	ExpectedCheckinDate *time.Time `json:"expectedCheckinDate"`
+	Notes               string     `json:"notes" validate:"max=255"`
+}
@@ -0,0 +1,36 @@
+package requests
+
+import "time"
+
+type CreateLicensePayload struct {
+	Name           string     `json:"name" validate:"required,max=150"`
+	ProductKey     string     `json:"productKey" validate:"max=1000"`
+	LicensedTo     string     `json:"licensedTo" validate:"max=150"`
+	ManufacturerID *int64     `json:"manufacturerId"`
+	SupplierID     *int64     `json:"supplierId"`
+	Seats          int        `json:"seats" validate:"required,min=1"`
+	PurchaseDate   *time.Time `json:"purchaseDate"`
+	PurchaseCost   float64    `json:"purchaseCost" validate:"gte=0"`
+	ExpiresAt      *time.Time `json:"expiresAt"`
+	Notes          string     `json:"notes" validate:"max=1000"`
+}
+
+type UpdateLicensePayload struct {
+	Name           *string    `json:"name" validate:"omitempty,min=1,max=150"`
+	ProductKey     *string    `json:"productKey" validate:"omitempty,max=1000"`
+	LicensedTo     *string    `json:"licensedTo" validate:"omitempty,max=150"`
+	ManufacturerID *int64     `json:"manufacturerId"`
+	SupplierID     *int64     `json:"supplierId"`
+	Seats          *int       `json:"seats" validate:"omitempty,min=1"`
+	PurchaseDate   *time.Time `json:"purchaseDate"`
+	PurchaseCost   *float64   `json:"purchaseCost" validate:"omitempty,gte=0"`
+	ExpiresAt      *time.Time `json:"expiresAt"`
+	Notes          *string    `json:"notes" validate:"omitempty,max=1000"`
+}
+
+// CheckoutLicensePayload takes a seat for either a user or an asset.
+type CheckoutLicensePayload struct {
+	UserID  *int64 `json:"userId" validate:"required_without=AssetID,excluded_with=AssetID"`
+	AssetID *int64 `json:"assetId" validate:"required_without=UserID,excluded_with=UserID"`
+	Notes   string `json:"notes" validate:"max=1000"`
+}
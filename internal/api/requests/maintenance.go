@@ -0,0 +1,26 @@
+package requests
+
+import "time"
+
+type CreateMaintenancePayload struct {
+	Title          string     `json:"title" validate:"required,max=150"`
+	AssetID        int64      `json:"assetId" validate:"required"`
+	SupplierID     *int64     `json:"supplierId"`
+	Type           string     `json:"type" validate:"required,oneof=REPAIR UPGRADE CALIBRATION PM"`
+	Status         string     `json:"status" validate:"omitempty,oneof=SCHEDULED IN_PROGRESS COMPLETED CANCELLED"`
+	StartDate      *time.Time `json:"startDate"`
+	CompletionDate *time.Time `json:"completionDate"`
+	Cost           float64    `json:"cost" validate:"gte=0"`
+	Notes          string     `json:"notes" validate:"max=1000"`
+}
+
+type UpdateMaintenancePayload struct {
+	Title          *string    `json:"title" validate:"omitempty,max=150"`
+	SupplierID     *int64     `json:"supplierId"`
+	Type           *string    `json:"type" validate:"omitempty,oneof=REPAIR UPGRADE CALIBRATION PM"`
+	Status         *string    `json:"status" validate:"omitempty,oneof=SCHEDULED IN_PROGRESS COMPLETED CANCELLED"`
+	StartDate      *time.Time `json:"startDate"`
+	CompletionDate *time.Time `json:"completionDate"`
+	Cost           *float64   `json:"cost" validate:"omitempty,gte=0"`
+	Notes          *string    `json:"notes" validate:"omitempty,max=1000"`
+}
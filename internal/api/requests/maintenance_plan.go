@@ -0,0 +1,33 @@
+package requests
+
+import "time"
+
+type CreateMaintenancePlanPayload struct {
+	Name         string    `json:"name" validate:"required,max=150"`
+	ModelID      *int64    `json:"modelId"`
+	AssetID      *int64    `json:"assetId"`
+	SupplierID   *int64    `json:"supplierId"`
+	OwnerID      *int64    `json:"ownerId"`
+	Type         string    `json:"type" validate:"omitempty,oneof=REPAIR UPGRADE CALIBRATION PM"`
+	Recurrence   string    `json:"recurrence" validate:"max=255"`
+	IntervalDays int       `json:"intervalDays" validate:"gte=0"`
+	StartDate    time.Time `json:"startDate" validate:"required"`
+	LeadDays     *int      `json:"leadDays" validate:"omitempty,gte=0,lte=365"`
+	Active       *bool     `json:"active"`
+	Notes        string    `json:"notes" validate:"max=1000"`
+}
+
+type UpdateMaintenancePlanPayload struct {
+	Name         *string    `json:"name" validate:"omitempty,max=150"`
+	ModelID      *int64     `json:"modelId"`
+	AssetID      *int64     `json:"assetId"`
+	SupplierID   *int64     `json:"supplierId"`
+	OwnerID      *int64     `json:"ownerId"`
+	Type         *string    `json:"type" validate:"omitempty,oneof=REPAIR UPGRADE CALIBRATION PM"`
+	Recurrence   *string    `json:"recurrence" validate:"omitempty,max=255"`
+	IntervalDays *int       `json:"intervalDays" validate:"omitempty,gte=0"`
+	StartDate    *time.Time `json:"startDate"`
+	LeadDays     *int       `json:"leadDays" validate:"omitempty,gte=0,lte=365"`
+	Active       *bool      `json:"active"`
+	Notes        *string    `json:"notes" validate:"omitempty,max=1000"`
+}
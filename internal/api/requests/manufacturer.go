@@ -1,11 +1,15 @@
 package requests
 
 type CreateManufacturerPayload struct {
-	Name  string `json:"name" validate:"required,max=100"`
-	Email string `json:"email" validate:"required,max=100"`
+	Name              string `json:"name" validate:"required,max=100"`
+	Email             string `json:"email" validate:"required,max=100"`
+	WarrantyLookupURL string `json:"warrantyLookupUrl" validate:"omitempty,url,contains={serial},max=500"`
+	VendorDetailsPayload
 }
 
 type UpdateManufacturerPayload struct {
-	Name  *string `json:"name" validate:"required,max=100"`
-	Email *string `json:"email" validate:"required,max=100"`
+	Name              *string `json:"name" validate:"required,max=100"`
+	Email             *string `json:"email" validate:"required,max=100"`
+	WarrantyLookupURL *string `json:"warrantyLookupUrl" validate:"omitempty,url,contains={serial},max=500"`
+	VendorDetailsPayload
 }
@@ -1,15 +1,33 @@
 package requests
 
-type CreateModelPayload struct {
-	Name           string `json:"name" validate:"required,max=100"`
-	CategoryID     int64  `json:"categoryID" validate:"required"`
-	ManufacturerID int64  `json:"manufacturerID" validate:"required"`
-	ModelNumber    string `json:"modelNumber" validate:"required,max=100"`
-}
+import "time"
 
-type UpdateModelPayload struct {
-	Name           *string `json:"name" validate:"required,max=100"`
+type CreateModelPayload struct {
+	Name           string  `json:"name" validate:"required,max=100"`
 	CategoryID     int64   `json:"categoryID" validate:"required"`
 	ManufacturerID int64   `json:"manufacturerID" validate:"required"`
 	ModelNumber    string  `json:"modelNumber" validate:"required,max=100"`
+	Requestable    bool    `json:"requestable"`
+	RequestCost    float64 `json:"requestCost" validate:"gte=0"`
+
+	EOLDate                   *time.Time `json:"eolDate"`
+	EndOfSupportDate          *time.Time `json:"endOfSupportDate"`
+	DefaultUsefulLifeYears    int        `json:"defaultUsefulLifeYears" validate:"gte=0,lte=100"`
+	DefaultDepreciationMethod string     `json:"defaultDepreciationMethod" validate:"omitempty,oneof=STRAIGHT_LINE DECLINING_BALANCE NONE"`
+	DefaultLoanDays           int        `json:"defaultLoanDays" validate:"gte=0,lte=3650"`
+}
+
+type UpdateModelPayload struct {
+	Name           *string  `json:"name" validate:"required,max=100"`
+	CategoryID     int64    `json:"categoryID" validate:"required"`
+	ManufacturerID int64    `json:"manufacturerID" validate:"required"`
+	ModelNumber    string   `json:"modelNumber" validate:"required,max=100"`
+	Requestable    *bool    `json:"requestable"`
+	RequestCost    *float64 `json:"requestCost" validate:"omitempty,gte=0"`
+
+	EOLDate                   *time.Time `json:"eolDate"`
+	EndOfSupportDate          *time.Time `json:"endOfSupportDate"`
+	DefaultUsefulLifeYears    *int       `json:"defaultUsefulLifeYears" validate:"omitempty,gte=0,lte=100"`
+	DefaultDepreciationMethod *string    `json:"defaultDepreciationMethod" validate:"omitempty,oneof=STRAIGHT_LINE DECLINING_BALANCE NONE"`
+	DefaultLoanDays           *int       `json:"defaultLoanDays" validate:"omitempty,gte=0,lte=3650"`
 }
@@ -0,0 +1,12 @@
+package requests
+
+import "time"
+
+type CreateOffboardingPayload struct {
+	LastDay *time.Time `json:"lastDay"`
+	Notes   string     `json:"notes" validate:"max=1000"`
+}
+
+type ResolveOffboardingItemPayload struct {
+	Notes string `json:"notes" validate:"max=255"`
+}
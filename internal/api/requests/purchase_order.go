@@ -0,0 +1,56 @@
+package requests
+
+import "time"
+
+type PurchaseOrderLinePayload struct {
+	ModelID     int64   `json:"modelId" validate:"required"`
+	Description string  `json:"description" validate:"max=255"`
+	Quantity    int     `json:"quantity" validate:"required,gte=1,lte=10000"`
+	UnitCost    float64 `json:"unitCost" validate:"gte=0"`
+	Currency    string  `json:"currency" validate:"required,len=3,uppercase"`
+}
+
+type CreatePurchaseOrderPayload struct {
+	Number     string                     `json:"number" validate:"required,max=50"`
+	SupplierID int64                      `json:"supplierId" validate:"required"`
+	OrderDate  *time.Time                 `json:"orderDate"`
+	Notes      string                     `json:"notes" validate:"max=1000"`
+	Lines      []PurchaseOrderLinePayload `json:"lines" validate:"required,min=1,dive"`
+}
+
+type UpdatePurchaseOrderPayload struct {
+	Number     *string                     `json:"number" validate:"omitempty,max=50"`
+	SupplierID *int64                      `json:"supplierId"`
+	Status     *string                     `json:"status" validate:"omitempty,oneof=DRAFT ORDERED CANCELLED"`
+	OrderDate  *time.Time                  `json:"orderDate"`
+	Notes      *string                     `json:"notes" validate:"omitempty,max=1000"`
+	Lines      *[]PurchaseOrderLinePayload `json:"lines" validate:"omitempty,min=1,dive"`
+}
+
+type CreateInvoicePayload struct {
+	Number      string    `json:"number" validate:"required,max=100"`
+	InvoiceDate time.Time `json:"invoiceDate" validate:"required"`
+	Amount      float64   `json:"amount" validate:"gte=0"`
+	Currency    string    `json:"currency" validate:"required,len=3,uppercase"`
+	DocumentURL string    `json:"documentUrl" validate:"omitempty,url,max=500"`
+	Notes       string    `json:"notes" validate:"max=1000"`
+}
+
+type ReceivedAssetPayload struct {
+	Name         string         `json:"name" validate:"max=100"`
+	Tag          string         `json:"tag" validate:"required,max=100"`
+	SerialNumber string         `json:"serialNumber" validate:"required,max=100"`
+	Location     string         `json:"location" validate:"max=100"`
+	CustomFields map[string]any `json:"customFields"`
+}
+
+type ReceiptLinePayload struct {
+	LineID int64                  `json:"lineId" validate:"required"`
+	Assets []ReceivedAssetPayload `json:"assets" validate:"required,min=1,dive"`
+}
+
+type ReceivePurchaseOrderPayload struct {
+	ReceivedDate time.Time            `json:"receivedDate" validate:"required"`
+	InvoiceID    *int64               `json:"invoiceId"`
+	Lines        []ReceiptLinePayload `json:"lines" validate:"required,min=1,dive"`
+}
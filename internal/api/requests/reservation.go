@@ -0,0 +1,14 @@
+package requests
+
+import "time"
+
+// CreateReservationPayload books a specific asset, or with only a model any
+// free asset of it.
+type CreateReservationPayload struct {
+	AssetID  *int64    `json:"assetId" validate:"required_without=ModelID"`
+	ModelID  *int64    `json:"modelId" validate:"required_without=AssetID"`
+	UserID   int64     `json:"userId" validate:"required"`
+	StartsAt time.Time `json:"startsAt" validate:"required"`
+	EndsAt   time.Time `json:"endsAt" validate:"required,gtfield=StartsAt"`
+	Notes    string    `json:"notes" validate:"max=255"`
+}
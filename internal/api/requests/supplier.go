@@ -0,0 +1,11 @@
+package requests
+
+type CreateSupplierPayload struct {
+	Name string `json:"name" validate:"required,max=100"`
+	VendorDetailsPayload
+}
+
+type UpdateSupplierPayload struct {
+	Name *string `json:"name" validate:"omitempty,max=100"`
+	VendorDetailsPayload
+}
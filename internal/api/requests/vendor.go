@@ -0,0 +1,39 @@
+package requests
+
+type AddressPayload struct {
+	Line1      *string `json:"line1" validate:"omitempty,max=150"`
+	Line2      *string `json:"line2" validate:"omitempty,max=150"`
+	City       *string `json:"city" validate:"omitempty,max=100"`
+	Region     *string `json:"region" validate:"omitempty,max=100"`
+	PostalCode *string `json:"postalCode" validate:"omitempty,max=20"`
+	Country    *string `json:"country" validate:"omitempty,max=100"`
+}
+
+// VendorDetailsPayload holds the contact, support and account details shared
+// by suppliers and manufacturers. Fields left out are not changed.
+type VendorDetailsPayload struct {
+	Website       *string         `json:"website" validate:"omitempty,url,max=255"`
+	Phone         *string         `json:"phone" validate:"omitempty,max=50"`
+	SupportURL    *string         `json:"supportUrl" validate:"omitempty,url,max=255"`
+	SupportPhone  *string         `json:"supportPhone" validate:"omitempty,max=50"`
+	SupportEmail  *string         `json:"supportEmail" validate:"omitempty,email,max=255"`
+	AccountNumber *string         `json:"accountNumber" validate:"omitempty,max=100"`
+	Notes         *string         `json:"notes" validate:"omitempty,max=2000"`
+	Address       *AddressPayload `json:"address"`
+}
+
+type CreateContactPayload struct {
+	Name  string `json:"name" validate:"required,max=150"`
+	Email string `json:"email" validate:"omitempty,email,max=255"`
+	Phone string `json:"phone" validate:"max=50"`
+	Role  string `json:"role" validate:"max=100"`
+	Notes string `json:"notes" validate:"max=1000"`
+}
+
+type UpdateContactPayload struct {
+	Name  *string `json:"name" validate:"omitempty,min=1,max=150"`
+	Email *string `json:"email" validate:"omitempty,email,max=255"`
+	Phone *string `json:"phone" validate:"omitempty,max=50"`
+	Role  *string `json:"role" validate:"omitempty,max=100"`
+	Notes *string `json:"notes" validate:"omitempty,max=1000"`
+}
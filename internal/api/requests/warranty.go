@@ -0,0 +1,28 @@
+package requests
+
+import "time"
+
+type CreateWarrantyPayload struct {
+	Kind           string    `json:"kind" validate:"omitempty,oneof=WARRANTY SUPPORT"`
+	AssetID        int64     `json:"assetId" validate:"required"`
+	SupplierID     *int64    `json:"supplierId"`
+	ManufacturerID *int64    `json:"manufacturerId"`
+	ContractNumber string    `json:"contractNumber" validate:"max=100"`
+	StartDate      time.Time `json:"startDate" validate:"required"`
+	EndDate        time.Time `json:"endDate" validate:"required,gtfield=StartDate"`
+	SLATerms       string    `json:"slaTerms" validate:"max=1000"`
+	Cost           float64   `json:"cost" validate:"gte=0"`
+	Notes          string    `json:"notes" validate:"max=1000"`
+}
+
+type UpdateWarrantyPayload struct {
+	Kind           *string    `json:"kind" validate:"omitempty,oneof=WARRANTY SUPPORT"`
+	SupplierID     *int64     `json:"supplierId"`
+	ManufacturerID *int64     `json:"manufacturerId"`
+	ContractNumber *string    `json:"contractNumber" validate:"omitempty,max=100"`
+	StartDate      *time.Time `json:"startDate"`
+	EndDate        *time.Time `json:"endDate"`
+	SLATerms       *string    `json:"slaTerms" validate:"omitempty,max=1000"`
+	Cost           *float64   `json:"cost" validate:"omitempty,gte=0"`
+	Notes          *string    `json:"notes" validate:"omitempty,max=1000"`
+}
@@ -0,0 +1,18 @@
+package requests
+
+type CreateWebhookPayload struct {
+	Name   string   `json:"name" validate:"required,max=100"`
+	URL    string   `json:"url" validate:"required,url,max=500"`
+	Events []string `json:"events" validate:"required,min=1"`
+	// generated when left out
+	Secret string `json:"secret" validate:"omitempty,min=16,max=128"`
+	Active *bool  `json:"active"`
+}
+
+type UpdateWebhookPayload struct {
+	Name   *string  `json:"name" validate:"omitempty,max=100"`
+	URL    *string  `json:"url" validate:"omitempty,url,max=500"`
+	Events []string `json:"events" validate:"omitempty,min=1"`
+	Secret *string  `json:"secret" validate:"omitempty,min=16,max=128"`
+	Active *bool    `json:"active"`
+}
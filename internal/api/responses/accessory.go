@@ -0,0 +1,116 @@
+package responses
+
+import (
+	"time"
+
+	"github.com/knr1997/assets-management-apiserver/internal/store"
+)
+
+type AccessoryResponse struct {
+	ID                int64                    `json:"id"`
+	Name              string                   `json:"name"`
+	Category          CategoryResponse         `json:"category"`
+	Manufacturer      *ManufacturerResponse    `json:"manufacturer"`
+	Supplier          *SupplierSummary         `json:"supplier"`
+	ModelNumber       string                   `json:"modelNumber"`
+	PurchaseDate      *time.Time               `json:"purchaseDate"`
+	UnitCost          float64                  `json:"unitCost"`
+	Notes             string                   `json:"notes"`
+	TotalQuantity     int                      `json:"totalQuantity"`
+	AvailableQuantity int                      `json:"availableQuantity"`
+	Stocks            []AccessoryStockResponse `json:"stocks"`
+	CreatedAt         time.Time                `json:"createdAt"`
+	UpdatedAt         time.Time                `json:"updatedAt"`
+}
+
+type AccessoryStockResponse struct {
+	Location  string `json:"location"`
+	Quantity  int    `json:"quantity"`
+	Available int    `json:"available"`
+}
+
+type AccessorySummary struct {
+	ID   int64  `json:"id"`
+	Name string `json:"name"`
+}
+
+type AccessoryLoanResponse struct {
+	ID                  int64            `json:"id"`
+	Accessory           AccessorySummary `json:"accessory"`
+	User                UserResponse     `json:"user"`
+	Location            string           `json:"location"`
+	Quantity            int              `json:"quantity"`
+	CheckoutDate        time.Time        `json:"checkoutDate"`
+	ExpectedCheckinDate *time.Time       `json:"expectedCheckinDate"`
+	ActualReturnDate    *time.Time       `json:"actualReturnDate"`
+	Notes               string           `json:"notes"`
+}
+
+func NewAccessoryResponse(a *store.Accessory) AccessoryResponse {
+	response := AccessoryResponse{
+		ID:           a.ID,
+		Name:         a.Name,
+		Category:     NewCategoryResponse(&a.Category),
+		ModelNumber:  a.ModelNumber,
+		PurchaseDate: a.PurchaseDate,
+		UnitCost:     a.UnitCost,
+		Notes:        a.Notes,
+		Stocks:       make([]AccessoryStockResponse, len(a.Stocks)),
+		CreatedAt:    a.CreatedAt,
+		UpdatedAt:    a.UpdatedAt,
+	}
+
+	if a.Manufacturer != nil {
+		manufacturer := NewManufacturerResponse(a.Manufacturer)
+		response.Manufacturer = &manufacturer
+	}
+	if a.Supplier != nil {
+		response.Supplier = &SupplierSummary{ID: a.Supplier.ID, Name: a.Supplier.Name}
+	}
+
+	for i, stock := range a.Stocks {
+		response.Stocks[i] = AccessoryStockResponse{
+			Location:  stock.Location,
+			Quantity:  stock.Quantity,
+			Available: stock.Available,
+		}
+		response.TotalQuantity += stock.Quantity
+		response.AvailableQuantity += stock.Available
+	}
+
+	return response
+}
+
+func NewAccessoriesResponse(accessories []store.Accessory) []AccessoryResponse {
+	responses := make([]AccessoryResponse, len(accessories))
+
+	for i := range accessories {
+		responses[i] = NewAccessoryResponse(&accessories[i])
+	}
+
+	return responses
+}
+
+func NewAccessoryLoanResponse(l *store.AccessoryLoan) AccessoryLoanResponse {
+	return AccessoryLoanResponse{
+		ID:                  l.ID,
+		Accessory:           AccessorySummary{ID: l.Accessory.ID, Name: l.Accessory.Name},
+		User:                NewUserResponse(&l.User),
+		Location:            l.Location,
+		Quantity:            l.Quantity,
+		CheckoutDate:        l.CheckoutDate,
+		ExpectedCheckinDate: l.ExpectedCheckinDate,
+		ActualReturnDate:    l.ActualReturnDate,
+		Notes:               l.Notes,
+	}
+}
+
+func NewAccessoryLoansResponse(loans []store.AccessoryLoan) []AccessoryLoanResponse {
+	responses := make([]AccessoryLoanResponse, len(loans))
+
+	for i := range loans {
+		responses[i] = NewAccessoryLoanResponse(&loans[i])
+	}
+
+	return responses
+}
@@ -1,17 +1,36 @@
 package responses
 
 import (
+	"time"
+
+	"github.com/knr1997/assets-management-apiserver/internal/secret"
 	"github.com/knr1997/assets-management-apiserver/internal/store"
 )
 
 type AssetResponse struct {
-	ID           int64         `json:"id"`
-	Name         string        `json:"name"`
-	SerialNumber string        `json:"serialNumber"`
-	Tag          string        `json:"tag"`
-	Status       string        `json:"status"`
-	Model        ModelResponse `json:"model"`
-	Description  string        `json:"description"`
+	ID           int64          `json:"id"`
+	Name         string         `json:"name"`
+	SerialNumber string         `json:"serialNumber"`
+	Tag          string         `json:"tag"`
+	Status       string         `json:"status"`
+	Model        ModelResponse  `json:"model"`
+	Description  string         `json:"description"`
+	CustomFields map[string]any `json:"customFields"`
+
+	Warranty WarrantyStatusResponse `json:"warranty"`
+
+	PurchaseOrderID *int64 `json:"purchaseOrderId"`
+	InvoiceID       *int64 `json:"invoiceId"`
+
+	PurchaseCost     float64                      `json:"purchaseCost"`
+	PurchaseCurrency string                       `json:"purchaseCurrency"`
+	Components       []InstalledComponentResponse `json:"components"`
+	ComponentCost    float64                      `json:"componentCost"`
+	TotalValue       float64                      `json:"totalValue"`
+
+	UsefulLifeYears    int     `json:"usefulLifeYears"`
+	DepreciationMethod string  `json:"depreciationMethod"`
+	BookValue          float64 `json:"bookValue"`
 }
 
 func NewAssetResponse(u *store.Asset) AssetResponse {
@@ -23,7 +42,38 @@ func NewAssetResponse(u *store.Asset) AssetResponse {
 		Status:       string(u.Status),
 		Model:        NewModelResponse(&u.Model),
 		Description:  u.Description,
+		CustomFields: maskCustomFields(u.CustomFields),
+		Warranty:     NewWarrantyStatusResponse(u.Warranties),
+
+		PurchaseOrderID: u.PurchaseOrderID,
+		InvoiceID:       u.InvoiceID,
+
+		PurchaseCost:     u.PurchaseCost,
+		PurchaseCurrency: u.PurchaseCurrency,
+		Components:       NewInstalledComponentsResponse(u.Components),
+		ComponentCost:    u.ComponentCost(),
+		TotalValue:       u.TotalValue(),
+
+		UsefulLifeYears:    u.UsefulLifeYears,
+		DepreciationMethod: string(u.DepreciationMethod),
+		BookValue:          u.BookValue(time.Now()),
+	}
+}
+
+// maskCustomFields hides encrypted values; handlers that may reveal them
+// replace the mask with the opened value.
+func maskCustomFields(values store.CustomFieldValues) map[string]any {
+	masked := make(map[string]any, len(values))
+
+	for k, v := range values {
+		if s, ok := v.(string); ok && secret.IsSealed(s) {
+			masked[k] = "********"
+			continue
+		}
+		masked[k] = v
 	}
+
+	return masked
 }
 
 func NewAssetsResponse(assets []store.Asset) []AssetResponse {
@@ -0,0 +1,59 @@
+package responses
+
+import (
+	"time"
+
+	"github.com/knr1997/assets-management-apiserver/internal/store"
+)
+
+type AssetAssignmentResponse struct {
+	ID         int64        `json:"id"`
+	Asset      AssetSummary `json:"asset"`
+	User       UserResponse `json:"user"`
+	AssignedAt time.Time    `json:"assignedAt"`
+	ReturnedAt *time.Time   `json:"returnedAt"`
+	Notes      string       `json:"notes"`
+
+	AcceptanceStatus string     `json:"acceptanceStatus"`
+	AcceptedAt       *time.Time `json:"acceptedAt"`
+	AcceptedName     string     `json:"acceptedName"`
+	AcceptedIP       string     `json:"acceptedIp"`
+	Signed           bool       `json:"signed"`
+	ReceiptID        *int64     `json:"receiptId"`
+	// the policy that was accepted, or that is to be accepted
+	EULA string `json:"eula"`
+}
+
+func NewAssetAssignmentResponse(a *store.AssetAssignment) AssetAssignmentResponse {
+	response := AssetAssignmentResponse{
+		ID:               a.ID,
+		Asset:            AssetSummary{ID: a.Asset.ID, Name: a.Asset.Name, Tag: a.Asset.Tag},
+		User:             NewUserResponse(&a.User),
+		AssignedAt:       a.AssignedAt,
+		ReturnedAt:       a.ReturnedAt,
+		Notes:            a.Notes,
+		AcceptanceStatus: string(a.AcceptanceStatus),
+		AcceptedAt:       a.AcceptedAt,
+		AcceptedName:     a.AcceptedName,
+		AcceptedIP:       a.AcceptedIP,
+		Signed:           len(a.Signature) > 0,
+		ReceiptID:        a.ReceiptID,
+		EULA:             a.EULA,
+	}
+
+	if a.AcceptanceStatus == store.AcceptancePending {
+		response.EULA = a.Asset.Model.Category.EULA
+	}
+
+	return response
+}
+
+func NewAssetAssignmentsResponse(assignments []store.AssetAssignment) []AssetAssignmentResponse {
+	responses := make([]AssetAssignmentResponse, len(assignments))
+
+	for i := range assignments {
+		responses[i] = NewAssetAssignmentResponse(&assignments[i])
+	}
+
+	return responses
+}
@@ -0,0 +1,51 @@
+package responses
+
+import (
+	"time"
+
+	"github.com/knr1997/assets-management-apiserver/internal/store"
+)
+
+type AssetLoanResponse struct {
+	ID                  int64        `json:"id"`
+	Asset               AssetSummary `json:"asset"`
+	User                UserResponse `json:"user"`
+	CheckoutDate        time.Time    `json:"checkoutDate"`
+	ExpectedCheckinDate *time.Time   `json:"expectedCheckinDate"`
+	ActualReturnDate    *time.Time   `json:"actualReturnDate"`
+	Status              string       `json:"status"`
+	Notes               string       `json:"notes"`
+}
+
+type AssetSummary struct {
+	ID   int64  `json:"id"`
+	Name string `json:"name"`
+	Tag  string `json:"tag"`
+}
+
+func NewAssetLoanResponse(l *store.AssetLoan) AssetLoanResponse {
+	return AssetLoanResponse{
+		ID: l.ID,
+		Asset: AssetSummary{
+			ID:   l.Asset.ID,
+			Name: l.Asset.Name,
+			Tag:  l.Asset.Tag,
+		},
+		User:                NewUserResponse(&l.User),
+		CheckoutDate:        l.CheckoutDate,
+		ExpectedCheckinDate: l.ExpectedCheckinDate,
+		ActualReturnDate:    l.ActualReturnDate,
+		Status:              string(l.Status),
+		Notes:               l.Notes,
+	}
+}
+
+func NewAssetLoansResponse(loans []store.AssetLoan) []AssetLoanResponse {
+	responses := make([]AssetLoanResponse, len(loans))
+
+	for i := range loans {
+		responses[i] = NewAssetLoanResponse(&loans[i])
+	}
+
+	return responses
+}
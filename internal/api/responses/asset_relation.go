@@ -0,0 +1,96 @@
+package responses
+
+import (
+	"time"
+
+	"github.com/knr1997/assets-management-apiserver/internal/store"
+)
+
+type AssetRelationResponse struct {
+	ID        int64        `json:"id"`
+	Type      string       `json:"type"`
+	From      AssetSummary `json:"from"`
+	To        AssetSummary `json:"to"`
+	Notes     string       `json:"notes"`
+	CreatedAt time.Time    `json:"createdAt"`
+}
+
+// AssetTreeNode is an asset in a walk over relations, with the relation it
+// was reached by and the assets reached from it.
+type AssetTreeNode struct {
+	Asset      AssetSummary    `json:"asset"`
+	RelationID int64           `json:"relationId,omitempty"`
+	Type       string          `json:"type,omitempty"`
+	Children   []AssetTreeNode `json:"children"`
+}
+
+func newAssetSummary(a *store.Asset) AssetSummary {
+	return AssetSummary{ID: a.ID, Name: a.Name, Tag: a.Tag}
+}
+
+func NewAssetRelationResponse(r *store.AssetRelation) AssetRelationResponse {
+	return AssetRelationResponse{
+		ID:        r.ID,
+		Type:      string(r.Type),
+		From:      newAssetSummary(&r.FromAsset),
+		To:        newAssetSummary(&r.ToAsset),
+		Notes:     r.Notes,
+		CreatedAt: r.CreatedAt,
+	}
+}
+
+func NewAssetRelationsResponse(relations []store.AssetRelation) []AssetRelationResponse {
+	responses := make([]AssetRelationResponse, len(relations))
+
+	for i := range relations {
+		responses[i] = NewAssetRelationResponse(&relations[i])
+	}
+
+	return responses
+}
+
+// NewAssetTreeResponse nests the relations found by a walk from root. With
+// reverse the relations were followed against their direction, so a node's
+// children are the assets on the from side.
+func NewAssetTreeResponse(root *store.Asset, relations []store.AssetRelation, reverse bool) AssetTreeNode {
+	next := make(map[int64][]*store.AssetRelation)
+	for i := range relations {
+		near := relations[i].FromAssetID
+		if reverse {
+			near = relations[i].ToAssetID
+		}
+		next[near] = append(next[near], &relations[i])
+	}
+
+	var children func(assetID int64, path map[int64]bool) []AssetTreeNode
+	children = func(assetID int64, path map[int64]bool) []AssetTreeNode {
+		nodes := []AssetTreeNode{}
+
+		path[assetID] = true
+		defer delete(path, assetID)
+
+		for _, r := range next[assetID] {
+			far := &r.ToAsset
+			if reverse {
+				far = &r.FromAsset
+			}
+			if path[far.ID] {
+				continue
+			}
+
+			nodes = append(nodes, AssetTreeNode{
+				Asset:      newAssetSummary(far),
+				RelationID: r.ID,
+				Type:       string(r.Type),
+				Children:   children(far.ID, path),
+			})
+		}
+
+		return nodes
+	}
+
+	return AssetTreeNode{
+		Asset:    newAssetSummary(root),
+		Children: children(root.ID, map[int64]bool{}),
+	}
+}
@@ -0,0 +1,129 @@
+package responses
+
+import (
+	"time"
+
+	"github.com/knr1997/assets-management-apiserver/internal/store"
+)
+
+type ApprovalStepResponse struct {
+	ID       int64   `json:"id"`
+	Position int     `json:"position"`
+	Name     string  `json:"name"`
+	Approver string  `json:"approver"`
+	RoleID   *int64  `json:"roleId"`
+	Role     string  `json:"role,omitempty"`
+	MinCost  float64 `json:"minCost"`
+}
+
+type AssetRequestApprovalResponse struct {
+	Position  int           `json:"position"`
+	Name      string        `json:"name"`
+	Approver  *UserResponse `json:"approver"`
+	Role      string        `json:"role,omitempty"`
+	Decision  string        `json:"decision"`
+	DecidedBy *UserResponse `json:"decidedBy"`
+	DecidedAt *time.Time    `json:"decidedAt"`
+	Comment   string        `json:"comment"`
+}
+
+type AssetRequestResponse struct {
+	ID            int64                          `json:"id"`
+	Requester     UserResponse                   `json:"requester"`
+	DepartmentID  *int64                         `json:"departmentId"`
+	Department    string                         `json:"department,omitempty"`
+	Model         ModelResponse                  `json:"model"`
+	Justification string                         `json:"justification"`
+	EstimatedCost float64                        `json:"estimatedCost"`
+	Status        string                         `json:"status"`
+	Approvals     []AssetRequestApprovalResponse `json:"approvals"`
+	Asset         *AssetSummary                  `json:"asset"`
+	AssignmentID  *int64                         `json:"assignmentId"`
+	FulfilledAt   *time.Time                     `json:"fulfilledAt"`
+	CreatedAt     time.Time                      `json:"createdAt"`
+	UpdatedAt     time.Time                      `json:"updatedAt"`
+}
+
+func NewApprovalStepResponse(s *store.ApprovalStep) ApprovalStepResponse {
+	response := ApprovalStepResponse{
+		ID:       s.ID,
+		Position: s.Position,
+		Name:     s.Name,
+		Approver: string(s.Approver),
+		RoleID:   s.RoleID,
+		MinCost:  s.MinCost,
+	}
+	if s.Role != nil {
+		response.Role = s.Role.Name
+	}
+
+	return response
+}
+
+func NewApprovalStepsResponse(steps []store.ApprovalStep) []ApprovalStepResponse {
+	responses := make([]ApprovalStepResponse, len(steps))
+
+	for i := range steps {
+		responses[i] = NewApprovalStepResponse(&steps[i])
+	}
+
+	return responses
+}
+
+func NewAssetRequestResponse(r *store.AssetRequest) AssetRequestResponse {
+	response := AssetRequestResponse{
+		ID:            r.ID,
+		Requester:     NewUserResponse(&r.Requester),
+		DepartmentID:  r.DepartmentID,
+		Model:         NewModelResponse(&r.Model),
+		Justification: r.Justification,
+		EstimatedCost: r.EstimatedCost,
+		Status:        string(r.Status),
+		Approvals:     make([]AssetRequestApprovalResponse, len(r.Approvals)),
+		AssignmentID:  r.AssignmentID,
+		FulfilledAt:   r.FulfilledAt,
+		CreatedAt:     r.CreatedAt,
+		UpdatedAt:     r.UpdatedAt,
+	}
+
+	if r.Department != nil {
+		response.Department = r.Department.Name
+	}
+	if r.Asset != nil {
+		response.Asset = &AssetSummary{ID: r.Asset.ID, Name: r.Asset.Name, Tag: r.Asset.Tag}
+	}
+
+	for i, a := range r.Approvals {
+		approval := AssetRequestApprovalResponse{
+			Position:  a.Position,
+			Name:      a.Name,
+			Decision:  string(a.Decision),
+			DecidedAt: a.DecidedAt,
+			Comment:   a.Comment,
+		}
+		if a.Approver != nil {
+			approver := NewUserResponse(a.Approver)
+			approval.Approver = &approver
+		}
+		if a.Role != nil {
+			approval.Role = a.Role.Name
+		}
+		if a.DecidedBy != nil {
+			decidedBy := NewUserResponse(a.DecidedBy)
+			approval.DecidedBy = &decidedBy
+		}
+		response.Approvals[i] = approval
+	}
+
+	return response
+}
+
+func NewAssetRequestsResponse(requests []store.AssetRequest) []AssetRequestResponse {
+	responses := make([]AssetRequestResponse, len(requests))
+
+	for i := range requests {
+		responses[i] = NewAssetRequestResponse(&requests[i])
+	}
+
+	return responses
+}
@@ -0,0 +1,41 @@
+package responses
+
+import (
+	"time"
+
+	"github.com/knr1997/assets-management-apiserver/internal/store"
+)
+
+type AttachmentResponse struct {
+	ID          int64     `json:"id"`
+	FileName    string    `json:"fileName"`
+	ContentType string    `json:"contentType"`
+	Size        int64     `json:"size"`
+	Checksum    string    `json:"checksum"`
+	CreatedAt   time.Time `json:"createdAt"`
+
+	// a signed download link, for attachments served through /api/files
+	URL          string     `json:"url,omitempty"`
+	URLExpiresAt *time.Time `json:"urlExpiresAt,omitempty"`
+}
+
+func NewAttachmentResponse(a *store.Attachment) AttachmentResponse {
+	return AttachmentResponse{
+		ID:          a.ID,
+		FileName:    a.FileName,
+		ContentType: a.ContentType,
+		Size:        a.Size,
+		Checksum:    a.Checksum,
+		CreatedAt:   a.CreatedAt,
+	}
+}
+
+func NewAttachmentsResponse(attachments []store.Attachment) []AttachmentResponse {
+	responses := make([]AttachmentResponse, len(attachments))
+
+	for i := range attachments {
+		responses[i] = NewAttachmentResponse(&attachments[i])
+	}
+
+	return responses
+}
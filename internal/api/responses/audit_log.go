@@ -0,0 +1,47 @@
+package responses
+
+import (
+	"time"
+
+	"github.com/knr1997/assets-management-apiserver/internal/store"
+)
+
+type AuditLogResponse struct {
+	ID        int64     `json:"id"`
+	TableName string    `json:"tableName"`
+	RecordID  string    `json:"recordId"`
+	Operation string    `json:"operation"`
+	OldValue  *string   `json:"oldValue"`
+	NewValue  *string   `json:"newValue"`
+	Diff      *string   `json:"diff"`
+	ChangedAt time.Time `json:"changedAt"`
+	ChangedBy string    `json:"changedBy"`
+	IPAddress *string   `json:"ipAddress"`
+	RequestID *string   `json:"requestId"`
+}
+
+func NewAuditLogResponse(l *store.AuditLog) AuditLogResponse {
+	return AuditLogResponse{
+		ID:        l.ID,
+		TableName: l.TableName,
+		RecordID:  l.RecordID,
+		Operation: l.Operation,
+		OldValue:  l.OldValue,
+		NewValue:  l.NewValue,
+		Diff:      l.Diff,
+		ChangedAt: l.ChangedAt,
+		ChangedBy: l.ChangedBy,
+		IPAddress: l.IPAddress,
+		RequestID: l.RequestID,
+	}
+}
+
+func NewAuditLogsResponse(logs []store.AuditLog) []AuditLogResponse {
+	responses := make([]AuditLogResponse, len(logs))
+
+	for i := range logs {
+		responses[i] = NewAuditLogResponse(&logs[i])
+	}
+
+	return responses
+}
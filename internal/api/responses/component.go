@@ -0,0 +1,141 @@
+package responses
+
+import (
+	"time"
+
+	"github.com/knr1997/assets-management-apiserver/internal/store"
+)
+
+type ComponentResponse struct {
+	ID           int64                 `json:"id"`
+	Name         string                `json:"name"`
+	Category     CategoryResponse      `json:"category"`
+	Manufacturer *ManufacturerResponse `json:"manufacturer"`
+	Supplier     *SupplierSummary      `json:"supplier"`
+	PartNumber   string                `json:"partNumber"`
+	SerialNumber string                `json:"serialNumber"`
+	Quantity     int                   `json:"quantity"`
+	Available    int                   `json:"available"`
+	UnitCost     float64               `json:"unitCost"`
+	PurchaseDate *time.Time            `json:"purchaseDate"`
+	Location     string                `json:"location"`
+	Notes        string                `json:"notes"`
+	CreatedAt    time.Time             `json:"createdAt"`
+	UpdatedAt    time.Time             `json:"updatedAt"`
+}
+
+type ComponentSummary struct {
+	ID           int64   `json:"id"`
+	Name         string  `json:"name"`
+	PartNumber   string  `json:"partNumber"`
+	SerialNumber string  `json:"serialNumber"`
+	UnitCost     float64 `json:"unitCost"`
+}
+
+// InstalledComponentResponse is a component as listed on the asset it is
+// installed in.
+type InstalledComponentResponse struct {
+	InstallID   int64            `json:"installId"`
+	Component   ComponentSummary `json:"component"`
+	Quantity    int              `json:"quantity"`
+	Cost        float64          `json:"cost"`
+	InstalledAt time.Time        `json:"installedAt"`
+	Notes       string           `json:"notes"`
+}
+
+type ComponentInstallResponse struct {
+	ID          int64            `json:"id"`
+	Component   ComponentSummary `json:"component"`
+	Asset       AssetSummary     `json:"asset"`
+	Quantity    int              `json:"quantity"`
+	InstalledAt time.Time        `json:"installedAt"`
+	RemovedAt   *time.Time       `json:"removedAt"`
+	Notes       string           `json:"notes"`
+}
+
+func NewComponentResponse(c *store.Component) ComponentResponse {
+	response := ComponentResponse{
+		ID:           c.ID,
+		Name:         c.Name,
+		Category:     NewCategoryResponse(&c.Category),
+		PartNumber:   c.PartNumber,
+		SerialNumber: c.SerialNumber,
+		Quantity:     c.Quantity,
+		Available:    c.Available,
+		UnitCost:     c.UnitCost,
+		PurchaseDate: c.PurchaseDate,
+		Location:     c.Location,
+		Notes:        c.Notes,
+		CreatedAt:    c.CreatedAt,
+		UpdatedAt:    c.UpdatedAt,
+	}
+
+	if c.Manufacturer != nil {
+		manufacturer := NewManufacturerResponse(c.Manufacturer)
+		response.Manufacturer = &manufacturer
+	}
+	if c.Supplier != nil {
+		response.Supplier = &SupplierSummary{ID: c.Supplier.ID, Name: c.Supplier.Name}
+	}
+
+	return response
+}
+
+func NewComponentsResponse(components []store.Component) []ComponentResponse {
+	responses := make([]ComponentResponse, len(components))
+
+	for i := range components {
+		responses[i] = NewComponentResponse(&components[i])
+	}
+
+	return responses
+}
+
+func newComponentSummary(c *store.Component) ComponentSummary {
+	return ComponentSummary{
+		ID:           c.ID,
+		Name:         c.Name,
+		PartNumber:   c.PartNumber,
+		SerialNumber: c.SerialNumber,
+		UnitCost:     c.UnitCost,
+	}
+}
+
+func NewInstalledComponentsResponse(installs []store.ComponentInstall) []InstalledComponentResponse {
+	responses := make([]InstalledComponentResponse, len(installs))
+
+	for i := range installs {
+		responses[i] = InstalledComponentResponse{
+			InstallID:   installs[i].ID,
+			Component:   newComponentSummary(&installs[i].Component),
+			Quantity:    installs[i].Quantity,
+			Cost:        installs[i].Cost(),
+			InstalledAt: installs[i].InstalledAt,
+			Notes:       installs[i].Notes,
+		}
+	}
+
+	return responses
+}
+
+func NewComponentInstallResponse(i *store.ComponentInstall) ComponentInstallResponse {
+	return ComponentInstallResponse{
+		ID:          i.ID,
+		Component:   newComponentSummary(&i.Component),
+		Asset:       AssetSummary{ID: i.Asset.ID, Name: i.Asset.Name, Tag: i.Asset.Tag},
+		Quantity:    i.Quantity,
+		InstalledAt: i.InstalledAt,
+		RemovedAt:   i.RemovedAt,
+		Notes:       i.Notes,
+	}
+}
+
+func NewComponentInstallsResponse(installs []store.ComponentInstall) []ComponentInstallResponse {
+	responses := make([]ComponentInstallResponse, len(installs))
+
+	for i := range installs {
+		responses[i] = NewComponentInstallResponse(&installs[i])
+	}
+
+	return responses
+}
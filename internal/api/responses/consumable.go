@@ -0,0 +1,178 @@
+package responses
+
+import (
+	"time"
+
+	"github.com/knr1997/assets-management-apiserver/internal/store"
+)
+
+type ConsumableResponse struct {
+	ID            int64                     `json:"id"`
+	Name          string                    `json:"name"`
+	Category      CategoryResponse          `json:"category"`
+	Manufacturer  *ManufacturerResponse     `json:"manufacturer"`
+	ItemNumber    string                    `json:"itemNumber"`
+	UnitCost      float64                   `json:"unitCost"`
+	Notes         string                    `json:"notes"`
+	TotalQuantity int                       `json:"totalQuantity"`
+	Stocks        []ConsumableStockResponse `json:"stocks"`
+	CreatedAt     time.Time                 `json:"createdAt"`
+	UpdatedAt     time.Time                 `json:"updatedAt"`
+}
+
+type ConsumableSummary struct {
+	ID   int64  `json:"id"`
+	Name string `json:"name"`
+}
+
+type ConsumableStockResponse struct {
+	ID          int64              `json:"id"`
+	Consumable  *ConsumableSummary `json:"consumable,omitempty"`
+	Location    string             `json:"location"`
+	Quantity    int                `json:"quantity"`
+	MinQuantity int                `json:"minQuantity"`
+	Low         bool               `json:"low"`
+	UpdatedAt   time.Time          `json:"updatedAt"`
+}
+
+type DepartmentSummary struct {
+	ID   int64  `json:"id"`
+	Name string `json:"name"`
+}
+
+type ConsumableMovementResponse struct {
+	ID         int64              `json:"id"`
+	Kind       string             `json:"kind"`
+	Location   string             `json:"location"`
+	Quantity   int                `json:"quantity"`
+	User       *UserResponse      `json:"user"`
+	Department *DepartmentSummary `json:"department"`
+	Supplier   *SupplierSummary   `json:"supplier"`
+	UnitCost   float64            `json:"unitCost"`
+	Notes      string             `json:"notes"`
+	CreatedAt  time.Time          `json:"createdAt"`
+}
+
+type ConsumableUsageResponse struct {
+	Department *DepartmentSummary `json:"department"`
+	Month      string             `json:"month"`
+	Consumable ConsumableSummary  `json:"consumable"`
+	Quantity   int64              `json:"quantity"`
+	Cost       float64            `json:"cost"`
+}
+
+func NewConsumableResponse(c *store.Consumable) ConsumableResponse {
+	response := ConsumableResponse{
+		ID:         c.ID,
+		Name:       c.Name,
+		Category:   NewCategoryResponse(&c.Category),
+		ItemNumber: c.ItemNumber,
+		UnitCost:   c.UnitCost,
+		Notes:      c.Notes,
+		Stocks:     make([]ConsumableStockResponse, len(c.Stocks)),
+		CreatedAt:  c.CreatedAt,
+		UpdatedAt:  c.UpdatedAt,
+	}
+
+	if c.Manufacturer != nil {
+		manufacturer := NewManufacturerResponse(c.Manufacturer)
+		response.Manufacturer = &manufacturer
+	}
+
+	for i := range c.Stocks {
+		response.Stocks[i] = NewConsumableStockResponse(&c.Stocks[i])
+		response.TotalQuantity += c.Stocks[i].Quantity
+	}
+
+	return response
+}
+
+func NewConsumablesResponse(consumables []store.Consumable) []ConsumableResponse {
+	responses := make([]ConsumableResponse, len(consumables))
+
+	for i := range consumables {
+		responses[i] = NewConsumableResponse(&consumables[i])
+	}
+
+	return responses
+}
+
+func NewConsumableStockResponse(s *store.ConsumableStock) ConsumableStockResponse {
+	response := ConsumableStockResponse{
+		ID:          s.ID,
+		Location:    s.Location,
+		Quantity:    s.Quantity,
+		MinQuantity: s.MinQuantity,
+		Low:         s.Low(),
+		UpdatedAt:   s.UpdatedAt,
+	}
+
+	if s.Consumable.ID != 0 {
+		response.Consumable = &ConsumableSummary{ID: s.Consumable.ID, Name: s.Consumable.Name}
+	}
+
+	return response
+}
+
+func NewConsumableStocksResponse(stocks []store.ConsumableStock) []ConsumableStockResponse {
+	responses := make([]ConsumableStockResponse, len(stocks))
+
+	for i := range stocks {
+		responses[i] = NewConsumableStockResponse(&stocks[i])
+	}
+
+	return responses
+}
+
+func NewConsumableMovementResponse(m *store.ConsumableMovement) ConsumableMovementResponse {
+	response := ConsumableMovementResponse{
+		ID:        m.ID,
+		Kind:      string(m.Kind),
+		Location:  m.Location,
+		Quantity:  m.Quantity,
+		UnitCost:  m.UnitCost,
+		Notes:     m.Notes,
+		CreatedAt: m.CreatedAt,
+	}
+
+	if m.User != nil {
+		user := NewUserResponse(m.User)
+		response.User = &user
+	}
+	if m.Department != nil {
+		response.Department = &DepartmentSummary{ID: m.Department.ID, Name: m.Department.Name}
+	}
+	if m.Supplier != nil {
+		response.Supplier = &SupplierSummary{ID: m.Supplier.ID, Name: m.Supplier.Name}
+	}
+
+	return response
+}
+
+func NewConsumableMovementsResponse(movements []store.ConsumableMovement) []ConsumableMovementResponse {
+	responses := make([]ConsumableMovementResponse, len(movements))
+
+	for i := range movements {
+		responses[i] = NewConsumableMovementResponse(&movements[i])
+	}
+
+	return responses
+}
+
+func NewConsumableUsageResponse(usage []store.ConsumableUsage) []ConsumableUsageResponse {
+	responses := make([]ConsumableUsageResponse, len(usage))
+
+	for i, u := range usage {
+		responses[i] = ConsumableUsageResponse{
+			Month:      u.Month.Format("2006-01"),
+			Consumable: ConsumableSummary{ID: u.ConsumableID, Name: u.ConsumableName},
+			Quantity:   u.Quantity,
+			Cost:       u.Cost,
+		}
+		if u.DepartmentID != nil {
+			responses[i].Department = &DepartmentSummary{ID: *u.DepartmentID, Name: u.DepartmentName}
+		}
+	}
+
+	return responses
+}
@@ -0,0 +1,63 @@
+package responses
+
+import "github.com/knr1997/assets-management-apiserver/internal/store"
+
+type CustomFieldResponse struct {
+	Key      string   `json:"key"`
+	Label    string   `json:"label"`
+	Type     string   `json:"type"`
+	Required bool     `json:"required"`
+	Unique   bool     `json:"unique"`
+	Options  []string `json:"options,omitempty"`
+	Pattern  string   `json:"pattern,omitempty"`
+}
+
+type CustomFieldSetResponse struct {
+	ID         int64                 `json:"id"`
+	Name       string                `json:"name"`
+	CategoryID *int64                `json:"categoryId"`
+	ModelID    *int64                `json:"modelId"`
+	Fields     []CustomFieldResponse `json:"fields"`
+}
+
+func NewCustomFieldResponse(f *store.CustomField) CustomFieldResponse {
+	return CustomFieldResponse{
+		Key:      f.Key,
+		Label:    f.Label,
+		Type:     string(f.Type),
+		Required: f.Required,
+		Unique:   f.Unique,
+		Options:  f.Options,
+		Pattern:  f.Pattern,
+	}
+}
+
+func NewCustomFieldsResponse(fields []store.CustomField) []CustomFieldResponse {
+	responses := make([]CustomFieldResponse, len(fields))
+
+	for i := range fields {
+		responses[i] = NewCustomFieldResponse(&fields[i])
+	}
+
+	return responses
+}
+
+func NewCustomFieldSetResponse(s *store.CustomFieldSet) CustomFieldSetResponse {
+	return CustomFieldSetResponse{
+		ID:         s.ID,
+		Name:       s.Name,
+		CategoryID: s.CategoryID,
+		ModelID:    s.ModelID,
+		Fields:     NewCustomFieldsResponse(s.Fields),
+	}
+}
+
+func NewCustomFieldSetsResponse(sets []store.CustomFieldSet) []CustomFieldSetResponse {
+	responses := make([]CustomFieldSetResponse, len(sets))
+
+	for i := range sets {
+		responses[i] = NewCustomFieldSetResponse(&sets[i])
+	}
+
+	return responses
+}
@@ -0,0 +1,107 @@
+package responses
+
+import (
+	"time"
+
+	"github.com/knr1997/assets-management-apiserver/internal/store"
+)
+
+type DisposalResponse struct {
+	ID              int64                `json:"id"`
+	Asset           AssetSummary         `json:"asset"`
+	Method          string               `json:"method"`
+	Status          string               `json:"status"`
+	Reason          string               `json:"reason"`
+	RequestedBy     *UserResponse        `json:"requestedBy"`
+	DecidedBy       *UserResponse        `json:"decidedBy"`
+	DecidedAt       *time.Time           `json:"decidedAt"`
+	DecisionComment string               `json:"decisionComment"`
+	DataWipeNeeded  bool                 `json:"dataWipeNeeded"`
+	DataWipedAt     *time.Time           `json:"dataWipedAt"`
+	DataWipeMethod  string               `json:"dataWipeMethod"`
+	DataWipedBy     *UserResponse        `json:"dataWipedBy"`
+	Recipient       string               `json:"recipient"`
+	Reference       string               `json:"reference"`
+	DisposedAt      *time.Time           `json:"disposedAt"`
+	Proceeds        float64              `json:"proceeds"`
+	BookValue       float64              `json:"bookValue"`
+	GainLoss        float64              `json:"gainLoss"`
+	CompletedBy     *UserResponse        `json:"completedBy"`
+	Certificates    []AttachmentResponse `json:"certificates"`
+	CreatedAt       time.Time            `json:"createdAt"`
+	UpdatedAt       time.Time            `json:"updatedAt"`
+}
+
+type DisposalSummaryResponse struct {
+	Method    string  `json:"method"`
+	Count     int64   `json:"count"`
+	BookValue float64 `json:"bookValue"`
+	Proceeds  float64 `json:"proceeds"`
+	GainLoss  float64 `json:"gainLoss"`
+}
+
+func optionalUser(u *store.User) *UserResponse {
+	if u == nil {
+		return nil
+	}
+	response := NewUserResponse(u)
+	return &response
+}
+
+func NewDisposalResponse(d *store.Disposal) DisposalResponse {
+	return DisposalResponse{
+		ID: d.ID,
+		Asset: AssetSummary{
+			ID:   d.Asset.ID,
+			Name: d.Asset.Name,
+			Tag:  d.Asset.Tag,
+		},
+		Method:          string(d.Method),
+		Status:          string(d.Status),
+		Reason:          d.Reason,
+		RequestedBy:     optionalUser(d.RequestedBy),
+		DecidedBy:       optionalUser(d.DecidedBy),
+		DecidedAt:       d.DecidedAt,
+		DecisionComment: d.DecisionComment,
+		DataWipeNeeded:  d.Method.NeedsDataWipe(),
+		DataWipedAt:     d.DataWipedAt,
+		DataWipeMethod:  d.DataWipeMethod,
+		DataWipedBy:     optionalUser(d.DataWipedBy),
+		Recipient:       d.Recipient,
+		Reference:       d.Reference,
+		DisposedAt:      d.DisposedAt,
+		Proceeds:        d.Proceeds,
+		BookValue:       d.BookValue,
+		GainLoss:        d.GainLoss,
+		CompletedBy:     optionalUser(d.CompletedBy),
+		Certificates:    NewAttachmentsResponse(d.Certificates),
+		CreatedAt:       d.CreatedAt,
+		UpdatedAt:       d.UpdatedAt,
+	}
+}
+
+func NewDisposalsResponse(disposals []store.Disposal) []DisposalResponse {
+	responses := make([]DisposalResponse, len(disposals))
+
+	for i := range disposals {
+		responses[i] = NewDisposalResponse(&disposals[i])
+	}
+
+	return responses
+}
+
+func NewDisposalSummariesResponse(summaries []store.DisposalSummary) []DisposalSummaryResponse {
+	responses := make([]DisposalSummaryResponse, len(summaries))
+
+	for i, s := range summaries {
+		responses[i] = DisposalSummaryResponse{
+			Method:    string(s.Method),
+			Count:     s.Count,
+			BookValue: s.BookValue,
+			Proceeds:  s.Proceeds,
+			GainLoss:  s.GainLoss,
+		}
+	}
+
+	return responses
+}
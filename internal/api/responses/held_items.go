@@ -0,0 +1,18 @@
+package responses
+
+import "github.com/knr1997/assets-management-apiserver/internal/store"
+
+// HeldItemsResponse lists everything checked out to a user.
+type HeldItemsResponse struct {
+	Assets      []AssetLoanResponse     `json:"assets"`
+	Accessories []AccessoryLoanResponse `json:"accessories"`
+	Licenses    []LicenseSeatResponse   `json:"licenses"`
+}
+
+func NewHeldItemsResponse(assets []store.AssetLoan, accessories []store.AccessoryLoan, licenses []store.LicenseSeat) HeldItemsResponse {
+	return HeldItemsResponse{
+		Assets:      NewAssetLoansResponse(assets),
+		Accessories: NewAccessoryLoansResponse(accessories),
+		Licenses:    NewLicenseSeatsResponse(licenses),
+	}
+}
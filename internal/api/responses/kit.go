@@ -0,0 +1,76 @@
+package responses
+
+import (
+	"time"
+
+	"github.com/knr1997/assets-management-apiserver/internal/store"
+)
+
+type KitResponse struct {
+	ID          int64             `json:"id"`
+	Name        string            `json:"name"`
+	Description string            `json:"description"`
+	Items       []KitItemResponse `json:"items"`
+	CreatedAt   time.Time         `json:"createdAt"`
+	UpdatedAt   time.Time         `json:"updatedAt"`
+}
+
+type KitItemResponse struct {
+	ID         int64              `json:"id"`
+	Model      *ModelResponse     `json:"model,omitempty"`
+	Accessory  *AccessorySummary  `json:"accessory,omitempty"`
+	Consumable *ConsumableSummary `json:"consumable,omitempty"`
+	Quantity   int                `json:"quantity"`
+}
+
+// KitCheckoutResponse lists what a kit checkout handed out.
+type KitCheckoutResponse struct {
+	Assets      []AssetLoanResponse          `json:"assets"`
+	Accessories []AccessoryLoanResponse      `json:"accessories"`
+	Consumables []ConsumableMovementResponse `json:"consumables"`
+}
+
+func NewKitResponse(k *store.Kit) KitResponse {
+	response := KitResponse{
+		ID:          k.ID,
+		Name:        k.Name,
+		Description: k.Description,
+		Items:       make([]KitItemResponse, len(k.Items)),
+		CreatedAt:   k.CreatedAt,
+		UpdatedAt:   k.UpdatedAt,
+	}
+
+	for i, item := range k.Items {
+		response.Items[i] = KitItemResponse{ID: item.ID, Quantity: item.Quantity}
+
+		switch {
+		case item.Model != nil:
+			model := NewModelResponse(item.Model)
+			response.Items[i].Model = &model
+		case item.Accessory != nil:
+			response.Items[i].Accessory = &AccessorySummary{ID: item.Accessory.ID, Name: item.Accessory.Name}
+		case item.Consumable != nil:
+			response.Items[i].Consumable = &ConsumableSummary{ID: item.Consumable.ID, Name: item.Consumable.Name}
+		}
+	}
+
+	return response
+}
+
+func NewKitsResponse(kits []store.Kit) []KitResponse {
+	responses := make([]KitResponse, len(kits))
+
+	for i := range kits {
+		responses[i] = NewKitResponse(&kits[i])
+	}
+
+	return responses
+}
+
+func NewKitCheckoutResponse(c *store.KitCheckout) KitCheckoutResponse {
+	return KitCheckoutResponse{
+		Assets:      NewAssetLoansResponse(c.AssetLoans),
+		Accessories: NewAccessoryLoansResponse(c.AccessoryLoans),
+		Consumables: NewConsumableMovementsResponse(c.ConsumableMovements),
+	}
+}
@@ -0,0 +1,152 @@
+package responses
+
+import (
+	"time"
+
+	"github.com/knr1997/assets-management-apiserver/internal/store"
+)
+
+type LicenseResponse struct {
+	ID   int64  `json:"id"`
+	Name string `json:"name"`
+	// only filled in when a single license is fetched
+	ProductKey     string                `json:"productKey,omitempty"`
+	HasProductKey  bool                  `json:"hasProductKey"`
+	LicensedTo     string                `json:"licensedTo"`
+	Manufacturer   *ManufacturerResponse `json:"manufacturer"`
+	Supplier       *SupplierSummary      `json:"supplier"`
+	Seats          int                   `json:"seats"`
+	SeatsUsed      int                   `json:"seatsUsed"`
+	SeatsAvailable int                   `json:"seatsAvailable"`
+	PurchaseDate   *time.Time            `json:"purchaseDate"`
+	PurchaseCost   float64               `json:"purchaseCost"`
+	ExpiresAt      *time.Time            `json:"expiresAt"`
+	Notes          string                `json:"notes"`
+	Status         string                `json:"status"`
+	CreatedAt      time.Time             `json:"createdAt"`
+	UpdatedAt      time.Time             `json:"updatedAt"`
+}
+
+type LicenseSeatResponse struct {
+	ID           int64           `json:"id"`
+	LicenseID    int64           `json:"licenseId"`
+	License      *LicenseSummary `json:"license,omitempty"`
+	User         *UserResponse   `json:"user"`
+	Asset        *AssetSummary   `json:"asset"`
+	CheckedOutAt time.Time       `json:"checkedOutAt"`
+	CheckedInAt  *time.Time      `json:"checkedInAt"`
+	Reclaimed    bool            `json:"reclaimed"`
+	Notes        string          `json:"notes"`
+}
+
+type LicenseSummary struct {
+	ID   int64  `json:"id"`
+	Name string `json:"name"`
+}
+
+// LicenseComplianceResponse compares purchased with assigned seats of one
+// license.
+type LicenseComplianceResponse struct {
+	ID             int64      `json:"id"`
+	Name           string     `json:"name"`
+	Seats          int        `json:"seats"`
+	SeatsUsed      int        `json:"seatsUsed"`
+	SeatsAvailable int        `json:"seatsAvailable"`
+	Shortfall      int        `json:"shortfall"`
+	ExpiresAt      *time.Time `json:"expiresAt"`
+	Status         string     `json:"status"`
+}
+
+func NewLicenseResponse(l *store.License) LicenseResponse {
+	response := LicenseResponse{
+		ID:             l.ID,
+		Name:           l.Name,
+		HasProductKey:  l.ProductKey != "",
+		LicensedTo:     l.LicensedTo,
+		Seats:          l.Seats,
+		SeatsUsed:      l.SeatsUsed,
+		SeatsAvailable: l.SeatsAvailable(),
+		PurchaseDate:   l.PurchaseDate,
+		PurchaseCost:   l.PurchaseCost,
+		ExpiresAt:      l.ExpiresAt,
+		Notes:          l.Notes,
+		Status:         string(l.ComplianceStatus(time.Now())),
+		CreatedAt:      l.CreatedAt,
+		UpdatedAt:      l.UpdatedAt,
+	}
+
+	if l.Manufacturer != nil {
+		manufacturer := NewManufacturerResponse(l.Manufacturer)
+		response.Manufacturer = &manufacturer
+	}
+	if l.Supplier != nil {
+		response.Supplier = &SupplierSummary{ID: l.Supplier.ID, Name: l.Supplier.Name}
+	}
+
+	return response
+}
+
+func NewLicensesResponse(licenses []store.License) []LicenseResponse {
+	responses := make([]LicenseResponse, len(licenses))
+
+	for i := range licenses {
+		responses[i] = NewLicenseResponse(&licenses[i])
+	}
+
+	return responses
+}
+
+func NewLicenseSeatResponse(s *store.LicenseSeat) LicenseSeatResponse {
+	response := LicenseSeatResponse{
+		ID:           s.ID,
+		LicenseID:    s.LicenseID,
+		CheckedOutAt: s.CheckedOutAt,
+		CheckedInAt:  s.CheckedInAt,
+		Reclaimed:    s.Reclaimed,
+		Notes:        s.Notes,
+	}
+
+	if s.License.ID != 0 {
+		response.License = &LicenseSummary{ID: s.License.ID, Name: s.License.Name}
+	}
+	if s.User != nil {
+		user := NewUserResponse(s.User)
+		response.User = &user
+	}
+	if s.Asset != nil {
+		response.Asset = &AssetSummary{ID: s.Asset.ID, Name: s.Asset.Name, Tag: s.Asset.Tag}
+	}
+
+	return response
+}
+
+func NewLicenseSeatsResponse(seats []store.LicenseSeat) []LicenseSeatResponse {
+	responses := make([]LicenseSeatResponse, len(seats))
+
+	for i := range seats {
+		responses[i] = NewLicenseSeatResponse(&seats[i])
+	}
+
+	return responses
+}
+
+func NewLicenseComplianceResponse(licenses []store.License) []LicenseComplianceResponse {
+	now := time.Now()
+	responses := make([]LicenseComplianceResponse, len(licenses))
+
+	for i := range licenses {
+		l := &licenses[i]
+		responses[i] = LicenseComplianceResponse{
+			ID:             l.ID,
+			Name:           l.Name,
+			Seats:          l.Seats,
+			SeatsUsed:      l.SeatsUsed,
+			SeatsAvailable: l.SeatsAvailable(),
+			Shortfall:      max(l.SeatsUsed-l.Seats, 0),
+			ExpiresAt:      l.ExpiresAt,
+			Status:         string(l.ComplianceStatus(now)),
+		}
+	}
+
+	return responses
+}
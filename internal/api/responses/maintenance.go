@@ -0,0 +1,91 @@
+package responses
+
+import (
+	"time"
+
+	"github.com/knr1997/assets-management-apiserver/internal/store"
+)
+
+type MaintenanceResponse struct {
+	ID             int64            `json:"id"`
+	Title          string           `json:"title"`
+	Asset          AssetSummary     `json:"asset"`
+	Supplier       *SupplierSummary `json:"supplier"`
+	Type           string           `json:"type"`
+	Status         string           `json:"status"`
+	StartDate      *time.Time       `json:"startDate"`
+	CompletionDate *time.Time       `json:"completionDate"`
+	Cost           float64          `json:"cost"`
+	Notes          string           `json:"notes"`
+	CreatedAt      time.Time        `json:"createdAt"`
+	UpdatedAt      time.Time        `json:"updatedAt"`
+}
+
+type SupplierSummary struct {
+	ID   int64  `json:"id"`
+	Name string `json:"name"`
+}
+
+type MaintenanceCostResponse struct {
+	ID               int64   `json:"id"`
+	Name             string  `json:"name"`
+	AssetCount       int64   `json:"assetCount"`
+	MaintenanceCount int64   `json:"maintenanceCount"`
+	PurchaseCost     float64 `json:"purchaseCost"`
+	MaintenanceCost  float64 `json:"maintenanceCost"`
+	TotalCost        float64 `json:"totalCost"`
+}
+
+func NewMaintenanceResponse(m *store.Maintenance) MaintenanceResponse {
+	response := MaintenanceResponse{
+		ID:    m.ID,
+		Title: m.Title,
+		Asset: AssetSummary{
+			ID:   m.Asset.ID,
+			Name: m.Asset.Name,
+			Tag:  m.Asset.Tag,
+		},
+		Type:           string(m.Type),
+		Status:         string(m.Status),
+		StartDate:      m.StartDate,
+		CompletionDate: m.CompletionDate,
+		Cost:           m.Cost,
+		Notes:          m.Notes,
+		CreatedAt:      m.CreatedAt,
+		UpdatedAt:      m.UpdatedAt,
+	}
+
+	if m.Supplier != nil {
+		response.Supplier = &SupplierSummary{ID: m.Supplier.ID, Name: m.Supplier.Name}
+	}
+
+	return response
+}
+
+func NewMaintenancesResponse(maintenances []store.Maintenance) []MaintenanceResponse {
+	responses := make([]MaintenanceResponse, len(maintenances))
+
+	for i := range maintenances {
+		responses[i] = NewMaintenanceResponse(&maintenances[i])
+	}
+
+	return responses
+}
+
+func NewMaintenanceCostsResponse(costs []store.MaintenanceCost) []MaintenanceCostResponse {
+	responses := make([]MaintenanceCostResponse, len(costs))
+
+	for i, c := range costs {
+		responses[i] = MaintenanceCostResponse{
+			ID:               c.ID,
+			Name:             c.Name,
+			AssetCount:       c.AssetCount,
+			MaintenanceCount: c.MaintenanceCount,
+			PurchaseCost:     c.PurchaseCost,
+			MaintenanceCost:  c.MaintenanceCost,
+			TotalCost:        c.PurchaseCost + c.MaintenanceCost,
+		}
+	}
+
+	return responses
+}
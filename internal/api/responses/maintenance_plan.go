@@ -0,0 +1,80 @@
+package responses
+
+import (
+	"time"
+
+	"github.com/knr1997/assets-management-apiserver/internal/store"
+)
+
+type MaintenancePlanResponse struct {
+	ID           int64            `json:"id"`
+	Name         string           `json:"name"`
+	Model        *ModelResponse   `json:"model"`
+	Asset        *AssetSummary    `json:"asset"`
+	Supplier     *SupplierSummary `json:"supplier"`
+	OwnerID      *int64           `json:"ownerId"`
+	Type         string           `json:"type"`
+	Recurrence   string           `json:"recurrence"`
+	IntervalDays int              `json:"intervalDays"`
+	StartDate    time.Time        `json:"startDate"`
+	LeadDays     int              `json:"leadDays"`
+	Active       bool             `json:"active"`
+	Notes        string           `json:"notes"`
+	CreatedAt    time.Time        `json:"createdAt"`
+	UpdatedAt    time.Time        `json:"updatedAt"`
+}
+
+type MaintenanceDueResponse struct {
+	PlanID        int64        `json:"planId"`
+	PlanName      string       `json:"planName"`
+	Type          string       `json:"type"`
+	Asset         AssetSummary `json:"asset"`
+	DueDate       time.Time    `json:"dueDate"`
+	Overdue       bool         `json:"overdue"`
+	MaintenanceID *int64       `json:"maintenanceId"`
+}
+
+type MaintenanceDueGroupResponse struct {
+	Location string                   `json:"location"`
+	Items    []MaintenanceDueResponse `json:"items"`
+}
+
+func NewMaintenancePlanResponse(p *store.MaintenancePlan) MaintenancePlanResponse {
+	response := MaintenancePlanResponse{
+		ID:           p.ID,
+		Name:         p.Name,
+		OwnerID:      p.OwnerID,
+		Type:         string(p.Type),
+		Recurrence:   p.Recurrence,
+		IntervalDays: p.IntervalDays,
+		StartDate:    p.StartDate,
+		LeadDays:     p.LeadDays,
+		Active:       p.Active,
+		Notes:        p.Notes,
+		CreatedAt:    p.CreatedAt,
+		UpdatedAt:    p.UpdatedAt,
+	}
+
+	if p.Model != nil {
+		model := NewModelResponse(p.Model)
+		response.Model = &model
+	}
+	if p.Asset != nil {
+		response.Asset = &AssetSummary{ID: p.Asset.ID, Name: p.Asset.Name, Tag: p.Asset.Tag}
+	}
+	if p.Supplier != nil {
+		response.Supplier = &SupplierSummary{ID: p.Supplier.ID, Name: p.Supplier.Name}
+	}
+
+	return response
+}
+
+func NewMaintenancePlansResponse(plans []store.MaintenancePlan) []MaintenancePlanResponse {
+	responses := make([]MaintenancePlanResponse, len(plans))
+
+	for i := range plans {
+		responses[i] = NewMaintenancePlanResponse(&plans[i])
+	}
+
+	return responses
+}
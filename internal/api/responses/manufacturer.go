@@ -6,13 +6,30 @@ type ManufacturerResponse struct {
 	ID    int64  `json:"id"`
 	Name  string `json:"name"`
 	Email string `json:"email"`
+	VendorDetailsResponse
+	WarrantyLookupURL string `json:"warrantyLookupUrl"`
+}
+
+// ManufacturerDetailResponse is a single manufacturer with its contacts.
+// WarrantyLookup is only set when a serial number was asked about.
+type ManufacturerDetailResponse struct {
+	ManufacturerResponse
+	Contacts       []ContactResponse       `json:"contacts"`
+	WarrantyLookup *WarrantyLookupResponse `json:"warrantyLookup,omitempty"`
+}
+
+type WarrantyLookupResponse struct {
+	Serial string `json:"serial"`
+	URL    string `json:"url"`
 }
 
 func NewManufacturerResponse(u *store.Manufacturer) ManufacturerResponse {
 	return ManufacturerResponse{
-		ID:    u.ID,
-		Name:  u.Name,
-		Email: u.Email,
+		ID:                    u.ID,
+		Name:                  u.Name,
+		Email:                 u.Email,
+		VendorDetailsResponse: NewVendorDetailsResponse(u.VendorDetails),
+		WarrantyLookupURL:     u.WarrantyLookupURL,
 	}
 }
 
@@ -25,3 +42,16 @@ func NewManufacturersResponse(Manufacturers []store.Manufacturer) []Manufacturer
 
 	return responses
 }
+
+func NewManufacturerDetailResponse(m *store.Manufacturer, serial string) ManufacturerDetailResponse {
+	response := ManufacturerDetailResponse{
+		ManufacturerResponse: NewManufacturerResponse(m),
+		Contacts:             NewContactsResponse(m.Contacts),
+	}
+
+	if url := m.WarrantyLookupURLFor(serial); url != "" {
+		response.WarrantyLookup = &WarrantyLookupResponse{Serial: serial, URL: url}
+	}
+
+	return response
+}
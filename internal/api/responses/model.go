@@ -1,16 +1,42 @@
 package responses
 
-import "github.com/knr1997/assets-management-apiserver/internal/store"
+import (
+	"time"
+
+	"github.com/knr1997/assets-management-apiserver/internal/store"
+)
 
 type ModelResponse struct {
 	ID   int64  `json:"id"`
 	Name string `json:"name"`
+
+	Requestable bool    `json:"requestable"`
+	RequestCost float64 `json:"requestCost"`
+
+	ImageID          *int64     `json:"imageId"`
+	EOLDate          *time.Time `json:"eolDate"`
+	EndOfSupportDate *time.Time `json:"endOfSupportDate"`
+
+	DefaultUsefulLifeYears    int    `json:"defaultUsefulLifeYears"`
+	DefaultDepreciationMethod string `json:"defaultDepreciationMethod"`
+	DefaultLoanDays           int    `json:"defaultLoanDays"`
 }
 
 func NewModelResponse(u *store.Model) ModelResponse {
 	return ModelResponse{
 		ID:   u.ID,
 		Name: u.Name,
+
+		Requestable: u.Requestable,
+		RequestCost: u.RequestCost,
+
+		ImageID:          u.ImageID,
+		EOLDate:          u.EOLDate,
+		EndOfSupportDate: u.EndOfSupportDate,
+
+		DefaultUsefulLifeYears:    u.DefaultUsefulLifeYears,
+		DefaultDepreciationMethod: string(u.DefaultDepreciationMethod),
+		DefaultLoanDays:           u.DefaultLoanDays,
 	}
 }
 
@@ -0,0 +1,39 @@
+package responses
+
+import (
+	"time"
+
+	"github.com/knr1997/assets-management-apiserver/internal/store"
+)
+
+type NotificationResponse struct {
+	ID        int64      `json:"id"`
+	Kind      string     `json:"kind"`
+	Title     string     `json:"title"`
+	Body      string     `json:"body"`
+	AssetID   *int64     `json:"assetId"`
+	ReadAt    *time.Time `json:"readAt"`
+	CreatedAt time.Time  `json:"createdAt"`
+}
+
+func NewNotificationResponse(n *store.Notification) NotificationResponse {
+	return NotificationResponse{
+		ID:        n.ID,
+		Kind:      n.Kind,
+		Title:     n.Title,
+		Body:      n.Body,
+		AssetID:   n.AssetID,
+		ReadAt:    n.ReadAt,
+		CreatedAt: n.CreatedAt,
+	}
+}
+
+func NewNotificationsResponse(notifications []store.Notification) []NotificationResponse {
+	responses := make([]NotificationResponse, len(notifications))
+
+	for i := range notifications {
+		responses[i] = NewNotificationResponse(&notifications[i])
+	}
+
+	return responses
+}
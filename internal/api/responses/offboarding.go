@@ -0,0 +1,86 @@
+package responses
+
+import (
+	"time"
+
+	"github.com/knr1997/assets-management-apiserver/internal/store"
+)
+
+type OffboardingItemResponse struct {
+	ID                int64         `json:"id"`
+	Kind              string        `json:"kind"`
+	Description       string        `json:"description"`
+	AssetID           *int64        `json:"assetId"`
+	AssetLoanID       *int64        `json:"assetLoanId"`
+	AssetAssignmentID *int64        `json:"assetAssignmentId"`
+	LicenseSeatID     *int64        `json:"licenseSeatId"`
+	AccessoryLoanID   *int64        `json:"accessoryLoanId"`
+	Status            string        `json:"status"`
+	ResolvedAt        *time.Time    `json:"resolvedAt"`
+	ResolvedBy        *UserResponse `json:"resolvedBy"`
+	Notes             string        `json:"notes"`
+}
+
+type OffboardingResponse struct {
+	ID          int64                     `json:"id"`
+	User        UserResponse              `json:"user"`
+	Status      string                    `json:"status"`
+	LastDay     *time.Time                `json:"lastDay"`
+	OpenedBy    *UserResponse             `json:"openedBy"`
+	Items       []OffboardingItemResponse `json:"items"`
+	Pending     int                       `json:"pending"`
+	Notes       string                    `json:"notes"`
+	CompletedAt *time.Time                `json:"completedAt"`
+	CreatedAt   time.Time                 `json:"createdAt"`
+}
+
+func NewOffboardingResponse(o *store.Offboarding) OffboardingResponse {
+	response := OffboardingResponse{
+		ID:          o.ID,
+		User:        NewUserResponse(&o.User),
+		Status:      string(o.Status),
+		LastDay:     o.LastDay,
+		Items:       make([]OffboardingItemResponse, len(o.Items)),
+		Pending:     o.Pending(),
+		Notes:       o.Notes,
+		CompletedAt: o.CompletedAt,
+		CreatedAt:   o.CreatedAt,
+	}
+
+	if o.OpenedBy != nil {
+		openedBy := NewUserResponse(o.OpenedBy)
+		response.OpenedBy = &openedBy
+	}
+
+	for i, item := range o.Items {
+		response.Items[i] = OffboardingItemResponse{
+			ID:                item.ID,
+			Kind:              string(item.Kind),
+			Description:       item.Description,
+			AssetID:           item.AssetID,
+			AssetLoanID:       item.AssetLoanID,
+			AssetAssignmentID: item.AssetAssignmentID,
+			LicenseSeatID:     item.LicenseSeatID,
+			AccessoryLoanID:   item.AccessoryLoanID,
+			Status:            string(item.Status),
+			ResolvedAt:        item.ResolvedAt,
+			Notes:             item.Notes,
+		}
+		if item.ResolvedBy != nil {
+			resolvedBy := NewUserResponse(item.ResolvedBy)
+			response.Items[i].ResolvedBy = &resolvedBy
+		}
+	}
+
+	return response
+}
+
+func NewOffboardingsResponse(offboardings []store.Offboarding) []OffboardingResponse {
+	responses := make([]OffboardingResponse, len(offboardings))
+
+	for i := range offboardings {
+		responses[i] = NewOffboardingResponse(&offboardings[i])
+	}
+
+	return responses
+}
@@ -0,0 +1,153 @@
+package responses
+
+import (
+	"time"
+
+	"github.com/knr1997/assets-management-apiserver/internal/store"
+)
+
+type PurchaseOrderResponse struct {
+	ID        int64                       `json:"id"`
+	Number    string                      `json:"number"`
+	Supplier  SupplierSummary             `json:"supplier"`
+	Status    string                      `json:"status"`
+	OrderDate *time.Time                  `json:"orderDate"`
+	Notes     string                      `json:"notes"`
+	Lines     []PurchaseOrderLineResponse `json:"lines"`
+	Invoices  []InvoiceResponse           `json:"invoices"`
+	Totals    map[string]float64          `json:"totals"`
+	CreatedAt time.Time                   `json:"createdAt"`
+	UpdatedAt time.Time                   `json:"updatedAt"`
+}
+
+type PurchaseOrderLineResponse struct {
+	ID               int64         `json:"id"`
+	Model            ModelResponse `json:"model"`
+	Description      string        `json:"description"`
+	Quantity         int           `json:"quantity"`
+	ReceivedQuantity int           `json:"receivedQuantity"`
+	UnitCost         float64       `json:"unitCost"`
+	Currency         string        `json:"currency"`
+}
+
+type InvoiceResponse struct {
+	ID          int64     `json:"id"`
+	Number      string    `json:"number"`
+	InvoiceDate time.Time `json:"invoiceDate"`
+	Amount      float64   `json:"amount"`
+	Currency    string    `json:"currency"`
+	DocumentURL string    `json:"documentUrl"`
+	Notes       string    `json:"notes"`
+}
+
+// ProcurementResponse traces an asset back to the order and invoice it was
+// bought with.
+type ProcurementResponse struct {
+	PurchaseOrder    *PurchaseOrderResponse     `json:"purchaseOrder"`
+	Line             *PurchaseOrderLineResponse `json:"line"`
+	Invoice          *InvoiceResponse           `json:"invoice"`
+	PurchaseDate     time.Time                  `json:"purchaseDate"`
+	PurchaseCost     float64                    `json:"purchaseCost"`
+	PurchaseCurrency string                     `json:"purchaseCurrency"`
+}
+
+func NewPurchaseOrderResponse(o *store.PurchaseOrder) PurchaseOrderResponse {
+	response := PurchaseOrderResponse{
+		ID:        o.ID,
+		Number:    o.Number,
+		Supplier:  SupplierSummary{ID: o.Supplier.ID, Name: o.Supplier.Name},
+		Status:    string(o.Status),
+		OrderDate: o.OrderDate,
+		Notes:     o.Notes,
+		Lines:     make([]PurchaseOrderLineResponse, len(o.Lines)),
+		Invoices:  make([]InvoiceResponse, len(o.Invoices)),
+		Totals:    map[string]float64{},
+		CreatedAt: o.CreatedAt,
+		UpdatedAt: o.UpdatedAt,
+	}
+
+	// lines may be in different currencies, so totals are kept per currency
+	for i := range o.Lines {
+		line := &o.Lines[i]
+		response.Lines[i] = NewPurchaseOrderLineResponse(line)
+		response.Totals[line.Currency] += float64(line.Quantity) * line.UnitCost
+	}
+
+	for i := range o.Invoices {
+		response.Invoices[i] = NewInvoiceResponse(&o.Invoices[i])
+	}
+
+	return response
+}
+
+func NewPurchaseOrdersResponse(orders []store.PurchaseOrder) []PurchaseOrderResponse {
+	responses := make([]PurchaseOrderResponse, len(orders))
+
+	for i := range orders {
+		responses[i] = NewPurchaseOrderResponse(&orders[i])
+	}
+
+	return responses
+}
+
+func NewPurchaseOrderLineResponse(l *store.PurchaseOrderLine) PurchaseOrderLineResponse {
+	return PurchaseOrderLineResponse{
+		ID:               l.ID,
+		Model:            NewModelResponse(&l.Model),
+		Description:      l.Description,
+		Quantity:         l.Quantity,
+		ReceivedQuantity: l.ReceivedQuantity,
+		UnitCost:         l.UnitCost,
+		Currency:         l.Currency,
+	}
+}
+
+func NewInvoiceResponse(i *store.Invoice) InvoiceResponse {
+	return InvoiceResponse{
+		ID:          i.ID,
+		Number:      i.Number,
+		InvoiceDate: i.InvoiceDate,
+		Amount:      i.Amount,
+		Currency:    i.Currency,
+		DocumentURL: i.DocumentURL,
+		Notes:       i.Notes,
+	}
+}
+
+// NewProcurementResponse builds the trace for asset; order is nil for assets
+// that were not received against a purchase order.
+func NewProcurementResponse(asset *store.Asset, order *store.PurchaseOrder) ProcurementResponse {
+	response := ProcurementResponse{
+		PurchaseDate:     asset.PurchaseDate,
+		PurchaseCost:     asset.PurchaseCost,
+		PurchaseCurrency: asset.PurchaseCurrency,
+	}
+
+	if order == nil {
+		return response
+	}
+
+	po := NewPurchaseOrderResponse(order)
+	response.PurchaseOrder = &po
+
+	for i := range order.Lines {
+		if asset.PurchaseOrderLineID != nil && order.Lines[i].ID == *asset.PurchaseOrderLineID {
+			line := NewPurchaseOrderLineResponse(&order.Lines[i])
+			response.Line = &line
+		}
+	}
+
+	for i := range order.Invoices {
+		if asset.InvoiceID != nil && order.Invoices[i].ID == *asset.InvoiceID {
+			invoice := NewInvoiceResponse(&order.Invoices[i])
+			response.Invoice = &invoice
+		}
+	}
+
+	return response
+}
+
+type ReceiptResponse struct {
+	PurchaseOrder PurchaseOrderResponse `json:"purchaseOrder"`
+	Assets        []AssetResponse       `json:"assets"`
+}
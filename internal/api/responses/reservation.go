@@ -0,0 +1,137 @@
+package responses
+
+import (
+	"time"
+
+	"github.com/knr1997/assets-management-apiserver/internal/store"
+)
+
+type ReservationResponse struct {
+	ID         int64         `json:"id"`
+	Asset      AssetSummary  `json:"asset"`
+	Model      ModelResponse `json:"model"`
+	Flexible   bool          `json:"flexible"`
+	User       UserResponse  `json:"user"`
+	StartsAt   time.Time     `json:"startsAt"`
+	EndsAt     time.Time     `json:"endsAt"`
+	Status     string        `json:"status"`
+	LoanID     *int64        `json:"loanId"`
+	PickedUpAt *time.Time    `json:"pickedUpAt"`
+	Notes      string        `json:"notes"`
+	CreatedAt  time.Time     `json:"createdAt"`
+}
+
+// BusyPeriodResponse is a booking or a loan keeping an asset busy.
+type BusyPeriodResponse struct {
+	Kind   string     `json:"kind"`
+	ID     int64      `json:"id"`
+	UserID int64      `json:"userId"`
+	Start  time.Time  `json:"start"`
+	End    *time.Time `json:"end"`
+}
+
+type AssetAvailabilityResponse struct {
+	Asset  AssetSummary         `json:"asset"`
+	Status string               `json:"status"`
+	Busy   []BusyPeriodResponse `json:"busy"`
+}
+
+type AvailabilityDayResponse struct {
+	Date      string `json:"date"`
+	Total     int    `json:"total"`
+	Busy      int    `json:"busy"`
+	Available int    `json:"available"`
+}
+
+type ModelAvailabilityResponse struct {
+	Model  ModelResponse               `json:"model"`
+	From   string                      `json:"from"`
+	To     string                      `json:"to"`
+	Assets []AssetAvailabilityResponse `json:"assets"`
+	Days   []AvailabilityDayResponse   `json:"days"`
+}
+
+func NewReservationResponse(r *store.Reservation) ReservationResponse {
+	return ReservationResponse{
+		ID:         r.ID,
+		Asset:      AssetSummary{ID: r.Asset.ID, Name: r.Asset.Name, Tag: r.Asset.Tag},
+		Model:      NewModelResponse(&r.Model),
+		Flexible:   r.Flexible,
+		User:       NewUserResponse(&r.User),
+		StartsAt:   r.StartsAt,
+		EndsAt:     r.EndsAt,
+		Status:     string(r.Status),
+		LoanID:     r.LoanID,
+		PickedUpAt: r.PickedUpAt,
+		Notes:      r.Notes,
+		CreatedAt:  r.CreatedAt,
+	}
+}
+
+func NewReservationsResponse(reservations []store.Reservation) []ReservationResponse {
+	responses := make([]ReservationResponse, len(reservations))
+
+	for i := range reservations {
+		responses[i] = NewReservationResponse(&reservations[i])
+	}
+
+	return responses
+}
+
+// NewModelAvailabilityResponse lists the bookings and loans of every asset
+// of the model and how many assets are free each day. to is exclusive.
+func NewModelAvailabilityResponse(model *store.Model, a *store.ModelAvailability) ModelAvailabilityResponse {
+	busy := make(map[int64][]BusyPeriodResponse)
+	for _, r := range a.Reservations {
+		end := r.EndsAt
+		busy[r.AssetID] = append(busy[r.AssetID], BusyPeriodResponse{
+			Kind:   "RESERVATION",
+			ID:     r.ID,
+			UserID: r.UserID,
+			Start:  r.StartsAt,
+			End:    &end,
+		})
+	}
+	for _, l := range a.Loans {
+		busy[l.AssetID] = append(busy[l.AssetID], BusyPeriodResponse{
+			Kind:   "LOAN",
+			ID:     l.ID,
+			UserID: l.UserID,
+			Start:  l.CheckoutDate,
+			End:    l.ExpectedCheckinDate,
+		})
+	}
+
+	response := ModelAvailabilityResponse{
+		Model:  NewModelResponse(model),
+		From:   a.From.Format(time.DateOnly),
+		To:     a.To.Format(time.DateOnly),
+		Assets: make([]AssetAvailabilityResponse, len(a.Assets)),
+	}
+
+	for i, asset := range a.Assets {
+		periods := busy[asset.ID]
+		if periods == nil {
+			periods = []BusyPeriodResponse{}
+		}
+
+		response.Assets[i] = AssetAvailabilityResponse{
+			Asset:  AssetSummary{ID: asset.ID, Name: asset.Name, Tag: asset.Tag},
+			Status: string(asset.Status),
+			Busy:   periods,
+		}
+	}
+
+	days := a.Days()
+	response.Days = make([]AvailabilityDayResponse, len(days))
+	for i, d := range days {
+		response.Days[i] = AvailabilityDayResponse{
+			Date:      d.Date.Format(time.DateOnly),
+			Total:     d.Total,
+			Busy:      d.Busy,
+			Available: d.Total - d.Busy,
+		}
+	}
+
+	return response
+}
@@ -0,0 +1,45 @@
+package responses
+
+import "github.com/knr1997/assets-management-apiserver/internal/store"
+
+type SearchHitResponse struct {
+	ID        int64   `json:"id"`
+	Title     string  `json:"title"`
+	Subtitle  string  `json:"subtitle"`
+	Highlight string  `json:"highlight"`
+	Rank      float64 `json:"rank"`
+}
+
+type SearchResponse struct {
+	Query         string              `json:"query"`
+	Assets        []SearchHitResponse `json:"assets"`
+	Models        []SearchHitResponse `json:"models"`
+	Manufacturers []SearchHitResponse `json:"manufacturers"`
+	Users         []SearchHitResponse `json:"users"`
+}
+
+func NewSearchResponse(q string, r *store.SearchResults) SearchResponse {
+	return SearchResponse{
+		Query:         q,
+		Assets:        newSearchHitsResponse(r.Assets),
+		Models:        newSearchHitsResponse(r.Models),
+		Manufacturers: newSearchHitsResponse(r.Manufacturers),
+		Users:         newSearchHitsResponse(r.Users),
+	}
+}
+
+func newSearchHitsResponse(hits []store.SearchHit) []SearchHitResponse {
+	responses := make([]SearchHitResponse, len(hits))
+
+	for i, h := range hits {
+		responses[i] = SearchHitResponse{
+			ID:        h.ID,
+			Title:     h.Title,
+			Subtitle:  h.Subtitle,
+			Highlight: h.Highlight,
+			Rank:      h.Rank,
+		}
+	}
+
+	return responses
+}
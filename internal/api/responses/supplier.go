@@ -0,0 +1,71 @@
+package responses
+
+import (
+	"time"
+
+	"github.com/knr1997/assets-management-apiserver/internal/store"
+)
+
+type SupplierResponse struct {
+	ID   int64  `json:"id"`
+	Name string `json:"name"`
+	VendorDetailsResponse
+	CreatedAt time.Time `json:"createdAt"`
+	UpdatedAt time.Time `json:"updatedAt"`
+}
+
+type SupplierStatsResponse struct {
+	PurchaseOrderCount int64              `json:"purchaseOrderCount"`
+	AssetCount         int64              `json:"assetCount"`
+	Ordered            map[string]float64 `json:"ordered"`
+	Invoiced           map[string]float64 `json:"invoiced"`
+	MaintenanceCount   int64              `json:"maintenanceCount"`
+	MaintenanceCost    float64            `json:"maintenanceCost"`
+	WarrantyCount      int64              `json:"warrantyCount"`
+	WarrantyCost       float64            `json:"warrantyCost"`
+}
+
+// SupplierDetailResponse is a single supplier with its contacts and what was
+// spent with it.
+type SupplierDetailResponse struct {
+	SupplierResponse
+	Contacts []ContactResponse     `json:"contacts"`
+	Stats    SupplierStatsResponse `json:"stats"`
+}
+
+func NewSupplierResponse(s *store.Supplier) SupplierResponse {
+	return SupplierResponse{
+		ID:                    s.ID,
+		Name:                  s.Name,
+		VendorDetailsResponse: NewVendorDetailsResponse(s.VendorDetails),
+		CreatedAt:             s.CreatedAt,
+		UpdatedAt:             s.UpdatedAt,
+	}
+}
+
+func NewSuppliersResponse(suppliers []store.Supplier) []SupplierResponse {
+	responses := make([]SupplierResponse, len(suppliers))
+
+	for i := range suppliers {
+		responses[i] = NewSupplierResponse(&suppliers[i])
+	}
+
+	return responses
+}
+
+func NewSupplierDetailResponse(s *store.Supplier, stats *store.SupplierStats) SupplierDetailResponse {
+	return SupplierDetailResponse{
+		SupplierResponse: NewSupplierResponse(s),
+		Contacts:         NewContactsResponse(s.Contacts),
+		Stats: SupplierStatsResponse{
+			PurchaseOrderCount: stats.PurchaseOrderCount,
+			AssetCount:         stats.AssetCount,
+			Ordered:            stats.Ordered,
+			Invoiced:           stats.Invoiced,
+			MaintenanceCount:   stats.MaintenanceCount,
+			MaintenanceCost:    stats.MaintenanceCost,
+			WarrantyCount:      stats.WarrantyCount,
+			WarrantyCost:       stats.WarrantyCost,
+		},
+	}
+}
@@ -0,0 +1,81 @@
+package responses
+
+import (
+	"time"
+
+	"github.com/knr1997/assets-management-apiserver/internal/store"
+)
+
+type AddressResponse struct {
+	Line1      string `json:"line1"`
+	Line2      string `json:"line2"`
+	City       string `json:"city"`
+	Region     string `json:"region"`
+	PostalCode string `json:"postalCode"`
+	Country    string `json:"country"`
+}
+
+type VendorDetailsResponse struct {
+	Website       string          `json:"website"`
+	Phone         string          `json:"phone"`
+	SupportURL    string          `json:"supportUrl"`
+	SupportPhone  string          `json:"supportPhone"`
+	SupportEmail  string          `json:"supportEmail"`
+	AccountNumber string          `json:"accountNumber"`
+	Notes         string          `json:"notes"`
+	Address       AddressResponse `json:"address"`
+}
+
+type ContactResponse struct {
+	ID        int64     `json:"id"`
+	Name      string    `json:"name"`
+	Email     string    `json:"email"`
+	Phone     string    `json:"phone"`
+	Role      string    `json:"role"`
+	Notes     string    `json:"notes"`
+	CreatedAt time.Time `json:"createdAt"`
+	UpdatedAt time.Time `json:"updatedAt"`
+}
+
+func NewVendorDetailsResponse(d store.VendorDetails) VendorDetailsResponse {
+	return VendorDetailsResponse{
+		Website:       d.Website,
+		Phone:         d.Phone,
+		SupportURL:    d.SupportURL,
+		SupportPhone:  d.SupportPhone,
+		SupportEmail:  d.SupportEmail,
+		AccountNumber: d.AccountNumber,
+		Notes:         d.Notes,
+		Address: AddressResponse{
+			Line1:      d.Address.Line1,
+			Line2:      d.Address.Line2,
+			City:       d.Address.City,
+			Region:     d.Address.Region,
+			PostalCode: d.Address.PostalCode,
+			Country:    d.Address.Country,
+		},
+	}
+}
+
+func NewContactResponse(c *store.Contact) ContactResponse {
+	return ContactResponse{
+		ID:        c.ID,
+		Name:      c.Name,
+		Email:     c.Email,
+		Phone:     c.Phone,
+		Role:      c.Role,
+		Notes:     c.Notes,
+		CreatedAt: c.CreatedAt,
+		UpdatedAt: c.UpdatedAt,
+	}
+}
+
+func NewContactsResponse(contacts []store.Contact) []ContactResponse {
+	responses := make([]ContactResponse, len(contacts))
+
+	for i := range contacts {
+		responses[i] = NewContactResponse(&contacts[i])
+	}
+
+	return responses
+}
@@ -0,0 +1,107 @@
+package responses
+
+import (
+	"math"
+	"time"
+
+	"github.com/knr1997/assets-management-apiserver/internal/store"
+)
+
+type WarrantyResponse struct {
+	ID             int64                 `json:"id"`
+	Kind           string                `json:"kind"`
+	Asset          AssetSummary          `json:"asset"`
+	Supplier       *SupplierSummary      `json:"supplier"`
+	Manufacturer   *ManufacturerResponse `json:"manufacturer"`
+	ContractNumber string                `json:"contractNumber"`
+	StartDate      time.Time             `json:"startDate"`
+	EndDate        time.Time             `json:"endDate"`
+	SLATerms       string                `json:"slaTerms"`
+	Cost           float64               `json:"cost"`
+	Notes          string                `json:"notes"`
+	Status         string                `json:"status"`
+	DaysRemaining  int                   `json:"daysRemaining"`
+	CreatedAt      time.Time             `json:"createdAt"`
+	UpdatedAt      time.Time             `json:"updatedAt"`
+}
+
+// WarrantyStatusResponse is the coverage summary embedded in an asset.
+type WarrantyStatusResponse struct {
+	Status         string     `json:"status"`
+	Kind           string     `json:"kind,omitempty"`
+	ContractNumber string     `json:"contractNumber,omitempty"`
+	EndDate        *time.Time `json:"endDate"`
+	DaysRemaining  *int       `json:"daysRemaining"`
+}
+
+func NewWarrantyResponse(w *store.Warranty) WarrantyResponse {
+	now := time.Now()
+	status, _ := store.WarrantyStatusOf([]store.Warranty{*w}, now)
+	if status == store.WarrantyNone {
+		// coverage has not started yet
+		status = store.WarrantyPending
+	}
+
+	response := WarrantyResponse{
+		ID:   w.ID,
+		Kind: string(w.Kind),
+		Asset: AssetSummary{
+			ID:   w.Asset.ID,
+			Name: w.Asset.Name,
+			Tag:  w.Asset.Tag,
+		},
+		ContractNumber: w.ContractNumber,
+		StartDate:      w.StartDate,
+		EndDate:        w.EndDate,
+		SLATerms:       w.SLATerms,
+		Cost:           w.Cost,
+		Notes:          w.Notes,
+		Status:         string(status),
+		DaysRemaining:  daysUntil(w.EndDate, now),
+		CreatedAt:      w.CreatedAt,
+		UpdatedAt:      w.UpdatedAt,
+	}
+
+	if w.Supplier != nil {
+		response.Supplier = &SupplierSummary{ID: w.Supplier.ID, Name: w.Supplier.Name}
+	}
+	if w.Manufacturer != nil {
+		manufacturer := NewManufacturerResponse(w.Manufacturer)
+		response.Manufacturer = &manufacturer
+	}
+
+	return response
+}
+
+func NewWarrantiesResponse(warranties []store.Warranty) []WarrantyResponse {
+	responses := make([]WarrantyResponse, len(warranties))
+
+	for i := range warranties {
+		responses[i] = NewWarrantyResponse(&warranties[i])
+	}
+
+	return responses
+}
+
+func NewWarrantyStatusResponse(warranties []store.Warranty) WarrantyStatusResponse {
+	now := time.Now()
+	status, w := store.WarrantyStatusOf(warranties, now)
+
+	response := WarrantyStatusResponse{Status: string(status)}
+	if w == nil {
+		return response
+	}
+
+	days := daysUntil(w.EndDate, now)
+	response.Kind = string(w.Kind)
+	response.ContractNumber = w.ContractNumber
+	response.EndDate = &w.EndDate
+	response.DaysRemaining = &days
+
+	return response
+}
+
+// daysUntil counts whole days left until t, negative once it has passed.
+func daysUntil(t, now time.Time) int {
+	return int(math.Ceil(t.Sub(now).Hours() / 24))
+}
@@ -0,0 +1,93 @@
+package responses
+
+import (
+	"encoding/json"
+	"time"
+
+	"github.com/knr1997/assets-management-apiserver/internal/store"
+)
+
+type WebhookResponse struct {
+	ID     int64    `json:"id"`
+	Name   string   `json:"name"`
+	URL    string   `json:"url"`
+	Events []string `json:"events"`
+	Active bool     `json:"active"`
+	// only shown when the webhook is created or its secret is changed
+	Secret    string        `json:"secret,omitempty"`
+	CreatedBy *UserResponse `json:"createdBy"`
+	CreatedAt time.Time     `json:"createdAt"`
+	UpdatedAt time.Time     `json:"updatedAt"`
+}
+
+type WebhookDeliveryResponse struct {
+	ID             int64           `json:"id"`
+	WebhookID      int64           `json:"webhookId"`
+	EventID        int64           `json:"eventId"`
+	EventType      string          `json:"eventType"`
+	Payload        json.RawMessage `json:"payload"`
+	Status         string          `json:"status"`
+	Attempts       int             `json:"attempts"`
+	NextAttemptAt  *time.Time      `json:"nextAttemptAt"`
+	LastAttemptAt  *time.Time      `json:"lastAttemptAt"`
+	ResponseCode   *int            `json:"responseCode"`
+	ResponseBody   string          `json:"responseBody"`
+	Error          string          `json:"error"`
+	DeliveredAt    *time.Time      `json:"deliveredAt"`
+	RedeliveryOfID *int64          `json:"redeliveryOfId"`
+	CreatedAt      time.Time       `json:"createdAt"`
+}
+
+func NewWebhookResponse(w *store.Webhook) WebhookResponse {
+	return WebhookResponse{
+		ID:        w.ID,
+		Name:      w.Name,
+		URL:       w.URL,
+		Events:    w.Events,
+		Active:    w.Active,
+		CreatedBy: optionalUser(w.CreatedBy),
+		CreatedAt: w.CreatedAt,
+		UpdatedAt: w.UpdatedAt,
+	}
+}
+
+func NewWebhooksResponse(webhooks []store.Webhook) []WebhookResponse {
+	response := make([]WebhookResponse, len(webhooks))
+	for i := range webhooks {
+		response[i] = NewWebhookResponse(&webhooks[i])
+	}
+	return response
+}
+
+func NewWebhookDeliveryResponse(d *store.WebhookDelivery) WebhookDeliveryResponse {
+	response := WebhookDeliveryResponse{
+		ID:             d.ID,
+		WebhookID:      d.WebhookID,
+		EventID:        d.EventID,
+		EventType:      d.Event.Type,
+		Status:         string(d.Status),
+		Attempts:       d.Attempts,
+		LastAttemptAt:  d.LastAttemptAt,
+		ResponseCode:   d.ResponseCode,
+		ResponseBody:   d.ResponseBody,
+		Error:          d.Error,
+		DeliveredAt:    d.DeliveredAt,
+		RedeliveryOfID: d.RedeliveryOfID,
+		CreatedAt:      d.CreatedAt,
+	}
+	if d.Event.Payload != "" {
+		response.Payload = json.RawMessage(d.Event.Payload)
+	}
+	if d.Status == store.DeliveryPending {
+		response.NextAttemptAt = &d.NextAttemptAt
+	}
+	return response
+}
+
+func NewWebhookDeliveriesResponse(deliveries []store.WebhookDelivery) []WebhookDeliveryResponse {
+	response := make([]WebhookDeliveryResponse, len(deliveries))
+	for i := range deliveries {
+		response[i] = NewWebhookDeliveryResponse(&deliveries[i])
+	}
+	return response
+}
@@ -0,0 +1,21 @@
+// Package blob keeps file contents outside the database, on the local
+// filesystem or in an S3-compatible bucket.
+package blob
+
+import (
+	"context"
+	"errors"
+	"io"
+)
+
+var ErrNotFound = errors.New("blob not found")
+
+// Store keeps blobs under slash separated keys.
+type Store interface {
+	// Put writes size bytes from r under key, replacing what was there.
+	Put(ctx context.Context, key string, r io.Reader, size int64, contentType string) error
+	// Get opens the blob under key, ErrNotFound when there is none.
+	Get(ctx context.Context, key string) (io.ReadCloser, error)
+	// Delete removes the blob under key. A missing blob is not an error.
+	Delete(ctx context.Context, key string) error
+}
@@ -0,0 +1,106 @@
+package export
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"mime"
+	"strconv"
+	"strings"
+	"time"
+)
+
+var ErrUnknownFormat = errors.New("unknown export format")
+
+type Format string
+
+const (
+	CSV    Format = "csv"
+	XLSX   Format = "xlsx"
+	NDJSON Format = "ndjson"
+)
+
+var contentTypes = map[Format]string{
+	CSV:    "text/csv",
+	XLSX:   "application/vnd.openxmlformats-officedocument.spreadsheetml.sheet",
+	NDJSON: "application/x-ndjson",
+}
+
+// Writer writes a tabular export one row at a time so callers never need to
+// hold the full result set in memory.
+type Writer interface {
+	WriteHeader(columns []string) error
+	WriteRow(values []any) error
+	Close() error
+}
+
+func ParseFormat(s string) (Format, error) {
+	f := Format(strings.ToLower(strings.TrimSpace(s)))
+	if _, ok := contentTypes[f]; !ok {
+		return "", fmt.Errorf("%w: %q", ErrUnknownFormat, s)
+	}
+	return f, nil
+}
+
+// FormatFromAccept returns the first export format listed in an Accept header.
+func FormatFromAccept(accept string) (Format, bool) {
+	for _, part := range strings.Split(accept, ",") {
+		mediaType, _, err := mime.ParseMediaType(strings.TrimSpace(part))
+		if err != nil {
+			continue
+		}
+		for f, ct := range contentTypes {
+			if ct == mediaType {
+				return f, true
+			}
+		}
+	}
+	return "", false
+}
+
+func (f Format) ContentType() string {
+	return contentTypes[f]
+}
+
+func (f Format) Extension() string {
+	return string(f)
+}
+
+func NewWriter(f Format, w io.Writer) (Writer, error) {
+	switch f {
+	case CSV:
+		return newCSVWriter(w), nil
+	case XLSX:
+		return newXLSXWriter(w)
+	case NDJSON:
+		return newNDJSONWriter(w), nil
+	default:
+		return nil, fmt.Errorf("%w: %q", ErrUnknownFormat, f)
+	}
+}
+
+// FormatValue renders a cell value for the text based formats.
+func FormatValue(v any) string {
+	switch val := v.(type) {
+	case nil:
+		return ""
+	case string:
+		return val
+	case time.Time:
+		if val.IsZero() {
+			return ""
+		}
+		return val.Format(time.RFC3339)
+	case *time.Time:
+		if val == nil {
+			return ""
+		}
+		return FormatValue(*val)
+	case float64:
+		return strconv.FormatFloat(val, 'f', -1, 64)
+	case fmt.Stringer:
+		return val.String()
+	default:
+		return fmt.Sprintf("%v", val)
+	}
+}
@@ -0,0 +1,44 @@
+package export
+
+import (
+	"encoding/json"
+	"errors"
+	"io"
+	"time"
+)
+
+type ndjsonWriter struct {
+	enc     *json.Encoder
+	columns []string
+}
+
+func newNDJSONWriter(w io.Writer) *ndjsonWriter {
+	return &ndjsonWriter{enc: json.NewEncoder(w)}
+}
+
+func (n *ndjsonWriter) WriteHeader(columns []string) error {
+	n.columns = columns
+	return nil
+}
+
+func (n *ndjsonWriter) WriteRow(values []any) error {
+	if len(values) != len(n.columns) {
+		return errors.New("ndjson: row does not match header")
+	}
+
+	row := make(map[string]any, len(values))
+	for i, v := range values {
+		switch val := v.(type) {
+		case time.Time, *time.Time:
+			row[n.columns[i]] = FormatValue(val)
+		default:
+			row[n.columns[i]] = val
+		}
+	}
+
+	return n.enc.Encode(row)
+}
+
+func (n *ndjsonWriter) Close() error {
+	return nil
+}
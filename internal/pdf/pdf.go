@@ -0,0 +1,266 @@
+// Package pdf writes the simple documents the API hands out, such as receipts
+// and checklists: A4 pages of Helvetica text flowing top to bottom, with the
+// occasional image. Text outside Latin-1 is replaced.
+package pdf
+
+import (
+	"bytes"
+	"compress/zlib"
+	"fmt"
+	"image"
+	"image/color"
+	"strings"
+)
+
+const (
+	pageWidth  = 595.0
+	pageHeight = 842.0
+	margin     = 56.0
+
+	fontRegular = "F1"
+	fontBold    = "F2"
+)
+
+type pdfImage struct {
+	width, height int
+	data          []byte
+}
+
+// Document is a PDF being written. Content is added below what was added
+// before, new pages are started as needed.
+type Document struct {
+	pages  []*bytes.Buffer
+	images []pdfImage
+	y      float64
+}
+
+func New() *Document {
+	d := &Document{}
+	d.newPage()
+	return d
+}
+
+func (d *Document) newPage() {
+	d.pages = append(d.pages, &bytes.Buffer{})
+	d.y = pageHeight - margin
+}
+
+func (d *Document) page() *bytes.Buffer {
+	return d.pages[len(d.pages)-1]
+}
+
+// reserve moves down by height, starting a new page when it does not fit.
+func (d *Document) reserve(height float64) {
+	if d.y-height < margin {
+		d.newPage()
+	}
+	d.y -= height
+}
+
+func (d *Document) line(font string, size float64, text string) {
+	d.reserve(size * 1.4)
+	fmt.Fprintf(d.page(), "BT /%s %.1f Tf %.2f %.2f Td (%s) Tj ET\n", font, size, margin, d.y, escape(text))
+}
+
+// Title adds a large bold line.
+func (d *Document) Title(text string) {
+	d.line(fontBold, 18, text)
+	d.Gap()
+}
+
+// Heading adds a bold line with some space above it.
+func (d *Document) Heading(text string) {
+	d.Gap()
+	d.line(fontBold, 12, text)
+}
+
+// Text adds a paragraph, wrapped to the page width.
+func (d *Document) Text(text string) {
+	for _, paragraph := range strings.Split(text, "\n") {
+		for _, l := range wrap(paragraph, 10) {
+			d.line(fontRegular, 10, l)
+		}
+	}
+}
+
+// Field adds a "label: value" line.
+func (d *Document) Field(label, value string) {
+	for i, l := range wrap(label+": "+value, 10) {
+		if i == 0 {
+			d.reserve(14)
+			fmt.Fprintf(d.page(), "BT /%s 10 Tf %.2f %.2f Td (%s) Tj /%s 10 Tf (%s) Tj ET\n",
+				fontBold, margin, d.y, escape(label+": "), fontRegular, escape(strings.TrimPrefix(l, label+": ")))
+			continue
+		}
+		d.line(fontRegular, 10, l)
+	}
+}
+
+// Checkbox adds a line with a box in front, ticked when done.
+func (d *Document) Checkbox(done bool, text string) {
+	d.reserve(16)
+	fmt.Fprintf(d.page(), "%.2f %.2f 9 9 re S\n", margin, d.y-1)
+	if done {
+		fmt.Fprintf(d.page(), "%.2f %.2f m %.2f %.2f l %.2f %.2f l S\n",
+			margin+1.5, d.y+3.5, margin+3.5, d.y+1, margin+7.5, d.y+7)
+	}
+	fmt.Fprintf(d.page(), "BT /%s 10 Tf %.2f %.2f Td (%s) Tj ET\n", fontRegular, margin+16, d.y, escape(text))
+}
+
+// Gap adds an empty line.
+func (d *Document) Gap() {
+	d.reserve(10)
+}
+
+// Image adds an image scaled to width points. Transparent parts show white.
+func (d *Document) Image(img image.Image, width float64) error {
+	bounds := img.Bounds()
+	if bounds.Dx() == 0 || bounds.Dy() == 0 {
+		return nil
+	}
+
+	var raw bytes.Buffer
+	zw := zlib.NewWriter(&raw)
+	row := make([]byte, 0, bounds.Dx()*3)
+	for y := bounds.Min.Y; y < bounds.Max.Y; y++ {
+		row = row[:0]
+		for x := bounds.Min.X; x < bounds.Max.X; x++ {
+			c := color.NRGBAModel.Convert(img.At(x, y)).(color.NRGBA)
+			row = append(row, onWhite(c.R, c.A), onWhite(c.G, c.A), onWhite(c.B, c.A))
+		}
+		if _, err := zw.Write(row); err != nil {
+			return err
+		}
+	}
+	if err := zw.Close(); err != nil {
+		return err
+	}
+
+	d.images = append(d.images, pdfImage{width: bounds.Dx(), height: bounds.Dy(), data: raw.Bytes()})
+
+	width = min(width, pageWidth-2*margin)
+	height := width * float64(bounds.Dy()) / float64(bounds.Dx())
+	d.reserve(height)
+	fmt.Fprintf(d.page(), "q %.2f 0 0 %.2f %.2f %.2f cm /Im%d Do Q\n", width, height, margin, d.y, len(d.images))
+
+	return nil
+}
+
+func onWhite(v, alpha uint8) uint8 {
+	return uint8((int(v)*int(alpha) + 255*(255-int(alpha))) / 255)
+}
+
+// Bytes renders the document.
+func (d *Document) Bytes() []byte {
+	var out bytes.Buffer
+	var offsets []int
+
+	object := func(body string, stream []byte) {
+		offsets = append(offsets, out.Len())
+		fmt.Fprintf(&out, "%d 0 obj\n%s", len(offsets), body)
+		if stream != nil {
+			out.WriteString("\nstream\n")
+			out.Write(stream)
+			out.WriteString("\nendstream")
+		}
+		out.WriteString("\nendobj\n")
+	}
+
+	// objects: 1 catalog, 2 page tree, 3 and 4 fonts, the images, then a page
+	// and its content for every page
+	firstImage := 5
+	firstPage := firstImage + len(d.images)
+
+	out.WriteString("%PDF-1.4\n%\xe2\xe3\xcf\xd3\n")
+
+	object("<< /Type /Catalog /Pages 2 0 R >>", nil)
+
+	kids := make([]string, len(d.pages))
+	for i := range d.pages {
+		kids[i] = fmt.Sprintf("%d 0 R", firstPage+2*i)
+	}
+	object(fmt.Sprintf("<< /Type /Pages /Kids [%s] /Count %d >>", strings.Join(kids, " "), len(d.pages)), nil)
+
+	object("<< /Type /Font /Subtype /Type1 /BaseFont /Helvetica /Encoding /WinAnsiEncoding >>", nil)
+	object("<< /Type /Font /Subtype /Type1 /BaseFont /Helvetica-Bold /Encoding /WinAnsiEncoding >>", nil)
+
+	var xobjects strings.Builder
+	for i, img := range d.images {
+		object(fmt.Sprintf("<< /Type /XObject /Subtype /Image /Width %d /Height %d /ColorSpace /DeviceRGB /BitsPerComponent 8 /Filter /FlateDecode /Length %d >>",
+			img.width, img.height, len(img.data)), img.data)
+		fmt.Fprintf(&xobjects, " /Im%d %d 0 R", i+1, firstImage+i)
+	}
+
+	resources := fmt.Sprintf("<< /Font << /%s 3 0 R /%s 4 0 R >> /XObject <<%s >> >>", fontRegular, fontBold, xobjects.String())
+	for i, content := range d.pages {
+		object(fmt.Sprintf("<< /Type /Page /Parent 2 0 R /MediaBox [0 0 %.0f %.0f] /Resources %s /Contents %d 0 R >>",
+			pageWidth, pageHeight, resources, firstPage+2*i+1), nil)
+		object(fmt.Sprintf("<< /Length %d >>", content.Len()), content.Bytes())
+	}
+
+	xref := out.Len()
+	fmt.Fprintf(&out, "xref\n0 %d\n0000000000 65535 f \n", len(offsets)+1)
+	for _, offset := range offsets {
+		fmt.Fprintf(&out, "%010d 00000 n \n", offset)
+	}
+	fmt.Fprintf(&out, "trailer\n<< /Size %d /Root 1 0 R >>\nstartxref\n%d\n%%%%EOF\n", len(offsets)+1, xref)
+
+	return out.Bytes()
+}
+
+// escape encodes text as a Latin-1 PDF string literal.
+func escape(text string) string {
+	var b strings.Builder
+	for _, r := range text {
+		switch {
+		case r == '\\' || r == '(' || r == ')':
+			b.WriteByte('\\')
+			b.WriteRune(r)
+		case r == '\t':
+			b.WriteByte(' ')
+		case r < 32:
+		case r < 256:
+			b.WriteByte(byte(r))
+		default:
+			b.WriteByte('?')
+		}
+	}
+	return b.String()
+}
+
+// wrap splits text into lines that fit the page width. Helvetica is about
+// half as wide as it is high on average.
+func wrap(text string, size float64) []string {
+	limit := int((pageWidth - 2*margin) / (size * 0.5))
+
+	words := strings.Fields(text)
+	if len(words) == 0 {
+		return []string{""}
+	}
+
+	var lines []string
+	current := ""
+	for _, word := range words {
+		for len([]rune(word)) > limit {
+			if current != "" {
+				lines = append(lines, current)
+				current = ""
+			}
+			runes := []rune(word)
+			lines = append(lines, string(runes[:limit]))
+			word = string(runes[limit:])
+		}
+
+		switch {
+		case current == "":
+			current = word
+		case len([]rune(current))+1+len([]rune(word)) <= limit:
+			current += " " + word
+		default:
+			lines = append(lines, current)
+			current = word
+		}
+	}
+
+	return append(lines, current)
+}
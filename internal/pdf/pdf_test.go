@@ -0,0 +1,27 @@
+package pdf
+
+import "testing"
+
+func TestEscape(t *testing.T) {
+	tests := []struct {
+		name string
+		text string
+		want string
+	}{
+		{"plain", "Laptop 42", "Laptop 42"},
+		{"parentheses", "Dell (refurbished)", `Dell \(refurbished\)`},
+		{"backslash", `C:\temp`, `C:\\temp`},
+		{"tab", "a\tb", "a b"},
+		{"control", "a\nb\x00c", "abc"},
+		{"latin-1", "Café", "Caf\xe9"},
+		{"outside latin-1", "5 €", "5 ?"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := escape(tt.text); got != tt.want {
+				t.Errorf("escape(%q) = %q, want %q", tt.text, got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,228 @@
+// Package recurrence implements the subset of RFC 5545 recurrence rules used
+// by maintenance plans: FREQ, INTERVAL, BYDAY, BYMONTHDAY, COUNT and UNTIL.
+package recurrence
+
+import (
+	"errors"
+	"fmt"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+)
+
+var ErrInvalidRule = errors.New("invalid recurrence rule")
+
+type Frequency string
+
+const (
+	Daily   Frequency = "DAILY"
+	Weekly  Frequency = "WEEKLY"
+	Monthly Frequency = "MONTHLY"
+	Yearly  Frequency = "YEARLY"
+)
+
+// occurrences are searched day by day, so a rule that produces nothing for
+// this long is treated as finished
+const horizon = 20 * 366
+
+var weekdays = map[string]time.Weekday{
+	"SU": time.Sunday,
+	"MO": time.Monday,
+	"TU": time.Tuesday,
+	"WE": time.Wednesday,
+	"TH": time.Thursday,
+	"FR": time.Friday,
+	"SA": time.Saturday,
+}
+
+type Rule struct {
+	Freq       Frequency
+	Interval   int
+	ByDay      []time.Weekday
+	ByMonthDay []int
+	Count      int
+	Until      *time.Time
+}
+
+// Parse reads a rule such as "FREQ=MONTHLY;INTERVAL=3;BYMONTHDAY=1". An
+// optional "RRULE:" prefix is accepted.
+func Parse(s string) (*Rule, error) {
+	s = strings.TrimPrefix(strings.TrimSpace(s), "RRULE:")
+	if s == "" {
+		return nil, fmt.Errorf("%w: empty rule", ErrInvalidRule)
+	}
+
+	rule := &Rule{Interval: 1}
+
+	for _, part := range strings.Split(s, ";") {
+		name, value, ok := strings.Cut(part, "=")
+		if !ok || value == "" {
+			return nil, fmt.Errorf("%w: %q", ErrInvalidRule, part)
+		}
+
+		switch strings.ToUpper(name) {
+		case "FREQ":
+			rule.Freq = Frequency(strings.ToUpper(value))
+			switch rule.Freq {
+			case Daily, Weekly, Monthly, Yearly:
+			default:
+				return nil, fmt.Errorf("%w: unsupported frequency %q", ErrInvalidRule, value)
+			}
+		case "INTERVAL":
+			n, err := strconv.Atoi(value)
+			if err != nil || n < 1 {
+				return nil, fmt.Errorf("%w: interval must be a positive number", ErrInvalidRule)
+			}
+			rule.Interval = n
+		case "COUNT":
+			n, err := strconv.Atoi(value)
+			if err != nil || n < 1 {
+				return nil, fmt.Errorf("%w: count must be a positive number", ErrInvalidRule)
+			}
+			rule.Count = n
+		case "UNTIL":
+			until, err := parseUntil(value)
+			if err != nil {
+				return nil, err
+			}
+			rule.Until = &until
+		case "BYDAY":
+			for _, d := range strings.Split(value, ",") {
+				wd, ok := weekdays[strings.ToUpper(d)]
+				if !ok {
+					return nil, fmt.Errorf("%w: unsupported day %q", ErrInvalidRule, d)
+				}
+				rule.ByDay = append(rule.ByDay, wd)
+			}
+		case "BYMONTHDAY":
+			for _, d := range strings.Split(value, ",") {
+				n, err := strconv.Atoi(d)
+				if err != nil || n == 0 || n < -31 || n > 31 {
+					return nil, fmt.Errorf("%w: invalid month day %q", ErrInvalidRule, d)
+				}
+				rule.ByMonthDay = append(rule.ByMonthDay, n)
+			}
+		default:
+			return nil, fmt.Errorf("%w: unsupported part %q", ErrInvalidRule, name)
+		}
+	}
+
+	if rule.Freq == "" {
+		return nil, fmt.Errorf("%w: FREQ is required", ErrInvalidRule)
+	}
+	if rule.Count > 0 && rule.Until != nil {
+		return nil, fmt.Errorf("%w: COUNT and UNTIL cannot be combined", ErrInvalidRule)
+	}
+
+	return rule, nil
+}
+
+func parseUntil(value string) (time.Time, error) {
+	for _, layout := range []string{"20060102T150405Z", "20060102"} {
+		if t, err := time.Parse(layout, value); err == nil {
+			if layout == "20060102" {
+				t = t.Add(24*time.Hour - time.Nanosecond)
+			}
+			return t, nil
+		}
+	}
+
+	return time.Time{}, fmt.Errorf("%w: invalid UNTIL %q", ErrInvalidRule, value)
+}
+
+// After returns the first occurrence strictly after t for a series starting
+// at start. The start itself is the first occurrence when it matches the
+// rule. ok is false once the series has ended.
+func (r *Rule) After(start, t time.Time) (next time.Time, ok bool) {
+	day := start
+	seen := 0
+
+	// matching is relative to start, so without a COUNT to keep track of the
+	// search can begin at t rather than at the start of the series
+	if r.Count == 0 && t.After(start) {
+		day = time.Date(t.Year(), t.Month(), t.Day(), start.Hour(), start.Minute(), start.Second(), 0, start.Location())
+	}
+
+	for i := 0; i < horizon; i++ {
+		if r.Until != nil && day.After(*r.Until) {
+			return time.Time{}, false
+		}
+
+		if r.matches(start, day) {
+			seen++
+			if day.After(t) {
+				return day, true
+			}
+			if r.Count > 0 && seen >= r.Count {
+				return time.Time{}, false
+			}
+		}
+
+		day = day.AddDate(0, 0, 1)
+	}
+
+	return time.Time{}, false
+}
+
+func (r *Rule) matches(start, day time.Time) bool {
+	switch r.Freq {
+	case Daily:
+		return daysBetween(start, day)%r.Interval == 0 && r.matchesDay(start, day, false)
+	case Weekly:
+		weeks := daysBetween(startOfWeek(start), startOfWeek(day)) / 7
+		if weeks%r.Interval != 0 {
+			return false
+		}
+		if len(r.ByDay) == 0 {
+			return day.Weekday() == start.Weekday()
+		}
+		return slices.Contains(r.ByDay, day.Weekday())
+	case Monthly:
+		months := (day.Year()-start.Year())*12 + int(day.Month()-start.Month())
+		return months%r.Interval == 0 && r.matchesDay(start, day, true)
+	case Yearly:
+		years := day.Year() - start.Year()
+		return years%r.Interval == 0 && day.Month() == start.Month() && r.matchesDay(start, day, true)
+	}
+
+	return false
+}
+
+// matchesDay applies BYDAY and BYMONTHDAY. Without BYMONTHDAY monthly and
+// yearly rules fall on the day of month of the start.
+func (r *Rule) matchesDay(start, day time.Time, monthly bool) bool {
+	if len(r.ByDay) > 0 && !slices.Contains(r.ByDay, day.Weekday()) {
+		return false
+	}
+
+	if len(r.ByMonthDay) > 0 {
+		last := time.Date(day.Year(), day.Month()+1, 0, 0, 0, 0, 0, day.Location()).Day()
+		for _, d := range r.ByMonthDay {
+			if d < 0 {
+				d = last + d + 1
+			}
+			if d == day.Day() {
+				return true
+			}
+		}
+		return false
+	}
+
+	if monthly && len(r.ByDay) == 0 {
+		return day.Day() == start.Day()
+	}
+
+	return true
+}
+
+func daysBetween(a, b time.Time) int {
+	a = time.Date(a.Year(), a.Month(), a.Day(), 0, 0, 0, 0, time.UTC)
+	b = time.Date(b.Year(), b.Month(), b.Day(), 0, 0, 0, 0, time.UTC)
+	return int(b.Sub(a).Hours() / 24)
+}
+
+func startOfWeek(t time.Time) time.Time {
+	offset := (int(t.Weekday()) + 6) % 7 // weeks start on Monday
+	return t.AddDate(0, 0, -offset)
+}
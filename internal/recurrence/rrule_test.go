@@ -0,0 +1,113 @@
+package recurrence
+
+import (
+	"errors"
+	"slices"
+	"testing"
+	"time"
+)
+
+func TestParse(t *testing.T) {
+	until := time.Date(2025, 3, 31, 23, 59, 59, 999999999, time.UTC)
+
+	tests := []struct {
+		rule string
+		want Rule
+	}{
+		{"FREQ=DAILY", Rule{Freq: Daily, Interval: 1}},
+		{"RRULE:FREQ=WEEKLY;INTERVAL=2;BYDAY=MO,FR", Rule{Freq: Weekly, Interval: 2, ByDay: []time.Weekday{time.Monday, time.Friday}}},
+		{"freq=monthly;bymonthday=1,-1", Rule{Freq: Monthly, Interval: 1, ByMonthDay: []int{1, -1}}},
+		{"FREQ=YEARLY;COUNT=3", Rule{Freq: Yearly, Interval: 1, Count: 3}},
+		{"FREQ=MONTHLY;UNTIL=20250331", Rule{Freq: Monthly, Interval: 1, Until: &until}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.rule, func(t *testing.T) {
+			got, err := Parse(tt.rule)
+			if err != nil {
+				t.Fatalf("Parse(%q) error: %v", tt.rule, err)
+			}
+
+			if got.Freq != tt.want.Freq || got.Interval != tt.want.Interval || got.Count != tt.want.Count ||
+				!slices.Equal(got.ByDay, tt.want.ByDay) || !slices.Equal(got.ByMonthDay, tt.want.ByMonthDay) {
+				t.Errorf("Parse(%q) = %+v, want %+v", tt.rule, *got, tt.want)
+			}
+			if (got.Until == nil) != (tt.want.Until == nil) || got.Until != nil && !got.Until.Equal(*tt.want.Until) {
+				t.Errorf("Parse(%q) until = %v, want %v", tt.rule, got.Until, tt.want.Until)
+			}
+		})
+	}
+}
+
+func TestParseInvalid(t *testing.T) {
+	tests := []string{
+		"",
+		"INTERVAL=2",
+		"FREQ=HOURLY",
+		"FREQ=DAILY;INTERVAL=0",
+		"FREQ=DAILY;COUNT=-1",
+		"FREQ=WEEKLY;BYDAY=XX",
+		"FREQ=MONTHLY;BYMONTHDAY=32",
+		"FREQ=MONTHLY;BYMONTHDAY=0",
+		"FREQ=DAILY;UNTIL=tomorrow",
+		"FREQ=DAILY;COUNT=2;UNTIL=20250101",
+		"FREQ=DAILY;BYSETPOS=1",
+		"FREQ",
+	}
+
+	for _, rule := range tests {
+		t.Run(rule, func(t *testing.T) {
+			if _, err := Parse(rule); !errors.Is(err, ErrInvalidRule) {
+				t.Errorf("Parse(%q) error = %v, want ErrInvalidRule", rule, err)
+			}
+		})
+	}
+}
+
+func TestAfter(t *testing.T) {
+	day := func(y int, m time.Month, d int) time.Time {
+		return time.Date(y, m, d, 9, 0, 0, 0, time.UTC)
+	}
+
+	// a Monday
+	start := day(2025, 1, 6)
+
+	tests := []struct {
+		name   string
+		rule   string
+		after  time.Time
+		want   time.Time
+		wantOK bool
+	}{
+		{"start is first", "FREQ=DAILY", start.Add(-time.Nanosecond), start, true},
+		{"daily interval", "FREQ=DAILY;INTERVAL=3", start, day(2025, 1, 9), true},
+		{"weekly on start day", "FREQ=WEEKLY", start, day(2025, 1, 13), true},
+		{"weekly by day", "FREQ=WEEKLY;BYDAY=WE,FR", day(2025, 1, 8), day(2025, 1, 10), true},
+		{"every other week", "FREQ=WEEKLY;INTERVAL=2;BYDAY=MO", start, day(2025, 1, 20), true},
+		{"monthly on start day", "FREQ=MONTHLY", start, day(2025, 2, 6), true},
+		{"quarterly", "FREQ=MONTHLY;INTERVAL=3", start, day(2025, 4, 6), true},
+		{"last day of month", "FREQ=MONTHLY;BYMONTHDAY=-1", start, day(2025, 1, 31), true},
+		{"last day of february", "FREQ=MONTHLY;BYMONTHDAY=-1", day(2025, 1, 31), day(2025, 2, 28), true},
+		{"yearly", "FREQ=YEARLY", start, day(2026, 1, 6), true},
+		{"far after start", "FREQ=WEEKLY", day(2030, 6, 1), day(2030, 6, 3), true},
+		{"count reached", "FREQ=DAILY;COUNT=3", day(2025, 1, 8), time.Time{}, false},
+		{"within count", "FREQ=DAILY;COUNT=3", day(2025, 1, 7), day(2025, 1, 8), true},
+		{"until passed", "FREQ=WEEKLY;UNTIL=20250120", day(2025, 1, 20), time.Time{}, false},
+		{"until inclusive", "FREQ=WEEKLY;UNTIL=20250120", day(2025, 1, 13), day(2025, 1, 20), true},
+		{"skips short months", "FREQ=MONTHLY;BYMONTHDAY=30", day(2025, 1, 30), day(2025, 3, 30), true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			rule, err := Parse(tt.rule)
+			if err != nil {
+				t.Fatalf("Parse(%q) error: %v", tt.rule, err)
+			}
+
+			got, ok := rule.After(start, tt.after)
+			if ok != tt.wantOK || !got.Equal(tt.want) {
+				t.Errorf("After(%v) = %v, %v, want %v, %v", tt.after, got, ok, tt.want, tt.wantOK)
+			}
+		})
+	}
+}
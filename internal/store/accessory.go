@@ -0,0 +1,287 @@
+package store
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+var ErrStockInUse = errors.New("more is checked out than the new quantity")
+
+// Accessory is equipment tracked by quantity rather than serial number, such
+// as keyboards or docks.
+type Accessory struct {
+	ID   int64  `gorm:"primaryKey"`
+	Name string `gorm:"size:150;not null"`
+
+	CategoryID     int64         `gorm:"not null;index"`
+	Category       Category      `gorm:"constraint:OnDelete:RESTRICT;"`
+	ManufacturerID *int64        `gorm:"index"`
+	Manufacturer   *Manufacturer `gorm:"constraint:OnDelete:SET NULL;"`
+	SupplierID     *int64        `gorm:"index"`
+	Supplier       *Supplier     `gorm:"constraint:OnDelete:SET NULL;"`
+
+	ModelNumber  string `gorm:"size:100"`
+	PurchaseDate *time.Time
+	UnitCost     float64 `gorm:"not null;default:0"`
+	Notes        string  `gorm:"size:1000"`
+
+	Stocks []AccessoryStock `gorm:"constraint:OnDelete:CASCADE;"`
+
+	CreatedAt time.Time
+	UpdatedAt time.Time
+}
+
+// AccessoryStock is how many of an accessory are kept at a location and how
+// many of those are not checked out.
+type AccessoryStock struct {
+	ID          int64  `gorm:"primaryKey"`
+	AccessoryID int64  `gorm:"not null;uniqueIndex:idx_accessory_stocks_location"`
+	Location    string `gorm:"size:100;not null;uniqueIndex:idx_accessory_stocks_location"`
+
+	Quantity  int `gorm:"not null;default:0;check:quantity >= 0"`
+	Available int `gorm:"not null;default:0;check:available >= 0 AND available <= quantity"`
+
+	UpdatedAt time.Time
+}
+
+// AccessoryLoan is a quantity of an accessory checked out to a user from one
+// location.
+type AccessoryLoan struct {
+	ID          int64     `gorm:"primaryKey"`
+	AccessoryID int64     `gorm:"not null;index"`
+	Accessory   Accessory `gorm:"constraint:OnDelete:CASCADE;"`
+
+	UserID int64 `gorm:"not null;index"`
+	User   User  `gorm:"constraint:OnDelete:RESTRICT;"`
+
+	Location string `gorm:"size:100;not null"`
+	Quantity int    `gorm:"not null;default:1"`
+
+	CheckedOutByID *int64 `gorm:"index"`
+	CheckedOutBy   *User  `gorm:"constraint:OnDelete:SET NULL;"`
+
+	CheckoutDate        time.Time `gorm:"not null"`
+	ExpectedCheckinDate *time.Time
+	ActualReturnDate    *time.Time
+
+	Notes     string `gorm:"size:255"`
+	CreatedAt time.Time
+}
+
+type AccessoryStore struct {
+	db *gorm.DB
+}
+
+var accessoryQueryFields = QueryFields{
+	"id":             {"accessories.id", IntField},
+	"name":           {"accessories.name", StringField},
+	"categoryId":     {"accessories.category_id", IntField},
+	"manufacturerId": {"accessories.manufacturer_id", IntField},
+	"supplierId":     {"accessories.supplier_id", IntField},
+	"modelNumber":    {"accessories.model_number", StringField},
+	"purchaseDate":   {"accessories.purchase_date", TimeField},
+	"unitCost":       {"accessories.unit_cost", FloatField},
+	"createdAt":      {"accessories.created_at", TimeField},
+}
+
+var accessoryLoanQueryFields = QueryFields{
+	"id":                  {"accessory_loans.id", IntField},
+	"userId":              {"accessory_loans.user_id", IntField},
+	"location":            {"accessory_loans.location", StringField},
+	"checkoutDate":        {"accessory_loans.checkout_date", TimeField},
+	"expectedCheckinDate": {"accessory_loans.expected_checkin_date", TimeField},
+	"actualReturnDate":    {"accessory_loans.actual_return_date", TimeField},
+}
+
+func preloadAccessory(db *gorm.DB) *gorm.DB {
+	return db.
+		Joins("Category").
+		Joins("Manufacturer").
+		Joins("Supplier").
+		Preload("Stocks", func(db *gorm.DB) *gorm.DB { return db.Order("location") })
+}
+
+func (s *AccessoryStore) List(ctx context.Context, spec QuerySpec) (*Pagination, error) {
+	query := preloadAccessory(s.db.WithContext(ctx).Model(&Accessory{}))
+
+	return paginate[Accessory](query, spec, accessoryQueryFields, "accessories.id")
+}
+
+func (s *AccessoryStore) GetByID(ctx context.Context, id int64) (*Accessory, error) {
+	var accessory Accessory
+
+	err := preloadAccessory(s.db.WithContext(ctx)).
+		First(&accessory, "accessories.id = ?", id).Error
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, ErrNotFound
+		}
+		return nil, err
+	}
+
+	return &accessory, nil
+}
+
+func (s *AccessoryStore) Create(ctx context.Context, accessory *Accessory) error {
+	return s.db.WithContext(ctx).Omit("Category", "Manufacturer", "Supplier", "Stocks").Create(accessory).Error
+}
+
+func (s *AccessoryStore) Update(ctx context.Context, accessory *Accessory) error {
+	result := s.db.WithContext(ctx).
+		Model(&Accessory{}).
+		Where("id = ?", accessory.ID).
+		Updates(map[string]interface{}{
+			"name":            accessory.Name,
+			"category_id":     accessory.CategoryID,
+			"manufacturer_id": accessory.ManufacturerID,
+			"supplier_id":     accessory.SupplierID,
+			"model_number":    accessory.ModelNumber,
+			"purchase_date":   accessory.PurchaseDate,
+			"unit_cost":       accessory.UnitCost,
+			"notes":           accessory.Notes,
+		})
+
+	if result.Error != nil {
+		return result.Error
+	}
+
+	if result.RowsAffected == 0 {
+		return ErrNotFound
+	}
+
+	return nil
+}
+
+func (s *AccessoryStore) Delete(ctx context.Context, id int64) error {
+	result := s.db.WithContext(ctx).
+		Delete(&Accessory{}, id)
+
+	if result.Error != nil {
+		return result.Error
+	}
+
+	if result.RowsAffected == 0 {
+		return ErrNotFound
+	}
+
+	return nil
+}
+
+// SetQuantity sets how many of an accessory are kept at location. What is
+// checked out there stays checked out, so the quantity cannot drop below it.
+func (s *AccessoryStore) SetQuantity(ctx context.Context, accessoryID int64, location string, quantity int) error {
+	stock := AccessoryStock{AccessoryID: accessoryID, Location: location, Quantity: quantity, Available: quantity}
+
+	result := s.db.WithContext(ctx).
+		Clauses(clause.OnConflict{
+			Columns: []clause.Column{{Name: "accessory_id"}, {Name: "location"}},
+			DoUpdates: clause.Set{
+				{Column: clause.Column{Name: "quantity"}, Value: gorm.Expr("excluded.quantity")},
+				{Column: clause.Column{Name: "available"}, Value: gorm.Expr("accessory_stocks.available + excluded.quantity - accessory_stocks.quantity")},
+				{Column: clause.Column{Name: "updated_at"}, Value: time.Now()},
+			},
+			Where: clause.Where{Exprs: []clause.Expression{
+				gorm.Expr("accessory_stocks.quantity - accessory_stocks.available <= excluded.quantity"),
+			}},
+		}).
+		Create(&stock)
+
+	if result.Error != nil {
+		return result.Error
+	}
+
+	if result.RowsAffected == 0 {
+		return ErrStockInUse
+	}
+
+	return nil
+}
+
+// Checkout takes loan.Quantity from what is available at loan.Location. The
+// decrement only applies when enough is available, so concurrent checkouts
+// cannot hand out more than there is.
+func (s *AccessoryStore) Checkout(ctx context.Context, loan *AccessoryLoan) error {
+	return s.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		return checkoutAccessory(tx, loan)
+	})
+}
+
+func checkoutAccessory(tx *gorm.DB, loan *AccessoryLoan) error {
+	result := tx.Model(&AccessoryStock{}).
+		Where("accessory_id = ? AND location = ? AND available >= ?", loan.AccessoryID, loan.Location, loan.Quantity).
+		Update("available", gorm.Expr("available - ?", loan.Quantity))
+	if result.Error != nil {
+		return result.Error
+	}
+	if result.RowsAffected == 0 {
+		return ErrInsufficientStock
+	}
+
+	if loan.CheckoutDate.IsZero() {
+		loan.CheckoutDate = time.Now()
+	}
+
+	return tx.Omit(clause.Associations).Create(loan).Error
+}
+
+// Checkin returns a loan and puts its quantity back at its location.
+func (s *AccessoryStore) Checkin(ctx context.Context, accessoryID, loanID int64) error {
+	return s.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		return checkinAccessory(tx, accessoryID, loanID, true)
+	})
+}
+
+// checkinAccessory closes an open loan. With restock its quantity is put
+// back at its location, without it the items were lost and leave the stock.
+func checkinAccessory(tx *gorm.DB, accessoryID, loanID int64, restock bool) error {
+	var loan AccessoryLoan
+
+	result := tx.Model(&loan).
+		Clauses(clause.Returning{}).
+		Where("id = ? AND accessory_id = ? AND actual_return_date IS NULL", loanID, accessoryID).
+		Update("actual_return_date", time.Now())
+	if result.Error != nil {
+		return result.Error
+	}
+	if result.RowsAffected == 0 {
+		return ErrNotFound
+	}
+
+	stock := tx.Model(&AccessoryStock{}).
+		Where("accessory_id = ? AND location = ?", loan.AccessoryID, loan.Location)
+	if !restock {
+		return stock.Update("quantity", gorm.Expr("quantity - ?", loan.Quantity)).Error
+	}
+
+	return stock.Update("available", gorm.Expr("available + ?", loan.Quantity)).Error
+}
+
+func (s *AccessoryStore) Loans(ctx context.Context, accessoryID int64, spec QuerySpec) (*Pagination, error) {
+	query := s.db.WithContext(ctx).Model(&AccessoryLoan{}).
+		Joins("Accessory").
+		Joins("User").
+		Where("accessory_loans.accessory_id = ?", accessoryID)
+
+	return paginate[AccessoryLoan](query, spec, accessoryLoanQueryFields, "accessory_loans.id")
+}
+
+// HeldBy returns the accessories checked out to a user.
+func (s *AccessoryStore) HeldBy(ctx context.Context, userID int64) ([]AccessoryLoan, error) {
+	loans := []AccessoryLoan{}
+
+	err := s.db.WithContext(ctx).
+		Joins("Accessory").
+		Joins("User").
+		Where("accessory_loans.user_id = ? AND accessory_loans.actual_return_date IS NULL", userID).
+		Order("accessory_loans.checkout_date, accessory_loans.id").
+		Find(&loans).Error
+	if err != nil {
+		return nil, err
+	}
+
+	return loans, nil
+}
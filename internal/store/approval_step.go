@@ -0,0 +1,132 @@
+package store
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+type ApproverKind string
+
+const (
+	// the manager of the requester's department
+	ApproverDepartmentManager ApproverKind = "DEPARTMENT_MANAGER"
+	// any active user holding the step's role
+	ApproverRole ApproverKind = "ROLE"
+)
+
+var ApproverKinds = []ApproverKind{ApproverDepartmentManager, ApproverRole}
+
+// ApprovalStep is a stage of the approval chain asset requests go through in
+// Position order. A step with a MinCost only applies to requests costing at
+// least that much, e.g. finance signing off expensive hardware.
+type ApprovalStep struct {
+	ID       int64        `gorm:"primaryKey"`
+	Position int          `gorm:"not null;uniqueIndex"`
+	Name     string       `gorm:"size:100;not null"`
+	Approver ApproverKind `gorm:"type:varchar(30);not null"`
+
+	RoleID *int64 `gorm:"index"`
+	Role   *Role  `gorm:"constraint:OnDelete:RESTRICT;"`
+
+	MinCost float64 `gorm:"not null;default:0"`
+
+	CreatedAt time.Time
+	UpdatedAt time.Time
+}
+
+// AppliesTo reports whether a request of the given cost goes through the
+// step.
+func (s *ApprovalStep) AppliesTo(cost float64) bool {
+	return cost >= s.MinCost
+}
+
+type ApprovalStepStore struct {
+	db *gorm.DB
+}
+
+// GetAll returns the approval chain in order.
+func (s *ApprovalStepStore) GetAll(ctx context.Context) ([]ApprovalStep, error) {
+	steps := []ApprovalStep{}
+
+	err := s.db.WithContext(ctx).
+		Joins("Role").
+		Order("approval_steps.position").
+		Find(&steps).Error
+	if err != nil {
+		return nil, err
+	}
+
+	return steps, nil
+}
+
+func (s *ApprovalStepStore) GetByID(ctx context.Context, id int64) (*ApprovalStep, error) {
+	var step ApprovalStep
+
+	err := s.db.WithContext(ctx).
+		Joins("Role").
+		First(&step, "approval_steps.id = ?", id).Error
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, ErrNotFound
+		}
+		return nil, err
+	}
+
+	return &step, nil
+}
+
+func (s *ApprovalStepStore) Create(ctx context.Context, step *ApprovalStep) error {
+	err := s.db.WithContext(ctx).Omit(clause.Associations).Create(step).Error
+	if isUniqueViolation(err) {
+		return ErrConflict
+	}
+
+	return err
+}
+
+func (s *ApprovalStepStore) Update(ctx context.Context, step *ApprovalStep) error {
+	result := s.db.WithContext(ctx).
+		Model(&ApprovalStep{}).
+		Where("id = ?", step.ID).
+		Updates(map[string]interface{}{
+			"position": step.Position,
+			"name":     step.Name,
+			"approver": step.Approver,
+			"role_id":  step.RoleID,
+			"min_cost": step.MinCost,
+		})
+
+	if result.Error != nil {
+		if isUniqueViolation(result.Error) {
+			return ErrConflict
+		}
+		return result.Error
+	}
+
+	if result.RowsAffected == 0 {
+		return ErrNotFound
+	}
+
+	return nil
+}
+
+// Delete removes a step from the chain. Requests already submitted keep the
+// approvals they were given.
+func (s *ApprovalStepStore) Delete(ctx context.Context, id int64) error {
+	result := s.db.WithContext(ctx).
+		Delete(&ApprovalStep{}, id)
+
+	if result.Error != nil {
+		return result.Error
+	}
+
+	if result.RowsAffected == 0 {
+		return ErrNotFound
+	}
+
+	return nil
+}
@@ -2,9 +2,12 @@ package store
 
 import (
 	"context"
+	"errors"
+	"math"
 	"time"
 
 	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
 )
 
 type AssetStatus string
@@ -22,6 +25,14 @@ const (
 	AssetLostStolen    AssetStatus = "LOST_STOLEN"
 )
 
+type DepreciationMethod string
+
+const (
+	DepreciationStraightLine     DepreciationMethod = "STRAIGHT_LINE"
+	DepreciationDecliningBalance DepreciationMethod = "DECLINING_BALANCE"
+	DepreciationNone             DepreciationMethod = "NONE"
+)
+
 type Asset struct {
 	ID           int64  `gorm:"primaryKey"`
 	Name         string `gorm:"size:150;not null"`
@@ -33,24 +44,119 @@ type Asset struct {
 	Model   Model `gorm:"constraint:OnUpdate:CASCADE,OnDelete:RESTRICT;"`
 
 	Status AssetStatus `gorm:"type:varchar(20);not null;default:'AVAILABLE'"`
+	// the status maintenance took the asset out of, restored once it ends
+	StatusBeforeRepair *AssetStatus `gorm:"type:varchar(20)"`
 
 	PurchaseDate time.Time
 	PurchaseCost float64
+	// ISO 4217 code of PurchaseCost, empty when it was not recorded
+	PurchaseCurrency string `gorm:"type:char(3)"`
 
-	UsefulLifeYears int     // for depreciation
-	SalvageValue    float64 // optional
+	UsefulLifeYears    int                // for depreciation
+	SalvageValue       float64            // optional
+	DepreciationMethod DepreciationMethod `gorm:"type:varchar(20);not null;default:'STRAIGHT_LINE'"`
 
 	Location string `gorm:"size:100"`
 
+	// where the asset was bought, set when it is received against an order
+	PurchaseOrderID     *int64             `gorm:"index"`
+	PurchaseOrder       *PurchaseOrder     `gorm:"constraint:OnDelete:SET NULL;"`
+	PurchaseOrderLineID *int64             `gorm:"index"`
+	PurchaseOrderLine   *PurchaseOrderLine `gorm:"constraint:OnDelete:SET NULL;"`
+	InvoiceID           *int64             `gorm:"index"`
+	Invoice             *Invoice           `gorm:"constraint:OnDelete:SET NULL;"`
+
+	CustomFields CustomFieldValues `gorm:"type:jsonb;not null;default:'{}'"`
+
+	Warranties []Warranty         `gorm:"foreignKey:AssetID"`
+	Components []ComponentInstall `gorm:"foreignKey:AssetID"`
+
 	CreatedAt time.Time
 	UpdatedAt time.Time
 	DeletedAt gorm.DeletedAt `gorm:"index"`
 }
 
+// ComponentCost is what the components installed in the asset are worth.
+func (a *Asset) ComponentCost() float64 {
+	var cost float64
+	for i := range a.Components {
+		cost += a.Components[i].Cost()
+	}
+	return cost
+}
+
+// TotalValue is the purchase cost of the asset and its installed components.
+func (a *Asset) TotalValue() float64 {
+	return a.PurchaseCost + a.ComponentCost()
+}
+
+// InheritModelDefaults fills in the depreciation settings the asset was not
+// given from its model.
+func (a *Asset) InheritModelDefaults(m *Model) {
+	if a.UsefulLifeYears == 0 {
+		a.UsefulLifeYears = m.DefaultUsefulLifeYears
+	}
+	if a.DepreciationMethod == "" {
+		a.DepreciationMethod = m.DefaultDepreciationMethod
+	}
+}
+
+// BookValue is the asset's purchase cost depreciated over its useful life,
+// down to the salvage value: straight-line, or declining balance at twice the
+// straight-line rate. Assets without a useful life keep their cost.
+// Installed components are left out on purpose: they are bought and fitted on
+// dates of their own, so their cost is reported apart by ComponentCost and
+// TotalValue instead of depreciating with the asset.
+func (a *Asset) BookValue(at time.Time) float64 {
+	if a.DepreciationMethod == DepreciationNone || a.UsefulLifeYears <= 0 || a.PurchaseDate.IsZero() {
+		return a.PurchaseCost
+	}
+
+	life := a.PurchaseDate.AddDate(a.UsefulLifeYears, 0, 0).Sub(a.PurchaseDate)
+	elapsed := min(max(at.Sub(a.PurchaseDate), 0), life)
+	if elapsed == life {
+		return min(a.SalvageValue, a.PurchaseCost)
+	}
+
+	var value float64
+	switch a.DepreciationMethod {
+	case DepreciationDecliningBalance:
+		rate := min(2/float64(a.UsefulLifeYears), 1)
+		years := float64(a.UsefulLifeYears) * float64(elapsed) / float64(life)
+		value = max(a.PurchaseCost*math.Pow(1-rate, years), a.SalvageValue)
+	default:
+		depreciable := max(a.PurchaseCost-a.SalvageValue, 0)
+		value = a.PurchaseCost - depreciable*float64(elapsed)/float64(life)
+	}
+
+	return math.Round(value*100) / 100
+}
+
 type AssetStore struct {
 	db *gorm.DB
 }
 
+var assetQueryFields = QueryFields{
+	"id":                 {"assets.id", IntField},
+	"name":               {"assets.name", StringField},
+	"tag":                {"assets.tag", StringField},
+	"serialNumber":       {"assets.serial_number", StringField},
+	"description":        {"assets.description", StringField},
+	"status":             {"assets.status", StringField},
+	"modelId":            {"assets.model_id", IntField},
+	"location":           {"assets.location", StringField},
+	"purchaseDate":       {"assets.purchase_date", TimeField},
+	"purchaseCost":       {"assets.purchase_cost", FloatField},
+	"purchaseCurrency":   {"assets.purchase_currency", StringField},
+	"usefulLifeYears":    {"assets.useful_life_years", IntField},
+	"depreciationMethod": {"assets.depreciation_method", StringField},
+	"purchaseOrderId":    {"assets.purchase_order_id", IntField},
+	"invoiceId":          {"assets.invoice_id", IntField},
+	"createdAt":          {"assets.created_at", TimeField},
+	"updatedAt":          {"assets.updated_at", TimeField},
+	"cf.*":               {"assets.custom_fields", JSONField},
+}
+
 func (s *AssetStore) GetAll(ctx context.Context) ([]Asset, error) {
 	var assets []Asset
 
@@ -62,34 +168,95 @@ func (s *AssetStore) GetAll(ctx context.Context) ([]Asset, error) {
 	return assets, nil
 }
 
+func (s *AssetStore) List(ctx context.Context, spec QuerySpec) (*Pagination, error) {
+	query := s.db.WithContext(ctx).Model(&Asset{}).
+		Joins("Model").
+		Preload("Warranties")
+	query = preloadInstalledComponents(query)
+
+	return paginate[Asset](query, spec, assetQueryFields, "assets.id")
+}
+
+// ListPastEndOfSupport pages through the assets still in the fleet whose
+// model's support ends by cutoff.
+func (s *AssetStore) ListPastEndOfSupport(ctx context.Context, spec QuerySpec, cutoff time.Time) (*Pagination, error) {
+	query := s.db.WithContext(ctx).Model(&Asset{}).
+		Joins("Model").
+		Preload("Warranties").
+		Where(`"Model"."end_of_support_date" <= ?`, cutoff).
+		Where("assets.status NOT IN ?", []AssetStatus{AssetArchived, AssetLostStolen})
+	query = preloadInstalledComponents(query)
+
+	return paginate[Asset](query, spec, assetQueryFields, "assets.id")
+}
+
+func (s *AssetStore) Stream(ctx context.Context, spec QuerySpec, fn func(*Asset) error) error {
+	query := s.db.Model(&Asset{}).Joins("Model")
+
+	return streamQuery(ctx, query, spec, assetQueryFields, "assets.id", fn)
+}
+
 func (s *AssetStore) GetByID(ctx context.Context, id int64) (*Asset, error) {
 	var asset Asset
 
-	err := s.db.WithContext(ctx).Preload("Model").First(&asset, id).Error
+	err := preloadInstalledComponents(s.db.WithContext(ctx)).
+		Preload("Model").
+		Preload("Warranties").
+		First(&asset, id).Error
 	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, ErrNotFound
+		}
 		return nil, err
 	}
 
 	return &asset, nil
 }
 
+// Create inserts the asset. A value of a unique custom field that another
+// asset holds fails with ErrCustomFieldTaken.
 func (s AssetStore) Create(ctx context.Context, asset *Asset) error {
-	return s.db.WithContext(ctx).Create(asset).Error
+	err := s.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		if err := tx.Create(asset).Error; err != nil {
+			return err
+		}
+
+		return publish(tx, newAssetCreated(asset))
+	})
+
+	return customFieldTaken(err)
 }
 
+func newAssetCreated(asset *Asset) AssetCreated {
+	return AssetCreated{
+		AssetID: asset.ID,
+		Name:    asset.Name,
+		Tag:     asset.Tag,
+		ModelID: asset.ModelID,
+		Status:  asset.Status,
+	}
+}
+
+// Update saves the editable fields of the asset; like Create it fails with
+// ErrCustomFieldTaken on a unique custom field value already in use.
 func (s *AssetStore) Update(ctx context.Context, asset *Asset) error {
 	result := s.db.WithContext(ctx).
 		Model(&Asset{}).
 		Where("id = ?", asset.ID).
 		Updates(map[string]interface{}{
-			"name":         asset.Name,
-			"tag":          asset.Tag,
-			"serialNumber": asset.SerialNumber,
-			"description":  asset.Description,
+			"name":          asset.Name,
+			"tag":           asset.Tag,
+			"serial_number": asset.SerialNumber,
+			"description":   asset.Description,
+			"model_id":      asset.ModelID,
+			"custom_fields": asset.CustomFields,
+
+			"useful_life_years":   asset.UsefulLifeYears,
+			"depreciation_method": asset.DepreciationMethod,
 		})
 
 	if result.Error != nil {
-		return result.Error
+		return customFieldTaken(result.Error)
 	}
 
 	if result.RowsAffected == 0 {
@@ -115,18 +282,55 @@ func (s *AssetStore) Delete(ctx context.Context, id int64) error {
 }
 
 func (s *AssetStore) UpdateStatus(ctx context.Context, assetID int64, status AssetStatus) error {
-	result := s.db.WithContext(ctx).
-		Model(&Asset{}).
-		Where("id = ?", assetID).
-		Update("status", status)
+	return s.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		changed, err := changeAssetStatus(tx, assetID, status)
+		if err != nil {
+			return err
+		}
+		if !changed {
+			return ErrNotFound
+		}
+
+		return nil
+	})
+}
 
-	if result.Error != nil {
-		return result.Error
+// changeAssetStatus moves an asset to status and records the change in the
+// outbox. When from is given only assets in one of those statuses are moved;
+// it reports whether the asset was found in such a status.
+func changeAssetStatus(tx *gorm.DB, assetID int64, status AssetStatus, from ...AssetStatus) (bool, error) {
+	query := tx.Clauses(clause.Locking{Strength: "UPDATE"}).Select("id", "status")
+	if len(from) > 0 {
+		query = query.Where("status IN ?", from)
 	}
 
-	if result.RowsAffected == 0 {
-		return ErrNotFound
+	var asset Asset
+	if err := query.Take(&asset, assetID).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return false, nil
+		}
+		return false, err
 	}
 
-	return nil
+	if asset.Status == status {
+		return true, nil
+	}
+
+	// whatever status maintenance remembered no longer applies once the
+	// status changed
+	err := tx.Model(&Asset{}).
+		Where("id = ?", assetID).
+		Updates(map[string]interface{}{
+			"status":               status,
+			"status_before_repair": nil,
+		}).Error
+	if err != nil {
+		return false, err
+	}
+
+	return true, publish(tx, AssetStatusChanged{
+		AssetID: assetID,
+		From:    asset.Status,
+		To:      status,
+	})
 }
@@ -2,11 +2,21 @@ package store
 
 import (
 	"context"
+	"errors"
 	"time"
 
 	"gorm.io/gorm"
 )
 
+var ErrAlreadyAccepted = errors.New("assignment was already accepted")
+
+type AcceptanceStatus string
+
+const (
+	AcceptancePending  AcceptanceStatus = "PENDING"
+	AcceptanceAccepted AcceptanceStatus = "ACCEPTED"
+)
+
 type AssetAssignment struct {
 	ID int64 `gorm:"primaryKey"`
 
@@ -21,13 +31,99 @@ type AssetAssignment struct {
 
 	Notes string `gorm:"size:255"`
 
+	// the user acknowledges receiving the asset and accepts the usage
+	// policy of its category, EULA keeps the text they accepted
+	AcceptanceStatus AcceptanceStatus `gorm:"type:varchar(20);not null;default:'PENDING';index"`
+	AcceptedAt       *time.Time
+	AcceptedName     string      `gorm:"size:150"`
+	Signature        []byte      `gorm:"type:bytea"`
+	AcceptedIP       string      `gorm:"size:45"`
+	EULA             string      `gorm:"type:text"`
+	ReceiptID        *int64      `gorm:"index"`
+	Receipt          *Attachment `gorm:"constraint:OnDelete:SET NULL;"`
+
 	CreatedAt time.Time
 }
 
+// AssignmentAcceptance is what the user gave when accepting an assignment.
+type AssignmentAcceptance struct {
+	AcceptedAt time.Time
+	Name       string
+	Signature  []byte
+	IP         string
+	EULA       string
+}
+
 type AssetAssignmentStore struct {
 	db *gorm.DB
 }
 
+var assetAssignmentQueryFields = QueryFields{
+	"id":         {"asset_assignments.id", IntField},
+	"assetId":    {"asset_assignments.asset_id", IntField},
+	"userId":     {"asset_assignments.user_id", IntField},
+	"assignedAt": {"asset_assignments.assigned_at", TimeField},
+}
+
+func preloadAssetAssignment(db *gorm.DB) *gorm.DB {
+	return db.
+		Joins("User").
+		Preload("Asset.Model.Category")
+}
+
 func (s AssetAssignmentStore) Create(ctx context.Context, asset *AssetAssignment) error {
 	return s.db.WithContext(ctx).Create(asset).Error
 }
+
+func (s *AssetAssignmentStore) GetByID(ctx context.Context, id int64) (*AssetAssignment, error) {
+	var assignment AssetAssignment
+
+	err := preloadAssetAssignment(s.db.WithContext(ctx)).
+		First(&assignment, "asset_assignments.id = ?", id).Error
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, ErrNotFound
+		}
+		return nil, err
+	}
+
+	return &assignment, nil
+}
+
+// ListAwaitingAcceptance pages through the current assignments their users
+// have not accepted yet.
+func (s *AssetAssignmentStore) ListAwaitingAcceptance(ctx context.Context, spec QuerySpec) (*Pagination, error) {
+	query := preloadAssetAssignment(s.db.WithContext(ctx).Model(&AssetAssignment{})).
+		Where("asset_assignments.returned_at IS NULL AND asset_assignments.acceptance_status = ?", AcceptancePending)
+
+	return paginate[AssetAssignment](query, spec, assetAssignmentQueryFields, "asset_assignments.id")
+}
+
+// Accept records the user's acceptance of an assignment with its receipt.
+func (s *AssetAssignmentStore) Accept(ctx context.Context, assignment *AssetAssignment, acceptance *AssignmentAcceptance, receipt *Attachment) error {
+	return s.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		if err := createAttachment(tx, receipt); err != nil {
+			return err
+		}
+
+		result := tx.Model(&AssetAssignment{}).
+			Where("id = ? AND acceptance_status = ?", assignment.ID, AcceptancePending).
+			Updates(map[string]interface{}{
+				"acceptance_status": AcceptanceAccepted,
+				"accepted_at":       acceptance.AcceptedAt,
+				"accepted_name":     acceptance.Name,
+				"signature":         acceptance.Signature,
+				"accepted_ip":       acceptance.IP,
+				"eula":              acceptance.EULA,
+				"receipt_id":        receipt.ID,
+			})
+		if result.Error != nil {
+			return result.Error
+		}
+		if result.RowsAffected == 0 {
+			return ErrAlreadyAccepted
+		}
+
+		return nil
+	})
+}
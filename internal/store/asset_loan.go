@@ -2,14 +2,16 @@ package store
 
 import (
 	"context"
+	"errors"
 	"time"
 
 	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
 )
 
 type AssetLoan struct {
 	ID        int64  `gorm:"primaryKey"`
-	AssetName string `gorm:"size:100;uniqueIndex;not null"`
+	AssetName string `gorm:"size:100;index;not null"`
 
 	AssetID int64 `gorm:"not null;index"`
 	Asset   Asset `gorm:"constraint:OnDelete:CASCADE;"`
@@ -28,14 +30,149 @@ type AssetLoan struct {
 	CreatedAt time.Time
 }
 
+var assetLoanMigrations = []string{
+	// an asset is loaned out again and again, so its name repeats across
+	// loans, which are keyed by asset_id; the status change on checkout keeps
+	// an asset to one open loan
+	`DROP INDEX IF EXISTS idx_asset_loans_asset_name`,
+	`CREATE INDEX IF NOT EXISTS idx_asset_loans_asset_name ON asset_loans (asset_name)`,
+}
+
+var (
+	ErrAssetNotAvailable = errors.New("asset is not available")
+	ErrAssetReserved     = errors.New("asset is reserved by another user")
+)
+
 type AssetLoanStore struct {
 	db *gorm.DB
 }
 
+var assetLoanQueryFields = QueryFields{
+	"id":                  {"asset_loans.id", IntField},
+	"assetId":             {"asset_loans.asset_id", IntField},
+	"assetName":           {"asset_loans.asset_name", StringField},
+	"userId":              {"asset_loans.user_id", IntField},
+	"status":              {"asset_loans.status", StringField},
+	"checkoutDate":        {"asset_loans.checkout_date", TimeField},
+	"expectedCheckinDate": {"asset_loans.expected_checkin_date", TimeField},
+	"actualReturnDate":    {"asset_loans.actual_return_date", TimeField},
+	"createdAt":           {"asset_loans.created_at", TimeField},
+}
+
 func (s AssetLoanStore) Create(ctx context.Context, assetLoan *AssetLoan) error {
 	return s.db.WithContext(ctx).Create(assetLoan).Error
 }
 
+// Checkout creates the loans together and marks their assets assigned. It
+// fails with ErrAssetNotAvailable, creating none of them, when one of the
+// assets is no longer available, and with ErrAssetReserved when another user
+// booked one of them while it would be out.
+func (s AssetLoanStore) Checkout(ctx context.Context, loans []AssetLoan) error {
+	return s.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		return checkoutAssets(tx, loans)
+	})
+}
+
+func checkoutAssets(tx *gorm.DB, loans []AssetLoan) error {
+	for i := range loans {
+		available, err := changeAssetStatus(tx, loans[i].AssetID, AssetAssigned, AssetAvailable)
+		if err != nil {
+			return err
+		}
+		if !available {
+			return ErrAssetNotAvailable
+		}
+
+		var free int64
+		err = notReservedByOthers(tx.Model(&Asset{}), loans[i].UserID, loans[i].CheckoutDate, loans[i].ExpectedCheckinDate).
+			Where("assets.id = ?", loans[i].AssetID).
+			Count(&free).Error
+		if err != nil {
+			return err
+		}
+		if free == 0 {
+			return ErrAssetReserved
+		}
+
+		if err := tx.Omit(clause.Associations).Create(&loans[i]).Error; err != nil {
+			return err
+		}
+
+		err = publish(tx, AssetCheckedOut{
+			AssetID:             loans[i].AssetID,
+			LoanID:              loans[i].ID,
+			UserID:              loans[i].UserID,
+			CheckoutDate:        loans[i].CheckoutDate,
+			ExpectedCheckinDate: loans[i].ExpectedCheckinDate,
+		})
+		if err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// Checkin moves an asset to status and closes its open loans, together with
+// those of the contained assets that are still assigned.
+func (s *AssetLoanStore) Checkin(ctx context.Context, assetID int64, contents []int64, status AssetStatus, at time.Time) error {
+	return s.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		changed, err := changeAssetStatus(tx, assetID, status)
+		if err != nil {
+			return err
+		}
+		if !changed {
+			return ErrNotFound
+		}
+
+		if err := closeLoans(tx, assetID, status, at); err != nil {
+			return err
+		}
+
+		for _, id := range contents {
+			assigned, err := changeAssetStatus(tx, id, status, AssetAssigned)
+			if err != nil {
+				return err
+			}
+			if !assigned {
+				continue
+			}
+
+			if err := closeLoans(tx, id, status, at); err != nil {
+				return err
+			}
+		}
+
+		return nil
+	})
+}
+
+func closeLoans(tx *gorm.DB, assetID int64, status AssetStatus, at time.Time) error {
+	var loans []AssetLoan
+	err := tx.Clauses(clause.Returning{}).
+		Model(&loans).
+		Where("asset_id = ? AND actual_return_date IS NULL", assetID).
+		Update("actual_return_date", at).Error
+	if err != nil {
+		return err
+	}
+
+	for _, loan := range loans {
+		err := publish(tx, AssetCheckedIn{
+			AssetID:    assetID,
+			LoanID:     loan.ID,
+			UserID:     loan.UserID,
+			ReturnDate: at,
+			Status:     status,
+		})
+		if err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
 func (s *AssetLoanStore) UpdateStatus(ctx context.Context, assetID int64, status AssetStatus) error {
 	result := s.db.WithContext(ctx).
 		Model(&AssetLoan{}).
@@ -52,3 +189,53 @@ func (s *AssetLoanStore) UpdateStatus(ctx context.Context, assetID int64, status
 
 	return nil
 }
+
+func (s *AssetLoanStore) List(ctx context.Context, spec QuerySpec) (*Pagination, error) {
+	query := s.db.WithContext(ctx).Model(&AssetLoan{}).
+		Joins("Asset").
+		Joins("User")
+
+	return paginate[AssetLoan](query, spec, assetLoanQueryFields, "asset_loans.id")
+}
+
+func (s *AssetLoanStore) Stream(ctx context.Context, spec QuerySpec, fn func(*AssetLoan) error) error {
+	query := s.db.Model(&AssetLoan{}).
+		Joins("Asset").
+		Joins("User")
+
+	return streamQuery(ctx, query, spec, assetLoanQueryFields, "asset_loans.id", fn)
+}
+
+// CurrentHolder returns the user an asset is checked out to, or nil when it is
+// not checked out.
+func (s *AssetLoanStore) CurrentHolder(ctx context.Context, assetID int64) (*int64, error) {
+	var loans []AssetLoan
+
+	err := s.db.WithContext(ctx).
+		Where("asset_id = ? AND actual_return_date IS NULL", assetID).
+		Order("checkout_date DESC").
+		Limit(1).
+		Find(&loans).Error
+	if err != nil || len(loans) == 0 {
+		return nil, err
+	}
+
+	return &loans[0].UserID, nil
+}
+
+// HeldBy returns the loans of the assets a user has checked out.
+func (s *AssetLoanStore) HeldBy(ctx context.Context, userID int64) ([]AssetLoan, error) {
+	loans := []AssetLoan{}
+
+	err := s.db.WithContext(ctx).
+		Joins("Asset").
+		Joins("User").
+		Where("asset_loans.user_id = ? AND asset_loans.actual_return_date IS NULL", userID).
+		Order("asset_loans.checkout_date, asset_loans.id").
+		Find(&loans).Error
+	if err != nil {
+		return nil, err
+	}
+
+	return loans, nil
+}
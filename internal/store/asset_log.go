@@ -15,6 +15,9 @@ const (
 	ActionAssigned AssetAction = "ASSIGNED"
 	ActionReturned AssetAction = "RETURNED"
 	ActionDeleted  AssetAction = "DELETED"
+
+	ActionComponentInstalled AssetAction = "COMPONENT_INSTALLED"
+	ActionComponentRemoved   AssetAction = "COMPONENT_REMOVED"
 )
 
 type AssetLog struct {
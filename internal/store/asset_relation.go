@@ -0,0 +1,238 @@
+package store
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sort"
+	"time"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+var ErrRelationCycle = errors.New("the relation would create a cycle")
+
+type AssetRelationType string
+
+const (
+	// the from asset physically holds the to asset, e.g. a rack and a server
+	RelationContains AssetRelationType = "CONTAINS"
+	// a cable or link between two assets, it has no direction
+	RelationConnectedTo AssetRelationType = "CONNECTED_TO"
+	// the from asset does not work without the to asset
+	RelationDependsOn AssetRelationType = "DEPENDS_ON"
+)
+
+var AssetRelationTypes = []AssetRelationType{RelationContains, RelationConnectedTo, RelationDependsOn}
+
+// Directed reports whether cycles are forbidden for the relation type.
+func (t AssetRelationType) Directed() bool {
+	return t != RelationConnectedTo
+}
+
+// AssetRelation links two assets, read as "from <type> to".
+type AssetRelation struct {
+	ID int64 `gorm:"primaryKey"`
+
+	FromAssetID int64 `gorm:"not null;uniqueIndex:idx_asset_relations_edge"`
+	FromAsset   Asset `gorm:"constraint:OnDelete:CASCADE;"`
+	ToAssetID   int64 `gorm:"not null;index;uniqueIndex:idx_asset_relations_edge;check:to_asset_id <> from_asset_id"`
+	ToAsset     Asset `gorm:"constraint:OnDelete:CASCADE;"`
+
+	Type AssetRelationType `gorm:"type:varchar(20);not null;uniqueIndex:idx_asset_relations_edge"`
+
+	CreatedByID *int64 `gorm:"index"`
+	CreatedBy   *User  `gorm:"constraint:OnDelete:SET NULL;"`
+
+	Notes     string `gorm:"size:255"`
+	CreatedAt time.Time
+
+	// how far from the starting asset a walk found the relation
+	Depth int `gorm:"-"`
+}
+
+var assetRelationMigrations = []string{
+	// an asset sits in one container at a time
+	`CREATE UNIQUE INDEX IF NOT EXISTS idx_asset_relations_container
+		ON asset_relations (to_asset_id)
+		WHERE type = 'CONTAINS'`,
+}
+
+type AssetRelationStore struct {
+	db *gorm.DB
+}
+
+func preloadAssetRelation(db *gorm.DB) *gorm.DB {
+	return db.
+		Joins("FromAsset").
+		Joins("ToAsset")
+}
+
+// GetByAsset returns the relations an asset is on either side of.
+func (s *AssetRelationStore) GetByAsset(ctx context.Context, assetID int64) ([]AssetRelation, error) {
+	relations := []AssetRelation{}
+
+	err := preloadAssetRelation(s.db.WithContext(ctx)).
+		Where("asset_relations.from_asset_id = ? OR asset_relations.to_asset_id = ?", assetID, assetID).
+		Order("asset_relations.type, asset_relations.id").
+		Find(&relations).Error
+	if err != nil {
+		return nil, err
+	}
+
+	return relations, nil
+}
+
+func (s *AssetRelationStore) GetByID(ctx context.Context, assetID, id int64) (*AssetRelation, error) {
+	var relation AssetRelation
+
+	err := preloadAssetRelation(s.db.WithContext(ctx)).
+		Where("asset_relations.from_asset_id = ? OR asset_relations.to_asset_id = ?", assetID, assetID).
+		First(&relation, "asset_relations.id = ?", id).Error
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, ErrNotFound
+		}
+		return nil, err
+	}
+
+	return &relation, nil
+}
+
+// Create adds a relation. It fails with ErrRelationCycle when the to asset
+// already reaches the from asset through relations of the same type, and
+// with ErrConflict when the relation exists or the to asset is already in
+// another container.
+func (s *AssetRelationStore) Create(ctx context.Context, relation *AssetRelation) error {
+	err := s.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		// writers take turns so two concurrent relations cannot close a cycle
+		// that neither of them sees on its own
+		if err := tx.Exec("LOCK TABLE asset_relations IN SHARE ROW EXCLUSIVE MODE").Error; err != nil {
+			return err
+		}
+
+		if relation.Type.Directed() {
+			var cycle bool
+			err := tx.Raw(`
+				WITH RECURSIVE reach(id) AS (
+					SELECT ?::bigint
+					UNION
+					SELECT r.to_asset_id
+					FROM asset_relations r
+					JOIN reach ON r.from_asset_id = reach.id
+					WHERE r.type = ?
+				)
+				SELECT EXISTS (SELECT 1 FROM reach WHERE id = ?)`,
+				relation.ToAssetID, relation.Type, relation.FromAssetID,
+			).Scan(&cycle).Error
+			if err != nil {
+				return err
+			}
+			if cycle {
+				return ErrRelationCycle
+			}
+		} else {
+			var reverse int64
+			err := tx.Model(&AssetRelation{}).
+				Where("from_asset_id = ? AND to_asset_id = ? AND type = ?", relation.ToAssetID, relation.FromAssetID, relation.Type).
+				Count(&reverse).Error
+			if err != nil {
+				return err
+			}
+			if reverse > 0 {
+				return ErrConflict
+			}
+		}
+
+		return tx.Omit(clause.Associations).Create(relation).Error
+	})
+	if isUniqueViolation(err) {
+		return ErrConflict
+	}
+
+	return err
+}
+
+func (s *AssetRelationStore) Delete(ctx context.Context, id int64) error {
+	result := s.db.WithContext(ctx).
+		Delete(&AssetRelation{}, id)
+
+	if result.Error != nil {
+		return result.Error
+	}
+
+	if result.RowsAffected == 0 {
+		return ErrNotFound
+	}
+
+	return nil
+}
+
+// Walk follows relations of the given types away from an asset, from to
+// to, or against their direction when reverse is set. Every relation found
+// is returned once, with the depth it was first reached at. Deleted assets
+// end a branch.
+func (s *AssetRelationStore) Walk(ctx context.Context, assetID int64, types []AssetRelationType, reverse bool) ([]AssetRelation, error) {
+	near, far := "from_asset_id", "to_asset_id"
+	if reverse {
+		near, far = far, near
+	}
+
+	var found []struct {
+		ID    int64
+		Depth int
+	}
+
+	err := s.db.WithContext(ctx).Raw(fmt.Sprintf(`
+		WITH RECURSIVE tree AS (
+			SELECT r.id, r.%[2]s AS far, 1 AS depth, ARRAY[r.%[1]s, r.%[2]s] AS path
+			FROM asset_relations r
+			JOIN assets a ON a.id = r.%[2]s AND a.deleted_at IS NULL
+			WHERE r.%[1]s = @asset AND r.type IN @types
+			UNION ALL
+			SELECT r.id, r.%[2]s, t.depth + 1, t.path || r.%[2]s
+			FROM asset_relations r
+			JOIN tree t ON r.%[1]s = t.far
+			JOIN assets a ON a.id = r.%[2]s AND a.deleted_at IS NULL
+			WHERE r.type IN @types AND NOT r.%[2]s = ANY(t.path)
+		)
+		SELECT id, MIN(depth) AS depth FROM tree GROUP BY id`, near, far),
+		map[string]interface{}{"asset": assetID, "types": types},
+	).Scan(&found).Error
+	if err != nil {
+		return nil, err
+	}
+
+	relations := []AssetRelation{}
+	if len(found) == 0 {
+		return relations, nil
+	}
+
+	ids := make([]int64, len(found))
+	depths := make(map[int64]int, len(found))
+	for i, f := range found {
+		ids[i] = f.ID
+		depths[f.ID] = f.Depth
+	}
+
+	err = preloadAssetRelation(s.db.WithContext(ctx)).
+		Where("asset_relations.id IN ?", ids).
+		Find(&relations).Error
+	if err != nil {
+		return nil, err
+	}
+
+	for i := range relations {
+		relations[i].Depth = depths[relations[i].ID]
+	}
+
+	sort.Slice(relations, func(i, j int) bool {
+		if relations[i].Depth != relations[j].Depth {
+			return relations[i].Depth < relations[j].Depth
+		}
+		return relations[i].ID < relations[j].ID
+	})
+
+	return relations, nil
+}
@@ -0,0 +1,429 @@
+package store
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+var (
+	ErrNotApprover        = errors.New("the request is not awaiting your approval")
+	ErrRequestNotPending  = errors.New("request is no longer pending")
+	ErrRequestNotApproved = errors.New("request is not approved")
+)
+
+// fallbackApproverRole takes over department manager steps for users who have
+// no department manager, or who manage their own department.
+const fallbackApproverRole = "admin"
+
+type AssetRequestStatus string
+
+const (
+	RequestPending   AssetRequestStatus = "PENDING"
+	RequestApproved  AssetRequestStatus = "APPROVED"
+	RequestRejected  AssetRequestStatus = "REJECTED"
+	RequestCancelled AssetRequestStatus = "CANCELLED"
+	RequestFulfilled AssetRequestStatus = "FULFILLED"
+)
+
+type ApprovalDecision string
+
+const (
+	DecisionPending  ApprovalDecision = "PENDING"
+	DecisionApproved ApprovalDecision = "APPROVED"
+	DecisionRejected ApprovalDecision = "REJECTED"
+)
+
+// AssetRequest is a user asking for an asset of a requestable model. Once
+// every approval is given it is fulfilled by assigning an asset of the model
+// to the requester.
+type AssetRequest struct {
+	ID int64 `gorm:"primaryKey"`
+
+	RequesterID  int64       `gorm:"not null;index"`
+	Requester    User        `gorm:"constraint:OnDelete:CASCADE;"`
+	DepartmentID *int64      `gorm:"index"`
+	Department   *Department `gorm:"constraint:OnDelete:SET NULL;"`
+	ModelID      int64       `gorm:"not null;index"`
+	Model        Model       `gorm:"constraint:OnDelete:RESTRICT;"`
+
+	Justification string  `gorm:"size:1000;not null"`
+	EstimatedCost float64 `gorm:"not null;default:0"`
+
+	Status AssetRequestStatus `gorm:"type:varchar(20);not null;default:'PENDING';index"`
+
+	Approvals []AssetRequestApproval `gorm:"foreignKey:RequestID;constraint:OnDelete:CASCADE;"`
+
+	AssetID       *int64           `gorm:"index"`
+	Asset         *Asset           `gorm:"constraint:OnDelete:SET NULL;"`
+	AssignmentID  *int64           `gorm:"index"`
+	Assignment    *AssetAssignment `gorm:"constraint:OnDelete:SET NULL;"`
+	FulfilledByID *int64           `gorm:"index"`
+	FulfilledBy   *User            `gorm:"constraint:OnDelete:SET NULL;"`
+	FulfilledAt   *time.Time
+
+	CreatedAt time.Time
+	UpdatedAt time.Time
+}
+
+// CurrentApproval is the first approval still waiting for a decision.
+func (r *AssetRequest) CurrentApproval() *AssetRequestApproval {
+	if r.Status != RequestPending {
+		return nil
+	}
+	for i := range r.Approvals {
+		if r.Approvals[i].Decision == DecisionPending {
+			return &r.Approvals[i]
+		}
+	}
+	return nil
+}
+
+// AssetRequestApproval is a step of the approval chain as it applied to a
+// request when it was submitted. It is decided by ApproverID, or by any user
+// holding RoleID.
+type AssetRequestApproval struct {
+	ID        int64  `gorm:"primaryKey"`
+	RequestID int64  `gorm:"not null;uniqueIndex:idx_asset_request_approvals_position"`
+	Position  int    `gorm:"not null;uniqueIndex:idx_asset_request_approvals_position"`
+	Name      string `gorm:"size:100;not null"`
+
+	ApproverID *int64 `gorm:"index"`
+	Approver   *User  `gorm:"constraint:OnDelete:SET NULL;"`
+	RoleID     *int64 `gorm:"index"`
+	Role       *Role  `gorm:"constraint:OnDelete:SET NULL;"`
+
+	Decision    ApprovalDecision `gorm:"type:varchar(20);not null;default:'PENDING'"`
+	DecidedByID *int64           `gorm:"index"`
+	DecidedBy   *User            `gorm:"constraint:OnDelete:SET NULL;"`
+	DecidedAt   *time.Time
+	Comment     string `gorm:"size:1000"`
+}
+
+// CanDecide reports whether the user is an approver of the step.
+func (a *AssetRequestApproval) CanDecide(user *User) bool {
+	if a.ApproverID != nil {
+		return *a.ApproverID == user.ID
+	}
+	return a.RoleID != nil && *a.RoleID == user.RoleID
+}
+
+var assetRequestMigrations = []string{
+	// users.department_id cannot be a gorm association, departments already
+	// reference users through their manager
+	`ALTER TABLE users ADD CONSTRAINT fk_users_department
+		FOREIGN KEY (department_id) REFERENCES departments (id) ON DELETE SET NULL`,
+}
+
+type AssetRequestStore struct {
+	db *gorm.DB
+}
+
+var assetRequestQueryFields = QueryFields{
+	"id":            {"asset_requests.id", IntField},
+	"requesterId":   {"asset_requests.requester_id", IntField},
+	"departmentId":  {"asset_requests.department_id", IntField},
+	"modelId":       {"asset_requests.model_id", IntField},
+	"status":        {"asset_requests.status", StringField},
+	"estimatedCost": {"asset_requests.estimated_cost", FloatField},
+	"createdAt":     {"asset_requests.created_at", TimeField},
+	"updatedAt":     {"asset_requests.updated_at", TimeField},
+}
+
+func preloadAssetRequest(db *gorm.DB) *gorm.DB {
+	return db.
+		Joins("Requester").
+		Joins("Department").
+		Joins("Model").
+		Joins("Asset").
+		Preload("Approvals", func(db *gorm.DB) *gorm.DB {
+			return db.
+				Joins("Approver").
+				Joins("Role").
+				Joins("DecidedBy").
+				Order("asset_request_approvals.position")
+		})
+}
+
+func (s *AssetRequestStore) List(ctx context.Context, spec QuerySpec) (*Pagination, error) {
+	query := preloadAssetRequest(s.db.WithContext(ctx).Model(&AssetRequest{}))
+
+	return paginate[AssetRequest](query, spec, assetRequestQueryFields, "asset_requests.id")
+}
+
+// ListByRequester pages through the requests a user submitted.
+func (s *AssetRequestStore) ListByRequester(ctx context.Context, userID int64, spec QuerySpec) (*Pagination, error) {
+	query := preloadAssetRequest(s.db.WithContext(ctx).Model(&AssetRequest{})).
+		Where("asset_requests.requester_id = ?", userID)
+
+	return paginate[AssetRequest](query, spec, assetRequestQueryFields, "asset_requests.id")
+}
+
+// ListAwaiting pages through the pending requests whose current approval the
+// user can decide. Users never approve their own requests.
+func (s *AssetRequestStore) ListAwaiting(ctx context.Context, user *User, spec QuerySpec) (*Pagination, error) {
+	query := preloadAssetRequest(s.db.WithContext(ctx).Model(&AssetRequest{})).
+		Where("asset_requests.status = ? AND asset_requests.requester_id <> ?", RequestPending, user.ID).
+		Where(`EXISTS (
+			SELECT 1 FROM asset_request_approvals a
+			WHERE a.request_id = asset_requests.id
+				AND a.position = (
+					SELECT MIN(p.position) FROM asset_request_approvals p
+					WHERE p.request_id = asset_requests.id AND p.decision = ?)
+				AND (a.approver_id = ? OR (a.approver_id IS NULL AND a.role_id = ?)))`,
+			DecisionPending, user.ID, user.RoleID)
+
+	return paginate[AssetRequest](query, spec, assetRequestQueryFields, "asset_requests.id")
+}
+
+func (s *AssetRequestStore) GetByID(ctx context.Context, id int64) (*AssetRequest, error) {
+	var request AssetRequest
+
+	err := preloadAssetRequest(s.db.WithContext(ctx)).
+		First(&request, "asset_requests.id = ?", id).Error
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, ErrNotFound
+		}
+		return nil, err
+	}
+
+	return &request, nil
+}
+
+// Create submits a request. The approval chain is copied onto it, leaving out
+// the steps its EstimatedCost is below. A request no step applies to is
+// approved straight away.
+func (s *AssetRequestStore) Create(ctx context.Context, request *AssetRequest) error {
+	return s.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		var requester User
+		if err := tx.First(&requester, request.RequesterID).Error; err != nil {
+			return err
+		}
+		request.DepartmentID = requester.DepartmentID
+
+		var steps []ApprovalStep
+		if err := tx.Order("position").Find(&steps).Error; err != nil {
+			return err
+		}
+
+		var approvals []AssetRequestApproval
+		for _, step := range steps {
+			if !step.AppliesTo(request.EstimatedCost) {
+				continue
+			}
+
+			approval := AssetRequestApproval{
+				Position: len(approvals) + 1,
+				Name:     step.Name,
+				Decision: DecisionPending,
+			}
+
+			switch step.Approver {
+			case ApproverDepartmentManager:
+				managerID, err := departmentManager(tx, requester.DepartmentID)
+				if err != nil {
+					return err
+				}
+				if managerID != nil && *managerID != requester.ID {
+					approval.ApproverID = managerID
+				} else {
+					var role Role
+					if err := tx.Where("name = ?", fallbackApproverRole).First(&role).Error; err != nil {
+						return fmt.Errorf("no approver for %s: %w", step.Name, err)
+					}
+					approval.RoleID = &role.ID
+				}
+			case ApproverRole:
+				approval.RoleID = step.RoleID
+			}
+
+			approvals = append(approvals, approval)
+		}
+
+		request.Status = RequestPending
+		if len(approvals) == 0 {
+			request.Status = RequestApproved
+		}
+
+		if err := tx.Omit(clause.Associations).Create(request).Error; err != nil {
+			return err
+		}
+
+		if len(approvals) == 0 {
+			return nil
+		}
+
+		for i := range approvals {
+			approvals[i].RequestID = request.ID
+		}
+		request.Approvals = approvals
+
+		return tx.Omit(clause.Associations).Create(&request.Approvals).Error
+	})
+}
+
+func departmentManager(tx *gorm.DB, departmentID *int64) (*int64, error) {
+	if departmentID == nil {
+		return nil, nil
+	}
+
+	var department Department
+	if err := tx.First(&department, *departmentID).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, nil
+		}
+		return nil, err
+	}
+
+	return department.ManagerID, nil
+}
+
+// lockRequest loads a request for update.
+func lockRequest(tx *gorm.DB, id int64) (*AssetRequest, error) {
+	var request AssetRequest
+
+	err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).
+		First(&request, id).Error
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, ErrNotFound
+		}
+		return nil, err
+	}
+
+	return &request, nil
+}
+
+// Decide records the user's decision on the current approval of a request.
+// A rejection rejects the request, the last approval approves it. It fails
+// with ErrNotApprover when the current approval is not the user's to give.
+func (s *AssetRequestStore) Decide(ctx context.Context, id int64, user *User, decision ApprovalDecision, comment string) error {
+	return s.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		request, err := lockRequest(tx, id)
+		if err != nil {
+			return err
+		}
+		if request.Status != RequestPending {
+			return ErrRequestNotPending
+		}
+		if request.RequesterID == user.ID {
+			return ErrNotApprover
+		}
+
+		if err := tx.Where("request_id = ?", id).Order("position").Find(&request.Approvals).Error; err != nil {
+			return err
+		}
+
+		current := request.CurrentApproval()
+		if current == nil || !current.CanDecide(user) {
+			return ErrNotApprover
+		}
+
+		now := time.Now()
+		err = tx.Model(current).Updates(map[string]interface{}{
+			"decision":      decision,
+			"decided_by_id": user.ID,
+			"decided_at":    now,
+			"comment":       comment,
+		}).Error
+		if err != nil {
+			return err
+		}
+		current.Decision = decision
+
+		status := RequestPending
+		switch {
+		case decision == DecisionRejected:
+			status = RequestRejected
+		case request.CurrentApproval() == nil:
+			status = RequestApproved
+		}
+		if status == RequestPending {
+			return nil
+		}
+
+		return tx.Model(request).Update("status", status).Error
+	})
+}
+
+// Cancel withdraws a request that was not fulfilled or rejected yet.
+func (s *AssetRequestStore) Cancel(ctx context.Context, id int64) error {
+	result := s.db.WithContext(ctx).
+		Model(&AssetRequest{}).
+		Where("id = ? AND status IN ?", id, []AssetRequestStatus{RequestPending, RequestApproved}).
+		Update("status", RequestCancelled)
+
+	if result.Error != nil {
+		return result.Error
+	}
+
+	if result.RowsAffected == 0 {
+		return ErrRequestNotPending
+	}
+
+	return nil
+}
+
+// Fulfill assigns an asset of the requested model to the requester of an
+// approved request. With no assetID the first available asset is picked.
+// It fails with ErrAssetNotAvailable when the asset is taken, booked by
+// another user, or none is left.
+func (s *AssetRequestStore) Fulfill(ctx context.Context, id int64, assetID *int64, performedBy int64) error {
+	return s.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		request, err := lockRequest(tx, id)
+		if err != nil {
+			return err
+		}
+		if request.Status != RequestApproved {
+			return ErrRequestNotApproved
+		}
+
+		// an assignment has no end, so any later booking stands in its way
+		query := notReservedByOthers(tx, request.RequesterID, time.Now(), nil).
+			Clauses(clause.Locking{Strength: "UPDATE", Options: "SKIP LOCKED"}).
+			Where("model_id = ? AND status = ?", request.ModelID, AssetAvailable)
+		if assetID != nil {
+			query = query.Where("id = ?", *assetID)
+		}
+
+		var asset Asset
+		if err := query.Order("id").First(&asset).Error; err != nil {
+			if errors.Is(err, gorm.ErrRecordNotFound) {
+				return ErrAssetNotAvailable
+			}
+			return err
+		}
+
+		assigned, err := changeAssetStatus(tx, asset.ID, AssetAssigned, AssetAvailable)
+		if err != nil {
+			return err
+		}
+		if !assigned {
+			return ErrAssetNotAvailable
+		}
+
+		now := time.Now()
+		assignment := AssetAssignment{
+			AssetID:    asset.ID,
+			UserID:     request.RequesterID,
+			AssignedAt: now,
+			Notes:      fmt.Sprintf("Asset request #%d", request.ID),
+		}
+		if err := tx.Omit(clause.Associations).Create(&assignment).Error; err != nil {
+			return err
+		}
+
+		return tx.Model(request).Updates(map[string]interface{}{
+			"status":          RequestFulfilled,
+			"asset_id":        asset.ID,
+			"assignment_id":   assignment.ID,
+			"fulfilled_by_id": performedBy,
+			"fulfilled_at":    now,
+		}).Error
+	})
+}
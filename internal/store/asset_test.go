@@ -0,0 +1,81 @@
+package store
+
+import (
+	"testing"
+	"time"
+)
+
+func TestAssetBookValue(t *testing.T) {
+	day := func(y int, m time.Month, d int) time.Time {
+		return time.Date(y, m, d, 0, 0, 0, 0, time.UTC)
+	}
+
+	tests := []struct {
+		name  string
+		asset Asset
+		at    time.Time
+		want  float64
+	}{
+		{
+			"not depreciated",
+			Asset{PurchaseCost: 1000, PurchaseDate: day(2025, 1, 1), UsefulLifeYears: 2, DepreciationMethod: DepreciationNone},
+			day(2026, 1, 1), 1000,
+		},
+		{
+			"no useful life",
+			Asset{PurchaseCost: 1000, PurchaseDate: day(2025, 1, 1), DepreciationMethod: DepreciationStraightLine},
+			day(2026, 1, 1), 1000,
+		},
+		{
+			"no purchase date",
+			Asset{PurchaseCost: 1000, UsefulLifeYears: 2, DepreciationMethod: DepreciationStraightLine},
+			day(2026, 1, 1), 1000,
+		},
+		{
+			"before purchase",
+			Asset{PurchaseCost: 1000, SalvageValue: 200, PurchaseDate: day(2025, 1, 1), UsefulLifeYears: 2, DepreciationMethod: DepreciationStraightLine},
+			day(2024, 6, 1), 1000,
+		},
+		{
+			"straight line halfway",
+			Asset{PurchaseCost: 1000, SalvageValue: 200, PurchaseDate: day(2025, 1, 1), UsefulLifeYears: 2, DepreciationMethod: DepreciationStraightLine},
+			day(2026, 1, 1), 600,
+		},
+		{
+			"end of life",
+			Asset{PurchaseCost: 1000, SalvageValue: 200, PurchaseDate: day(2025, 1, 1), UsefulLifeYears: 2, DepreciationMethod: DepreciationStraightLine},
+			day(2030, 1, 1), 200,
+		},
+		{
+			"salvage above cost",
+			Asset{PurchaseCost: 100, SalvageValue: 150, PurchaseDate: day(2025, 1, 1), UsefulLifeYears: 2, DepreciationMethod: DepreciationStraightLine},
+			day(2030, 1, 1), 100,
+		},
+		{
+			"declining balance",
+			Asset{PurchaseCost: 1000, PurchaseDate: day(2025, 1, 1), UsefulLifeYears: 4, DepreciationMethod: DepreciationDecliningBalance},
+			day(2026, 1, 1), 500.24,
+		},
+		{
+			"declining balance floored at salvage",
+			Asset{PurchaseCost: 1000, SalvageValue: 200, PurchaseDate: day(2025, 1, 1), UsefulLifeYears: 4, DepreciationMethod: DepreciationDecliningBalance},
+			day(2028, 1, 1), 200,
+		},
+		{
+			"components left out",
+			Asset{
+				PurchaseCost: 1000, PurchaseDate: day(2025, 1, 1), UsefulLifeYears: 2, DepreciationMethod: DepreciationNone,
+				Components: []ComponentInstall{{Quantity: 2, Component: Component{UnitCost: 50}}},
+			},
+			day(2026, 1, 1), 1000,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := tt.asset.BookValue(tt.at); got != tt.want {
+				t.Errorf("BookValue(%v) = %v, want %v", tt.at, got, tt.want)
+			}
+		})
+	}
+}
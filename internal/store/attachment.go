@@ -0,0 +1,149 @@
+package store
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"time"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+const (
+	AttachmentOwnerAsset       = "assets"
+	AttachmentOwnerModel       = "models"
+	AttachmentOwnerSupplier    = "suppliers"
+	AttachmentOwnerMaintenance = "maintenances"
+	AttachmentOwnerAssignment  = "asset_assignments"
+	AttachmentOwnerDisposal    = "disposals"
+	AttachmentOwnerInvoice     = "invoices"
+)
+
+// Attachment is a file kept for a record, such as an invoice or the signed
+// receipt of an assignment. OwnerType is the owner's table. Uploads live in
+// the blob store under StorageKey; documents the API generates, and files
+// from before there was one, are kept inline in Content.
+type Attachment struct {
+	ID        int64  `gorm:"primaryKey"`
+	OwnerType string `gorm:"size:50;not null;index:idx_attachments_owner"`
+	OwnerID   int64  `gorm:"not null;index:idx_attachments_owner"`
+
+	FileName    string `gorm:"size:255;not null"`
+	ContentType string `gorm:"size:100;not null"`
+	Size        int64  `gorm:"not null"`
+	Checksum    string `gorm:"size:64;not null"`
+	StorageKey  string `gorm:"size:255"`
+	Content     []byte `gorm:"type:bytea"`
+
+	UploadedByID *int64 `gorm:"index"`
+	UploadedBy   *User  `gorm:"constraint:OnDelete:SET NULL;"`
+
+	CreatedAt time.Time
+}
+
+// NewAttachment wraps generated content, filling in its size and checksum.
+func NewAttachment(ownerType string, ownerID int64, fileName, contentType string, content []byte) *Attachment {
+	sum := sha256.Sum256(content)
+
+	return &Attachment{
+		OwnerType:   ownerType,
+		OwnerID:     ownerID,
+		FileName:    fileName,
+		ContentType: contentType,
+		Size:        int64(len(content)),
+		Checksum:    hex.EncodeToString(sum[:]),
+		Content:     content,
+	}
+}
+
+type AttachmentStore struct {
+	db *gorm.DB
+}
+
+func (s *AttachmentStore) GetByID(ctx context.Context, id int64) (*Attachment, error) {
+	var attachment Attachment
+
+	err := s.db.WithContext(ctx).First(&attachment, id).Error
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, ErrNotFound
+		}
+		return nil, err
+	}
+
+	return &attachment, nil
+}
+
+// ListByOwner lists the attachments of a record, leaving out inline content.
+func (s *AttachmentStore) ListByOwner(ctx context.Context, ownerType string, ownerID int64) ([]Attachment, error) {
+	var attachments []Attachment
+
+	err := s.db.WithContext(ctx).
+		Omit("content").
+		Where("owner_type = ? AND owner_id = ?", ownerType, ownerID).
+		Order("id").
+		Find(&attachments).Error
+	if err != nil {
+		return nil, err
+	}
+
+	return attachments, nil
+}
+
+func (s *AttachmentStore) Create(ctx context.Context, attachment *Attachment) error {
+	return createAttachment(s.db.WithContext(ctx), attachment)
+}
+
+func (s *AttachmentStore) Delete(ctx context.Context, id int64) error {
+	result := s.db.WithContext(ctx).Delete(&Attachment{}, id)
+	if result.Error != nil {
+		return result.Error
+	}
+
+	if result.RowsAffected == 0 {
+		return ErrNotFound
+	}
+
+	return nil
+}
+
+// DeleteByOwner removes the attachments of a deleted record, returning the
+// storage keys of the blobs left to remove. Attachments are owned
+// polymorphically, so there is no foreign key to cascade the delete.
+func (s *AttachmentStore) DeleteByOwner(ctx context.Context, ownerType string, ownerID int64) ([]string, error) {
+	var attachments []Attachment
+
+	err := s.db.WithContext(ctx).
+		Clauses(clause.Returning{Columns: []clause.Column{{Name: "storage_key"}}}).
+		Where("owner_type = ? AND owner_id = ?", ownerType, ownerID).
+		Delete(&attachments).Error
+	if err != nil {
+		return nil, err
+	}
+
+	var keys []string
+	for _, a := range attachments {
+		if a.StorageKey != "" {
+			keys = append(keys, a.StorageKey)
+		}
+	}
+
+	return keys, nil
+}
+
+// deleteAttachment removes an attachment, returning its storage key.
+func deleteAttachment(tx *gorm.DB, id int64) (string, error) {
+	var attachment Attachment
+
+	err := tx.Clauses(clause.Returning{Columns: []clause.Column{{Name: "storage_key"}}}).
+		Where("id = ?", id).
+		Delete(&attachment).Error
+
+	return attachment.StorageKey, err
+}
+
+func createAttachment(tx *gorm.DB, attachment *Attachment) error {
+	return tx.Omit(clause.Associations).Create(attachment).Error
+}
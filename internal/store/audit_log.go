@@ -106,5 +106,41 @@ func (r *auditRepository) Log(
 	// 	log.Metadata = datatypes.JSON(entry.Metadata)
 	// }
 
-	return tx.WithContext(ctx).Create(&log).Error
+	if err := tx.WithContext(ctx).Create(&log).Error; err != nil {
+		return err
+	}
+
+	return publish(tx.WithContext(ctx), AuditLogged{
+		AuditLogID: log.ID,
+		TableName:  log.TableName,
+		RecordID:   log.RecordID,
+		Operation:  log.Operation,
+		ChangedBy:  log.ChangedBy,
+	})
+}
+
+type AuditLogStore struct {
+	db *gorm.DB
+}
+
+var auditLogQueryFields = QueryFields{
+	"id":        {"audit_logs.id", IntField},
+	"tableName": {"audit_logs.table_name", StringField},
+	"recordId":  {"audit_logs.record_id", StringField},
+	"operation": {"audit_logs.operation", StringField},
+	"changedAt": {"audit_logs.changed_at", TimeField},
+	"changedBy": {"audit_logs.changed_by", StringField},
+	"requestId": {"audit_logs.request_id", StringField},
+}
+
+func (s *AuditLogStore) List(ctx context.Context, spec QuerySpec) (*Pagination, error) {
+	query := s.db.WithContext(ctx).Model(&AuditLog{})
+
+	return paginate[AuditLog](query, spec, auditLogQueryFields, "audit_logs.id")
+}
+
+func (s *AuditLogStore) Stream(ctx context.Context, spec QuerySpec, fn func(*AuditLog) error) error {
+	query := s.db.Model(&AuditLog{})
+
+	return streamQuery(ctx, query, spec, auditLogQueryFields, "audit_logs.id", fn)
 }
@@ -12,6 +12,9 @@ type Category struct {
 	Name        string `gorm:"size:100;uniqueIndex;not null"`
 	Description string `gorm:"size:255"`
 
+	// EULA is the usage policy users accept with assets of the category
+	EULA string `gorm:"type:text"`
+
 	CreatedAt time.Time
 	UpdatedAt time.Time
 }
@@ -21,6 +24,14 @@ type CategoryStore struct {
 	auditService AuditService
 }
 
+var categoryQueryFields = QueryFields{
+	"id":          {"categories.id", IntField},
+	"name":        {"categories.name", StringField},
+	"description": {"categories.description", StringField},
+	"createdAt":   {"categories.created_at", TimeField},
+	"updatedAt":   {"categories.updated_at", TimeField},
+}
+
 func (s *CategoryStore) GetAll(ctx context.Context) ([]Category, error) {
 	var categories []Category
 
@@ -32,13 +43,10 @@ func (s *CategoryStore) GetAll(ctx context.Context) ([]Category, error) {
 	return categories, nil
 }
 
-func (s *CategoryStore) List(pagination Pagination) (*Pagination, error) {
-	var categories []*Category
-
-	s.db.Scopes(paginate(categories, &pagination, s.db)).Find(&categories)
-	pagination.Rows = categories
+func (s *CategoryStore) List(ctx context.Context, spec QuerySpec) (*Pagination, error) {
+	query := s.db.WithContext(ctx).Model(&Category{})
 
-	return &pagination, nil
+	return paginate[Category](query, spec, categoryQueryFields, "categories.id")
 }
 
 func (s *CategoryStore) GetByID(ctx context.Context, id int64) (*Category, error) {
@@ -0,0 +1,278 @@
+package store
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// Component is a part that is installed into assets, such as a memory module
+// or a drive. Available is what is not installed anywhere.
+type Component struct {
+	ID   int64  `gorm:"primaryKey"`
+	Name string `gorm:"size:150;not null"`
+
+	CategoryID     int64         `gorm:"not null;index"`
+	Category       Category      `gorm:"constraint:OnDelete:RESTRICT;"`
+	ManufacturerID *int64        `gorm:"index"`
+	Manufacturer   *Manufacturer `gorm:"constraint:OnDelete:SET NULL;"`
+	SupplierID     *int64        `gorm:"index"`
+	Supplier       *Supplier     `gorm:"constraint:OnDelete:SET NULL;"`
+
+	PartNumber   string `gorm:"size:100"`
+	SerialNumber string `gorm:"size:100;index"`
+
+	Quantity  int `gorm:"not null;default:1;check:quantity >= 0"`
+	Available int `gorm:"not null;default:1;check:available >= 0 AND available <= quantity"`
+
+	UnitCost     float64 `gorm:"not null;default:0"`
+	PurchaseDate *time.Time
+	Location     string `gorm:"size:100"`
+	Notes        string `gorm:"size:1000"`
+
+	CreatedAt time.Time
+	UpdatedAt time.Time
+}
+
+// ComponentInstall is a quantity of a component installed into an asset.
+// RemovedAt is set once it was taken out again.
+type ComponentInstall struct {
+	ID          int64     `gorm:"primaryKey"`
+	ComponentID int64     `gorm:"not null;index"`
+	Component   Component `gorm:"constraint:OnDelete:CASCADE;"`
+	AssetID     int64     `gorm:"not null;index"`
+	Asset       Asset
+
+	Quantity int `gorm:"not null;default:1"`
+
+	InstalledByID *int64 `gorm:"index"`
+	InstalledBy   *User  `gorm:"constraint:OnDelete:SET NULL;"`
+	RemovedByID   *int64 `gorm:"index"`
+	RemovedBy     *User  `gorm:"constraint:OnDelete:SET NULL;"`
+
+	InstalledAt time.Time `gorm:"not null"`
+	RemovedAt   *time.Time
+	Notes       string `gorm:"size:255"`
+}
+
+// Cost is what the installed parts are worth.
+func (i *ComponentInstall) Cost() float64 {
+	return float64(i.Quantity) * i.Component.UnitCost
+}
+
+type ComponentStore struct {
+	db *gorm.DB
+}
+
+var componentQueryFields = QueryFields{
+	"id":             {"components.id", IntField},
+	"name":           {"components.name", StringField},
+	"categoryId":     {"components.category_id", IntField},
+	"manufacturerId": {"components.manufacturer_id", IntField},
+	"supplierId":     {"components.supplier_id", IntField},
+	"partNumber":     {"components.part_number", StringField},
+	"serialNumber":   {"components.serial_number", StringField},
+	"quantity":       {"components.quantity", IntField},
+	"available":      {"components.available", IntField},
+	"unitCost":       {"components.unit_cost", FloatField},
+	"location":       {"components.location", StringField},
+	"createdAt":      {"components.created_at", TimeField},
+}
+
+var componentInstallQueryFields = QueryFields{
+	"id":          {"component_installs.id", IntField},
+	"assetId":     {"component_installs.asset_id", IntField},
+	"installedAt": {"component_installs.installed_at", TimeField},
+	"removedAt":   {"component_installs.removed_at", TimeField},
+}
+
+func preloadComponent(db *gorm.DB) *gorm.DB {
+	return db.
+		Joins("Category").
+		Joins("Manufacturer").
+		Joins("Supplier")
+}
+
+// preloadInstalledComponents loads the components currently installed in
+// assets.
+func preloadInstalledComponents(db *gorm.DB) *gorm.DB {
+	return db.Preload("Components", func(db *gorm.DB) *gorm.DB {
+		return db.Joins("Component").
+			Where("component_installs.removed_at IS NULL").
+			Order("component_installs.installed_at, component_installs.id")
+	})
+}
+
+func (s *ComponentStore) List(ctx context.Context, spec QuerySpec) (*Pagination, error) {
+	query := preloadComponent(s.db.WithContext(ctx).Model(&Component{}))
+
+	return paginate[Component](query, spec, componentQueryFields, "components.id")
+}
+
+func (s *ComponentStore) GetByID(ctx context.Context, id int64) (*Component, error) {
+	var component Component
+
+	err := preloadComponent(s.db.WithContext(ctx)).
+		First(&component, "components.id = ?", id).Error
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, ErrNotFound
+		}
+		return nil, err
+	}
+
+	return &component, nil
+}
+
+func (s *ComponentStore) Create(ctx context.Context, component *Component) error {
+	component.Available = component.Quantity
+
+	return s.db.WithContext(ctx).Omit("Category", "Manufacturer", "Supplier").Create(component).Error
+}
+
+// Update saves the component. A changed quantity moves what is available by
+// the same amount, and cannot drop below what is installed.
+func (s *ComponentStore) Update(ctx context.Context, component *Component) error {
+	result := s.db.WithContext(ctx).
+		Model(&Component{}).
+		Where("id = ?", component.ID).
+		Where("quantity - available <= ?", component.Quantity).
+		Updates(map[string]interface{}{
+			"name":            component.Name,
+			"category_id":     component.CategoryID,
+			"manufacturer_id": component.ManufacturerID,
+			"supplier_id":     component.SupplierID,
+			"part_number":     component.PartNumber,
+			"serial_number":   component.SerialNumber,
+			"available":       gorm.Expr("available + ? - quantity", component.Quantity),
+			"quantity":        component.Quantity,
+			"unit_cost":       component.UnitCost,
+			"purchase_date":   component.PurchaseDate,
+			"location":        component.Location,
+			"notes":           component.Notes,
+		})
+
+	if result.Error != nil {
+		return result.Error
+	}
+
+	if result.RowsAffected == 0 {
+		return ErrStockInUse
+	}
+
+	return nil
+}
+
+// Delete removes a component and its install history. A component that is
+// still installed somewhere cannot be deleted.
+func (s *ComponentStore) Delete(ctx context.Context, id int64) error {
+	result := s.db.WithContext(ctx).
+		Where("available = quantity").
+		Delete(&Component{}, id)
+
+	if result.Error != nil {
+		return result.Error
+	}
+
+	if result.RowsAffected == 0 {
+		var count int64
+		if err := s.db.WithContext(ctx).Model(&Component{}).Where("id = ?", id).Count(&count).Error; err != nil {
+			return err
+		}
+		if count == 0 {
+			return ErrNotFound
+		}
+		return ErrStockInUse
+	}
+
+	return nil
+}
+
+func componentLabel(c *Component, quantity int) string {
+	label := fmt.Sprintf("%d x %s", quantity, c.Name)
+	if c.SerialNumber != "" {
+		label += fmt.Sprintf(" (serial %s)", c.SerialNumber)
+	}
+	return label
+}
+
+// Install puts install.Quantity of a component into an asset and records it
+// in the asset log. The component must have enough available.
+func (s *ComponentStore) Install(ctx context.Context, component *Component, install *ComponentInstall, performedBy int64) error {
+	return s.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		result := tx.Model(&Component{}).
+			Where("id = ? AND available >= ?", component.ID, install.Quantity).
+			Update("available", gorm.Expr("available - ?", install.Quantity))
+		if result.Error != nil {
+			return result.Error
+		}
+		if result.RowsAffected == 0 {
+			return ErrInsufficientStock
+		}
+
+		install.ComponentID = component.ID
+		if install.InstalledAt.IsZero() {
+			install.InstalledAt = time.Now()
+		}
+
+		if err := tx.Omit(clause.Associations).Create(install).Error; err != nil {
+			return err
+		}
+
+		return tx.Omit(clause.Associations).Create(&AssetLog{
+			AssetID:       install.AssetID,
+			PerformedByID: performedBy,
+			Action:        ActionComponentInstalled,
+			Details:       "Installed " + componentLabel(component, install.Quantity),
+		}).Error
+	})
+}
+
+// Remove takes an installed component out of its asset, makes it available
+// again and records it in the asset log.
+func (s *ComponentStore) Remove(ctx context.Context, component *Component, installID int64, performedBy int64) error {
+	return s.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		var install ComponentInstall
+
+		result := tx.Model(&install).
+			Clauses(clause.Returning{}).
+			Where("id = ? AND component_id = ? AND removed_at IS NULL", installID, component.ID).
+			Updates(map[string]interface{}{
+				"removed_at":    time.Now(),
+				"removed_by_id": performedBy,
+			})
+		if result.Error != nil {
+			return result.Error
+		}
+		if result.RowsAffected == 0 {
+			return ErrNotFound
+		}
+
+		err := tx.Model(&Component{}).
+			Where("id = ?", component.ID).
+			Update("available", gorm.Expr("available + ?", install.Quantity)).Error
+		if err != nil {
+			return err
+		}
+
+		return tx.Omit(clause.Associations).Create(&AssetLog{
+			AssetID:       install.AssetID,
+			PerformedByID: performedBy,
+			Action:        ActionComponentRemoved,
+			Details:       "Removed " + componentLabel(component, install.Quantity),
+		}).Error
+	})
+}
+
+func (s *ComponentStore) Installs(ctx context.Context, componentID int64, spec QuerySpec) (*Pagination, error) {
+	query := s.db.WithContext(ctx).Model(&ComponentInstall{}).
+		Joins("Component").
+		Joins("Asset").
+		Where("component_installs.component_id = ?", componentID)
+
+	return paginate[ComponentInstall](query, spec, componentInstallQueryFields, "component_installs.id")
+}
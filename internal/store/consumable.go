@@ -0,0 +1,397 @@
+package store
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+var ErrInsufficientStock = errors.New("insufficient stock")
+
+type ConsumableMovementKind string
+
+const (
+	ConsumableIssue   ConsumableMovementKind = "ISSUE"
+	ConsumableReceipt ConsumableMovementKind = "RECEIPT"
+)
+
+// Consumable is stock that is handed out by quantity rather than tracked per
+// item, such as toner or cables.
+type Consumable struct {
+	ID   int64  `gorm:"primaryKey"`
+	Name string `gorm:"size:150;not null"`
+
+	CategoryID int64    `gorm:"not null;index"`
+	Category   Category `gorm:"constraint:OnDelete:RESTRICT;"`
+
+	ManufacturerID *int64        `gorm:"index"`
+	Manufacturer   *Manufacturer `gorm:"constraint:OnDelete:SET NULL;"`
+
+	ItemNumber string  `gorm:"size:100"`
+	UnitCost   float64 `gorm:"not null;default:0"`
+	Notes      string  `gorm:"size:1000"`
+
+	Stocks []ConsumableStock `gorm:"constraint:OnDelete:CASCADE;"`
+
+	CreatedAt time.Time
+	UpdatedAt time.Time
+}
+
+// ConsumableStock is the quantity of a consumable on hand at one location.
+type ConsumableStock struct {
+	ID           int64      `gorm:"primaryKey"`
+	ConsumableID int64      `gorm:"not null;uniqueIndex:idx_consumable_stocks_location"`
+	Consumable   Consumable `gorm:"constraint:OnDelete:CASCADE;"`
+	Location     string     `gorm:"size:100;not null;uniqueIndex:idx_consumable_stocks_location"`
+
+	Quantity    int `gorm:"not null;default:0;check:quantity >= 0"`
+	MinQuantity int `gorm:"not null;default:0"`
+
+	// set once the low-stock alert went out, cleared when stock is
+	// replenished above the minimum
+	LowStockNotifiedAt *time.Time
+
+	UpdatedAt time.Time
+}
+
+func (s *ConsumableStock) Low() bool {
+	return s.Quantity <= s.MinQuantity
+}
+
+// ConsumableMovement records stock issued or received. Quantity is always
+// positive; Kind tells the direction.
+type ConsumableMovement struct {
+	ID           int64      `gorm:"primaryKey"`
+	ConsumableID int64      `gorm:"not null;index"`
+	Consumable   Consumable `gorm:"constraint:OnDelete:CASCADE;"`
+
+	Kind     ConsumableMovementKind `gorm:"type:varchar(20);not null"`
+	Location string                 `gorm:"size:100;not null"`
+	Quantity int                    `gorm:"not null"`
+
+	// issued to
+	UserID       *int64      `gorm:"index"`
+	User         *User       `gorm:"constraint:OnDelete:SET NULL;"`
+	DepartmentID *int64      `gorm:"index"`
+	Department   *Department `gorm:"constraint:OnDelete:SET NULL;"`
+
+	// received from
+	SupplierID *int64    `gorm:"index"`
+	Supplier   *Supplier `gorm:"constraint:OnDelete:SET NULL;"`
+	UnitCost   float64   `gorm:"not null;default:0"`
+
+	PerformedByID *int64 `gorm:"index"`
+	PerformedBy   *User  `gorm:"constraint:OnDelete:SET NULL;"`
+
+	Notes     string `gorm:"size:1000"`
+	CreatedAt time.Time
+}
+
+// ConsumableUsage is the quantity of a consumable a department used in a month.
+type ConsumableUsage struct {
+	DepartmentID   *int64
+	DepartmentName string
+	Month          time.Time
+	ConsumableID   int64
+	ConsumableName string
+	Quantity       int64
+	Cost           float64
+}
+
+type ConsumableStore struct {
+	db *gorm.DB
+}
+
+var consumableQueryFields = QueryFields{
+	"id":             {"consumables.id", IntField},
+	"name":           {"consumables.name", StringField},
+	"categoryId":     {"consumables.category_id", IntField},
+	"manufacturerId": {"consumables.manufacturer_id", IntField},
+	"itemNumber":     {"consumables.item_number", StringField},
+	"unitCost":       {"consumables.unit_cost", FloatField},
+	"createdAt":      {"consumables.created_at", TimeField},
+}
+
+var consumableMovementQueryFields = QueryFields{
+	"id":           {"consumable_movements.id", IntField},
+	"kind":         {"consumable_movements.kind", StringField},
+	"location":     {"consumable_movements.location", StringField},
+	"quantity":     {"consumable_movements.quantity", IntField},
+	"userId":       {"consumable_movements.user_id", IntField},
+	"departmentId": {"consumable_movements.department_id", IntField},
+	"supplierId":   {"consumable_movements.supplier_id", IntField},
+	"createdAt":    {"consumable_movements.created_at", TimeField},
+}
+
+func preloadConsumable(db *gorm.DB) *gorm.DB {
+	return db.
+		Joins("Category").
+		Joins("Manufacturer").
+		Preload("Stocks", func(db *gorm.DB) *gorm.DB { return db.Order("location") })
+}
+
+func (s *ConsumableStore) List(ctx context.Context, spec QuerySpec) (*Pagination, error) {
+	query := preloadConsumable(s.db.WithContext(ctx).Model(&Consumable{}))
+
+	return paginate[Consumable](query, spec, consumableQueryFields, "consumables.id")
+}
+
+func (s *ConsumableStore) GetByID(ctx context.Context, id int64) (*Consumable, error) {
+	var consumable Consumable
+
+	err := preloadConsumable(s.db.WithContext(ctx)).
+		First(&consumable, "consumables.id = ?", id).Error
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, ErrNotFound
+		}
+		return nil, err
+	}
+
+	return &consumable, nil
+}
+
+func (s *ConsumableStore) Create(ctx context.Context, consumable *Consumable) error {
+	return s.db.WithContext(ctx).Omit("Category", "Manufacturer", "Stocks").Create(consumable).Error
+}
+
+func (s *ConsumableStore) Update(ctx context.Context, consumable *Consumable) error {
+	result := s.db.WithContext(ctx).
+		Model(&Consumable{}).
+		Where("id = ?", consumable.ID).
+		Updates(map[string]interface{}{
+			"name":            consumable.Name,
+			"category_id":     consumable.CategoryID,
+			"manufacturer_id": consumable.ManufacturerID,
+			"item_number":     consumable.ItemNumber,
+			"unit_cost":       consumable.UnitCost,
+			"notes":           consumable.Notes,
+		})
+
+	if result.Error != nil {
+		return result.Error
+	}
+
+	if result.RowsAffected == 0 {
+		return ErrNotFound
+	}
+
+	return nil
+}
+
+func (s *ConsumableStore) Delete(ctx context.Context, id int64) error {
+	result := s.db.WithContext(ctx).
+		Delete(&Consumable{}, id)
+
+	if result.Error != nil {
+		return result.Error
+	}
+
+	if result.RowsAffected == 0 {
+		return ErrNotFound
+	}
+
+	return nil
+}
+
+// SetMinQuantity sets the low-stock threshold of a consumable at location,
+// creating an empty stock entry there if there is none.
+func (s *ConsumableStore) SetMinQuantity(ctx context.Context, consumableID int64, location string, min int) error {
+	stock := ConsumableStock{ConsumableID: consumableID, Location: location, MinQuantity: min}
+
+	return s.db.WithContext(ctx).
+		Omit("Consumable").
+		Clauses(clause.OnConflict{
+			Columns: []clause.Column{{Name: "consumable_id"}, {Name: "location"}},
+			DoUpdates: clause.Set{
+				{Column: clause.Column{Name: "min_quantity"}, Value: min},
+				{Column: clause.Column{Name: "low_stock_notified_at"}, Value: gorm.Expr("CASE WHEN consumable_stocks.quantity > ? THEN NULL ELSE consumable_stocks.low_stock_notified_at END", min)},
+				{Column: clause.Column{Name: "updated_at"}, Value: time.Now()},
+			},
+		}).
+		Create(&stock).Error
+}
+
+// Issue takes movement.Quantity off the stock at movement.Location. The
+// decrement only applies when enough is on hand, so concurrent issues cannot
+// drive stock below zero.
+func (s *ConsumableStore) Issue(ctx context.Context, movement *ConsumableMovement) (*ConsumableStock, error) {
+	var stock *ConsumableStock
+
+	err := s.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		var err error
+		stock, err = issueConsumable(tx, movement)
+		return err
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return stock, nil
+}
+
+func issueConsumable(tx *gorm.DB, movement *ConsumableMovement) (*ConsumableStock, error) {
+	movement.Kind = ConsumableIssue
+
+	var stock ConsumableStock
+
+	result := tx.Model(&stock).
+		Clauses(clause.Returning{}).
+		Where("consumable_id = ? AND location = ? AND quantity >= ?", movement.ConsumableID, movement.Location, movement.Quantity).
+		Updates(map[string]interface{}{
+			"quantity":   gorm.Expr("quantity - ?", movement.Quantity),
+			"updated_at": time.Now(),
+		})
+	if result.Error != nil {
+		return nil, result.Error
+	}
+	if result.RowsAffected == 0 {
+		return nil, ErrInsufficientStock
+	}
+
+	if err := tx.Omit(clause.Associations).Create(movement).Error; err != nil {
+		return nil, err
+	}
+
+	return &stock, nil
+}
+
+// Receive adds movement.Quantity to the stock at movement.Location. Stock
+// that is back above its minimum can alert again.
+func (s *ConsumableStore) Receive(ctx context.Context, movement *ConsumableMovement) (*ConsumableStock, error) {
+	movement.Kind = ConsumableReceipt
+
+	stock := ConsumableStock{
+		ConsumableID: movement.ConsumableID,
+		Location:     movement.Location,
+		Quantity:     movement.Quantity,
+	}
+
+	err := s.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		err := tx.Omit("Consumable").
+			Clauses(
+				clause.OnConflict{
+					Columns: []clause.Column{{Name: "consumable_id"}, {Name: "location"}},
+					DoUpdates: clause.Set{
+						{Column: clause.Column{Name: "quantity"}, Value: gorm.Expr("consumable_stocks.quantity + excluded.quantity")},
+						{Column: clause.Column{Name: "low_stock_notified_at"}, Value: gorm.Expr("CASE WHEN consumable_stocks.quantity + excluded.quantity > consumable_stocks.min_quantity THEN NULL ELSE consumable_stocks.low_stock_notified_at END")},
+						{Column: clause.Column{Name: "updated_at"}, Value: time.Now()},
+					},
+				},
+				clause.Returning{},
+			).
+			Create(&stock).Error
+		if err != nil {
+			return err
+		}
+
+		return tx.Omit(clause.Associations).Create(movement).Error
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return &stock, nil
+}
+
+func (s *ConsumableStore) Movements(ctx context.Context, consumableID int64, spec QuerySpec) (*Pagination, error) {
+	query := s.db.WithContext(ctx).Model(&ConsumableMovement{}).
+		Joins("User").
+		Joins("Department").
+		Joins("Supplier").
+		Where("consumable_movements.consumable_id = ?", consumableID)
+
+	return paginate[ConsumableMovement](query, spec, consumableMovementQueryFields, "consumable_movements.id")
+}
+
+// GetLow returns the stock entries at or below their minimum, emptiest first.
+func (s *ConsumableStore) GetLow(ctx context.Context) ([]ConsumableStock, error) {
+	ctx, cancel := context.WithTimeout(ctx, QueryTimeoutDuration)
+	defer cancel()
+
+	return s.getLow(s.db.WithContext(ctx))
+}
+
+// GetUnnotifiedLow returns the low stock entries no alert went out for yet.
+func (s *ConsumableStore) GetUnnotifiedLow(ctx context.Context) ([]ConsumableStock, error) {
+	return s.getLow(s.db.WithContext(ctx).Where("consumable_stocks.low_stock_notified_at IS NULL"))
+}
+
+func (s *ConsumableStore) getLow(db *gorm.DB) ([]ConsumableStock, error) {
+	stocks := []ConsumableStock{}
+
+	err := db.
+		Joins("Consumable").
+		Where("consumable_stocks.quantity <= consumable_stocks.min_quantity").
+		Where("consumable_stocks.min_quantity > 0").
+		Order("consumable_stocks.quantity, consumable_stocks.id").
+		Find(&stocks).Error
+	if err != nil {
+		return nil, err
+	}
+
+	return stocks, nil
+}
+
+// MarkLowNotified records the low-stock alert for a stock entry together with
+// the notifications carrying it. It reports false when another run got there
+// first or the stock was replenished meanwhile.
+func (s *ConsumableStore) MarkLowNotified(ctx context.Context, stockID int64, notifications []Notification) (bool, error) {
+	marked := false
+
+	err := s.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		result := tx.Model(&ConsumableStock{}).
+			Where("id = ? AND low_stock_notified_at IS NULL AND quantity <= min_quantity", stockID).
+			Update("low_stock_notified_at", time.Now())
+		if result.Error != nil {
+			return result.Error
+		}
+		if result.RowsAffected == 0 {
+			return nil
+		}
+
+		marked = true
+
+		if len(notifications) == 0 {
+			return nil
+		}
+
+		return tx.Create(&notifications).Error
+	})
+
+	return marked, err
+}
+
+// Usage sums what was issued per department, month and consumable between
+// from and to. Issues to a user only are reported without a department.
+func (s *ConsumableStore) Usage(ctx context.Context, from, to time.Time, departmentID *int64) ([]ConsumableUsage, error) {
+	ctx, cancel := context.WithTimeout(ctx, QueryTimeoutDuration)
+	defer cancel()
+
+	query := s.db.WithContext(ctx).
+		Table("consumable_movements cm").
+		Select(`cm.department_id, coalesce(d.name, '') AS department_name,
+			date_trunc('month', cm.created_at) AS month,
+			c.id AS consumable_id, c.name AS consumable_name,
+			sum(cm.quantity) AS quantity,
+			sum(cm.quantity * c.unit_cost) AS cost`).
+		Joins("JOIN consumables c ON c.id = cm.consumable_id").
+		Joins("LEFT JOIN departments d ON d.id = cm.department_id").
+		Where("cm.kind = ? AND cm.created_at >= ? AND cm.created_at < ?", ConsumableIssue, from, to).
+		Group("cm.department_id, d.name, month, c.id, c.name").
+		Order("month, department_name, c.name")
+
+	if departmentID != nil {
+		query = query.Where("cm.department_id = ?", *departmentID)
+	}
+
+	usage := []ConsumableUsage{}
+	if err := query.Scan(&usage).Error; err != nil {
+		return nil, err
+	}
+
+	return usage, nil
+}
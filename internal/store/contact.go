@@ -0,0 +1,153 @@
+package store
+
+import (
+	"context"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+const (
+	ContactOwnerSupplier     = "suppliers"
+	ContactOwnerManufacturer = "manufacturers"
+)
+
+type Address struct {
+	Line1      string `gorm:"size:150"`
+	Line2      string `gorm:"size:150"`
+	City       string `gorm:"size:100"`
+	Region     string `gorm:"size:100"`
+	PostalCode string `gorm:"size:20"`
+	Country    string `gorm:"size:100"`
+}
+
+// VendorDetails are the contact, support and account details shared by
+// suppliers and manufacturers.
+type VendorDetails struct {
+	Website       string  `gorm:"size:255"`
+	Phone         string  `gorm:"size:50"`
+	SupportURL    string  `gorm:"size:255"`
+	SupportPhone  string  `gorm:"size:50"`
+	SupportEmail  string  `gorm:"size:255"`
+	AccountNumber string  `gorm:"size:100"`
+	Notes         string  `gorm:"size:2000"`
+	Address       Address `gorm:"embedded;embeddedPrefix:address_"`
+}
+
+func vendorDetailsUpdates(d VendorDetails) map[string]interface{} {
+	return map[string]interface{}{
+		"website":             d.Website,
+		"phone":               d.Phone,
+		"support_url":         d.SupportURL,
+		"support_phone":       d.SupportPhone,
+		"support_email":       d.SupportEmail,
+		"account_number":      d.AccountNumber,
+		"notes":               d.Notes,
+		"address_line1":       d.Address.Line1,
+		"address_line2":       d.Address.Line2,
+		"address_city":        d.Address.City,
+		"address_region":      d.Address.Region,
+		"address_postal_code": d.Address.PostalCode,
+		"address_country":     d.Address.Country,
+	}
+}
+
+// contacts are owned polymorphically, so there is no foreign key to cascade
+// the owner's delete
+func deleteContacts(tx *gorm.DB, ownerType string, ownerID int64) error {
+	return tx.Where("owner_type = ? AND owner_id = ?", ownerType, ownerID).Delete(&Contact{}).Error
+}
+
+// Contact is a person at a supplier or manufacturer.
+type Contact struct {
+	ID        int64  `gorm:"primaryKey"`
+	OwnerID   int64  `gorm:"not null;index:idx_contacts_owner"`
+	OwnerType string `gorm:"size:50;not null;index:idx_contacts_owner"`
+
+	Name  string `gorm:"size:150;not null"`
+	Email string `gorm:"size:255"`
+	Phone string `gorm:"size:50"`
+	Role  string `gorm:"size:100"`
+	Notes string `gorm:"size:1000"`
+
+	CreatedAt time.Time
+	UpdatedAt time.Time
+}
+
+type ContactStore struct {
+	db *gorm.DB
+}
+
+func (s *ContactStore) GetAll(ctx context.Context, ownerType string, ownerID int64) ([]Contact, error) {
+	contacts := []Contact{}
+
+	err := s.db.WithContext(ctx).
+		Where("owner_type = ? AND owner_id = ?", ownerType, ownerID).
+		Order("name, id").
+		Find(&contacts).Error
+	if err != nil {
+		return nil, err
+	}
+
+	return contacts, nil
+}
+
+func (s *ContactStore) GetByID(ctx context.Context, ownerType string, ownerID, id int64) (*Contact, error) {
+	var contacts []Contact
+
+	err := s.db.WithContext(ctx).
+		Where("id = ? AND owner_type = ? AND owner_id = ?", id, ownerType, ownerID).
+		Limit(1).
+		Find(&contacts).Error
+	if err != nil {
+		return nil, err
+	}
+	if len(contacts) == 0 {
+		return nil, ErrNotFound
+	}
+
+	return &contacts[0], nil
+}
+
+func (s *ContactStore) Create(ctx context.Context, contact *Contact) error {
+	return s.db.WithContext(ctx).Create(contact).Error
+}
+
+func (s *ContactStore) Update(ctx context.Context, contact *Contact) error {
+	result := s.db.WithContext(ctx).
+		Model(&Contact{}).
+		Where("id = ?", contact.ID).
+		Updates(map[string]interface{}{
+			"name":  contact.Name,
+			"email": contact.Email,
+			"phone": contact.Phone,
+			"role":  contact.Role,
+			"notes": contact.Notes,
+		})
+
+	if result.Error != nil {
+		return result.Error
+	}
+
+	if result.RowsAffected == 0 {
+		return ErrNotFound
+	}
+
+	return nil
+}
+
+func (s *ContactStore) Delete(ctx context.Context, ownerType string, ownerID, id int64) error {
+	result := s.db.WithContext(ctx).
+		Where("owner_type = ? AND owner_id = ?", ownerType, ownerID).
+		Delete(&Contact{}, id)
+
+	if result.Error != nil {
+		return result.Error
+	}
+
+	if result.RowsAffected == 0 {
+		return ErrNotFound
+	}
+
+	return nil
+}
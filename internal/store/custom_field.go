@@ -0,0 +1,421 @@
+package store
+
+import (
+	"context"
+	"database/sql/driver"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"hash/fnv"
+	"regexp"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/jackc/pgx/v5/pgconn"
+	"gorm.io/gorm"
+)
+
+var (
+	ErrInvalidCustomField = errors.New("invalid custom field")
+	ErrCustomFieldTaken   = errors.New("custom field value already in use")
+)
+
+type CustomFieldType string
+
+const (
+	CustomFieldText      CustomFieldType = "text"
+	CustomFieldNumber    CustomFieldType = "number"
+	CustomFieldDate      CustomFieldType = "date"
+	CustomFieldEnum      CustomFieldType = "enum"
+	CustomFieldRegex     CustomFieldType = "regex"
+	CustomFieldEncrypted CustomFieldType = "encrypted"
+)
+
+var customFieldKeyPattern = regexp.MustCompile(`^[a-z][a-z0-9_]{0,63}$`)
+
+// CustomFieldSet groups the extra fields that apply to every asset of a
+// category, or of a single model.
+type CustomFieldSet struct {
+	ID   int64  `gorm:"primaryKey"`
+	Name string `gorm:"size:100;uniqueIndex;not null"`
+
+	CategoryID *int64    `gorm:"index"`
+	Category   *Category `gorm:"constraint:OnUpdate:CASCADE,OnDelete:CASCADE;"`
+
+	ModelID *int64 `gorm:"index"`
+	Model   *Model `gorm:"constraint:OnUpdate:CASCADE,OnDelete:CASCADE;"`
+
+	Fields []CustomField `gorm:"foreignKey:FieldSetID;constraint:OnDelete:CASCADE;"`
+
+	CreatedAt time.Time
+	UpdatedAt time.Time
+}
+
+type CustomField struct {
+	ID         int64 `gorm:"primaryKey"`
+	FieldSetID int64 `gorm:"not null;uniqueIndex:idx_custom_field_set_key"`
+
+	Key      string          `gorm:"size:64;not null;uniqueIndex:idx_custom_field_set_key"`
+	Label    string          `gorm:"size:100;not null"`
+	Type     CustomFieldType `gorm:"type:varchar(20);not null"`
+	Required bool            `gorm:"not null;default:false"`
+	Unique   bool            `gorm:"not null;default:false"`
+	Options  []string        `gorm:"serializer:json"`
+	Pattern  string          `gorm:"size:255"`
+	Position int             `gorm:"not null;default:0"`
+}
+
+// CustomFieldValues holds the custom field values of an asset as JSONB.
+type CustomFieldValues map[string]any
+
+func (v CustomFieldValues) Value() (driver.Value, error) {
+	if v == nil {
+		return "{}", nil
+	}
+	b, err := json.Marshal(v)
+	return string(b), err
+}
+
+func (v *CustomFieldValues) Scan(src any) error {
+	var b []byte
+	switch s := src.(type) {
+	case nil:
+		*v = CustomFieldValues{}
+		return nil
+	case []byte:
+		b = s
+	case string:
+		b = []byte(s)
+	default:
+		return fmt.Errorf("custom fields: unsupported type %T", src)
+	}
+	return json.Unmarshal(b, v)
+}
+
+func (CustomFieldValues) GormDataType() string {
+	return "jsonb"
+}
+
+// Custom field values are folded into the asset search vector. Generated
+// column expressions cannot be altered in place, so the column is rebuilt.
+var assetCustomFieldSearchMigrations = []string{
+	`ALTER TABLE assets DROP COLUMN IF EXISTS search_vector`,
+	`ALTER TABLE assets ADD COLUMN search_vector tsvector GENERATED ALWAYS AS (
+		setweight(to_tsvector('simple', coalesce(name, '') || ' ' || coalesce(tag, '') || ' ' || coalesce(serial_number, '')), 'A') ||
+		setweight(to_tsvector('simple', coalesce(description, '')), 'B') ||
+		setweight(jsonb_to_tsvector('simple', coalesce(custom_fields, '{}'::jsonb), '["string", "numeric"]'), 'C')
+	) STORED`,
+	`CREATE INDEX IF NOT EXISTS idx_assets_search_vector ON assets USING GIN (search_vector)`,
+	`CREATE INDEX IF NOT EXISTS idx_assets_custom_fields ON assets USING GIN (custom_fields jsonb_path_ops)`,
+}
+
+// Sealed values are left out of the asset search vector; their ciphertext is
+// noise to search and says nothing about the plaintext.
+var assetSealedFieldSearchMigrations = []string{
+	`ALTER TABLE assets DROP COLUMN IF EXISTS search_vector`,
+	`ALTER TABLE assets ADD COLUMN search_vector tsvector GENERATED ALWAYS AS (
+		setweight(to_tsvector('simple', coalesce(name, '') || ' ' || coalesce(tag, '') || ' ' || coalesce(serial_number, '')), 'A') ||
+		setweight(to_tsvector('simple', coalesce(description, '')), 'B') ||
+		setweight(jsonb_to_tsvector('simple',
+			jsonb_path_query_array(coalesce(custom_fields, '{}'::jsonb), '$.* ? (@.type() == "number" || !(@ starts with "enc:"))'),
+			'["string", "numeric"]'), 'C')
+	) STORED`,
+	`CREATE INDEX IF NOT EXISTS idx_assets_search_vector ON assets USING GIN (search_vector)`,
+}
+
+// uniqueFieldIndexPrefix names the unique indexes that hold unique custom
+// fields to one asset per value.
+const uniqueFieldIndexPrefix = "idx_assets_custom_field_"
+
+// uniqueFieldIndex is the name of the index for key, kept within the 63
+// bytes Postgres allows.
+func uniqueFieldIndex(key string) string {
+	name := uniqueFieldIndexPrefix + key
+	if len(name) <= 63 {
+		return name
+	}
+
+	h := fnv.New32a()
+	h.Write([]byte(key))
+	return fmt.Sprintf("%s_%08x", name[:54], h.Sum32())
+}
+
+// syncUniqueFieldIndexes creates a unique index for every key some field
+// marks unique, and drops the ones no field needs anymore. Creating one fails
+// with ErrCustomFieldTaken when assets already share a value.
+func syncUniqueFieldIndexes(tx *gorm.DB) error {
+	var keys []string
+	if err := tx.Model(&CustomField{}).Where("\"unique\"").Distinct().Pluck("key", &keys).Error; err != nil {
+		return err
+	}
+
+	var existing []string
+	err := tx.Raw(`SELECT indexname FROM pg_indexes WHERE tablename = 'assets' AND indexname LIKE ?`,
+		escapeLike(uniqueFieldIndexPrefix)+"%").
+		Scan(&existing).Error
+	if err != nil {
+		return err
+	}
+
+	wanted := make(map[string]bool, len(keys))
+	for _, key := range keys {
+		name := uniqueFieldIndex(key)
+		wanted[name] = true
+		if slices.Contains(existing, name) {
+			continue
+		}
+
+		// key is restricted to [a-z0-9_], so it is safe to inline
+		err := tx.Exec(fmt.Sprintf(`CREATE UNIQUE INDEX %s ON assets ((custom_fields -> '%s')) WHERE deleted_at IS NULL`, name, key)).Error
+		if isUniqueViolation(err) {
+			return fmt.Errorf("%w: assets already share values of %s", ErrCustomFieldTaken, key)
+		}
+		if err != nil {
+			return err
+		}
+	}
+
+	for _, name := range existing {
+		if !wanted[name] {
+			if err := tx.Exec(fmt.Sprintf(`DROP INDEX IF EXISTS %s`, name)).Error; err != nil {
+				return err
+			}
+		}
+	}
+
+	return nil
+}
+
+// customFieldTaken translates the violation of a unique field's index into
+// ErrCustomFieldTaken.
+func customFieldTaken(err error) error {
+	var pgErr *pgconn.PgError
+	if errors.As(err, &pgErr) && pgErr.Code == "23505" && strings.HasPrefix(pgErr.ConstraintName, uniqueFieldIndexPrefix) {
+		return fmt.Errorf("%w: %s", ErrCustomFieldTaken, strings.TrimPrefix(pgErr.ConstraintName, uniqueFieldIndexPrefix))
+	}
+	return err
+}
+
+// Validate checks the definition of a field set before it is stored.
+func (s *CustomFieldSet) Validate() error {
+	if (s.CategoryID == nil) == (s.ModelID == nil) {
+		return fmt.Errorf("%w: a field set belongs to exactly one category or model", ErrInvalidCustomField)
+	}
+
+	seen := make(map[string]bool, len(s.Fields))
+	for _, f := range s.Fields {
+		if !customFieldKeyPattern.MatchString(f.Key) {
+			return fmt.Errorf("%w: key %q must be lower case letters, digits and underscores", ErrInvalidCustomField, f.Key)
+		}
+		if seen[f.Key] {
+			return fmt.Errorf("%w: duplicate key %q", ErrInvalidCustomField, f.Key)
+		}
+		seen[f.Key] = true
+
+		switch f.Type {
+		case CustomFieldText, CustomFieldNumber, CustomFieldDate:
+		case CustomFieldEnum:
+			if len(f.Options) == 0 {
+				return fmt.Errorf("%w: enum field %q needs options", ErrInvalidCustomField, f.Key)
+			}
+		case CustomFieldRegex:
+			if _, err := regexp.Compile(f.Pattern); err != nil || f.Pattern == "" {
+				return fmt.Errorf("%w: field %q has an invalid pattern", ErrInvalidCustomField, f.Key)
+			}
+		case CustomFieldEncrypted:
+			// sealed values use a random nonce, so equal plaintexts never
+			// compare equal in the database
+			if f.Unique {
+				return fmt.Errorf("%w: encrypted field %q cannot be unique", ErrInvalidCustomField, f.Key)
+			}
+		default:
+			return fmt.Errorf("%w: field %q has unknown type %q", ErrInvalidCustomField, f.Key, f.Type)
+		}
+	}
+
+	return nil
+}
+
+// Normalize checks value against the field definition and returns it in the
+// form it is stored in. Encrypted values are returned as plain strings; the
+// caller seals them.
+func (f *CustomField) Normalize(value any) (any, error) {
+	switch f.Type {
+	case CustomFieldNumber:
+		switch v := value.(type) {
+		case float64:
+			return v, nil
+		case string:
+			n, err := strconv.ParseFloat(v, 64)
+			if err != nil {
+				return nil, fmt.Errorf("%w: %s must be a number", ErrInvalidCustomField, f.Key)
+			}
+			return n, nil
+		default:
+			return nil, fmt.Errorf("%w: %s must be a number", ErrInvalidCustomField, f.Key)
+		}
+	}
+
+	s, ok := value.(string)
+	if !ok {
+		return nil, fmt.Errorf("%w: %s must be a string", ErrInvalidCustomField, f.Key)
+	}
+
+	switch f.Type {
+	case CustomFieldDate:
+		d, err := time.Parse(time.DateOnly, s)
+		if err != nil {
+			return nil, fmt.Errorf("%w: %s must be a date (YYYY-MM-DD)", ErrInvalidCustomField, f.Key)
+		}
+		return d.Format(time.DateOnly), nil
+	case CustomFieldEnum:
+		if !slices.Contains(f.Options, s) {
+			return nil, fmt.Errorf("%w: %s must be one of %v", ErrInvalidCustomField, f.Key, f.Options)
+		}
+	case CustomFieldRegex:
+		re, err := regexp.Compile(f.Pattern)
+		if err != nil || !re.MatchString(s) {
+			return nil, fmt.Errorf("%w: %s does not match the expected format", ErrInvalidCustomField, f.Key)
+		}
+	}
+
+	if len(s) > 1000 {
+		return nil, fmt.Errorf("%w: %s is too long", ErrInvalidCustomField, f.Key)
+	}
+
+	return s, nil
+}
+
+type CustomFieldStore struct {
+	db *gorm.DB
+}
+
+func (s *CustomFieldStore) GetAll(ctx context.Context) ([]CustomFieldSet, error) {
+	var sets []CustomFieldSet
+
+	err := s.db.WithContext(ctx).
+		Preload("Fields", func(db *gorm.DB) *gorm.DB { return db.Order("position, id") }).
+		Order("id").
+		Find(&sets).Error
+	if err != nil {
+		return nil, err
+	}
+
+	return sets, nil
+}
+
+func (s *CustomFieldStore) GetByID(ctx context.Context, id int64) (*CustomFieldSet, error) {
+	var set CustomFieldSet
+
+	err := s.db.WithContext(ctx).
+		Preload("Fields", func(db *gorm.DB) *gorm.DB { return db.Order("position, id") }).
+		First(&set, id).Error
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, ErrNotFound
+		}
+		return nil, err
+	}
+
+	return &set, nil
+}
+
+func (s *CustomFieldStore) Create(ctx context.Context, set *CustomFieldSet) error {
+	return s.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		if err := tx.Create(set).Error; err != nil {
+			return err
+		}
+
+		return syncUniqueFieldIndexes(tx)
+	})
+}
+
+// Update saves the set and replaces its fields with set.Fields.
+func (s *CustomFieldStore) Update(ctx context.Context, set *CustomFieldSet) error {
+	return s.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		result := tx.Model(&CustomFieldSet{}).
+			Where("id = ?", set.ID).
+			Updates(map[string]interface{}{
+				"name":        set.Name,
+				"category_id": set.CategoryID,
+				"model_id":    set.ModelID,
+			})
+		if result.Error != nil {
+			return result.Error
+		}
+		if result.RowsAffected == 0 {
+			return ErrNotFound
+		}
+
+		if err := tx.Where("field_set_id = ?", set.ID).Delete(&CustomField{}).Error; err != nil {
+			return err
+		}
+
+		for i := range set.Fields {
+			set.Fields[i].ID = 0
+			set.Fields[i].FieldSetID = set.ID
+		}
+		if len(set.Fields) > 0 {
+			if err := tx.Create(&set.Fields).Error; err != nil {
+				return err
+			}
+		}
+
+		return syncUniqueFieldIndexes(tx)
+	})
+}
+
+func (s *CustomFieldStore) Delete(ctx context.Context, id int64) error {
+	return s.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		result := tx.Delete(&CustomFieldSet{}, id)
+
+		if result.Error != nil {
+			return result.Error
+		}
+
+		if result.RowsAffected == 0 {
+			return ErrNotFound
+		}
+
+		return syncUniqueFieldIndexes(tx)
+	})
+}
+
+// GetForModel returns every field that applies to assets of the model, from
+// sets attached to the model itself or to its category.
+func (s *CustomFieldStore) GetForModel(ctx context.Context, modelID int64) ([]CustomField, error) {
+	var fields []CustomField
+
+	err := s.db.WithContext(ctx).
+		Joins("JOIN custom_field_sets s ON s.id = custom_fields.field_set_id").
+		Where("s.model_id = ? OR s.category_id = (SELECT category_id FROM models WHERE id = ?)", modelID, modelID).
+		Order("custom_fields.position, custom_fields.id").
+		Find(&fields).Error
+	if err != nil {
+		return nil, err
+	}
+
+	return fields, nil
+}
+
+// IsValueTaken reports whether another asset already holds value for key. It
+// lets a clash be reported before saving; the unique index of the field is
+// what prevents it.
+func (s *CustomFieldStore) IsValueTaken(ctx context.Context, key string, value any, excludeAssetID int64) (bool, error) {
+	encoded, err := json.Marshal(value)
+	if err != nil {
+		return false, err
+	}
+
+	var taken bool
+	err = s.db.WithContext(ctx).
+		Raw(`SELECT EXISTS (
+			SELECT 1 FROM assets
+			WHERE deleted_at IS NULL AND id <> ? AND custom_fields -> ? = ?::jsonb
+		)`, excludeAssetID, key, string(encoded)).
+		Scan(&taken).Error
+
+	return taken, err
+}
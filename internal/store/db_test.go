@@ -0,0 +1,90 @@
+package store
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"gorm.io/driver/postgres"
+	"gorm.io/gorm"
+)
+
+// testDB connects to the database in TEST_DB_ADDR and migrates it. Tests
+// needing one are skipped when it is not set.
+func testDB(t *testing.T) *gorm.DB {
+	t.Helper()
+
+	addr := os.Getenv("TEST_DB_ADDR")
+	if addr == "" {
+		t.Skip("TEST_DB_ADDR not set")
+	}
+
+	db, err := gorm.Open(postgres.Open(addr), &gorm.Config{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	sqlDB, err := db.DB()
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { sqlDB.Close() })
+
+	if err := Migrate(context.Background(), db, Models()...); err != nil {
+		t.Fatal(err)
+	}
+
+	return db
+}
+
+var uniqueSeq atomic.Int64
+
+// unique suffixes names so tests sharing a database do not collide.
+func unique(name string) string {
+	return fmt.Sprintf("%s-%d-%d", name, time.Now().UnixNano(), uniqueSeq.Add(1))
+}
+
+func create(t *testing.T, db *gorm.DB, value any) {
+	t.Helper()
+
+	if err := db.Create(value).Error; err != nil {
+		t.Fatal(err)
+	}
+}
+
+func testUser(t *testing.T, db *gorm.DB) *User {
+	t.Helper()
+
+	role := &Role{Name: unique("role")}
+	create(t, db, role)
+
+	name := unique("user")
+	user := &User{Username: name, Email: name + "@example.com", PasswordHash: []byte("x"), IsActive: true, RoleID: role.ID}
+	create(t, db, user)
+
+	return user
+}
+
+// testAssets creates n available assets of a new model.
+func testAssets(t *testing.T, db *gorm.DB, n int) (*Model, []Asset) {
+	t.Helper()
+
+	category := &Category{Name: unique("category")}
+	create(t, db, category)
+	name := unique("manufacturer")
+	manufacturer := &Manufacturer{Name: name, Email: name + "@example.com"}
+	create(t, db, manufacturer)
+	model := &Model{Name: unique("model"), CategoryID: category.ID, ManufacturerID: manufacturer.ID}
+	create(t, db, model)
+
+	assets := make([]Asset, n)
+	for i := range assets {
+		tag := unique("asset")
+		assets[i] = Asset{Name: model.Name, SerialNumber: tag, Tag: tag, ModelID: model.ID, Status: AssetAvailable}
+		create(t, db, &assets[i])
+	}
+
+	return model, assets
+}
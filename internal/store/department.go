@@ -12,6 +12,10 @@ type Department struct {
 	Name  string `gorm:"size:100;uniqueIndex;not null"`
 	Notes string `gorm:"size:255"`
 
+	// the manager approves the asset requests of the department's users
+	ManagerID *int64 `gorm:"index"`
+	Manager   *User  `gorm:"constraint:OnDelete:SET NULL;"`
+
 	CreatedAt time.Time
 	UpdatedAt time.Time
 }
@@ -20,6 +24,15 @@ type DepartmentStore struct {
 	db *gorm.DB
 }
 
+var departmentQueryFields = QueryFields{
+	"id":        {"departments.id", IntField},
+	"name":      {"departments.name", StringField},
+	"notes":     {"departments.notes", StringField},
+	"managerId": {"departments.manager_id", IntField},
+	"createdAt": {"departments.created_at", TimeField},
+	"updatedAt": {"departments.updated_at", TimeField},
+}
+
 func (s *DepartmentStore) GetAll(ctx context.Context) ([]Department, error) {
 	var categories []Department
 
@@ -31,6 +44,12 @@ func (s *DepartmentStore) GetAll(ctx context.Context) ([]Department, error) {
 	return categories, nil
 }
 
+func (s *DepartmentStore) List(ctx context.Context, spec QuerySpec) (*Pagination, error) {
+	query := s.db.WithContext(ctx).Model(&Department{})
+
+	return paginate[Department](query, spec, departmentQueryFields, "departments.id")
+}
+
 func (s *DepartmentStore) GetByID(ctx context.Context, id int64) (*Department, error) {
 	var Department Department
 
@@ -53,8 +72,9 @@ func (s *DepartmentStore) Update(ctx context.Context, department *Department) er
 		Model(&Department{}).
 		Where("id = ?", department.ID).
 		Updates(map[string]interface{}{
-			"name":  department.Name,
-			"notes": department.Notes,
+			"name":       department.Name,
+			"notes":      department.Notes,
+			"manager_id": department.ManagerID,
 		})
 
 	if result.Error != nil {
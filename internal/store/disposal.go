@@ -0,0 +1,417 @@
+package store
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+var (
+	ErrDisposalNotPending  = errors.New("disposal is no longer pending")
+	ErrDisposalNotApproved = errors.New("disposal is not approved")
+	ErrDisposalClosed      = errors.New("disposal is closed")
+	ErrDataWipeRequired    = errors.New("the data wipe was not confirmed")
+	ErrCertificateRequired = errors.New("no certificate was attached")
+)
+
+// DisposalApproverRole approves disposals, never the user who asked for one.
+const DisposalApproverRole = "admin"
+
+type DisposalMethod string
+
+const (
+	DisposalSale        DisposalMethod = "SALE"
+	DisposalDonation    DisposalMethod = "DONATION"
+	DisposalRecycling   DisposalMethod = "RECYCLING"
+	DisposalDestruction DisposalMethod = "DESTRUCTION"
+	DisposalWriteOff    DisposalMethod = "WRITE_OFF"
+)
+
+// NeedsDataWipe reports whether the asset leaves with its storage intact.
+func (m DisposalMethod) NeedsDataWipe() bool {
+	return m == DisposalSale || m == DisposalDonation || m == DisposalRecycling
+}
+
+// NeedsCertificate reports whether a recycling or destruction certificate
+// has to be kept.
+func (m DisposalMethod) NeedsCertificate() bool {
+	return m == DisposalRecycling || m == DisposalDestruction
+}
+
+type DisposalStatus string
+
+const (
+	DisposalPending   DisposalStatus = "PENDING"
+	DisposalApproved  DisposalStatus = "APPROVED"
+	DisposalRejected  DisposalStatus = "REJECTED"
+	DisposalCancelled DisposalStatus = "CANCELLED"
+	DisposalCompleted DisposalStatus = "COMPLETED"
+)
+
+// Disposal records how an asset left the company. It is approved, then
+// completed once the asset is gone, which archives the asset and books the
+// gain or loss against its depreciated value.
+type Disposal struct {
+	ID int64 `gorm:"primaryKey"`
+
+	AssetID int64 `gorm:"not null;index"`
+	Asset   Asset `gorm:"constraint:OnDelete:CASCADE;"`
+
+	Method DisposalMethod `gorm:"type:varchar(20);not null"`
+	Status DisposalStatus `gorm:"type:varchar(20);not null;default:'PENDING';index"`
+	Reason string         `gorm:"size:500"`
+
+	RequestedByID *int64 `gorm:"index"`
+	RequestedBy   *User  `gorm:"constraint:OnDelete:SET NULL;"`
+
+	DecidedByID     *int64 `gorm:"index"`
+	DecidedBy       *User  `gorm:"constraint:OnDelete:SET NULL;"`
+	DecidedAt       *time.Time
+	DecisionComment string `gorm:"size:255"`
+
+	DataWipedAt    *time.Time
+	DataWipeMethod string `gorm:"size:100"`
+	DataWipedByID  *int64 `gorm:"index"`
+	DataWipedBy    *User  `gorm:"constraint:OnDelete:SET NULL;"`
+
+	// the buyer, charity or recycler, and their invoice or certificate number
+	Recipient string `gorm:"size:150"`
+	Reference string `gorm:"size:100"`
+
+	DisposedAt    *time.Time `gorm:"index"`
+	Proceeds      float64
+	BookValue     float64
+	GainLoss      float64
+	CompletedByID *int64 `gorm:"index"`
+	CompletedBy   *User  `gorm:"constraint:OnDelete:SET NULL;"`
+
+	Certificates []Attachment `gorm:"polymorphic:Owner;polymorphicValue:disposals"`
+
+	CreatedAt time.Time
+	UpdatedAt time.Time
+}
+
+var disposalMigrations = []string{
+	// an asset is disposed of once, one disposal at a time
+	`CREATE UNIQUE INDEX IF NOT EXISTS idx_disposals_asset_active
+		ON disposals (asset_id)
+		WHERE status IN ('PENDING', 'APPROVED', 'COMPLETED')`,
+}
+
+// DisposalSummary totals the completed disposals of a method.
+type DisposalSummary struct {
+	Method    DisposalMethod
+	Count     int64
+	BookValue float64
+	Proceeds  float64
+	GainLoss  float64
+}
+
+type DisposalStore struct {
+	db *gorm.DB
+}
+
+var disposalQueryFields = QueryFields{
+	"id":            {"disposals.id", IntField},
+	"assetId":       {"disposals.asset_id", IntField},
+	"method":        {"disposals.method", StringField},
+	"status":        {"disposals.status", StringField},
+	"requestedById": {"disposals.requested_by_id", IntField},
+	"recipient":     {"disposals.recipient", StringField},
+	"disposedAt":    {"disposals.disposed_at", TimeField},
+	"proceeds":      {"disposals.proceeds", FloatField},
+	"bookValue":     {"disposals.book_value", FloatField},
+	"gainLoss":      {"disposals.gain_loss", FloatField},
+	"createdAt":     {"disposals.created_at", TimeField},
+}
+
+func preloadDisposal(db *gorm.DB) *gorm.DB {
+	return db.
+		Joins("Asset").
+		Joins("RequestedBy").
+		Joins("DecidedBy").
+		Joins("DataWipedBy").
+		Joins("CompletedBy").
+		Preload("Certificates", func(db *gorm.DB) *gorm.DB {
+			return db.Omit("content").Order("attachments.id")
+		})
+}
+
+func (s *DisposalStore) List(ctx context.Context, spec QuerySpec) (*Pagination, error) {
+	query := preloadDisposal(s.db.WithContext(ctx).Model(&Disposal{}))
+
+	return paginate[Disposal](query, spec, disposalQueryFields, "disposals.id")
+}
+
+// ListRegister lists the completed disposals, the disposal register.
+func (s *DisposalStore) ListRegister(ctx context.Context, spec QuerySpec) (*Pagination, error) {
+	query := preloadDisposal(s.db.WithContext(ctx).Model(&Disposal{})).
+		Where("disposals.status = ?", DisposalCompleted)
+
+	return paginate[Disposal](query, spec, disposalQueryFields, "disposals.id")
+}
+
+// Summary totals the disposals completed between from and to by method.
+// Either bound may be nil.
+func (s *DisposalStore) Summary(ctx context.Context, from, to *time.Time) ([]DisposalSummary, error) {
+	var summaries []DisposalSummary
+
+	query := s.db.WithContext(ctx).
+		Model(&Disposal{}).
+		Select(`method,
+			COUNT(*) AS count,
+			COALESCE(SUM(book_value), 0) AS book_value,
+			COALESCE(SUM(proceeds), 0) AS proceeds,
+			COALESCE(SUM(gain_loss), 0) AS gain_loss`).
+		Where("status = ?", DisposalCompleted)
+	if from != nil {
+		query = query.Where("disposed_at >= ?", *from)
+	}
+	if to != nil {
+		query = query.Where("disposed_at < ?", *to)
+	}
+
+	err := query.Group("method").Order("method").Scan(&summaries).Error
+	if err != nil {
+		return nil, err
+	}
+
+	return summaries, nil
+}
+
+func (s *DisposalStore) GetByID(ctx context.Context, id int64) (*Disposal, error) {
+	var disposal Disposal
+
+	err := preloadDisposal(s.db.WithContext(ctx)).
+		First(&disposal, "disposals.id = ?", id).Error
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, ErrNotFound
+		}
+		return nil, err
+	}
+
+	return &disposal, nil
+}
+
+// Create asks for an asset to be disposed of. Only lost or stolen assets may
+// still be checked out. It fails with ErrConflict when the asset already has
+// a disposal under way or completed.
+func (s *DisposalStore) Create(ctx context.Context, disposal *Disposal) error {
+	err := s.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		var asset Asset
+		if err := tx.First(&asset, disposal.AssetID).Error; err != nil {
+			if errors.Is(err, gorm.ErrRecordNotFound) {
+				return ErrNotFound
+			}
+			return err
+		}
+		if asset.Status == AssetAssigned && disposal.Method != DisposalWriteOff {
+			return ErrAssetNotAvailable
+		}
+
+		disposal.Status = DisposalPending
+		return tx.Omit(clause.Associations).Create(disposal).Error
+	})
+	if isUniqueViolation(err) {
+		return ErrConflict
+	}
+
+	return err
+}
+
+func lockDisposal(tx *gorm.DB, id int64) (*Disposal, error) {
+	var disposal Disposal
+
+	err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).First(&disposal, id).Error
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, ErrNotFound
+		}
+		return nil, err
+	}
+
+	return &disposal, nil
+}
+
+func isOpenDisposal(status DisposalStatus) bool {
+	return status == DisposalPending || status == DisposalApproved
+}
+
+// Decide approves or rejects a pending disposal. The approver needs the
+// disposal approver role and cannot approve their own disposal.
+func (s *DisposalStore) Decide(ctx context.Context, id int64, user *User, decision ApprovalDecision, comment string) error {
+	return s.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		disposal, err := lockDisposal(tx, id)
+		if err != nil {
+			return err
+		}
+		if disposal.Status != DisposalPending {
+			return ErrDisposalNotPending
+		}
+		if disposal.RequestedByID != nil && *disposal.RequestedByID == user.ID {
+			return ErrNotApprover
+		}
+
+		var role Role
+		if err := tx.Where("name = ?", DisposalApproverRole).First(&role).Error; err != nil {
+			return err
+		}
+		if user.RoleID != role.ID {
+			return ErrNotApprover
+		}
+
+		status := DisposalApproved
+		if decision == DecisionRejected {
+			status = DisposalRejected
+		}
+
+		return tx.Model(disposal).Updates(map[string]interface{}{
+			"status":           status,
+			"decided_by_id":    user.ID,
+			"decided_at":       time.Now(),
+			"decision_comment": comment,
+		}).Error
+	})
+}
+
+// ConfirmDataWipe records that the asset's storage was wiped.
+func (s *DisposalStore) ConfirmDataWipe(ctx context.Context, id, userID int64, method string) error {
+	result := s.db.WithContext(ctx).
+		Model(&Disposal{}).
+		Where("id = ? AND status IN ?", id, []DisposalStatus{DisposalPending, DisposalApproved}).
+		Updates(map[string]interface{}{
+			"data_wiped_at":    time.Now(),
+			"data_wipe_method": method,
+			"data_wiped_by_id": userID,
+		})
+
+	if result.Error != nil {
+		return result.Error
+	}
+
+	if result.RowsAffected == 0 {
+		return ErrDisposalClosed
+	}
+
+	return nil
+}
+
+// AddCertificate keeps a recycling or destruction certificate with the
+// disposal. Certificates can be added until the disposal is completed and
+// after, as recyclers often send them late.
+func (s *DisposalStore) AddCertificate(ctx context.Context, id int64, certificate *Attachment) error {
+	return s.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		disposal, err := lockDisposal(tx, id)
+		if err != nil {
+			return err
+		}
+		if !isOpenDisposal(disposal.Status) && disposal.Status != DisposalCompleted {
+			return ErrDisposalClosed
+		}
+
+		certificate.OwnerType = AttachmentOwnerDisposal
+		certificate.OwnerID = id
+		return createAttachment(tx, certificate)
+	})
+}
+
+// Complete books an approved disposal: the asset's book value on the disposal
+// date is kept with the gain or loss against the proceeds, and the asset is
+// archived, or marked lost or stolen for a write-off, which also ends any
+// loan or assignment it was still out on.
+func (s *DisposalStore) Complete(ctx context.Context, disposal *Disposal) error {
+	return s.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		current, err := lockDisposal(tx, disposal.ID)
+		if err != nil {
+			return err
+		}
+		if current.Status != DisposalApproved {
+			return ErrDisposalNotApproved
+		}
+		if current.Method.NeedsDataWipe() && current.DataWipedAt == nil {
+			return ErrDataWipeRequired
+		}
+		if current.Method.NeedsCertificate() {
+			var certificates int64
+			err := tx.Model(&Attachment{}).
+				Where("owner_type = ? AND owner_id = ?", AttachmentOwnerDisposal, current.ID).
+				Count(&certificates).Error
+			if err != nil {
+				return err
+			}
+			if certificates == 0 {
+				return ErrCertificateRequired
+			}
+		}
+
+		var asset Asset
+		err = tx.Clauses(clause.Locking{Strength: "UPDATE"}).First(&asset, current.AssetID).Error
+		if err != nil {
+			return err
+		}
+
+		disposedAt := *disposal.DisposedAt
+		assetStatus := AssetArchived
+
+		if current.Method == DisposalWriteOff {
+			assetStatus = AssetLostStolen
+
+			err := tx.Model(&AssetLoan{}).
+				Where("asset_id = ? AND actual_return_date IS NULL", asset.ID).
+				Update("actual_return_date", disposedAt).Error
+			if err != nil {
+				return err
+			}
+			err = tx.Model(&AssetAssignment{}).
+				Where("asset_id = ? AND returned_at IS NULL", asset.ID).
+				Update("returned_at", disposedAt).Error
+			if err != nil {
+				return err
+			}
+		} else if asset.Status == AssetAssigned {
+			return ErrAssetNotAvailable
+		}
+
+		disposal.BookValue = asset.BookValue(disposedAt)
+		disposal.GainLoss = disposal.Proceeds - disposal.BookValue
+
+		err = tx.Model(current).Updates(map[string]interface{}{
+			"status":          DisposalCompleted,
+			"disposed_at":     disposedAt,
+			"proceeds":        disposal.Proceeds,
+			"book_value":      disposal.BookValue,
+			"gain_loss":       disposal.GainLoss,
+			"recipient":       disposal.Recipient,
+			"reference":       disposal.Reference,
+			"completed_by_id": disposal.CompletedByID,
+		}).Error
+		if err != nil {
+			return err
+		}
+
+		_, err = changeAssetStatus(tx, asset.ID, assetStatus)
+		return err
+	})
+}
+
+// Cancel withdraws a disposal that was not completed yet.
+func (s *DisposalStore) Cancel(ctx context.Context, id int64) error {
+	result := s.db.WithContext(ctx).
+		Model(&Disposal{}).
+		Where("id = ? AND status IN ?", id, []DisposalStatus{DisposalPending, DisposalApproved}).
+		Update("status", DisposalCancelled)
+
+	if result.Error != nil {
+		return result.Error
+	}
+
+	if result.RowsAffected == 0 {
+		return ErrDisposalClosed
+	}
+
+	return nil
+}
@@ -0,0 +1,289 @@
+package store
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// Kit is a bundle of items that is always handed out together, such as the
+// equipment a new hire receives.
+type Kit struct {
+	ID          int64  `gorm:"primaryKey"`
+	Name        string `gorm:"size:150;uniqueIndex;not null"`
+	Description string `gorm:"size:1000"`
+
+	Items []KitItem `gorm:"constraint:OnDelete:CASCADE;"`
+
+	CreatedAt time.Time
+	UpdatedAt time.Time
+}
+
+// KitItem is a quantity of assets of a model, of an accessory or of a
+// consumable. Exactly one of them is set.
+type KitItem struct {
+	ID    int64 `gorm:"primaryKey"`
+	KitID int64 `gorm:"not null;index"`
+
+	ModelID      *int64      `gorm:"index"`
+	Model        *Model      `gorm:"constraint:OnDelete:RESTRICT;"`
+	AccessoryID  *int64      `gorm:"index"`
+	Accessory    *Accessory  `gorm:"constraint:OnDelete:RESTRICT;"`
+	ConsumableID *int64      `gorm:"index;check:chk_kit_items_item,num_nonnulls(model_id, accessory_id, consumable_id) = 1"`
+	Consumable   *Consumable `gorm:"constraint:OnDelete:RESTRICT;"`
+
+	Quantity int `gorm:"not null;default:1;check:quantity > 0"`
+}
+
+func (i *KitItem) label() string {
+	switch {
+	case i.Model != nil:
+		return fmt.Sprintf("%d x %s", i.Quantity, i.Model.Name)
+	case i.Accessory != nil:
+		return fmt.Sprintf("%d x %s", i.Quantity, i.Accessory.Name)
+	case i.Consumable != nil:
+		return fmt.Sprintf("%d x %s", i.Quantity, i.Consumable.Name)
+	}
+	return fmt.Sprintf("item %d", i.ID)
+}
+
+// KitCheckout hands a kit to a user. Accessories and consumables are taken
+// from Location. The loans and movements are filled in by the checkout.
+type KitCheckout struct {
+	UserID              int64
+	Location            string
+	CheckoutDate        time.Time
+	ExpectedCheckinDate *time.Time
+	CheckedOutByID      *int64
+	Notes               string
+
+	AssetLoans          []AssetLoan
+	AccessoryLoans      []AccessoryLoan
+	ConsumableMovements []ConsumableMovement
+}
+
+type KitStore struct {
+	db *gorm.DB
+}
+
+var kitQueryFields = QueryFields{
+	"id":        {"kits.id", IntField},
+	"name":      {"kits.name", StringField},
+	"createdAt": {"kits.created_at", TimeField},
+	"updatedAt": {"kits.updated_at", TimeField},
+}
+
+func preloadKit(db *gorm.DB) *gorm.DB {
+	return db.Preload("Items", func(db *gorm.DB) *gorm.DB {
+		return db.
+			Joins("Model").
+			Joins("Accessory").
+			Joins("Consumable").
+			Order("kit_items.id")
+	})
+}
+
+func (s *KitStore) List(ctx context.Context, spec QuerySpec) (*Pagination, error) {
+	query := preloadKit(s.db.WithContext(ctx).Model(&Kit{}))
+
+	return paginate[Kit](query, spec, kitQueryFields, "kits.id")
+}
+
+func (s *KitStore) GetByID(ctx context.Context, id int64) (*Kit, error) {
+	var kit Kit
+
+	err := preloadKit(s.db.WithContext(ctx)).
+		First(&kit, "kits.id = ?", id).Error
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, ErrNotFound
+		}
+		return nil, err
+	}
+
+	return &kit, nil
+}
+
+func (s *KitStore) Create(ctx context.Context, kit *Kit) error {
+	err := s.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		if err := tx.Omit(clause.Associations).Create(kit).Error; err != nil {
+			return err
+		}
+
+		return createKitItems(tx, kit)
+	})
+	if isUniqueViolation(err) {
+		return ErrConflict
+	}
+
+	return err
+}
+
+func createKitItems(tx *gorm.DB, kit *Kit) error {
+	if len(kit.Items) == 0 {
+		return nil
+	}
+
+	for i := range kit.Items {
+		kit.Items[i].KitID = kit.ID
+	}
+
+	return tx.Omit(clause.Associations).Create(&kit.Items).Error
+}
+
+// Update saves the kit, and with replaceItems replaces its items with
+// kit.Items.
+func (s *KitStore) Update(ctx context.Context, kit *Kit, replaceItems bool) error {
+	err := s.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		result := tx.Model(&Kit{}).
+			Where("id = ?", kit.ID).
+			Updates(map[string]interface{}{
+				"name":        kit.Name,
+				"description": kit.Description,
+			})
+		if result.Error != nil {
+			return result.Error
+		}
+		if result.RowsAffected == 0 {
+			return ErrNotFound
+		}
+
+		if !replaceItems {
+			return nil
+		}
+
+		if err := tx.Where("kit_id = ?", kit.ID).Delete(&KitItem{}).Error; err != nil {
+			return err
+		}
+
+		return createKitItems(tx, kit)
+	})
+	if isUniqueViolation(err) {
+		return ErrConflict
+	}
+
+	return err
+}
+
+func (s *KitStore) Delete(ctx context.Context, id int64) error {
+	result := s.db.WithContext(ctx).
+		Delete(&Kit{}, id)
+
+	if result.Error != nil {
+		return result.Error
+	}
+
+	if result.RowsAffected == 0 {
+		return ErrNotFound
+	}
+
+	return nil
+}
+
+// Checkout hands every item of the kit to checkout.UserID in one
+// transaction: available assets of each model are picked and loaned,
+// accessories are checked out and consumables issued. When any item is short
+// nothing is handed out and the error wraps ErrInsufficientStock.
+func (s *KitStore) Checkout(ctx context.Context, kit *Kit, checkout *KitCheckout) error {
+	if checkout.CheckoutDate.IsZero() {
+		checkout.CheckoutDate = time.Now()
+	}
+
+	return s.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		for i := range kit.Items {
+			item := &kit.Items[i]
+
+			var err error
+			switch {
+			case item.ModelID != nil:
+				err = checkoutKitAssets(tx, item, checkout)
+			case item.AccessoryID != nil:
+				loan := AccessoryLoan{
+					AccessoryID:         *item.AccessoryID,
+					UserID:              checkout.UserID,
+					Location:            checkout.Location,
+					Quantity:            item.Quantity,
+					CheckedOutByID:      checkout.CheckedOutByID,
+					CheckoutDate:        checkout.CheckoutDate,
+					ExpectedCheckinDate: checkout.ExpectedCheckinDate,
+					Notes:               checkout.Notes,
+				}
+				if err = checkoutAccessory(tx, &loan); err == nil {
+					loan.Accessory = *item.Accessory
+					checkout.AccessoryLoans = append(checkout.AccessoryLoans, loan)
+				}
+			case item.ConsumableID != nil:
+				movement := ConsumableMovement{
+					ConsumableID:  *item.ConsumableID,
+					Location:      checkout.Location,
+					Quantity:      item.Quantity,
+					UserID:        &checkout.UserID,
+					UnitCost:      item.Consumable.UnitCost,
+					PerformedByID: checkout.CheckedOutByID,
+					Notes:         checkout.Notes,
+				}
+				if _, err = issueConsumable(tx, &movement); err == nil {
+					movement.Consumable = *item.Consumable
+					checkout.ConsumableMovements = append(checkout.ConsumableMovements, movement)
+				}
+			}
+
+			if errors.Is(err, ErrInsufficientStock) || errors.Is(err, ErrAssetNotAvailable) || errors.Is(err, ErrAssetReserved) {
+				return fmt.Errorf("%w: %s", ErrInsufficientStock, item.label())
+			}
+			if err != nil {
+				return err
+			}
+		}
+
+		return nil
+	})
+}
+
+// checkoutKitAssets picks the first available assets of the item's model and
+// loans them. Assets locked by another checkout or booked by another user
+// while the kit is out are passed over.
+func checkoutKitAssets(tx *gorm.DB, item *KitItem, checkout *KitCheckout) error {
+	var assets []Asset
+
+	query := notReservedByOthers(tx, checkout.UserID, checkout.CheckoutDate, checkout.ExpectedCheckinDate)
+	err := query.Clauses(clause.Locking{Strength: "UPDATE", Options: "SKIP LOCKED"}).
+		Where("model_id = ? AND status = ?", *item.ModelID, AssetAvailable).
+		Order("id").
+		Limit(item.Quantity).
+		Find(&assets).Error
+	if err != nil {
+		return err
+	}
+	if len(assets) < item.Quantity {
+		return ErrInsufficientStock
+	}
+
+	loans := make([]AssetLoan, len(assets))
+	for i, asset := range assets {
+		loans[i] = AssetLoan{
+			AssetName:           asset.Name,
+			AssetID:             asset.ID,
+			UserID:              checkout.UserID,
+			CheckoutDate:        checkout.CheckoutDate,
+			ExpectedCheckinDate: checkout.ExpectedCheckinDate,
+			Status:              AssetPending,
+			Notes:               checkout.Notes,
+		}
+	}
+
+	if err := checkoutAssets(tx, loans); err != nil {
+		return err
+	}
+
+	for i := range loans {
+		loans[i].Asset = assets[i]
+	}
+	checkout.AssetLoans = append(checkout.AssetLoans, loans...)
+
+	return nil
+}
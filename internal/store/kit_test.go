@@ -0,0 +1,41 @@
+package store
+
+import (
+	"context"
+	"testing"
+	"time"
+)
+
+func TestKitCheckoutTwice(t *testing.T) {
+	db := testDB(t)
+	ctx := context.Background()
+
+	user := testUser(t, db)
+	model, assets := testAssets(t, db, 1)
+
+	kits := KitStore{db}
+	loans := AssetLoanStore{db}
+
+	kit := &Kit{Name: unique("kit"), Items: []KitItem{{ModelID: &model.ID, Quantity: 1}}}
+	if err := kits.Create(ctx, kit); err != nil {
+		t.Fatal(err)
+	}
+	kit, err := kits.GetByID(ctx, kit.ID)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for i := range 2 {
+		checkout := &KitCheckout{UserID: user.ID}
+		if err := kits.Checkout(ctx, kit, checkout); err != nil {
+			t.Fatalf("checkout %d: %v", i+1, err)
+		}
+		if len(checkout.AssetLoans) != 1 || checkout.AssetLoans[0].AssetID != assets[0].ID {
+			t.Fatalf("checkout %d loaned %+v, want asset %d", i+1, checkout.AssetLoans, assets[0].ID)
+		}
+
+		if err := loans.Checkin(ctx, assets[0].ID, nil, AssetAvailable, time.Now()); err != nil {
+			t.Fatalf("checkin %d: %v", i+1, err)
+		}
+	}
+}
@@ -0,0 +1,389 @@
+package store
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+var (
+	ErrNoSeatsAvailable = errors.New("no seats available")
+	ErrSeatsInUse       = errors.New("more seats are checked out than the license would have")
+)
+
+// licenses ending within this window are reported as expiring, and is when
+// the scheduler sends its alert
+const LicenseExpiringWindow = 30 * 24 * time.Hour
+
+type LicenseComplianceStatus string
+
+const (
+	LicenseCompliant     LicenseComplianceStatus = "COMPLIANT"
+	LicenseOverAllocated LicenseComplianceStatus = "OVER_ALLOCATED"
+	LicenseExpiring      LicenseComplianceStatus = "EXPIRING"
+	LicenseExpired       LicenseComplianceStatus = "EXPIRED"
+)
+
+// License is a software product bought for a number of seats.
+type License struct {
+	ID   int64  `gorm:"primaryKey"`
+	Name string `gorm:"size:150;not null"`
+
+	// sealed with the application secret, see secret.Box
+	ProductKey string `gorm:"size:2000"`
+	LicensedTo string `gorm:"size:150"`
+
+	ManufacturerID *int64        `gorm:"index"`
+	Manufacturer   *Manufacturer `gorm:"constraint:OnDelete:SET NULL;"`
+	SupplierID     *int64        `gorm:"index"`
+	Supplier       *Supplier     `gorm:"constraint:OnDelete:SET NULL;"`
+
+	Seats        int `gorm:"not null;check:seats > 0"`
+	PurchaseDate *time.Time
+	PurchaseCost float64    `gorm:"not null;default:0"`
+	ExpiresAt    *time.Time `gorm:"index"`
+	Notes        string     `gorm:"size:1000"`
+
+	// set once the expiry alert went out, cleared when the expiry moves
+	ExpiryNotifiedAt *time.Time
+
+	// active checkouts, counted when the license is loaded
+	SeatsUsed int `gorm:"->;-:migration"`
+
+	CreatedAt time.Time
+	UpdatedAt time.Time
+}
+
+func (l *License) SeatsAvailable() int {
+	return max(l.Seats-l.SeatsUsed, 0)
+}
+
+// ComplianceStatus compares the license with its checkouts at now.
+func (l *License) ComplianceStatus(now time.Time) LicenseComplianceStatus {
+	switch {
+	case l.SeatsUsed > l.Seats:
+		return LicenseOverAllocated
+	case l.ExpiresAt != nil && l.ExpiresAt.Before(now):
+		return LicenseExpired
+	case l.ExpiresAt != nil && l.ExpiresAt.Sub(now) <= LicenseExpiringWindow:
+		return LicenseExpiring
+	}
+
+	return LicenseCompliant
+}
+
+// LicenseSeat is a seat of a license checked out to a user or an asset.
+// CheckedInAt is set once the seat is returned.
+type LicenseSeat struct {
+	ID        int64   `gorm:"primaryKey"`
+	LicenseID int64   `gorm:"not null;index"`
+	License   License `gorm:"constraint:OnDelete:CASCADE;"`
+
+	UserID  *int64 `gorm:"index"`
+	User    *User  `gorm:"constraint:OnDelete:CASCADE;"`
+	AssetID *int64 `gorm:"index"`
+	Asset   *Asset `gorm:"constraint:OnDelete:CASCADE;"`
+
+	CheckedOutByID *int64 `gorm:"index"`
+	CheckedOutBy   *User  `gorm:"constraint:OnDelete:SET NULL;"`
+
+	CheckedOutAt time.Time `gorm:"not null"`
+	CheckedInAt  *time.Time
+	// set when the seat was taken back from an inactive user
+	Reclaimed bool   `gorm:"not null;default:false"`
+	Notes     string `gorm:"size:1000"`
+}
+
+var licenseSeatMigrations = []string{
+	// a user or asset holds at most one seat of a license at a time
+	`CREATE UNIQUE INDEX IF NOT EXISTS idx_license_seats_user
+		ON license_seats (license_id, user_id)
+		WHERE checked_in_at IS NULL AND user_id IS NOT NULL`,
+	`CREATE UNIQUE INDEX IF NOT EXISTS idx_license_seats_asset
+		ON license_seats (license_id, asset_id)
+		WHERE checked_in_at IS NULL AND asset_id IS NOT NULL`,
+	`ALTER TABLE license_seats ADD CONSTRAINT chk_license_seats_holder
+		CHECK ((user_id IS NULL) <> (asset_id IS NULL))`,
+}
+
+type LicenseStore struct {
+	db *gorm.DB
+}
+
+var licenseQueryFields = QueryFields{
+	"id":             {"licenses.id", IntField},
+	"name":           {"licenses.name", StringField},
+	"licensedTo":     {"licenses.licensed_to", StringField},
+	"manufacturerId": {"licenses.manufacturer_id", IntField},
+	"supplierId":     {"licenses.supplier_id", IntField},
+	"seats":          {"licenses.seats", IntField},
+	"purchaseDate":   {"licenses.purchase_date", TimeField},
+	"purchaseCost":   {"licenses.purchase_cost", FloatField},
+	"expiresAt":      {"licenses.expires_at", TimeField},
+	"createdAt":      {"licenses.created_at", TimeField},
+}
+
+var licenseSeatQueryFields = QueryFields{
+	"id":           {"license_seats.id", IntField},
+	"userId":       {"license_seats.user_id", IntField},
+	"assetId":      {"license_seats.asset_id", IntField},
+	"checkedOutAt": {"license_seats.checked_out_at", TimeField},
+	"checkedInAt":  {"license_seats.checked_in_at", TimeField},
+	"reclaimed":    {"license_seats.reclaimed", BoolField},
+}
+
+// selectLicenses loads licenses with the number of seats in use.
+func selectLicenses(db *gorm.DB) *gorm.DB {
+	return db.
+		Select(`licenses.*, (SELECT count(*) FROM license_seats s
+			WHERE s.license_id = licenses.id AND s.checked_in_at IS NULL) AS seats_used`).
+		Joins("Manufacturer").
+		Joins("Supplier")
+}
+
+func (s *LicenseStore) List(ctx context.Context, spec QuerySpec) (*Pagination, error) {
+	query := selectLicenses(s.db.WithContext(ctx).Model(&License{}))
+
+	return paginate[License](query, spec, licenseQueryFields, "licenses.id")
+}
+
+func (s *LicenseStore) GetAll(ctx context.Context) ([]License, error) {
+	ctx, cancel := context.WithTimeout(ctx, QueryTimeoutDuration)
+	defer cancel()
+
+	licenses := []License{}
+
+	err := selectLicenses(s.db.WithContext(ctx)).
+		Order("licenses.name, licenses.id").
+		Find(&licenses).Error
+	if err != nil {
+		return nil, err
+	}
+
+	return licenses, nil
+}
+
+func (s *LicenseStore) GetByID(ctx context.Context, id int64) (*License, error) {
+	var license License
+
+	err := selectLicenses(s.db.WithContext(ctx)).
+		First(&license, "licenses.id = ?", id).Error
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, ErrNotFound
+		}
+		return nil, err
+	}
+
+	return &license, nil
+}
+
+func (s *LicenseStore) Create(ctx context.Context, license *License) error {
+	return s.db.WithContext(ctx).Omit("Manufacturer", "Supplier").Create(license).Error
+}
+
+// Update saves the license. Seats cannot drop below the seats checked out.
+func (s *LicenseStore) Update(ctx context.Context, license *License) error {
+	return s.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		_, used, err := lockLicense(tx, license.ID)
+		if err != nil {
+			return err
+		}
+		if license.Seats < used {
+			return ErrSeatsInUse
+		}
+
+		return tx.Model(&License{}).
+			Where("id = ?", license.ID).
+			Updates(map[string]interface{}{
+				"name":               license.Name,
+				"product_key":        license.ProductKey,
+				"licensed_to":        license.LicensedTo,
+				"manufacturer_id":    license.ManufacturerID,
+				"supplier_id":        license.SupplierID,
+				"seats":              license.Seats,
+				"purchase_date":      license.PurchaseDate,
+				"purchase_cost":      license.PurchaseCost,
+				"expires_at":         license.ExpiresAt,
+				"notes":              license.Notes,
+				"expiry_notified_at": license.ExpiryNotifiedAt,
+			}).Error
+	})
+}
+
+func (s *LicenseStore) Delete(ctx context.Context, id int64) error {
+	result := s.db.WithContext(ctx).
+		Delete(&License{}, id)
+
+	if result.Error != nil {
+		return result.Error
+	}
+
+	if result.RowsAffected == 0 {
+		return ErrNotFound
+	}
+
+	return nil
+}
+
+// lockLicense locks the license row so seat counts stay put until the
+// transaction ends, and returns its seats and the seats in use.
+func lockLicense(tx *gorm.DB, id int64) (seats, used int, err error) {
+	var license License
+
+	err = tx.Clauses(clause.Locking{Strength: "UPDATE"}).
+		Select("id", "seats").
+		First(&license, id).Error
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return 0, 0, ErrNotFound
+		}
+		return 0, 0, err
+	}
+
+	var count int64
+	err = tx.Model(&LicenseSeat{}).
+		Where("license_id = ? AND checked_in_at IS NULL", id).
+		Count(&count).Error
+
+	return license.Seats, int(count), err
+}
+
+// Checkout hands out a seat. It fails with ErrNoSeatsAvailable once every
+// seat is taken and with ErrConflict when the holder already has one.
+func (s *LicenseStore) Checkout(ctx context.Context, seat *LicenseSeat) error {
+	err := s.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		seats, used, err := lockLicense(tx, seat.LicenseID)
+		if err != nil {
+			return err
+		}
+		if used >= seats {
+			return ErrNoSeatsAvailable
+		}
+
+		if seat.CheckedOutAt.IsZero() {
+			seat.CheckedOutAt = time.Now()
+		}
+
+		return tx.Omit(clause.Associations).Create(seat).Error
+	})
+	if isUniqueViolation(err) {
+		return ErrConflict
+	}
+	return err
+}
+
+func (s *LicenseStore) Checkin(ctx context.Context, licenseID, seatID int64) error {
+	result := s.db.WithContext(ctx).
+		Model(&LicenseSeat{}).
+		Where("id = ? AND license_id = ? AND checked_in_at IS NULL", seatID, licenseID).
+		Update("checked_in_at", time.Now())
+
+	if result.Error != nil {
+		return result.Error
+	}
+
+	if result.RowsAffected == 0 {
+		return ErrNotFound
+	}
+
+	return nil
+}
+
+func (s *LicenseStore) Seats(ctx context.Context, licenseID int64, spec QuerySpec) (*Pagination, error) {
+	query := s.db.WithContext(ctx).Model(&LicenseSeat{}).
+		Joins("User").
+		Joins("Asset").
+		Where("license_seats.license_id = ?", licenseID)
+
+	return paginate[LicenseSeat](query, spec, licenseSeatQueryFields, "license_seats.id")
+}
+
+// HeldBy returns the license seats checked out to a user.
+func (s *LicenseStore) HeldBy(ctx context.Context, userID int64) ([]LicenseSeat, error) {
+	seats := []LicenseSeat{}
+
+	err := s.db.WithContext(ctx).
+		Joins("License").
+		Joins("User").
+		Where("license_seats.user_id = ? AND license_seats.checked_in_at IS NULL", userID).
+		Order("license_seats.checked_out_at, license_seats.id").
+		Find(&seats).Error
+	if err != nil {
+		return nil, err
+	}
+
+	return seats, nil
+}
+
+// ReclaimInactive checks in the seats held by users that were deactivated or
+// deleted and returns them.
+func (s *LicenseStore) ReclaimInactive(ctx context.Context) ([]LicenseSeat, error) {
+	var seats []LicenseSeat
+
+	err := s.db.WithContext(ctx).
+		Model(&seats).
+		Clauses(clause.Returning{}).
+		Where("checked_in_at IS NULL").
+		Where("user_id IN (?)", s.db.Unscoped().Model(&User{}).
+			Select("id").
+			Where("NOT is_active OR deleted_at IS NOT NULL")).
+		Updates(map[string]interface{}{
+			"checked_in_at": time.Now(),
+			"reclaimed":     true,
+		}).Error
+	if err != nil {
+		return nil, err
+	}
+
+	return seats, nil
+}
+
+// GetUnnotifiedExpiring returns the licenses entering their expiring window
+// no alert went out for yet.
+func (s *LicenseStore) GetUnnotifiedExpiring(ctx context.Context) ([]License, error) {
+	var licenses []License
+
+	err := s.db.WithContext(ctx).
+		Where("expiry_notified_at IS NULL").
+		Where("expires_at >= ? AND expires_at < ?", time.Now(), time.Now().Add(LicenseExpiringWindow)).
+		Order("expires_at, id").
+		Find(&licenses).Error
+	if err != nil {
+		return nil, err
+	}
+
+	return licenses, nil
+}
+
+// MarkNotified records the expiry alert for a license together with the
+// notifications carrying it. It reports false when another run got there
+// first.
+func (s *LicenseStore) MarkNotified(ctx context.Context, id int64, notifications []Notification) (bool, error) {
+	marked := false
+
+	err := s.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		result := tx.Model(&License{}).
+			Where("id = ? AND expiry_notified_at IS NULL", id).
+			Update("expiry_notified_at", time.Now())
+		if result.Error != nil {
+			return result.Error
+		}
+		if result.RowsAffected == 0 {
+			return nil
+		}
+
+		marked = true
+
+		if len(notifications) == 0 {
+			return nil
+		}
+
+		return tx.Create(&notifications).Error
+	})
+
+	return marked, err
+}
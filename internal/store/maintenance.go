@@ -0,0 +1,303 @@
+package store
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+type MaintenanceType string
+
+const (
+	MaintenanceRepair      MaintenanceType = "REPAIR"
+	MaintenanceUpgrade     MaintenanceType = "UPGRADE"
+	MaintenanceCalibration MaintenanceType = "CALIBRATION"
+	MaintenancePreventive  MaintenanceType = "PM"
+)
+
+type MaintenanceStatus string
+
+const (
+	MaintenanceScheduled  MaintenanceStatus = "SCHEDULED"
+	MaintenanceInProgress MaintenanceStatus = "IN_PROGRESS"
+	MaintenanceCompleted  MaintenanceStatus = "COMPLETED"
+	MaintenanceCancelled  MaintenanceStatus = "CANCELLED"
+)
+
+type Maintenance struct {
+	ID    int64  `gorm:"primaryKey"`
+	Title string `gorm:"size:150;not null"`
+
+	AssetID int64 `gorm:"not null;index"`
+	Asset   Asset `gorm:"constraint:OnDelete:CASCADE;"`
+
+	SupplierID *int64    `gorm:"index"`
+	Supplier   *Supplier `gorm:"constraint:OnDelete:SET NULL;"`
+
+	// set on work orders generated from a maintenance plan
+	PlanID  *int64           `gorm:"index"`
+	Plan    *MaintenancePlan `gorm:"constraint:OnDelete:SET NULL;"`
+	DueDate *time.Time
+
+	Type   MaintenanceType   `gorm:"type:varchar(20);not null"`
+	Status MaintenanceStatus `gorm:"type:varchar(20);not null;default:'SCHEDULED';index"`
+
+	StartDate      *time.Time
+	CompletionDate *time.Time
+
+	Cost  float64 `gorm:"not null;default:0"`
+	Notes string  `gorm:"size:1000"`
+
+	CreatedAt time.Time
+	UpdatedAt time.Time
+}
+
+// MaintenanceCost is the cost of ownership of a single asset or, when grouped
+// by model, of every asset of that model.
+type MaintenanceCost struct {
+	ID               int64
+	Name             string
+	AssetCount       int64
+	MaintenanceCount int64
+	PurchaseCost     float64
+	MaintenanceCost  float64
+}
+
+type MaintenanceStore struct {
+	db *gorm.DB
+}
+
+var maintenanceQueryFields = QueryFields{
+	"id":             {"maintenances.id", IntField},
+	"title":          {"maintenances.title", StringField},
+	"assetId":        {"maintenances.asset_id", IntField},
+	"supplierId":     {"maintenances.supplier_id", IntField},
+	"planId":         {"maintenances.plan_id", IntField},
+	"dueDate":        {"maintenances.due_date", TimeField},
+	"type":           {"maintenances.type", StringField},
+	"status":         {"maintenances.status", StringField},
+	"startDate":      {"maintenances.start_date", TimeField},
+	"completionDate": {"maintenances.completion_date", TimeField},
+	"cost":           {"maintenances.cost", FloatField},
+	"createdAt":      {"maintenances.created_at", TimeField},
+}
+
+func (s *MaintenanceStore) List(ctx context.Context, spec QuerySpec) (*Pagination, error) {
+	query := s.db.WithContext(ctx).Model(&Maintenance{}).
+		Joins("Asset").
+		Joins("Supplier")
+
+	return paginate[Maintenance](query, spec, maintenanceQueryFields, "maintenances.id")
+}
+
+func (s *MaintenanceStore) Stream(ctx context.Context, spec QuerySpec, fn func(*Maintenance) error) error {
+	query := s.db.Model(&Maintenance{}).
+		Joins("Asset").
+		Joins("Supplier")
+
+	return streamQuery(ctx, query, spec, maintenanceQueryFields, "maintenances.id", fn)
+}
+
+func (s *MaintenanceStore) GetByID(ctx context.Context, id int64) (*Maintenance, error) {
+	var maintenance Maintenance
+
+	err := s.db.WithContext(ctx).
+		Joins("Asset").
+		Joins("Supplier").
+		First(&maintenance, "maintenances.id = ?", id).Error
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, ErrNotFound
+		}
+		return nil, err
+	}
+
+	return &maintenance, nil
+}
+
+// Create stores the record and brings the asset status in line with it.
+func (s *MaintenanceStore) Create(ctx context.Context, maintenance *Maintenance) error {
+	return s.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		if err := tx.Omit("Asset", "Supplier", "Plan").Create(maintenance).Error; err != nil {
+			return err
+		}
+
+		return syncMaintenanceStatus(tx, maintenance.AssetID, maintenance.Status == MaintenanceCompleted)
+	})
+}
+
+// Update saves the record and brings the asset status in line with it.
+func (s *MaintenanceStore) Update(ctx context.Context, maintenance *Maintenance) error {
+	return s.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		result := tx.Model(&Maintenance{}).
+			Where("id = ?", maintenance.ID).
+			Updates(map[string]interface{}{
+				"title":           maintenance.Title,
+				"supplier_id":     maintenance.SupplierID,
+				"type":            maintenance.Type,
+				"status":          maintenance.Status,
+				"start_date":      maintenance.StartDate,
+				"completion_date": maintenance.CompletionDate,
+				"due_date":        maintenance.DueDate,
+				"cost":            maintenance.Cost,
+				"notes":           maintenance.Notes,
+			})
+		if result.Error != nil {
+			return result.Error
+		}
+		if result.RowsAffected == 0 {
+			return ErrNotFound
+		}
+
+		return syncMaintenanceStatus(tx, maintenance.AssetID, maintenance.Status == MaintenanceCompleted)
+	})
+}
+
+func (s *MaintenanceStore) Delete(ctx context.Context, maintenance *Maintenance) error {
+	return s.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		result := tx.Delete(&Maintenance{}, maintenance.ID)
+		if result.Error != nil {
+			return result.Error
+		}
+		if result.RowsAffected == 0 {
+			return ErrNotFound
+		}
+
+		return syncMaintenanceStatus(tx, maintenance.AssetID, false)
+	})
+}
+
+// statuses maintenance takes an asset into REPAIR from; assets on loan or
+// retired, say, are left alone
+var repairableAssetStatuses = []AssetStatus{AssetAvailable, AssetBroken}
+
+// statusAfterRepair is the status an asset goes back to once its maintenance
+// ended. A broken asset is available again when the work was completed, and
+// stays broken when it was cancelled.
+func statusAfterRepair(before AssetStatus, completed bool) AssetStatus {
+	if before == AssetBroken && completed {
+		return AssetAvailable
+	}
+	return before
+}
+
+// syncMaintenanceStatus puts an AVAILABLE or BROKEN asset into REPAIR while
+// any of its maintenance is in progress, remembering the status it had, and
+// gives it back via statusAfterRepair once the last one ended, completed
+// telling whether that one was. A REPAIR status maintenance did not set is
+// left alone.
+func syncMaintenanceStatus(tx *gorm.DB, assetID int64, completed bool) error {
+	var active int64
+	err := tx.Model(&Maintenance{}).
+		Where("asset_id = ? AND status = ?", assetID, MaintenanceInProgress).
+		Count(&active).Error
+	if err != nil {
+		return err
+	}
+
+	if active > 0 {
+		var asset Asset
+		err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).
+			Select("id", "status").
+			Where("status IN ?", repairableAssetStatuses).
+			Take(&asset, assetID).Error
+		if err != nil {
+			if errors.Is(err, gorm.ErrRecordNotFound) {
+				return nil
+			}
+			return err
+		}
+
+		if _, err := changeAssetStatus(tx, assetID, AssetRepair, asset.Status); err != nil {
+			return err
+		}
+
+		return tx.Model(&Asset{}).
+			Where("id = ?", assetID).
+			Update("status_before_repair", asset.Status).Error
+	}
+
+	var asset Asset
+	err = tx.Select("id", "status_before_repair").
+		Where("status_before_repair IS NOT NULL").
+		Take(&asset, assetID).Error
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil
+		}
+		return err
+	}
+
+	// still set, so nothing else changed the status meanwhile
+	_, err = changeAssetStatus(tx, assetID, statusAfterRepair(*asset.StatusBeforeRepair, completed), AssetRepair)
+	return err
+}
+
+// CostByAsset totals the maintenance spent on each asset next to its purchase
+// cost. Cancelled records are not counted.
+func (s *MaintenanceStore) CostByAsset(ctx context.Context, assetID *int64) ([]MaintenanceCost, error) {
+	ctx, cancel := context.WithTimeout(ctx, QueryTimeoutDuration)
+	defer cancel()
+
+	query := s.db.WithContext(ctx).
+		Table("assets a").
+		Select(`a.id, a.name, 1 AS asset_count,
+			count(m.id) AS maintenance_count,
+			coalesce(a.purchase_cost, 0) AS purchase_cost,
+			coalesce(sum(m.cost), 0) AS maintenance_cost`).
+		Joins("LEFT JOIN maintenances m ON m.asset_id = a.id AND m.status <> ?", MaintenanceCancelled).
+		Where("a.deleted_at IS NULL").
+		Group("a.id").
+		Order("maintenance_cost DESC, a.id")
+
+	if assetID != nil {
+		query = query.Where("a.id = ?", *assetID)
+	}
+
+	costs := []MaintenanceCost{}
+	if err := query.Scan(&costs).Error; err != nil {
+		return nil, err
+	}
+
+	return costs, nil
+}
+
+// CostByModel totals purchase and maintenance cost over every asset of each
+// model.
+func (s *MaintenanceStore) CostByModel(ctx context.Context, modelID *int64) ([]MaintenanceCost, error) {
+	ctx, cancel := context.WithTimeout(ctx, QueryTimeoutDuration)
+	defer cancel()
+
+	// maintenance is summed per asset first so the purchase cost of an asset
+	// is not counted once for every record
+	query := s.db.WithContext(ctx).
+		Table("models md").
+		Select(`md.id, md.name,
+			count(a.id) AS asset_count,
+			coalesce(sum(c.maintenance_count), 0) AS maintenance_count,
+			coalesce(sum(a.purchase_cost), 0) AS purchase_cost,
+			coalesce(sum(c.maintenance_cost), 0) AS maintenance_cost`).
+		Joins("JOIN assets a ON a.model_id = md.id AND a.deleted_at IS NULL").
+		Joins(`LEFT JOIN (
+			SELECT asset_id, count(*) AS maintenance_count, sum(cost) AS maintenance_cost
+			FROM maintenances
+			WHERE status <> ?
+			GROUP BY asset_id
+		) c ON c.asset_id = a.id`, MaintenanceCancelled).
+		Group("md.id").
+		Order("maintenance_cost DESC, md.id")
+
+	if modelID != nil {
+		query = query.Where("md.id = ?", *modelID)
+	}
+
+	costs := []MaintenanceCost{}
+	if err := query.Scan(&costs).Error; err != nil {
+		return nil, err
+	}
+
+	return costs, nil
+}
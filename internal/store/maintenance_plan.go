@@ -0,0 +1,321 @@
+package store
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/knr1997/assets-management-apiserver/internal/recurrence"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+var ErrInvalidMaintenancePlan = errors.New("invalid maintenance plan")
+
+// MaintenancePlan describes recurring preventive maintenance for one asset or
+// for every asset of a model. A plan recurs either by an RRULE or every
+// IntervalDays after its last completed work order.
+type MaintenancePlan struct {
+	ID   int64  `gorm:"primaryKey"`
+	Name string `gorm:"size:150;not null"`
+
+	ModelID *int64 `gorm:"index"`
+	Model   *Model `gorm:"constraint:OnDelete:CASCADE;"`
+
+	AssetID *int64 `gorm:"index"`
+	Asset   *Asset `gorm:"constraint:OnDelete:CASCADE;"`
+
+	SupplierID *int64    `gorm:"index"`
+	Supplier   *Supplier `gorm:"constraint:OnDelete:SET NULL;"`
+
+	// notified along with whoever holds the asset when a work order is due
+	OwnerID *int64 `gorm:"index"`
+	Owner   *User  `gorm:"constraint:OnDelete:SET NULL;"`
+
+	Type         MaintenanceType `gorm:"type:varchar(20);not null;default:'PM'"`
+	Recurrence   string          `gorm:"size:255"`
+	IntervalDays int             `gorm:"not null;default:0"`
+	StartDate    time.Time       `gorm:"not null"`
+
+	// work orders are generated this many days before they are due
+	LeadDays int  `gorm:"not null;default:14"`
+	Active   bool `gorm:"not null;default:true"`
+
+	Notes string `gorm:"size:1000"`
+
+	CreatedAt time.Time
+	UpdatedAt time.Time
+}
+
+// PlanTarget is an asset covered by a plan along with the state of its work
+// orders for that plan.
+type PlanTarget struct {
+	AssetID       int64
+	AssetName     string
+	AssetTag      string
+	Location      string
+	HolderID      *int64
+	OpenID        *int64
+	OpenDue       *time.Time
+	LastDue       *time.Time
+	LastCompleted *time.Time
+}
+
+type MaintenancePlanStore struct {
+	db *gorm.DB
+}
+
+var maintenancePlanQueryFields = QueryFields{
+	"id":        {"maintenance_plans.id", IntField},
+	"name":      {"maintenance_plans.name", StringField},
+	"modelId":   {"maintenance_plans.model_id", IntField},
+	"assetId":   {"maintenance_plans.asset_id", IntField},
+	"type":      {"maintenance_plans.type", StringField},
+	"active":    {"maintenance_plans.active", BoolField},
+	"startDate": {"maintenance_plans.start_date", TimeField},
+	"createdAt": {"maintenance_plans.created_at", TimeField},
+}
+
+// Work orders are generated by every running instance; the index lets all but
+// one of them lose the race quietly.
+var maintenancePlanMigrations = []string{
+	`CREATE UNIQUE INDEX IF NOT EXISTS idx_maintenances_plan_due
+		ON maintenances (plan_id, asset_id, due_date)
+		WHERE plan_id IS NOT NULL`,
+}
+
+// Validate checks the plan and its recurrence before it is stored.
+func (p *MaintenancePlan) Validate() error {
+	if (p.ModelID == nil) == (p.AssetID == nil) {
+		return fmt.Errorf("%w: a plan belongs to exactly one model or asset", ErrInvalidMaintenancePlan)
+	}
+
+	if (p.Recurrence == "") == (p.IntervalDays == 0) {
+		return fmt.Errorf("%w: set either a recurrence rule or an interval in days", ErrInvalidMaintenancePlan)
+	}
+
+	if p.Recurrence != "" {
+		if _, err := recurrence.Parse(p.Recurrence); err != nil {
+			return fmt.Errorf("%w: %w", ErrInvalidMaintenancePlan, err)
+		}
+	}
+
+	if p.IntervalDays < 0 || p.LeadDays < 0 {
+		return fmt.Errorf("%w: intervals cannot be negative", ErrInvalidMaintenancePlan)
+	}
+
+	return nil
+}
+
+// NextDue returns when the next work order for target is due. An open work
+// order is always the next one due. ok is false once a recurrence has ended.
+//
+// Interval plans count from the last completion, but never come due at or
+// before the last work order: after a cancelled one, or one done early, they
+// count from its due date instead.
+func (p *MaintenancePlan) NextDue(target PlanTarget) (time.Time, bool) {
+	if target.OpenDue != nil {
+		return *target.OpenDue, true
+	}
+
+	if p.IntervalDays > 0 {
+		next := p.StartDate
+		if target.LastCompleted != nil {
+			next = target.LastCompleted.AddDate(0, 0, p.IntervalDays)
+		}
+		if target.LastDue != nil && !next.After(*target.LastDue) {
+			next = target.LastDue.AddDate(0, 0, p.IntervalDays)
+		}
+		return next, true
+	}
+
+	rule, err := recurrence.Parse(p.Recurrence)
+	if err != nil {
+		return time.Time{}, false
+	}
+
+	after := p.StartDate.Add(-time.Nanosecond)
+	if target.LastDue != nil {
+		after = *target.LastDue
+	}
+
+	return rule.After(p.StartDate, after)
+}
+
+func (s *MaintenancePlanStore) List(ctx context.Context, spec QuerySpec) (*Pagination, error) {
+	query := s.db.WithContext(ctx).Model(&MaintenancePlan{}).
+		Joins("Model").
+		Joins("Asset").
+		Joins("Supplier")
+
+	return paginate[MaintenancePlan](query, spec, maintenancePlanQueryFields, "maintenance_plans.id")
+}
+
+// GetActive returns every active plan.
+func (s *MaintenancePlanStore) GetActive(ctx context.Context) ([]MaintenancePlan, error) {
+	var plans []MaintenancePlan
+
+	err := s.db.WithContext(ctx).
+		Where("active").
+		Order("id").
+		Find(&plans).Error
+	if err != nil {
+		return nil, err
+	}
+
+	return plans, nil
+}
+
+func (s *MaintenancePlanStore) GetByID(ctx context.Context, id int64) (*MaintenancePlan, error) {
+	var plan MaintenancePlan
+
+	err := s.db.WithContext(ctx).
+		Joins("Model").
+		Joins("Asset").
+		Joins("Supplier").
+		First(&plan, "maintenance_plans.id = ?", id).Error
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, ErrNotFound
+		}
+		return nil, err
+	}
+
+	return &plan, nil
+}
+
+func (s *MaintenancePlanStore) Create(ctx context.Context, plan *MaintenancePlan) error {
+	return s.db.WithContext(ctx).Omit("Model", "Asset", "Supplier", "Owner").Create(plan).Error
+}
+
+func (s *MaintenancePlanStore) Update(ctx context.Context, plan *MaintenancePlan) error {
+	result := s.db.WithContext(ctx).
+		Model(&MaintenancePlan{}).
+		Where("id = ?", plan.ID).
+		Updates(map[string]interface{}{
+			"name":          plan.Name,
+			"model_id":      plan.ModelID,
+			"asset_id":      plan.AssetID,
+			"supplier_id":   plan.SupplierID,
+			"owner_id":      plan.OwnerID,
+			"type":          plan.Type,
+			"recurrence":    plan.Recurrence,
+			"interval_days": plan.IntervalDays,
+			"start_date":    plan.StartDate,
+			"lead_days":     plan.LeadDays,
+			"active":        plan.Active,
+			"notes":         plan.Notes,
+		})
+
+	if result.Error != nil {
+		return result.Error
+	}
+
+	if result.RowsAffected == 0 {
+		return ErrNotFound
+	}
+
+	return nil
+}
+
+func (s *MaintenancePlanStore) Delete(ctx context.Context, id int64) error {
+	result := s.db.WithContext(ctx).
+		Delete(&MaintenancePlan{}, id)
+
+	if result.Error != nil {
+		return result.Error
+	}
+
+	if result.RowsAffected == 0 {
+		return ErrNotFound
+	}
+
+	return nil
+}
+
+const planTargetsSQL = `
+SELECT a.id AS asset_id,
+	a.name AS asset_name,
+	a.tag AS asset_tag,
+	a.location,
+	(
+		SELECT l.user_id FROM asset_loans l
+		WHERE l.asset_id = a.id AND l.actual_return_date IS NULL
+		ORDER BY l.checkout_date DESC
+		LIMIT 1
+	) AS holder_id,
+	o.id AS open_id,
+	o.due_date AS open_due,
+	(
+		SELECT max(m.due_date) FROM maintenances m
+		WHERE m.plan_id = @plan AND m.asset_id = a.id
+	) AS last_due,
+	(
+		SELECT max(m.completion_date) FROM maintenances m
+		WHERE m.plan_id = @plan AND m.asset_id = a.id AND m.status = @completed
+	) AS last_completed
+FROM assets a
+LEFT JOIN LATERAL (
+	SELECT m.id, m.due_date FROM maintenances m
+	WHERE m.plan_id = @plan AND m.asset_id = a.id AND m.status IN @open
+	ORDER BY m.due_date
+	LIMIT 1
+) o ON true
+WHERE a.deleted_at IS NULL
+	AND a.status NOT IN @retired
+	AND (a.id = @asset OR a.model_id = @model)
+ORDER BY a.id`
+
+// Targets returns the assets a plan covers. Retired, archived and lost assets
+// are left out.
+func (s *MaintenancePlanStore) Targets(ctx context.Context, plan *MaintenancePlan) ([]PlanTarget, error) {
+	ctx, cancel := context.WithTimeout(ctx, QueryTimeoutDuration)
+	defer cancel()
+
+	targets := []PlanTarget{}
+
+	err := s.db.WithContext(ctx).Raw(planTargetsSQL,
+		sql.Named("plan", plan.ID),
+		sql.Named("asset", plan.AssetID),
+		sql.Named("model", plan.ModelID),
+		sql.Named("completed", MaintenanceCompleted),
+		sql.Named("open", []MaintenanceStatus{MaintenanceScheduled, MaintenanceInProgress}),
+		sql.Named("retired", []AssetStatus{AssetRetired, AssetArchived, AssetLostStolen}),
+	).Scan(&targets).Error
+	if err != nil {
+		return nil, err
+	}
+
+	return targets, nil
+}
+
+// CreateWorkOrder stores a generated work order and the notifications that
+// announce it. It reports false when another run already generated the same
+// work order.
+func (s *MaintenancePlanStore) CreateWorkOrder(ctx context.Context, order *Maintenance, notifications []Notification) (bool, error) {
+	created := false
+
+	err := s.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		result := tx.Omit("Asset", "Supplier", "Plan").
+			Clauses(clause.OnConflict{DoNothing: true}).
+			Create(order)
+		if result.Error != nil {
+			return result.Error
+		}
+		if result.RowsAffected == 0 {
+			return nil
+		}
+
+		created = true
+
+		if len(notifications) == 0 {
+			return nil
+		}
+
+		return tx.Create(&notifications).Error
+	})
+
+	return created, err
+}
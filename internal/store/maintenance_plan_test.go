@@ -0,0 +1,42 @@
+package store
+
+import (
+	"testing"
+	"time"
+)
+
+func TestMaintenancePlanNextDue(t *testing.T) {
+	day := func(m time.Month, d int) *time.Time {
+		t := time.Date(2025, m, d, 0, 0, 0, 0, time.UTC)
+		return &t
+	}
+
+	interval := &MaintenancePlan{IntervalDays: 30, StartDate: *day(1, 1)}
+	monthly := &MaintenancePlan{Recurrence: "FREQ=MONTHLY", StartDate: *day(1, 1)}
+
+	tests := []struct {
+		name   string
+		plan   *MaintenancePlan
+		target PlanTarget
+		want   *time.Time
+		wantOK bool
+	}{
+		{"first", interval, PlanTarget{}, day(1, 1), true},
+		{"open order", interval, PlanTarget{OpenDue: day(2, 1), LastDue: day(2, 1)}, day(2, 1), true},
+		{"after completion", interval, PlanTarget{LastDue: day(1, 1), LastCompleted: day(1, 10)}, day(2, 9), true},
+		{"first cancelled", interval, PlanTarget{LastDue: day(1, 1)}, day(1, 31), true},
+		{"cancelled after completion", interval, PlanTarget{LastDue: day(2, 9), LastCompleted: day(1, 10)}, day(3, 11), true},
+		{"completed early", interval, PlanTarget{LastDue: day(3, 1), LastCompleted: day(1, 20)}, day(3, 31), true},
+		{"rule first", monthly, PlanTarget{}, day(1, 1), true},
+		{"rule after last", monthly, PlanTarget{LastDue: day(1, 1), LastCompleted: day(1, 20)}, day(2, 1), true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, ok := tt.plan.NextDue(tt.target)
+			if ok != tt.wantOK || !got.Equal(*tt.want) {
+				t.Errorf("NextDue() = %v, %v, want %v, %v", got, ok, *tt.want, tt.wantOK)
+			}
+		})
+	}
+}
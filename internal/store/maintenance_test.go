@@ -0,0 +1,37 @@
+package store
+
+import (
+	"slices"
+	"testing"
+)
+
+func TestMaintenanceRepairStatus(t *testing.T) {
+	tests := []struct {
+		name      string
+		before    AssetStatus
+		completed bool
+		want      AssetStatus
+	}{
+		{"available completed", AssetAvailable, true, AssetAvailable},
+		{"available cancelled", AssetAvailable, false, AssetAvailable},
+		{"broken completed", AssetBroken, true, AssetAvailable},
+		{"broken cancelled", AssetBroken, false, AssetBroken},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if !slices.Contains(repairableAssetStatuses, tt.before) {
+				t.Fatalf("%s does not go into repair", tt.before)
+			}
+			if got := statusAfterRepair(tt.before, tt.completed); got != tt.want {
+				t.Errorf("statusAfterRepair(%s, %v) = %s, want %s", tt.before, tt.completed, got, tt.want)
+			}
+		})
+	}
+
+	for _, status := range []AssetStatus{AssetAssigned, AssetRetired, AssetLostStolen} {
+		if slices.Contains(repairableAssetStatuses, status) {
+			t.Errorf("%s goes into repair", status)
+		}
+	}
+}
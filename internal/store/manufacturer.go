@@ -2,6 +2,9 @@ package store
 
 import (
 	"context"
+	"errors"
+	"net/url"
+	"strings"
 
 	"gorm.io/gorm"
 )
@@ -10,12 +13,35 @@ type Manufacturer struct {
 	ID    int64  `json:"id"`
 	Name  string `gorm:"uniqueIndex;not null" json:"name"`
 	Email string `gorm:"uniqueIndex;not null" json:"email"`
+
+	VendorDetails
+	// URL of the manufacturer's warranty lookup with a {serial} placeholder
+	WarrantyLookupURL string    `gorm:"size:500"`
+	Contacts          []Contact `gorm:"polymorphic:Owner;polymorphicValue:manufacturers"`
+}
+
+const SerialPlaceholder = "{serial}"
+
+// WarrantyLookupURLFor fills serial into the warranty lookup template. It
+// returns "" when there is no template or no serial.
+func (m *Manufacturer) WarrantyLookupURLFor(serial string) string {
+	if m.WarrantyLookupURL == "" || serial == "" {
+		return ""
+	}
+
+	return strings.ReplaceAll(m.WarrantyLookupURL, SerialPlaceholder, url.PathEscape(serial))
 }
 
 type ManufacturerStore struct {
 	db *gorm.DB
 }
 
+var manufacturerQueryFields = QueryFields{
+	"id":    {"manufacturers.id", IntField},
+	"name":  {"manufacturers.name", StringField},
+	"email": {"manufacturers.email", StringField},
+}
+
 func (s *ManufacturerStore) GetAll(ctx context.Context) ([]Manufacturer, error) {
 	var manufacturers []Manufacturer
 
@@ -27,32 +53,51 @@ func (s *ManufacturerStore) GetAll(ctx context.Context) ([]Manufacturer, error)
 	return manufacturers, nil
 }
 
+func (s *ManufacturerStore) List(ctx context.Context, spec QuerySpec) (*Pagination, error) {
+	query := s.db.WithContext(ctx).Model(&Manufacturer{})
+
+	return paginate[Manufacturer](query, spec, manufacturerQueryFields, "manufacturers.id")
+}
+
 func (s *ManufacturerStore) GetByID(ctx context.Context, id int64) (*Manufacturer, error) {
 	var manufacturer Manufacturer
 
 	err := s.db.WithContext(ctx).
+		Preload("Contacts", func(db *gorm.DB) *gorm.DB { return db.Order("name, id") }).
 		First(&manufacturer, id).
 		Error
 	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, ErrNotFound
+		}
 		return nil, err
 	}
 
 	return &manufacturer, nil
 }
 
-func (s ManufacturerStore) Create(ctx context.Context, asset *Manufacturer) error {
-	return s.db.WithContext(ctx).Create(asset).Error
+func (s ManufacturerStore) Create(ctx context.Context, manufacturer *Manufacturer) error {
+	err := s.db.WithContext(ctx).Omit("Contacts").Create(manufacturer).Error
+	if isUniqueViolation(err) {
+		return ErrConflict
+	}
+	return err
 }
 
 func (s *ManufacturerStore) Update(ctx context.Context, manufacturer *Manufacturer) error {
+	updates := vendorDetailsUpdates(manufacturer.VendorDetails)
+	updates["name"] = manufacturer.Name
+	updates["email"] = manufacturer.Email
+	updates["warranty_lookup_url"] = manufacturer.WarrantyLookupURL
+
 	result := s.db.WithContext(ctx).
 		Model(&Manufacturer{}).
 		Where("id = ?", manufacturer.ID).
-		Updates(map[string]interface{}{
-			"name":  manufacturer.Name,
-			"email": manufacturer.Email,
-		})
+		Updates(updates)
 
+	if isUniqueViolation(result.Error) {
+		return ErrConflict
+	}
 	if result.Error != nil {
 		return result.Error
 	}
@@ -65,16 +110,15 @@ func (s *ManufacturerStore) Update(ctx context.Context, manufacturer *Manufactur
 }
 
 func (s *ManufacturerStore) Delete(ctx context.Context, id int64) error {
-	result := s.db.WithContext(ctx).
-		Delete(&Manufacturer{}, id)
-
-	if result.Error != nil {
-		return result.Error
-	}
-
-	if result.RowsAffected == 0 {
-		return ErrNotFound
-	}
-
-	return nil
+	return s.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		result := tx.Delete(&Manufacturer{}, id)
+		if result.Error != nil {
+			return result.Error
+		}
+		if result.RowsAffected == 0 {
+			return ErrNotFound
+		}
+
+		return deleteContacts(tx, ContactOwnerManufacturer, id)
+	})
 }
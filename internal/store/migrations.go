@@ -0,0 +1,135 @@
+package store
+
+import (
+	"context"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// migration holds schema changes AutoMigrate cannot express, such as
+// extensions, generated columns and expression indexes. Migrations run in
+// order, once each, after AutoMigrate.
+type migration struct {
+	Name       string
+	Statements []string
+}
+
+var migrations = []migration{
+	{"0001_search_indexes", searchMigrations},
+	{"0002_asset_custom_fields_search", assetCustomFieldSearchMigrations},
+	{"0003_maintenance_plan_due_index", maintenancePlanMigrations},
+	{"0004_license_seat_holders", licenseSeatMigrations},
+	{"0005_asset_relation_container", assetRelationMigrations},
+	{"0006_reservation_exclusion", reservationMigrations},
+	{"0007_user_department", assetRequestMigrations},
+	{"0008_offboarding_open_case", offboardingMigrations},
+	{"0009_disposal_active_asset", disposalMigrations},
+	{"0010_webhook_dispatch_indexes", webhookMigrations},
+	{"0011_webhook_delivery_per_event", webhookDeliveryMigrations},
+	{"0012_notification_per_event", notificationEventMigrations},
+	{"0013_asset_search_skips_sealed_fields", assetSealedFieldSearchMigrations},
+	{"0014_activate_registered_users", userActivationMigrations},
+	{"0015_asset_loan_name_not_unique", assetLoanMigrations},
+}
+
+type SchemaMigration struct {
+	Name      string `gorm:"primaryKey;size:100"`
+	AppliedAt time.Time
+}
+
+// migrationLock keys the advisory lock held while migrating, so replicas
+// starting together migrate one after the other and the later ones find the
+// work done.
+const migrationLock = 4_207_311_829
+
+// Models lists the models the schema is auto-migrated from.
+func Models() []any {
+	return []any{
+		&User{},
+		&Category{},
+		&PurchaseOrder{},
+		&PurchaseOrderLine{},
+		&Invoice{},
+		&Asset{},
+		&AssetAssignment{},
+		&AssetLoan{},
+		&AssetRelation{},
+		&Manufacturer{},
+		&Model{},
+		&Department{},
+		&Supplier{},
+		&Contact{},
+		&AuditLog{},
+		&CustomFieldSet{},
+		&CustomField{},
+		&MaintenancePlan{},
+		&Maintenance{},
+		&Notification{},
+		&Warranty{},
+		&Consumable{},
+		&ConsumableStock{},
+		&ConsumableMovement{},
+		&License{},
+		&LicenseSeat{},
+		&Accessory{},
+		&AccessoryStock{},
+		&AccessoryLoan{},
+		&Component{},
+		&ComponentInstall{},
+		&Kit{},
+		&KitItem{},
+		&Reservation{},
+		&ApprovalStep{},
+		&AssetRequest{},
+		&AssetRequestApproval{},
+		&Attachment{},
+		&Offboarding{},
+		&OffboardingItem{},
+		&Disposal{},
+		&OutboxEvent{},
+		&Webhook{},
+		&WebhookDelivery{},
+	}
+}
+
+// Migrate auto-migrates models, then applies the migrations not applied yet.
+func Migrate(ctx context.Context, db *gorm.DB, models ...any) error {
+	// the lock is held by a session, so everything runs on one connection
+	return db.WithContext(ctx).Connection(func(conn *gorm.DB) error {
+		if err := conn.Exec("SELECT pg_advisory_lock(?)", migrationLock).Error; err != nil {
+			return err
+		}
+		// released even when ctx is done; the connection goes back to the pool
+		defer conn.WithContext(context.Background()).Exec("SELECT pg_advisory_unlock(?)", migrationLock)
+
+		if err := conn.AutoMigrate(append(models, &SchemaMigration{})...); err != nil {
+			return err
+		}
+
+		for _, m := range migrations {
+			err := conn.Transaction(func(tx *gorm.DB) error {
+				var applied int64
+				if err := tx.Model(&SchemaMigration{}).Where("name = ?", m.Name).Count(&applied).Error; err != nil {
+					return err
+				}
+				if applied > 0 {
+					return nil
+				}
+
+				for _, stmt := range m.Statements {
+					if err := tx.Exec(stmt).Error; err != nil {
+						return err
+					}
+				}
+
+				return tx.Create(&SchemaMigration{Name: m.Name, AppliedAt: time.Now()}).Error
+			})
+			if err != nil {
+				return err
+			}
+		}
+
+		return nil
+	})
+}
@@ -2,9 +2,11 @@ package store
 
 import (
 	"context"
+	"errors"
 	"time"
 
 	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
 )
 
 type Model struct {
@@ -19,6 +21,25 @@ type Model struct {
 
 	ModelNumber string `gorm:"size:255"`
 
+	// Requestable models are offered to users in the asset request catalog,
+	// RequestCost is what one costs the approval chain is sized by.
+	Requestable bool    `gorm:"not null;default:false"`
+	RequestCost float64 `gorm:"not null;default:0"`
+
+	// a picture of the model, one of its attachments
+	ImageID *int64      `gorm:"index"`
+	Image   *Attachment `gorm:"constraint:OnDelete:SET NULL;"`
+
+	// when the manufacturer stops selling the model, and stops supporting it
+	EOLDate          *time.Time
+	EndOfSupportDate *time.Time `gorm:"index"`
+
+	// new assets of the model start with these, checkouts are due back after
+	// DefaultLoanDays unless given a date
+	DefaultUsefulLifeYears    int                `gorm:"not null;default:0"`
+	DefaultDepreciationMethod DepreciationMethod `gorm:"type:varchar(20);not null;default:'STRAIGHT_LINE'"`
+	DefaultLoanDays           int                `gorm:"not null;default:0"`
+
 	CreatedAt time.Time
 	UpdatedAt time.Time
 }
@@ -27,6 +48,20 @@ type ModelStore struct {
 	db *gorm.DB
 }
 
+var modelQueryFields = QueryFields{
+	"id":             {"models.id", IntField},
+	"name":           {"models.name", StringField},
+	"modelNumber":    {"models.model_number", StringField},
+	"categoryId":     {"models.category_id", IntField},
+	"manufacturerId": {"models.manufacturer_id", IntField},
+	"requestable":    {"models.requestable", BoolField},
+	"requestCost":    {"models.request_cost", FloatField},
+	"eolDate":        {"models.eol_date", TimeField},
+	"endOfSupport":   {"models.end_of_support_date", TimeField},
+	"createdAt":      {"models.created_at", TimeField},
+	"updatedAt":      {"models.updated_at", TimeField},
+}
+
 func (s *ModelStore) GetAll(ctx context.Context) ([]Model, error) {
 	var models []Model
 
@@ -38,6 +73,32 @@ func (s *ModelStore) GetAll(ctx context.Context) ([]Model, error) {
 	return models, nil
 }
 
+func (s *ModelStore) List(ctx context.Context, spec QuerySpec) (*Pagination, error) {
+	query := s.db.WithContext(ctx).Model(&Model{}).
+		Joins("Category").
+		Joins("Manufacturer")
+
+	return paginate[Model](query, spec, modelQueryFields, "models.id")
+}
+
+// ListRequestable pages through the catalog of models users can request.
+func (s *ModelStore) ListRequestable(ctx context.Context, spec QuerySpec) (*Pagination, error) {
+	query := s.db.WithContext(ctx).Model(&Model{}).
+		Joins("Category").
+		Joins("Manufacturer").
+		Where("models.requestable")
+
+	return paginate[Model](query, spec, modelQueryFields, "models.id")
+}
+
+func (s *ModelStore) Stream(ctx context.Context, spec QuerySpec, fn func(*Model) error) error {
+	query := s.db.Model(&Model{}).
+		Joins("Category").
+		Joins("Manufacturer")
+
+	return streamQuery(ctx, query, spec, modelQueryFields, "models.id", fn)
+}
+
 func (s *ModelStore) GetByID(ctx context.Context, id int64) (*Model, error) {
 	var model Model
 
@@ -45,6 +106,9 @@ func (s *ModelStore) GetByID(ctx context.Context, id int64) (*Model, error) {
 		First(&model, id).
 		Error
 	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, ErrNotFound
+		}
 		return nil, err
 	}
 
@@ -60,7 +124,15 @@ func (s *ModelStore) Update(ctx context.Context, model *Model) error {
 		Model(&Model{}).
 		Where("id = ?", model.ID).
 		Updates(map[string]interface{}{
-			"name": model.Name,
+			"name":         model.Name,
+			"requestable":  model.Requestable,
+			"request_cost": model.RequestCost,
+
+			"eol_date":                    model.EOLDate,
+			"end_of_support_date":         model.EndOfSupportDate,
+			"default_useful_life_years":   model.DefaultUsefulLifeYears,
+			"default_depreciation_method": model.DefaultDepreciationMethod,
+			"default_loan_days":           model.DefaultLoanDays,
 		})
 
 	if result.Error != nil {
@@ -88,3 +160,61 @@ func (s *ModelStore) Delete(ctx context.Context, id int64) error {
 
 	return nil
 }
+
+// SetImage makes image, a new attachment of the model, its picture. It
+// returns the storage key of the picture it replaces, if any.
+func (s *ModelStore) SetImage(ctx context.Context, modelID int64, image *Attachment) (string, error) {
+	var replaced string
+
+	err := s.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		var model Model
+		err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).First(&model, modelID).Error
+		if err != nil {
+			if errors.Is(err, gorm.ErrRecordNotFound) {
+				return ErrNotFound
+			}
+			return err
+		}
+
+		if err := createAttachment(tx, image); err != nil {
+			return err
+		}
+
+		if err := tx.Model(&model).Update("image_id", image.ID).Error; err != nil {
+			return err
+		}
+
+		if model.ImageID == nil {
+			return nil
+		}
+
+		replaced, err = deleteAttachment(tx, *model.ImageID)
+		return err
+	})
+
+	return replaced, err
+}
+
+// RemoveImage deletes the model's picture, returning its storage key.
+func (s *ModelStore) RemoveImage(ctx context.Context, modelID int64) (string, error) {
+	var removed string
+
+	err := s.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		var model Model
+		err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).First(&model, modelID).Error
+		if err != nil {
+			if errors.Is(err, gorm.ErrRecordNotFound) {
+				return ErrNotFound
+			}
+			return err
+		}
+		if model.ImageID == nil {
+			return ErrNotFound
+		}
+
+		removed, err = deleteAttachment(tx, *model.ImageID)
+		return err
+	})
+
+	return removed, err
+}
@@ -0,0 +1,113 @@
+package store
+
+import (
+	"context"
+	"time"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// Notification is an in-app message for a single user.
+type Notification struct {
+	ID int64 `gorm:"primaryKey"`
+
+	UserID int64 `gorm:"not null;index"`
+	User   User  `gorm:"constraint:OnDelete:CASCADE;"`
+
+	Kind  string `gorm:"size:50;not null"`
+	Title string `gorm:"size:150;not null"`
+	Body  string `gorm:"size:1000"`
+
+	AssetID *int64 `gorm:"index"`
+	Asset   *Asset `gorm:"constraint:OnDelete:CASCADE;"`
+
+	// the domain event the notification was sent for
+	EventID *int64
+
+	ReadAt    *time.Time
+	CreatedAt time.Time
+}
+
+type NotificationStore struct {
+	db *gorm.DB
+}
+
+var notificationQueryFields = QueryFields{
+	"id":        {"notifications.id", IntField},
+	"kind":      {"notifications.kind", StringField},
+	"assetId":   {"notifications.asset_id", IntField},
+	"readAt":    {"notifications.read_at", TimeField},
+	"createdAt": {"notifications.created_at", TimeField},
+}
+
+// Notifications sent for a domain event are sent once per user, however
+// often the event is dispatched.
+var notificationEventMigrations = []string{
+	`CREATE UNIQUE INDEX IF NOT EXISTS idx_notifications_event_user
+		ON notifications (event_id, user_id) WHERE event_id IS NOT NULL`,
+}
+
+// List pages through the notifications of a single user.
+func (s *NotificationStore) List(ctx context.Context, userID int64, spec QuerySpec) (*Pagination, error) {
+	query := s.db.WithContext(ctx).Model(&Notification{}).
+		Where("notifications.user_id = ?", userID)
+
+	return paginate[Notification](query, spec, notificationQueryFields, "notifications.id")
+}
+
+func (s *NotificationStore) Create(ctx context.Context, notifications []Notification) error {
+	if len(notifications) == 0 {
+		return nil
+	}
+
+	return s.db.WithContext(ctx).Create(&notifications).Error
+}
+
+// CreateForEvent saves notifications sent for a domain event, skipping the
+// ones already sent for it when the event is dispatched again.
+func (s *NotificationStore) CreateForEvent(ctx context.Context, eventID int64, notifications []Notification) error {
+	if len(notifications) == 0 {
+		return nil
+	}
+
+	for i := range notifications {
+		notifications[i].EventID = &eventID
+	}
+
+	return s.db.WithContext(ctx).
+		Omit(clause.Associations).
+		Clauses(clause.OnConflict{
+			Columns:     []clause.Column{{Name: "event_id"}, {Name: "user_id"}},
+			TargetWhere: clause.Where{Exprs: []clause.Expression{clause.Expr{SQL: "event_id IS NOT NULL"}}},
+			DoNothing:   true,
+		}).
+		Create(&notifications).Error
+}
+
+// MarkRead marks a notification of the user as read. A notification that was
+// already read keeps its original time.
+func (s *NotificationStore) MarkRead(ctx context.Context, userID, id int64) error {
+	var count int64
+	err := s.db.WithContext(ctx).Model(&Notification{}).
+		Where("id = ? AND user_id = ?", id, userID).
+		Count(&count).Error
+	if err != nil {
+		return err
+	}
+	if count == 0 {
+		return ErrNotFound
+	}
+
+	return s.db.WithContext(ctx).
+		Model(&Notification{}).
+		Where("id = ? AND read_at IS NULL", id).
+		Update("read_at", time.Now()).Error
+}
+
+func (s *NotificationStore) MarkAllRead(ctx context.Context, userID int64) error {
+	return s.db.WithContext(ctx).
+		Model(&Notification{}).
+		Where("user_id = ? AND read_at IS NULL", userID).
+		Update("read_at", time.Now()).Error
+}
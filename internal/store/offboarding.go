@@ -0,0 +1,438 @@
+package store
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+var (
+	ErrOffboardingClosed = errors.New("offboarding is closed")
+	ErrItemResolved      = errors.New("item was already resolved")
+)
+
+type OffboardingStatus string
+
+const (
+	OffboardingOpen      OffboardingStatus = "OPEN"
+	OffboardingCompleted OffboardingStatus = "COMPLETED"
+	OffboardingCancelled OffboardingStatus = "CANCELLED"
+)
+
+type OffboardingItemKind string
+
+const (
+	ItemAssetLoan       OffboardingItemKind = "ASSET_LOAN"
+	ItemAssetAssignment OffboardingItemKind = "ASSET_ASSIGNMENT"
+	ItemLicenseSeat     OffboardingItemKind = "LICENSE_SEAT"
+	ItemAccessoryLoan   OffboardingItemKind = "ACCESSORY_LOAN"
+)
+
+type OffboardingItemStatus string
+
+const (
+	ItemPending    OffboardingItemStatus = "PENDING"
+	ItemRecovered  OffboardingItemStatus = "RECOVERED"
+	ItemWrittenOff OffboardingItemStatus = "WRITTEN_OFF"
+)
+
+// Offboarding is the case of a user leaving: everything they hold is listed
+// as items to get back. Once every item is recovered or written off the case
+// completes and the user is deactivated.
+type Offboarding struct {
+	ID int64 `gorm:"primaryKey"`
+
+	UserID int64 `gorm:"not null;index"`
+	User   User  `gorm:"constraint:OnDelete:CASCADE;"`
+
+	Status  OffboardingStatus `gorm:"type:varchar(20);not null;default:'OPEN';index"`
+	LastDay *time.Time
+
+	OpenedByID *int64 `gorm:"index"`
+	OpenedBy   *User  `gorm:"constraint:OnDelete:SET NULL;"`
+
+	Items []OffboardingItem `gorm:"constraint:OnDelete:CASCADE;"`
+
+	Notes       string `gorm:"size:1000"`
+	CompletedAt *time.Time
+	CreatedAt   time.Time
+	UpdatedAt   time.Time
+}
+
+// Pending counts the items still to be resolved.
+func (o *Offboarding) Pending() int {
+	pending := 0
+	for i := range o.Items {
+		if o.Items[i].Status == ItemPending {
+			pending++
+		}
+	}
+	return pending
+}
+
+// OffboardingItem is something the user held when the case was opened. The
+// record it stands for is set by Kind, AssetID is set for both asset kinds.
+type OffboardingItem struct {
+	ID            int64               `gorm:"primaryKey"`
+	OffboardingID int64               `gorm:"not null;index"`
+	Kind          OffboardingItemKind `gorm:"type:varchar(30);not null"`
+	Description   string              `gorm:"size:255;not null"`
+
+	AssetID           *int64           `gorm:"index"`
+	Asset             *Asset           `gorm:"constraint:OnDelete:SET NULL;"`
+	AssetLoanID       *int64           `gorm:"index"`
+	AssetLoan         *AssetLoan       `gorm:"constraint:OnDelete:SET NULL;"`
+	AssetAssignmentID *int64           `gorm:"index"`
+	AssetAssignment   *AssetAssignment `gorm:"constraint:OnDelete:SET NULL;"`
+	LicenseSeatID     *int64           `gorm:"index"`
+	LicenseSeat       *LicenseSeat     `gorm:"constraint:OnDelete:SET NULL;"`
+	AccessoryLoanID   *int64           `gorm:"index"`
+	AccessoryLoan     *AccessoryLoan   `gorm:"constraint:OnDelete:SET NULL;"`
+
+	Status       OffboardingItemStatus `gorm:"type:varchar(20);not null;default:'PENDING'"`
+	ResolvedAt   *time.Time
+	ResolvedByID *int64 `gorm:"index"`
+	ResolvedBy   *User  `gorm:"constraint:OnDelete:SET NULL;"`
+	Notes        string `gorm:"size:255"`
+}
+
+var offboardingMigrations = []string{
+	// a user has one open case at a time
+	`CREATE UNIQUE INDEX IF NOT EXISTS idx_offboardings_open
+		ON offboardings (user_id)
+		WHERE status = 'OPEN'`,
+}
+
+type OffboardingStore struct {
+	db *gorm.DB
+}
+
+var offboardingQueryFields = QueryFields{
+	"id":          {"offboardings.id", IntField},
+	"userId":      {"offboardings.user_id", IntField},
+	"status":      {"offboardings.status", StringField},
+	"lastDay":     {"offboardings.last_day", TimeField},
+	"completedAt": {"offboardings.completed_at", TimeField},
+	"createdAt":   {"offboardings.created_at", TimeField},
+}
+
+func preloadOffboarding(db *gorm.DB) *gorm.DB {
+	return db.
+		Joins("User").
+		Joins("OpenedBy").
+		Preload("Items", func(db *gorm.DB) *gorm.DB {
+			return db.Joins("ResolvedBy").Order("offboarding_items.id")
+		})
+}
+
+func (s *OffboardingStore) List(ctx context.Context, spec QuerySpec) (*Pagination, error) {
+	query := preloadOffboarding(s.db.WithContext(ctx).Model(&Offboarding{}))
+
+	return paginate[Offboarding](query, spec, offboardingQueryFields, "offboardings.id")
+}
+
+func (s *OffboardingStore) GetByID(ctx context.Context, id int64) (*Offboarding, error) {
+	var offboarding Offboarding
+
+	err := preloadOffboarding(s.db.WithContext(ctx)).
+		First(&offboarding, "offboardings.id = ?", id).Error
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, ErrNotFound
+		}
+		return nil, err
+	}
+
+	return &offboarding, nil
+}
+
+func assetLabel(asset *Asset) string {
+	if asset.Tag == "" {
+		return asset.Name
+	}
+	return fmt.Sprintf("%s (%s)", asset.Name, asset.Tag)
+}
+
+// scanHoldings lists what a user holds as offboarding items: the open asset
+// loans, asset assignments, license seats and accessory loans.
+func scanHoldings(tx *gorm.DB, userID int64) ([]OffboardingItem, error) {
+	var loans []AssetLoan
+	err := tx.Joins("Asset").
+		Where("asset_loans.user_id = ? AND asset_loans.actual_return_date IS NULL", userID).
+		Order("asset_loans.id").
+		Find(&loans).Error
+	if err != nil {
+		return nil, err
+	}
+
+	var assignments []AssetAssignment
+	err = tx.Joins("Asset").
+		Where("asset_assignments.user_id = ? AND asset_assignments.returned_at IS NULL", userID).
+		Order("asset_assignments.id").
+		Find(&assignments).Error
+	if err != nil {
+		return nil, err
+	}
+
+	var seats []LicenseSeat
+	err = tx.Joins("License").
+		Where("license_seats.user_id = ? AND license_seats.checked_in_at IS NULL", userID).
+		Order("license_seats.id").
+		Find(&seats).Error
+	if err != nil {
+		return nil, err
+	}
+
+	var accessories []AccessoryLoan
+	err = tx.Joins("Accessory").
+		Where("accessory_loans.user_id = ? AND accessory_loans.actual_return_date IS NULL", userID).
+		Order("accessory_loans.id").
+		Find(&accessories).Error
+	if err != nil {
+		return nil, err
+	}
+
+	var items []OffboardingItem
+	for i := range loans {
+		items = append(items, OffboardingItem{
+			Kind:        ItemAssetLoan,
+			Description: assetLabel(&loans[i].Asset),
+			AssetID:     &loans[i].AssetID,
+			AssetLoanID: &loans[i].ID,
+		})
+	}
+	for i := range assignments {
+		items = append(items, OffboardingItem{
+			Kind:              ItemAssetAssignment,
+			Description:       assetLabel(&assignments[i].Asset),
+			AssetID:           &assignments[i].AssetID,
+			AssetAssignmentID: &assignments[i].ID,
+		})
+	}
+	for i := range seats {
+		items = append(items, OffboardingItem{
+			Kind:          ItemLicenseSeat,
+			Description:   "Seat of " + seats[i].License.Name,
+			LicenseSeatID: &seats[i].ID,
+		})
+	}
+	for i := range accessories {
+		items = append(items, OffboardingItem{
+			Kind:            ItemAccessoryLoan,
+			Description:     fmt.Sprintf("%d x %s", accessories[i].Quantity, accessories[i].Accessory.Name),
+			AccessoryLoanID: &accessories[i].ID,
+		})
+	}
+
+	return items, nil
+}
+
+// Open starts the offboarding of a user, listing the open asset loans,
+// asset assignments, license seats and accessory loans they hold. A user
+// holding nothing is deactivated straight away. It fails with ErrConflict
+// when the user already has an open case.
+func (s *OffboardingStore) Open(ctx context.Context, offboarding *Offboarding) error {
+	err := s.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		items, err := scanHoldings(tx, offboarding.UserID)
+		if err != nil {
+			return err
+		}
+
+		offboarding.Status = OffboardingOpen
+		if err := tx.Omit(clause.Associations).Create(offboarding).Error; err != nil {
+			return err
+		}
+
+		if len(items) == 0 {
+			return completeOffboarding(tx, offboarding)
+		}
+
+		for i := range items {
+			items[i].OffboardingID = offboarding.ID
+			items[i].Status = ItemPending
+		}
+		offboarding.Items = items
+
+		return tx.Omit(clause.Associations).Create(&offboarding.Items).Error
+	})
+	if isUniqueViolation(err) {
+		return ErrConflict
+	}
+
+	return err
+}
+
+func completeOffboarding(tx *gorm.DB, offboarding *Offboarding) error {
+	now := time.Now()
+
+	err := tx.Model(offboarding).Updates(map[string]interface{}{
+		"status":       OffboardingCompleted,
+		"completed_at": now,
+	}).Error
+	if err != nil {
+		return err
+	}
+
+	result := tx.Model(&User{}).
+		Where("id = ? AND is_active", offboarding.UserID).
+		Update("is_active", false)
+	if result.Error != nil || result.RowsAffected == 0 {
+		return result.Error
+	}
+
+	return publish(tx, UserDeactivated{
+		UserID:        offboarding.UserID,
+		OffboardingID: &offboarding.ID,
+	})
+}
+
+// Resolve marks an item recovered or written off and ends the record it
+// stands for. A recovered asset is available again, a written off one is
+// LOST_STOLEN. Both asset items of an asset resolve together. When it was the
+// last item the user's holdings are scanned again: anything handed out since
+// the case was opened is added as new items, otherwise the case completes and
+// the user is deactivated.
+func (s *OffboardingStore) Resolve(ctx context.Context, offboardingID, itemID int64, status OffboardingItemStatus, performedBy int64, notes string) error {
+	return s.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		var offboarding Offboarding
+		err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).
+			First(&offboarding, offboardingID).Error
+		if err != nil {
+			if errors.Is(err, gorm.ErrRecordNotFound) {
+				return ErrNotFound
+			}
+			return err
+		}
+		if offboarding.Status != OffboardingOpen {
+			return ErrOffboardingClosed
+		}
+
+		var item OffboardingItem
+		err = tx.Joins("AccessoryLoan").
+			Where("offboarding_items.offboarding_id = ?", offboardingID).
+			First(&item, "offboarding_items.id = ?", itemID).Error
+		if err != nil {
+			if errors.Is(err, gorm.ErrRecordNotFound) {
+				return ErrNotFound
+			}
+			return err
+		}
+		if item.Status != ItemPending {
+			return ErrItemResolved
+		}
+
+		now := time.Now()
+		resolved := tx.Model(&OffboardingItem{}).Where("offboarding_id = ? AND status = ?", offboardingID, ItemPending)
+
+		// records deleted since the case was opened leave nothing to end
+		switch {
+		case item.AssetID != nil:
+			if err := returnAsset(tx, *item.AssetID, offboarding.UserID, status, now); err != nil {
+				return err
+			}
+			resolved = resolved.Where("asset_id = ?", *item.AssetID)
+		case item.LicenseSeatID != nil:
+			err := tx.Model(&LicenseSeat{}).
+				Where("id = ? AND checked_in_at IS NULL", *item.LicenseSeatID).
+				Update("checked_in_at", now).Error
+			if err != nil {
+				return err
+			}
+			resolved = resolved.Where("id = ?", item.ID)
+		case item.AccessoryLoan != nil:
+			err := checkinAccessory(tx, item.AccessoryLoan.AccessoryID, item.AccessoryLoan.ID, status == ItemRecovered)
+			if err != nil && !errors.Is(err, ErrNotFound) {
+				return err
+			}
+			resolved = resolved.Where("id = ?", item.ID)
+		default:
+			resolved = resolved.Where("id = ?", item.ID)
+		}
+
+		err = resolved.Updates(map[string]interface{}{
+			"status":         status,
+			"resolved_at":    now,
+			"resolved_by_id": performedBy,
+			"notes":          notes,
+		}).Error
+		if err != nil {
+			return err
+		}
+
+		var pending int64
+		err = tx.Model(&OffboardingItem{}).
+			Where("offboarding_id = ? AND status = ?", offboardingID, ItemPending).
+			Count(&pending).Error
+		if err != nil || pending > 0 {
+			return err
+		}
+
+		items, err := scanHoldings(tx, offboarding.UserID)
+		if err != nil {
+			return err
+		}
+		if len(items) == 0 {
+			return completeOffboarding(tx, &offboarding)
+		}
+
+		for i := range items {
+			items[i].OffboardingID = offboarding.ID
+			items[i].Status = ItemPending
+		}
+
+		return tx.Omit(clause.Associations).Create(&items).Error
+	})
+}
+
+// returnAsset ends the user's loans and assignments of an asset. The asset's
+// status only changes when the user still had it.
+func returnAsset(tx *gorm.DB, assetID, userID int64, status OffboardingItemStatus, at time.Time) error {
+	loans := tx.Model(&AssetLoan{}).
+		Where("asset_id = ? AND user_id = ? AND actual_return_date IS NULL", assetID, userID).
+		Update("actual_return_date", at)
+	if loans.Error != nil {
+		return loans.Error
+	}
+
+	assignments := tx.Model(&AssetAssignment{}).
+		Where("asset_id = ? AND user_id = ? AND returned_at IS NULL", assetID, userID).
+		Update("returned_at", at)
+	if assignments.Error != nil {
+		return assignments.Error
+	}
+
+	if loans.RowsAffected+assignments.RowsAffected == 0 {
+		return nil
+	}
+
+	assetStatus := AssetAvailable
+	if status == ItemWrittenOff {
+		assetStatus = AssetLostStolen
+	}
+
+	_, err := changeAssetStatus(tx, assetID, assetStatus)
+	return err
+}
+
+// Cancel closes an open case without deactivating the user, e.g. when they
+// stay after all. Items already resolved stay resolved.
+func (s *OffboardingStore) Cancel(ctx context.Context, id int64) error {
+	result := s.db.WithContext(ctx).
+		Model(&Offboarding{}).
+		Where("id = ? AND status = ?", id, OffboardingOpen).
+		Update("status", OffboardingCancelled)
+
+	if result.Error != nil {
+		return result.Error
+	}
+
+	if result.RowsAffected == 0 {
+		return ErrOffboardingClosed
+	}
+
+	return nil
+}
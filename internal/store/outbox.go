@@ -0,0 +1,271 @@
+package store
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"strconv"
+	"strings"
+	"time"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+const (
+	EventAssetCreated       = "asset.created"
+	EventAssetCheckedOut    = "asset.checked_out"
+	EventAssetCheckedIn     = "asset.checked_in"
+	EventAssetStatusChanged = "asset.status_changed"
+	EventUserDeactivated    = "user.deactivated"
+	EventAuditLogged        = "audit.logged"
+)
+
+// OutboxChannel is the channel every published event is notified on, with
+// its id as the payload, once its transaction commits.
+const OutboxChannel = "outbox_events"
+
+// EventTypes are the event types that can be subscribed to.
+var EventTypes = []string{
+	EventAssetCreated,
+	EventAssetCheckedOut,
+	EventAssetCheckedIn,
+	EventAssetStatusChanged,
+	EventUserDeactivated,
+	EventAuditLogged,
+}
+
+// Event is a domain event. Store operations publish events in the transaction
+// of the change they describe.
+type Event interface {
+	EventType() string
+}
+
+// OutboxEvent is a change recorded in the same transaction as the change
+// itself, so it is handed on exactly when the change commits.
+type OutboxEvent struct {
+	ID      int64  `gorm:"primaryKey"`
+	Type    string `gorm:"size:100;not null"`
+	Payload string `gorm:"type:jsonb;not null"`
+
+	CreatedAt time.Time `gorm:"index"`
+	// set once every subscriber handled the event
+	PublishedAt *time.Time
+
+	// failed dispatches are retried from RetryAt on
+	Attempts  int `gorm:"not null;default:0"`
+	RetryAt   *time.Time
+	LastError string `gorm:"size:500"`
+}
+
+// Decode unmarshals the payload into the event of the matching type.
+func (e *OutboxEvent) Decode(event Event) error {
+	return json.Unmarshal([]byte(e.Payload), event)
+}
+
+type AssetCreated struct {
+	AssetID int64       `json:"assetId"`
+	Name    string      `json:"name"`
+	Tag     string      `json:"tag"`
+	ModelID int64       `json:"modelId"`
+	Status  AssetStatus `json:"status"`
+}
+
+type AssetCheckedOut struct {
+	AssetID             int64      `json:"assetId"`
+	LoanID              int64      `json:"loanId"`
+	UserID              int64      `json:"userId"`
+	CheckoutDate        time.Time  `json:"checkoutDate"`
+	ExpectedCheckinDate *time.Time `json:"expectedCheckinDate"`
+}
+
+type AssetCheckedIn struct {
+	AssetID    int64       `json:"assetId"`
+	LoanID     int64       `json:"loanId"`
+	UserID     int64       `json:"userId"`
+	ReturnDate time.Time   `json:"returnDate"`
+	Status     AssetStatus `json:"status"`
+}
+
+type AssetStatusChanged struct {
+	AssetID int64       `json:"assetId"`
+	From    AssetStatus `json:"from"`
+	To      AssetStatus `json:"to"`
+}
+
+type UserDeactivated struct {
+	UserID        int64  `json:"userId"`
+	OffboardingID *int64 `json:"offboardingId"`
+}
+
+type AuditLogged struct {
+	AuditLogID int64  `json:"auditLogId"`
+	TableName  string `json:"tableName"`
+	RecordID   string `json:"recordId"`
+	Operation  string `json:"operation"`
+	ChangedBy  string `json:"changedBy"`
+}
+
+func (AssetCreated) EventType() string       { return EventAssetCreated }
+func (AssetCheckedOut) EventType() string    { return EventAssetCheckedOut }
+func (AssetCheckedIn) EventType() string     { return EventAssetCheckedIn }
+func (AssetStatusChanged) EventType() string { return EventAssetStatusChanged }
+func (UserDeactivated) EventType() string    { return EventUserDeactivated }
+func (AuditLogged) EventType() string        { return EventAuditLogged }
+
+// publish records an event in the outbox of the transaction tx.
+func publish(tx *gorm.DB, event Event) error {
+	b, err := json.Marshal(event)
+	if err != nil {
+		return err
+	}
+
+	outboxEvent := OutboxEvent{Type: event.EventType(), Payload: string(b)}
+	if err := tx.Create(&outboxEvent).Error; err != nil {
+		return err
+	}
+
+	return tx.Exec("SELECT pg_notify(?, ?)", OutboxChannel, strconv.FormatInt(outboxEvent.ID, 10)).Error
+}
+
+// outboxRetryMax caps the wait between dispatches of a failing event.
+const outboxRetryMax = time.Hour
+
+type OutboxStore struct {
+	db *gorm.DB
+}
+
+// Dispatch claims up to limit unpublished events that are due, oldest first,
+// and passes each to handle. Events locked by another replica's dispatch are
+// skipped. Handled events are marked published; failed ones are retried with
+// backoff, so handle may see an event more than once. It returns the number
+// of events claimed.
+func (s *OutboxStore) Dispatch(ctx context.Context, limit int, handle func(*OutboxEvent) error) (int, error) {
+	var events []OutboxEvent
+
+	err := s.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		now := time.Now()
+
+		// NO KEY UPDATE still lets handlers insert rows referencing the
+		// events, which a plain FOR UPDATE lock would block until commit.
+		err := tx.Clauses(clause.Locking{Strength: "NO KEY UPDATE", Options: "SKIP LOCKED"}).
+			Where("published_at IS NULL AND (retry_at IS NULL OR retry_at <= ?)", now).
+			Order("id").
+			Limit(limit).
+			Find(&events).Error
+		if err != nil || len(events) == 0 {
+			return err
+		}
+
+		var published []int64
+		for i := range events {
+			event := &events[i]
+
+			if err := handle(event); err != nil {
+				if ctx.Err() != nil {
+					return ctx.Err()
+				}
+
+				event.Attempts++
+				n := time.Duration(min(event.Attempts, 20))
+				wait := min(n*n*10*time.Second, outboxRetryMax)
+
+				message := err.Error()
+				if len(message) > 500 {
+					message = strings.ToValidUTF8(message[:500], "")
+				}
+
+				err = tx.Model(event).Updates(map[string]interface{}{
+					"attempts":   event.Attempts,
+					"retry_at":   now.Add(wait),
+					"last_error": message,
+				}).Error
+				if err != nil {
+					return err
+				}
+				continue
+			}
+
+			published = append(published, event.ID)
+		}
+
+		if len(published) == 0 {
+			return nil
+		}
+
+		return tx.Model(&OutboxEvent{}).
+			Where("id IN ?", published).
+			Updates(map[string]interface{}{
+				"published_at": now,
+				"last_error":   "",
+			}).Error
+	})
+
+	return len(events), err
+}
+
+func (s *OutboxStore) GetByID(ctx context.Context, id int64) (*OutboxEvent, error) {
+	var event OutboxEvent
+
+	if err := s.db.WithContext(ctx).First(&event, id).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, ErrNotFound
+		}
+		return nil, err
+	}
+
+	return &event, nil
+}
+
+// Resume returns up to limit events, oldest first, for a reader that last saw
+// the event with the given id: the events after it, and those created within
+// lookback before it. Ids are taken when an event is recorded, not when it
+// commits, so one of those may have committed after the reader saw id. ok is
+// false when the event is no longer in the outbox.
+func (s *OutboxStore) Resume(ctx context.Context, id int64, lookback time.Duration, limit int) (events []OutboxEvent, ok bool, err error) {
+	db := s.db.WithContext(ctx)
+
+	var last OutboxEvent
+	if err := db.Select("id", "created_at").Take(&last, id).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, false, nil
+		}
+		return nil, false, err
+	}
+
+	err = db.Where("id > ? OR (id < ? AND created_at >= ?)", id, id, last.CreatedAt.Add(-lookback)).
+		Order("id").
+		Limit(limit).
+		Find(&events).Error
+
+	return events, true, err
+}
+
+// Prune deletes up to limit events published before the given time, with the
+// webhook deliveries of them that finished; events with deliveries still
+// pending are kept. It returns the number of events deleted.
+func (s *OutboxStore) Prune(ctx context.Context, before time.Time, limit int) (int64, error) {
+	result := s.db.WithContext(ctx).Exec(`DELETE FROM outbox_events WHERE id IN (
+		SELECT e.id FROM outbox_events e
+		WHERE e.published_at < ?
+			AND NOT EXISTS (
+				SELECT 1 FROM webhook_deliveries d
+				WHERE d.event_id = e.id AND d.status = ?)
+		ORDER BY e.id
+		LIMIT ?)`,
+		before, DeliveryPending, limit)
+
+	return result.RowsAffected, result.Error
+}
+
+// LatestID is the id of the last event published, 0 when there is none.
+func (s *OutboxStore) LatestID(ctx context.Context) (int64, error) {
+	var id int64
+
+	err := s.db.WithContext(ctx).
+		Model(&OutboxEvent{}).
+		Select("coalesce(max(id), 0)").
+		Scan(&id).Error
+
+	return id, err
+}
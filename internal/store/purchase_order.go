@@ -0,0 +1,269 @@
+package store
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+var ErrInvalidReceipt = errors.New("invalid receipt")
+
+type PurchaseOrderStatus string
+
+const (
+	PurchaseOrderDraft             PurchaseOrderStatus = "DRAFT"
+	PurchaseOrderOrdered           PurchaseOrderStatus = "ORDERED"
+	PurchaseOrderPartiallyReceived PurchaseOrderStatus = "PARTIALLY_RECEIVED"
+	PurchaseOrderReceived          PurchaseOrderStatus = "RECEIVED"
+	PurchaseOrderCancelled         PurchaseOrderStatus = "CANCELLED"
+)
+
+type PurchaseOrder struct {
+	ID     int64  `gorm:"primaryKey"`
+	Number string `gorm:"size:50;uniqueIndex;not null"`
+
+	SupplierID int64    `gorm:"not null;index"`
+	Supplier   Supplier `gorm:"constraint:OnDelete:RESTRICT;"`
+
+	Status    PurchaseOrderStatus `gorm:"type:varchar(20);not null;default:'DRAFT';index"`
+	OrderDate *time.Time
+	Notes     string `gorm:"size:1000"`
+
+	Lines    []PurchaseOrderLine `gorm:"constraint:OnDelete:CASCADE;"`
+	Invoices []Invoice           `gorm:"constraint:OnDelete:CASCADE;"`
+
+	CreatedAt time.Time
+	UpdatedAt time.Time
+}
+
+type PurchaseOrderLine struct {
+	ID              int64 `gorm:"primaryKey"`
+	PurchaseOrderID int64 `gorm:"not null;index"`
+
+	ModelID int64 `gorm:"not null;index"`
+	Model   Model `gorm:"constraint:OnDelete:RESTRICT;"`
+
+	Description      string  `gorm:"size:255"`
+	Quantity         int     `gorm:"not null"`
+	ReceivedQuantity int     `gorm:"not null;default:0"`
+	UnitCost         float64 `gorm:"not null"`
+	Currency         string  `gorm:"type:char(3);not null"`
+}
+
+// Invoice is a supplier invoice billed against a purchase order. The
+// document itself lives elsewhere; DocumentURL points at it.
+type Invoice struct {
+	ID              int64 `gorm:"primaryKey"`
+	PurchaseOrderID int64 `gorm:"not null;index"`
+
+	Number      string    `gorm:"size:100;not null"`
+	InvoiceDate time.Time `gorm:"not null"`
+	Amount      float64   `gorm:"not null"`
+	Currency    string    `gorm:"type:char(3);not null"`
+	DocumentURL string    `gorm:"size:500"`
+	Notes       string    `gorm:"size:1000"`
+
+	CreatedAt time.Time
+}
+
+// ReceiptLine lists the assets received against a single order line.
+type ReceiptLine struct {
+	LineID int64
+	Assets []*Asset
+}
+
+type PurchaseOrderStore struct {
+	db *gorm.DB
+}
+
+var purchaseOrderQueryFields = QueryFields{
+	"id":         {"purchase_orders.id", IntField},
+	"number":     {"purchase_orders.number", StringField},
+	"supplierId": {"purchase_orders.supplier_id", IntField},
+	"status":     {"purchase_orders.status", StringField},
+	"orderDate":  {"purchase_orders.order_date", TimeField},
+	"createdAt":  {"purchase_orders.created_at", TimeField},
+}
+
+func preloadPurchaseOrder(db *gorm.DB) *gorm.DB {
+	return db.
+		Joins("Supplier").
+		Preload("Lines", func(db *gorm.DB) *gorm.DB { return db.Order("id") }).
+		Preload("Lines.Model").
+		Preload("Invoices", func(db *gorm.DB) *gorm.DB { return db.Order("invoice_date, id") })
+}
+
+func (s *PurchaseOrderStore) List(ctx context.Context, spec QuerySpec) (*Pagination, error) {
+	query := preloadPurchaseOrder(s.db.WithContext(ctx).Model(&PurchaseOrder{}))
+
+	return paginate[PurchaseOrder](query, spec, purchaseOrderQueryFields, "purchase_orders.id")
+}
+
+func (s *PurchaseOrderStore) GetByID(ctx context.Context, id int64) (*PurchaseOrder, error) {
+	var order PurchaseOrder
+
+	err := preloadPurchaseOrder(s.db.WithContext(ctx)).
+		First(&order, "purchase_orders.id = ?", id).Error
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, ErrNotFound
+		}
+		return nil, err
+	}
+
+	return &order, nil
+}
+
+func (s *PurchaseOrderStore) Create(ctx context.Context, order *PurchaseOrder) error {
+	err := s.db.WithContext(ctx).Omit("Supplier", "Lines.Model").Create(order).Error
+	if isUniqueViolation(err) {
+		return ErrConflict
+	}
+	return err
+}
+
+// Update saves the order. Lines are replaced with order.Lines unless replaceLines
+// is false, which is the case once anything has been received.
+func (s *PurchaseOrderStore) Update(ctx context.Context, order *PurchaseOrder, replaceLines bool) error {
+	err := s.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		result := tx.Model(&PurchaseOrder{}).
+			Where("id = ?", order.ID).
+			Updates(map[string]interface{}{
+				"number":      order.Number,
+				"supplier_id": order.SupplierID,
+				"status":      order.Status,
+				"order_date":  order.OrderDate,
+				"notes":       order.Notes,
+			})
+		if result.Error != nil {
+			return result.Error
+		}
+		if result.RowsAffected == 0 {
+			return ErrNotFound
+		}
+
+		if !replaceLines {
+			return nil
+		}
+
+		if err := tx.Where("purchase_order_id = ?", order.ID).Delete(&PurchaseOrderLine{}).Error; err != nil {
+			return err
+		}
+
+		for i := range order.Lines {
+			order.Lines[i].ID = 0
+			order.Lines[i].PurchaseOrderID = order.ID
+		}
+		if len(order.Lines) == 0 {
+			return nil
+		}
+
+		return tx.Omit("Model").Create(&order.Lines).Error
+	})
+	if isUniqueViolation(err) {
+		return ErrConflict
+	}
+	return err
+}
+
+func (s *PurchaseOrderStore) Delete(ctx context.Context, id int64) error {
+	result := s.db.WithContext(ctx).
+		Delete(&PurchaseOrder{}, id)
+
+	if result.Error != nil {
+		return result.Error
+	}
+
+	if result.RowsAffected == 0 {
+		return ErrNotFound
+	}
+
+	return nil
+}
+
+func (s *PurchaseOrderStore) CreateInvoice(ctx context.Context, invoice *Invoice) error {
+	return s.db.WithContext(ctx).Create(invoice).Error
+}
+
+func (s *PurchaseOrderStore) DeleteInvoice(ctx context.Context, orderID, invoiceID int64) error {
+	result := s.db.WithContext(ctx).
+		Where("purchase_order_id = ?", orderID).
+		Delete(&Invoice{}, invoiceID)
+
+	if result.Error != nil {
+		return result.Error
+	}
+
+	if result.RowsAffected == 0 {
+		return ErrNotFound
+	}
+
+	return nil
+}
+
+// Receive creates the received assets and books them against their order
+// lines in one transaction. The lines are locked so concurrent receipts
+// cannot receive more than was ordered.
+func (s *PurchaseOrderStore) Receive(ctx context.Context, order *PurchaseOrder, receipt []ReceiptLine) error {
+	err := s.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		var lines []PurchaseOrderLine
+		err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).
+			Where("purchase_order_id = ?", order.ID).
+			Order("id").
+			Find(&lines).Error
+		if err != nil {
+			return err
+		}
+
+		byID := make(map[int64]*PurchaseOrderLine, len(lines))
+		for i := range lines {
+			byID[lines[i].ID] = &lines[i]
+		}
+
+		for _, r := range receipt {
+			line, ok := byID[r.LineID]
+			if !ok {
+				return fmt.Errorf("%w: line %d is not on this order", ErrInvalidReceipt, r.LineID)
+			}
+			if line.ReceivedQuantity+len(r.Assets) > line.Quantity {
+				return fmt.Errorf("%w: line %d has %d of %d left to receive", ErrInvalidReceipt, line.ID, line.Quantity-line.ReceivedQuantity, line.Quantity)
+			}
+
+			for _, asset := range r.Assets {
+				if err := tx.Omit(clause.Associations).Create(asset).Error; err != nil {
+					return err
+				}
+				if err := publish(tx, newAssetCreated(asset)); err != nil {
+					return err
+				}
+			}
+
+			line.ReceivedQuantity += len(r.Assets)
+			err := tx.Model(&PurchaseOrderLine{}).
+				Where("id = ?", line.ID).
+				Update("received_quantity", line.ReceivedQuantity).Error
+			if err != nil {
+				return err
+			}
+		}
+
+		status := PurchaseOrderReceived
+		for _, line := range lines {
+			if line.ReceivedQuantity < line.Quantity {
+				status = PurchaseOrderPartiallyReceived
+				break
+			}
+		}
+
+		order.Status = status
+		return tx.Model(&PurchaseOrder{}).Where("id = ?", order.ID).Update("status", status).Error
+	})
+	if isUniqueViolation(err) {
+		return ErrConflict
+	}
+	return err
+}
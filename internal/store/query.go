@@ -0,0 +1,557 @@
+package store
+
+import (
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/url"
+	"reflect"
+	"strconv"
+	"strings"
+	"time"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+var ErrInvalidQuery = errors.New("invalid query")
+
+const (
+	defaultQueryLimit = 10
+	maxQueryLimit     = 100
+)
+
+type FilterOperator string
+
+const (
+	OpEq   FilterOperator = "eq"
+	OpNe   FilterOperator = "ne"
+	OpIn   FilterOperator = "in"
+	OpGt   FilterOperator = "gt"
+	OpGte  FilterOperator = "gte"
+	OpLt   FilterOperator = "lt"
+	OpLte  FilterOperator = "lte"
+	OpLike FilterOperator = "like"
+)
+
+var filterOperators = map[FilterOperator]string{
+	OpEq:   "=",
+	OpNe:   "<>",
+	OpIn:   "IN",
+	OpGt:   ">",
+	OpGte:  ">=",
+	OpLt:   "<",
+	OpLte:  "<=",
+	OpLike: "ILIKE",
+}
+
+// query parameters that control the listing rather than filter it
+var reservedQueryParams = map[string]bool{
+	"limit":   true,
+	"page":    true,
+	"sort":    true,
+	"cursor":  true,
+	"format":  true,
+	"columns": true,
+	// the window of the due and expiring reports
+	"days": true,
+	// cache busters and parameters the web client sends along
+	"_":          true,
+	"searchJoin": true,
+	"self":       true,
+	"language":   true,
+}
+
+type FieldKind int
+
+const (
+	StringField FieldKind = iota
+	IntField
+	FloatField
+	TimeField
+	BoolField
+	JSONField
+)
+
+// QueryField maps a public field name onto a column that may be filtered and
+// sorted on.
+type QueryField struct {
+	Column string
+	Kind   FieldKind
+}
+
+type QueryFields map[string]QueryField
+
+// lookup resolves a field name. A "prefix.*" entry pointing at a JSONB column
+// makes every "prefix.key" available, which is how custom fields are exposed.
+func (f QueryFields) lookup(name string) (QueryField, bool) {
+	if field, ok := f[name]; ok {
+		return field, true
+	}
+
+	prefix, key, found := strings.Cut(name, ".")
+	if !found || !customFieldKeyPattern.MatchString(key) {
+		return QueryField{}, false
+	}
+
+	base, ok := f[prefix+".*"]
+	if !ok {
+		return QueryField{}, false
+	}
+
+	// key is restricted to [a-z0-9_] above, so it is safe to inline
+	return QueryField{Column: fmt.Sprintf("%s->'%s'", base.Column, key), Kind: JSONField}, true
+}
+
+type Filter struct {
+	Field  string
+	Op     FilterOperator
+	Values []string
+}
+
+type SortField struct {
+	Field string
+	Desc  bool
+}
+
+// QuerySpec describes how a list endpoint should filter, sort and page its
+// results. Field names are checked against the resource's QueryFields when the
+// spec is applied, never interpolated directly into SQL.
+type QuerySpec struct {
+	Filters []Filter
+	Sort    []SortField
+	Limit   int
+	Page    int
+
+	// UseCursor switches from offset to keyset pagination; an empty Cursor
+	// requests the first page.
+	UseCursor bool
+	Cursor    string
+}
+
+// ParseQuerySpec reads limit, page, sort and cursor from the query string.
+// Every other parameter is a filter of the form field=value or
+// field=op:value, e.g. status=in:AVAILABLE,REPAIR or purchaseDate=gte:2024-01-01.
+// Applying the spec fails with ErrInvalidQuery for a parameter that names no
+// field of the resource, so a mistyped filter is not taken for no filter.
+func ParseQuerySpec(values url.Values) (QuerySpec, error) {
+	spec := QuerySpec{
+		Limit: defaultQueryLimit,
+		Page:  1,
+	}
+
+	if l := values.Get("limit"); l != "" {
+		v, err := strconv.Atoi(l)
+		if err != nil || v <= 0 {
+			return spec, fmt.Errorf("%w: limit must be a positive integer", ErrInvalidQuery)
+		}
+		spec.Limit = min(v, maxQueryLimit)
+	}
+
+	if p := values.Get("page"); p != "" {
+		v, err := strconv.Atoi(p)
+		if err != nil || v <= 0 {
+			return spec, fmt.Errorf("%w: page must be a positive integer", ErrInvalidQuery)
+		}
+		spec.Page = v
+	}
+
+	if _, ok := values["cursor"]; ok {
+		spec.UseCursor = true
+		spec.Cursor = values.Get("cursor")
+	}
+
+	if s := values.Get("sort"); s != "" {
+		for _, field := range strings.Split(s, ",") {
+			field = strings.TrimSpace(field)
+			if field == "" {
+				continue
+			}
+			desc := strings.HasPrefix(field, "-")
+			spec.Sort = append(spec.Sort, SortField{
+				Field: strings.TrimPrefix(field, "-"),
+				Desc:  desc,
+			})
+		}
+	}
+
+	for key, vals := range values {
+		if reservedQueryParams[key] {
+			continue
+		}
+		for _, raw := range vals {
+			spec.Filters = append(spec.Filters, parseFilter(key, raw))
+		}
+	}
+
+	return spec, nil
+}
+
+func parseFilter(field, raw string) Filter {
+	op, value, found := strings.Cut(raw, ":")
+	if !found {
+		return Filter{Field: field, Op: OpEq, Values: []string{raw}}
+	}
+
+	operator := FilterOperator(op)
+	if _, ok := filterOperators[operator]; !ok {
+		// not an operator prefix, the colon is part of the value
+		return Filter{Field: field, Op: OpEq, Values: []string{raw}}
+	}
+
+	if operator == OpIn {
+		return Filter{Field: field, Op: operator, Values: strings.Split(value, ",")}
+	}
+
+	return Filter{Field: field, Op: operator, Values: []string{value}}
+}
+
+func (spec QuerySpec) GetLimit() int {
+	if spec.Limit <= 0 {
+		return defaultQueryLimit
+	}
+	return min(spec.Limit, maxQueryLimit)
+}
+
+func (spec QuerySpec) GetPage() int {
+	if spec.Page <= 0 {
+		return 1
+	}
+	return spec.Page
+}
+
+func (spec QuerySpec) GetOffset() int {
+	return (spec.GetPage() - 1) * spec.GetLimit()
+}
+
+func (spec QuerySpec) sortString() string {
+	fields := make([]string, len(spec.Sort))
+	for i, s := range spec.Sort {
+		if s.Desc {
+			fields[i] = "-" + s.Field
+		} else {
+			fields[i] = s.Field
+		}
+	}
+	return strings.Join(fields, ",")
+}
+
+func (spec QuerySpec) applyFilters(query *gorm.DB, fields QueryFields) (*gorm.DB, error) {
+	for _, f := range spec.Filters {
+		field, ok := fields.lookup(f.Field)
+		if !ok {
+			return nil, fmt.Errorf("%w: unknown filter field %q", ErrInvalidQuery, f.Field)
+		}
+
+		args := make([]any, 0, len(f.Values))
+		for _, v := range f.Values {
+			parsed, err := parseFieldValue(field.Kind, v)
+			if err != nil {
+				return nil, fmt.Errorf("%w: %s: %v", ErrInvalidQuery, f.Field, err)
+			}
+			args = append(args, parsed)
+		}
+
+		column := clause.Column{Name: field.Column, Raw: true}
+
+		// JSON values are compared as jsonb so numbers order numerically
+		placeholder := "?"
+		if field.Kind == JSONField {
+			placeholder = "?::jsonb"
+		}
+
+		switch f.Op {
+		case OpIn:
+			if field.Kind == JSONField {
+				query = query.Where("? IN (SELECT jsonb_array_elements(?::jsonb))", column, "["+strings.Join(toStrings(args), ",")+"]")
+			} else {
+				query = query.Where("? IN ?", column, args)
+			}
+		case OpLike:
+			switch field.Kind {
+			case StringField:
+			case JSONField:
+				// ->> extracts the value as text
+				column.Name = strings.Replace(column.Name, "->'", "->>'", 1)
+			default:
+				return nil, fmt.Errorf("%w: like is only supported on text fields", ErrInvalidQuery)
+			}
+			query = query.Where("? ILIKE ?", column, "%"+escapeLike(f.Values[0])+"%")
+		default:
+			query = query.Where(fmt.Sprintf("? %s %s", filterOperators[f.Op], placeholder), column, args[0])
+		}
+	}
+
+	return query, nil
+}
+
+func (spec QuerySpec) applyOrder(query *gorm.DB, fields QueryFields, pk string) (*gorm.DB, error) {
+	desc := false
+
+	for _, s := range spec.Sort {
+		field, ok := fields.lookup(s.Field)
+		if !ok {
+			return nil, fmt.Errorf("%w: unknown sort field %q", ErrInvalidQuery, s.Field)
+		}
+		query = query.Order(clause.OrderByColumn{
+			Column: clause.Column{Name: field.Column, Raw: true},
+			Desc:   s.Desc,
+		})
+		desc = s.Desc
+	}
+
+	// newest first by default, and always break ties on the primary key so
+	// pages are stable
+	if len(spec.Sort) == 0 {
+		desc = true
+	}
+
+	return query.Order(clause.OrderByColumn{
+		Column: clause.Column{Name: pk, Raw: true},
+		Desc:   desc,
+	}), nil
+}
+
+type queryCursor struct {
+	Value string `json:"v,omitempty"`
+	ID    int64  `json:"id"`
+}
+
+// applyCursor restricts query to the rows after the cursor using a row value
+// comparison on (sort column, primary key).
+func (spec QuerySpec) applyCursor(query *gorm.DB, fields QueryFields, pk string) (*gorm.DB, error) {
+	if len(spec.Sort) > 1 {
+		return nil, fmt.Errorf("%w: cursor pagination supports a single sort field", ErrInvalidQuery)
+	}
+
+	if len(spec.Sort) == 1 {
+		if field, _ := fields.lookup(spec.Sort[0].Field); field.Kind == JSONField {
+			return nil, fmt.Errorf("%w: cursor pagination cannot sort on custom fields", ErrInvalidQuery)
+		}
+	}
+
+	if spec.Cursor == "" {
+		return query, nil
+	}
+
+	cur, err := decodeCursor(spec.Cursor)
+	if err != nil {
+		return nil, err
+	}
+
+	pkColumn := clause.Column{Name: pk, Raw: true}
+
+	if len(spec.Sort) == 0 {
+		return query.Where("? < ?", pkColumn, cur.ID), nil
+	}
+
+	s := spec.Sort[0]
+	field, _ := fields.lookup(s.Field)
+
+	value, err := parseFieldValue(field.Kind, cur.Value)
+	if err != nil {
+		return nil, fmt.Errorf("%w: malformed cursor", ErrInvalidQuery)
+	}
+
+	cmp := ">"
+	if s.Desc {
+		cmp = "<"
+	}
+
+	return query.Where(
+		fmt.Sprintf("(?, ?) %s (?, ?)", cmp),
+		clause.Column{Name: field.Column, Raw: true}, pkColumn, value, cur.ID,
+	), nil
+}
+
+func encodeCursor(cur queryCursor) string {
+	b, _ := json.Marshal(cur)
+	return base64.RawURLEncoding.EncodeToString(b)
+}
+
+func decodeCursor(s string) (queryCursor, error) {
+	var cur queryCursor
+
+	b, err := base64.RawURLEncoding.DecodeString(s)
+	if err != nil {
+		return cur, fmt.Errorf("%w: malformed cursor", ErrInvalidQuery)
+	}
+
+	if err := json.Unmarshal(b, &cur); err != nil {
+		return cur, fmt.Errorf("%w: malformed cursor", ErrInvalidQuery)
+	}
+
+	return cur, nil
+}
+
+// cursorFor builds the cursor pointing just past row.
+func (spec QuerySpec) cursorFor(db *gorm.DB, row any, fields QueryFields, pk string) (string, error) {
+	stmt := &gorm.Statement{DB: db}
+	if err := stmt.Parse(row); err != nil {
+		return "", err
+	}
+
+	rv := reflect.Indirect(reflect.ValueOf(row))
+	ctx := context.Background()
+
+	cur := queryCursor{}
+
+	pkField := stmt.Schema.LookUpField(columnName(pk))
+	if pkField == nil {
+		return "", fmt.Errorf("cursor: unknown primary key %q", pk)
+	}
+	id, _ := pkField.ValueOf(ctx, rv)
+	cur.ID = reflect.ValueOf(id).Int()
+
+	if len(spec.Sort) == 1 {
+		column := fields[spec.Sort[0].Field].Column
+		field := stmt.Schema.LookUpField(columnName(column))
+		if field == nil {
+			return "", fmt.Errorf("cursor: unknown sort column %q", column)
+		}
+		v, _ := field.ValueOf(ctx, rv)
+		cur.Value = formatFieldValue(v)
+	}
+
+	return encodeCursor(cur), nil
+}
+
+// paginate applies spec to query and loads a single page of results.
+// Counts are taken after the filters are applied so TotalRows reflects what
+// the caller actually asked for.
+func paginate[T any](query *gorm.DB, spec QuerySpec, fields QueryFields, pk string) (*Pagination, error) {
+	query, err := spec.applyFilters(query, fields)
+	if err != nil {
+		return nil, err
+	}
+
+	var totalRows int64
+	if err := query.Session(&gorm.Session{}).Count(&totalRows).Error; err != nil {
+		return nil, err
+	}
+
+	query, err = spec.applyOrder(query, fields, pk)
+	if err != nil {
+		return nil, err
+	}
+
+	limit := spec.GetLimit()
+	pagination := &Pagination{
+		Limit:      limit,
+		Page:       spec.GetPage(),
+		Sort:       spec.sortString(),
+		TotalRows:  totalRows,
+		TotalPages: int((totalRows + int64(limit) - 1) / int64(limit)),
+	}
+
+	rows := []T{}
+
+	if !spec.UseCursor {
+		if err := query.Offset(spec.GetOffset()).Limit(limit).Find(&rows).Error; err != nil {
+			return nil, err
+		}
+		pagination.Rows = rows
+		return pagination, nil
+	}
+
+	query, err = spec.applyCursor(query, fields, pk)
+	if err != nil {
+		return nil, err
+	}
+
+	// fetch one extra row to find out whether there is a next page
+	if err := query.Limit(limit + 1).Find(&rows).Error; err != nil {
+		return nil, err
+	}
+
+	if len(rows) > limit {
+		rows = rows[:limit]
+		next, err := spec.cursorFor(query, &rows[limit-1], fields, pk)
+		if err != nil {
+			return nil, err
+		}
+		pagination.NextCursor = next
+	}
+
+	pagination.Page = 0
+	pagination.Rows = rows
+
+	return pagination, nil
+}
+
+// streamQuery applies the filters and sort order of spec, ignoring paging, and
+// streams every matching row to fn.
+func streamQuery[T any](ctx context.Context, query *gorm.DB, spec QuerySpec, fields QueryFields, pk string, fn func(*T) error) error {
+	query, err := spec.applyFilters(query, fields)
+	if err != nil {
+		return err
+	}
+
+	query, err = spec.applyOrder(query, fields, pk)
+	if err != nil {
+		return err
+	}
+
+	return streamRows(ctx, query, fn)
+}
+
+func parseFieldValue(kind FieldKind, v string) (any, error) {
+	switch kind {
+	case IntField:
+		return strconv.ParseInt(v, 10, 64)
+	case FloatField:
+		return strconv.ParseFloat(v, 64)
+	case BoolField:
+		return strconv.ParseBool(v)
+	case TimeField:
+		if t, err := time.Parse(time.RFC3339Nano, v); err == nil {
+			return t, nil
+		}
+		return time.Parse(time.DateOnly, v)
+	case JSONField:
+		// numbers and booleans keep their JSON type, anything else is a string
+		if json.Valid([]byte(v)) && v != "" && v[0] != '"' && v[0] != '{' && v[0] != '[' {
+			return v, nil
+		}
+		b, err := json.Marshal(v)
+		return string(b), err
+	default:
+		return v, nil
+	}
+}
+
+func formatFieldValue(v any) string {
+	switch val := v.(type) {
+	case time.Time:
+		return val.Format(time.RFC3339Nano)
+	case *time.Time:
+		if val == nil {
+			return ""
+		}
+		return val.Format(time.RFC3339Nano)
+	default:
+		return fmt.Sprintf("%v", val)
+	}
+}
+
+// columnName strips the table qualifier from a column reference.
+func columnName(column string) string {
+	if i := strings.LastIndex(column, "."); i >= 0 {
+		column = column[i+1:]
+	}
+	return strings.Trim(column, `"`)
+}
+
+func toStrings(values []any) []string {
+	out := make([]string, len(values))
+	for i, v := range values {
+		out[i] = fmt.Sprintf("%v", v)
+	}
+	return out
+}
+
+func escapeLike(s string) string {
+	return strings.NewReplacer(`\`, `\\`, `%`, `\%`, `_`, `\_`).Replace(s)
+}
@@ -0,0 +1,56 @@
+package store
+
+import (
+	"errors"
+	"net/url"
+	"testing"
+
+	"gorm.io/driver/postgres"
+	"gorm.io/gorm"
+)
+
+// dryRunDB builds statements without a database to run them on.
+func dryRunDB(t *testing.T) *gorm.DB {
+	t.Helper()
+
+	db, err := gorm.Open(postgres.New(postgres.Config{DSN: "host=localhost"}), &gorm.Config{DryRun: true, DisableAutomaticPing: true})
+	if err != nil {
+		t.Fatal(err)
+	}
+	return db
+}
+
+func TestApplyFilters(t *testing.T) {
+	tests := []struct {
+		query   string
+		wantErr bool
+	}{
+		{"status=in:AVAILABLE,REPAIR", false},
+		{"status=AVAILABLE&limit=10&page=2&sort=-name", false},
+		{"format=csv&columns=id,name", false},
+		{"_=1700000000&searchJoin=and&language=en", false},
+		{"stauts=in:REPAIR", true},
+		{"id=abc", true},
+	}
+
+	db := dryRunDB(t)
+
+	for _, tt := range tests {
+		t.Run(tt.query, func(t *testing.T) {
+			values, err := url.ParseQuery(tt.query)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			spec, err := ParseQuerySpec(values)
+			if err != nil {
+				t.Fatalf("ParseQuerySpec(%q) error: %v", tt.query, err)
+			}
+
+			_, err = spec.applyFilters(db.Model(&Asset{}), assetQueryFields)
+			if gotErr := errors.Is(err, ErrInvalidQuery); gotErr != tt.wantErr {
+				t.Errorf("applyFilters(%q) error = %v, want invalid query %v", tt.query, err, tt.wantErr)
+			}
+		})
+	}
+}
@@ -0,0 +1,373 @@
+package store
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+var (
+	ErrReservationNotBooked = errors.New("reservation is no longer booked")
+	ErrReservationNotDue    = errors.New("reservation cannot be picked up yet")
+)
+
+// ReservationPickupGrace is how long before its start a reservation can be
+// picked up, and how long after its start it is kept for a late pickup
+// before it expires as a no-show.
+const ReservationPickupGrace = 2 * time.Hour
+
+type ReservationStatus string
+
+const (
+	ReservationBooked    ReservationStatus = "BOOKED"
+	ReservationPickedUp  ReservationStatus = "PICKED_UP"
+	ReservationCancelled ReservationStatus = "CANCELLED"
+	ReservationExpired   ReservationStatus = "EXPIRED"
+)
+
+// statuses of reservations that hold their asset
+var activeReservationStatuses = []ReservationStatus{ReservationBooked, ReservationPickedUp}
+
+// statuses of assets that can be reserved, an assigned asset may be back
+// by the time the reservation starts
+var reservableAssetStatuses = []AssetStatus{AssetAvailable, AssetAssigned, AssetReadyToDeploy}
+
+// Reservation books an asset for a user over [StartsAt, EndsAt). A
+// reservation for any asset of a model is booked on a free asset of it,
+// Flexible records that the user did not ask for that asset.
+type Reservation struct {
+	ID int64 `gorm:"primaryKey"`
+
+	AssetID  int64 `gorm:"not null;index"`
+	Asset    Asset `gorm:"constraint:OnDelete:CASCADE;"`
+	ModelID  int64 `gorm:"not null;index"`
+	Model    Model `gorm:"constraint:OnDelete:CASCADE;"`
+	Flexible bool  `gorm:"not null;default:false"`
+
+	UserID int64 `gorm:"not null;index"`
+	User   User  `gorm:"constraint:OnDelete:CASCADE;"`
+
+	StartsAt time.Time `gorm:"not null"`
+	EndsAt   time.Time `gorm:"not null;check:ends_at > starts_at"`
+
+	Status ReservationStatus `gorm:"type:varchar(20);not null;default:'BOOKED';index"`
+
+	LoanID       *int64     `gorm:"index"`
+	Loan         *AssetLoan `gorm:"constraint:OnDelete:SET NULL;"`
+	PickedUpAt   *time.Time
+	ReservedByID *int64 `gorm:"index"`
+	ReservedBy   *User  `gorm:"constraint:OnDelete:SET NULL;"`
+
+	Notes     string `gorm:"size:255"`
+	CreatedAt time.Time
+	UpdatedAt time.Time
+}
+
+var reservationMigrations = []string{
+	`CREATE EXTENSION IF NOT EXISTS btree_gist`,
+	// an asset is booked once for any moment
+	`ALTER TABLE reservations ADD CONSTRAINT excl_reservations_asset_window
+		EXCLUDE USING gist (asset_id WITH =, tstzrange(starts_at, ends_at) WITH &&)
+		WHERE (status IN ('BOOKED', 'PICKED_UP'))`,
+}
+
+type ReservationStore struct {
+	db *gorm.DB
+}
+
+var reservationQueryFields = QueryFields{
+	"id":        {"reservations.id", IntField},
+	"assetId":   {"reservations.asset_id", IntField},
+	"modelId":   {"reservations.model_id", IntField},
+	"userId":    {"reservations.user_id", IntField},
+	"status":    {"reservations.status", StringField},
+	"startsAt":  {"reservations.starts_at", TimeField},
+	"endsAt":    {"reservations.ends_at", TimeField},
+	"createdAt": {"reservations.created_at", TimeField},
+}
+
+func preloadReservation(db *gorm.DB) *gorm.DB {
+	return db.
+		Joins("Asset").
+		Joins("Model").
+		Joins("User")
+}
+
+// reservableAssets selects the assets that can be booked over [from, to):
+// no active reservation overlaps the window and no open loan is due back
+// after it starts.
+func reservableAssets(db *gorm.DB, from, to time.Time) *gorm.DB {
+	return db.Model(&Asset{}).
+		Where("assets.status IN ?", reservableAssetStatuses).
+		Where(`NOT EXISTS (
+			SELECT 1 FROM reservations r
+			WHERE r.asset_id = assets.id AND r.status IN ?
+				AND tstzrange(r.starts_at, r.ends_at) && tstzrange(?, ?))`,
+			activeReservationStatuses, from, to).
+		Where(`NOT EXISTS (
+			SELECT 1 FROM asset_loans l
+			WHERE l.asset_id = assets.id AND l.actual_return_date IS NULL
+				AND (l.expected_checkin_date IS NULL OR l.expected_checkin_date > ?))`,
+			from)
+}
+
+// notReservedByOthers keeps the assets no booking of a user other than userID
+// holds during [from, to). A nil to leaves the window open ended. Picked up
+// reservations are left to the loan they turned into.
+func notReservedByOthers(db *gorm.DB, userID int64, from time.Time, to *time.Time) *gorm.DB {
+	return db.Where(`NOT EXISTS (
+		SELECT 1 FROM reservations r
+		WHERE r.asset_id = assets.id AND r.status = ? AND r.user_id <> ?
+			AND tstzrange(r.starts_at, r.ends_at) && tstzrange(?, ?))`,
+		ReservationBooked, userID, from, to)
+}
+
+func (s *ReservationStore) List(ctx context.Context, spec QuerySpec) (*Pagination, error) {
+	query := preloadReservation(s.db.WithContext(ctx).Model(&Reservation{}))
+
+	return paginate[Reservation](query, spec, reservationQueryFields, "reservations.id")
+}
+
+func (s *ReservationStore) GetByID(ctx context.Context, id int64) (*Reservation, error) {
+	var reservation Reservation
+
+	err := preloadReservation(s.db.WithContext(ctx)).
+		First(&reservation, "reservations.id = ?", id).Error
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, ErrNotFound
+		}
+		return nil, err
+	}
+
+	return &reservation, nil
+}
+
+// reservationAttempts bounds how often a booking for any asset of a model
+// moves on to the next free asset after a concurrent booking took one.
+const reservationAttempts = 5
+
+// Create books the reservation. With no AssetID the first free asset of
+// ModelID is booked, passing on to the next one when a concurrent booking
+// took it. It fails with ErrAssetNotAvailable when no asset is free for the
+// window and with ErrConflict when a concurrent booking took the asset.
+func (s *ReservationStore) Create(ctx context.Context, reservation *Reservation) error {
+	reservation.Flexible = reservation.AssetID == 0
+
+	for attempt := 1; ; attempt++ {
+		err := s.book(ctx, reservation)
+		if !isExclusionViolation(err) {
+			return err
+		}
+		if !reservation.Flexible || attempt == reservationAttempts {
+			return ErrConflict
+		}
+	}
+}
+
+func (s *ReservationStore) book(ctx context.Context, reservation *Reservation) error {
+	return s.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		query := reservableAssets(tx, reservation.StartsAt, reservation.EndsAt)
+		if reservation.Flexible {
+			query = query.Where("assets.model_id = ?", reservation.ModelID)
+		} else {
+			query = query.Where("assets.id = ?", reservation.AssetID)
+		}
+
+		var asset Asset
+		if err := query.Order("assets.id").First(&asset).Error; err != nil {
+			if errors.Is(err, gorm.ErrRecordNotFound) {
+				return ErrAssetNotAvailable
+			}
+			return err
+		}
+
+		reservation.AssetID = asset.ID
+		reservation.ModelID = asset.ModelID
+		reservation.Status = ReservationBooked
+
+		return tx.Omit(clause.Associations).Create(reservation).Error
+	})
+}
+
+// Cancel releases a booked reservation.
+func (s *ReservationStore) Cancel(ctx context.Context, id int64) error {
+	result := s.db.WithContext(ctx).
+		Model(&Reservation{}).
+		Where("id = ? AND status = ?", id, ReservationBooked).
+		Update("status", ReservationCancelled)
+
+	if result.Error != nil {
+		return result.Error
+	}
+
+	if result.RowsAffected == 0 {
+		return ErrReservationNotBooked
+	}
+
+	return nil
+}
+
+// Pickup turns a booked reservation into a loan of its asset that is due
+// back when the reservation ends.
+func (s *ReservationStore) Pickup(ctx context.Context, id int64) error {
+	return s.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		var reservation Reservation
+
+		err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).
+			First(&reservation, id).Error
+		if err != nil {
+			if errors.Is(err, gorm.ErrRecordNotFound) {
+				return ErrNotFound
+			}
+			return err
+		}
+
+		now := time.Now()
+
+		if reservation.Status != ReservationBooked {
+			return ErrReservationNotBooked
+		}
+		if now.Before(reservation.StartsAt.Add(-ReservationPickupGrace)) {
+			return ErrReservationNotDue
+		}
+
+		var asset Asset
+		if err := tx.First(&asset, reservation.AssetID).Error; err != nil {
+			return err
+		}
+
+		loans := []AssetLoan{{
+			AssetName:           asset.Name,
+			AssetID:             asset.ID,
+			UserID:              reservation.UserID,
+			CheckoutDate:        now,
+			ExpectedCheckinDate: &reservation.EndsAt,
+			Status:              AssetPending,
+			Notes:               reservation.Notes,
+		}}
+
+		if err := checkoutAssets(tx, loans); err != nil {
+			return err
+		}
+
+		return tx.Model(&reservation).Updates(map[string]interface{}{
+			"status":       ReservationPickedUp,
+			"loan_id":      loans[0].ID,
+			"picked_up_at": now,
+		}).Error
+	})
+}
+
+// ExpireNoShows expires the booked reservations that were not picked up
+// within the grace period after their start.
+func (s *ReservationStore) ExpireNoShows(ctx context.Context) ([]Reservation, error) {
+	var reservations []Reservation
+
+	err := s.db.WithContext(ctx).
+		Model(&reservations).
+		Clauses(clause.Returning{}).
+		Where("status = ? AND starts_at < ?", ReservationBooked, time.Now().Add(-ReservationPickupGrace)).
+		Update("status", ReservationExpired).Error
+	if err != nil {
+		return nil, err
+	}
+
+	return reservations, nil
+}
+
+// ModelAvailability is what keeps the assets of a model busy within
+// [From, To).
+type ModelAvailability struct {
+	From time.Time
+	To   time.Time
+
+	Assets       []Asset
+	Reservations []Reservation
+	Loans        []AssetLoan
+}
+
+// AvailabilityDay counts the assets of a model that are busy at some point
+// of a day.
+type AvailabilityDay struct {
+	Date  time.Time
+	Total int
+	Busy  int
+}
+
+// Days splits the window into days and counts the busy assets of each.
+func (a *ModelAvailability) Days() []AvailabilityDay {
+	type period struct{ start, end time.Time }
+
+	busy := make(map[int64][]period)
+	for _, r := range a.Reservations {
+		busy[r.AssetID] = append(busy[r.AssetID], period{r.StartsAt, r.EndsAt})
+	}
+	for _, l := range a.Loans {
+		end := a.To
+		if l.ExpectedCheckinDate != nil && l.ExpectedCheckinDate.Before(end) {
+			end = *l.ExpectedCheckinDate
+		}
+		busy[l.AssetID] = append(busy[l.AssetID], period{l.CheckoutDate, end})
+	}
+
+	var days []AvailabilityDay
+	for day := a.From; day.Before(a.To); day = day.AddDate(0, 0, 1) {
+		next := day.AddDate(0, 0, 1)
+		d := AvailabilityDay{Date: day, Total: len(a.Assets)}
+
+		for _, asset := range a.Assets {
+			for _, p := range busy[asset.ID] {
+				if p.start.Before(next) && p.end.After(day) {
+					d.Busy++
+					break
+				}
+			}
+		}
+
+		days = append(days, d)
+	}
+
+	return days
+}
+
+// Availability loads the assets of a model with their bookings and open
+// loans overlapping [from, to).
+func (s *ReservationStore) Availability(ctx context.Context, modelID int64, from, to time.Time) (*ModelAvailability, error) {
+	availability := &ModelAvailability{From: from, To: to}
+
+	db := s.db.WithContext(ctx)
+
+	err := db.Where("model_id = ?", modelID).
+		Order("id").
+		Find(&availability.Assets).Error
+	if err != nil {
+		return nil, err
+	}
+
+	// picked up reservations show as their loans
+	err = db.Joins("User").
+		Where("reservations.model_id = ? AND reservations.status = ?", modelID, ReservationBooked).
+		Where("reservations.starts_at < ? AND reservations.ends_at > ?", to, from).
+		Order("reservations.starts_at").
+		Find(&availability.Reservations).Error
+	if err != nil {
+		return nil, err
+	}
+
+	err = db.Joins("User").
+		Joins("JOIN assets a ON a.id = asset_loans.asset_id AND a.deleted_at IS NULL").
+		Where("a.model_id = ? AND asset_loans.actual_return_date IS NULL", modelID).
+		Where("asset_loans.checkout_date < ?", to).
+		Where("asset_loans.expected_checkin_date IS NULL OR asset_loans.expected_checkin_date > ?", from).
+		Order("asset_loans.checkout_date").
+		Find(&availability.Loans).Error
+	if err != nil {
+		return nil, err
+	}
+
+	return availability, nil
+}
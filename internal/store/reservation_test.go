@@ -0,0 +1,40 @@
+package store
+
+import (
+	"context"
+	"testing"
+	"time"
+)
+
+func TestReservationPickupTwice(t *testing.T) {
+	db := testDB(t)
+	ctx := context.Background()
+
+	user := testUser(t, db)
+	_, assets := testAssets(t, db, 1)
+
+	reservations := ReservationStore{db}
+	loans := AssetLoanStore{db}
+
+	// back to back, both due for pickup now
+	start := time.Now().Truncate(time.Second)
+	for i := range 2 {
+		reservation := &Reservation{
+			AssetID:  assets[0].ID,
+			UserID:   user.ID,
+			StartsAt: start.Add(time.Duration(i) * time.Hour),
+			EndsAt:   start.Add(time.Duration(i+1) * time.Hour),
+		}
+		if err := reservations.Create(ctx, reservation); err != nil {
+			t.Fatalf("reservation %d: %v", i+1, err)
+		}
+
+		if err := reservations.Pickup(ctx, reservation.ID); err != nil {
+			t.Fatalf("pickup %d: %v", i+1, err)
+		}
+
+		if err := loans.Checkin(ctx, assets[0].ID, nil, AssetAvailable, time.Now()); err != nil {
+			t.Fatalf("checkin %d: %v", i+1, err)
+		}
+	}
+}
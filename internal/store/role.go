@@ -2,6 +2,7 @@ package store
 
 import (
 	"context"
+	"errors"
 
 	"gorm.io/gorm"
 )
@@ -21,9 +22,13 @@ func (s *RoleStore) GetByName(ctx context.Context, name string) (*Role, error) {
 	var role Role
 
 	err := s.db.WithContext(ctx).
-		First(&role, name).
+		Where("name = ?", name).
+		First(&role).
 		Error
 	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, ErrNotFound
+		}
 		return nil, err
 	}
 
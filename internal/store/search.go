@@ -0,0 +1,344 @@
+package store
+
+import (
+	"context"
+	"database/sql"
+	"html"
+	"slices"
+	"strings"
+	"unicode"
+
+	"gorm.io/gorm"
+)
+
+type SearchHit struct {
+	ID       int64
+	Title    string
+	Subtitle string
+	// Text is the searched text of the hit; Highlight is that text, HTML
+	// escaped, with the matches wrapped in <mark> tags.
+	Text      string
+	Highlight string `gorm:"-"`
+	Rank      float64
+}
+
+type SearchResults struct {
+	Assets        []SearchHit
+	Models        []SearchHit
+	Manufacturers []SearchHit
+	Users         []SearchHit
+}
+
+type SearchStore struct {
+	db *gorm.DB
+}
+
+// The search vectors are stored generated columns, so Postgres keeps them in
+// step with every insert and update without any application code.
+var searchMigrations = []string{
+	`CREATE EXTENSION IF NOT EXISTS pg_trgm`,
+
+	`ALTER TABLE assets ADD COLUMN IF NOT EXISTS search_vector tsvector GENERATED ALWAYS AS (
+		setweight(to_tsvector('simple', coalesce(name, '') || ' ' || coalesce(tag, '') || ' ' || coalesce(serial_number, '')), 'A') ||
+		setweight(to_tsvector('simple', coalesce(description, '')), 'B')
+	) STORED`,
+	`CREATE INDEX IF NOT EXISTS idx_assets_search_vector ON assets USING GIN (search_vector)`,
+	`CREATE INDEX IF NOT EXISTS idx_assets_name_trgm ON assets USING GIN (name gin_trgm_ops)`,
+	`CREATE INDEX IF NOT EXISTS idx_assets_tag_trgm ON assets USING GIN (tag gin_trgm_ops)`,
+	`CREATE INDEX IF NOT EXISTS idx_assets_serial_number_trgm ON assets USING GIN (serial_number gin_trgm_ops)`,
+
+	`ALTER TABLE models ADD COLUMN IF NOT EXISTS search_vector tsvector GENERATED ALWAYS AS (
+		to_tsvector('simple', coalesce(name, '') || ' ' || coalesce(model_number, ''))
+	) STORED`,
+	`CREATE INDEX IF NOT EXISTS idx_models_search_vector ON models USING GIN (search_vector)`,
+	`CREATE INDEX IF NOT EXISTS idx_models_name_trgm ON models USING GIN (name gin_trgm_ops)`,
+
+	`ALTER TABLE manufacturers ADD COLUMN IF NOT EXISTS search_vector tsvector GENERATED ALWAYS AS (
+		to_tsvector('simple', coalesce(name, '') || ' ' || coalesce(email, ''))
+	) STORED`,
+	`CREATE INDEX IF NOT EXISTS idx_manufacturers_search_vector ON manufacturers USING GIN (search_vector)`,
+	`CREATE INDEX IF NOT EXISTS idx_manufacturers_name_trgm ON manufacturers USING GIN (name gin_trgm_ops)`,
+
+	`ALTER TABLE users ADD COLUMN IF NOT EXISTS search_vector tsvector GENERATED ALWAYS AS (
+		to_tsvector('simple', coalesce(username, '') || ' ' || coalesce(email, ''))
+	) STORED`,
+	`CREATE INDEX IF NOT EXISTS idx_users_search_vector ON users USING GIN (search_vector)`,
+	`CREATE INDEX IF NOT EXISTS idx_users_username_trgm ON users USING GIN (username gin_trgm_ops)`,
+}
+
+// Assets match on the full text vector, on partial tags and serial numbers,
+// on fuzzy names, and on the name of whoever currently holds them.
+const searchAssetsSQL = `
+SELECT a.id,
+	a.name AS title,
+	a.tag || ' / ' || a.serial_number AS subtitle,
+	a.name || ' ' || a.tag || ' ' || a.serial_number || ' ' || coalesce(a.description, '') AS text,
+	ts_rank(a.search_vector, q.query) + greatest(
+		word_similarity(@q, a.name),
+		similarity(@q, a.tag),
+		similarity(@q, a.serial_number)
+	) AS rank
+FROM assets a, websearch_to_tsquery('simple', @q) AS q(query)
+WHERE a.deleted_at IS NULL
+	AND (
+		a.search_vector @@ q.query
+		OR @q <% a.name
+		OR a.tag ILIKE @pattern ESCAPE '\'
+		OR a.serial_number ILIKE @pattern ESCAPE '\'
+		OR EXISTS (
+			SELECT 1
+			FROM asset_loans l
+			JOIN users u ON u.id = l.user_id
+			WHERE l.asset_id = a.id
+				AND l.actual_return_date IS NULL
+				AND @q <% u.username
+		)
+	)
+ORDER BY rank DESC, a.id
+LIMIT @limit`
+
+const searchModelsSQL = `
+SELECT m.id,
+	m.name AS title,
+	m.model_number AS subtitle,
+	m.name || ' ' || coalesce(m.model_number, '') AS text,
+	ts_rank(m.search_vector, q.query) + word_similarity(@q, m.name) AS rank
+FROM models m, websearch_to_tsquery('simple', @q) AS q(query)
+WHERE m.search_vector @@ q.query
+	OR @q <% m.name
+	OR m.model_number ILIKE @pattern ESCAPE '\'
+ORDER BY rank DESC, m.id
+LIMIT @limit`
+
+const searchManufacturersSQL = `
+SELECT m.id,
+	m.name AS title,
+	m.email AS subtitle,
+	m.name AS text,
+	ts_rank(m.search_vector, q.query) + word_similarity(@q, m.name) AS rank
+FROM manufacturers m, websearch_to_tsquery('simple', @q) AS q(query)
+WHERE m.search_vector @@ q.query
+	OR @q <% m.name
+ORDER BY rank DESC, m.id
+LIMIT @limit`
+
+const searchUsersSQL = `
+SELECT u.id,
+	u.username AS title,
+	u.email AS subtitle,
+	u.username || ' ' || u.email AS text,
+	ts_rank(u.search_vector, q.query) + word_similarity(@q, u.username) AS rank
+FROM users u, websearch_to_tsquery('simple', @q) AS q(query)
+WHERE u.deleted_at IS NULL
+	AND (
+		u.search_vector @@ q.query
+		OR @q <% u.username
+		OR u.email ILIKE @pattern ESCAPE '\'
+	)
+ORDER BY rank DESC, u.id
+LIMIT @limit`
+
+// Search ranks matches for q in each searchable resource and returns at most
+// limit hits per resource.
+func (s *SearchStore) Search(ctx context.Context, q string, limit int) (*SearchResults, error) {
+	ctx, cancel := context.WithTimeout(ctx, QueryTimeoutDuration)
+	defer cancel()
+
+	args := []any{
+		sql.Named("q", q),
+		// partial matches take q literally, wildcards included
+		sql.Named("pattern", "%"+escapeLike(q)+"%"),
+		sql.Named("limit", limit),
+	}
+
+	terms := searchTerms(q)
+	results := &SearchResults{}
+
+	groups := []struct {
+		query string
+		dest  *[]SearchHit
+	}{
+		{searchAssetsSQL, &results.Assets},
+		{searchModelsSQL, &results.Models},
+		{searchManufacturersSQL, &results.Manufacturers},
+		{searchUsersSQL, &results.Users},
+	}
+
+	for _, g := range groups {
+		*g.dest = []SearchHit{}
+		if err := s.db.WithContext(ctx).Raw(g.query, args...).Scan(g.dest).Error; err != nil {
+			return nil, err
+		}
+		for i := range *g.dest {
+			hit := &(*g.dest)[i]
+			hit.Highlight = highlight(hit.Text, terms)
+		}
+	}
+
+	return results, nil
+}
+
+const (
+	// words around a match kept in a highlight fragment
+	highlightContext      = 8
+	highlightMaxFragments = 2
+	// the word_similarity threshold pg_trgm's <% operator matches at
+	highlightSimilarity = 0.6
+)
+
+// searchTerms splits q into the lowercased words it is made of, leaving out
+// the quotes, or and excluded words of the websearch syntax.
+func searchTerms(q string) []string {
+	var terms []string
+	for _, term := range strings.Fields(strings.ToLower(strings.ReplaceAll(q, `"`, " "))) {
+		if term == "or" || strings.HasPrefix(term, "-") {
+			continue
+		}
+		terms = append(terms, term)
+	}
+	return terms
+}
+
+// highlight marks the parts of text the search terms matched: the terms where
+// they appear in it, partial matches included, and otherwise the words a term
+// is a likely misspelling of. Text outside the marks is HTML escaped, as it is
+// stored content. Long texts are cut down to the fragments around the first
+// matches.
+func highlight(text string, terms []string) string {
+	runes := []rune(text)
+	lower := make([]rune, len(runes))
+	for i, r := range runes {
+		lower[i] = unicode.ToLower(r)
+	}
+
+	marked := make([]bool, len(runes))
+	for _, term := range terms {
+		t := []rune(term)
+		for i := 0; i+len(t) <= len(lower); i++ {
+			if string(lower[i:i+len(t)]) == term {
+				for j := i; j < i+len(t); j++ {
+					marked[j] = true
+				}
+			}
+		}
+	}
+
+	words := splitWords(lower)
+	for _, w := range words {
+		if slices.Contains(marked[w[0]:w[1]], true) {
+			continue
+		}
+		for _, term := range terms {
+			if wordSimilarity(term, string(lower[w[0]:w[1]])) >= highlightSimilarity {
+				for j := w[0]; j < w[1]; j++ {
+					marked[j] = true
+				}
+				break
+			}
+		}
+	}
+
+	return strings.Join(highlightFragments(runes, marked, words), " ... ")
+}
+
+// splitWords returns the start and end of each run of letters and digits.
+func splitWords(runes []rune) [][2]int {
+	var words [][2]int
+	start := -1
+	for i, r := range runes {
+		word := unicode.IsLetter(r) || unicode.IsDigit(r)
+		switch {
+		case word && start < 0:
+			start = i
+		case !word && start >= 0:
+			words = append(words, [2]int{start, i})
+			start = -1
+		}
+	}
+	if start >= 0 {
+		words = append(words, [2]int{start, len(runes)})
+	}
+	return words
+}
+
+// highlightFragments renders the text around the first matched words, or the
+// start of the text when nothing matched.
+func highlightFragments(runes []rune, marked []bool, words [][2]int) []string {
+	if len(words) <= 2*highlightContext+1 {
+		return []string{markRunes(runes, marked)}
+	}
+
+	var fragments []string
+	next := 0
+	for i, w := range words {
+		if len(fragments) == highlightMaxFragments {
+			break
+		}
+		if i < next || !slices.Contains(marked[w[0]:w[1]], true) {
+			continue
+		}
+
+		first := max(i-highlightContext, next)
+		last := min(i+highlightContext, len(words)-1)
+		fragments = append(fragments, markRunes(runes[words[first][0]:words[last][1]], marked[words[first][0]:words[last][1]]))
+		next = last + 1
+	}
+
+	if len(fragments) == 0 {
+		last := words[2*highlightContext][1]
+		return []string{markRunes(runes[:last], marked[:last])}
+	}
+
+	return fragments
+}
+
+func markRunes(runes []rune, marked []bool) string {
+	var b strings.Builder
+	for i := 0; i < len(runes); {
+		j := i
+		for j < len(runes) && marked[j] == marked[i] {
+			j++
+		}
+		if marked[i] {
+			b.WriteString("<mark>")
+			b.WriteString(html.EscapeString(string(runes[i:j])))
+			b.WriteString("</mark>")
+		} else {
+			b.WriteString(html.EscapeString(string(runes[i:j])))
+		}
+		i = j
+	}
+	return b.String()
+}
+
+// wordSimilarity is the share of the term's trigrams found in word, as
+// pg_trgm's word_similarity computes it for a single word.
+func wordSimilarity(term, word string) float64 {
+	termTrigrams := trigrams(term)
+	if len(termTrigrams) == 0 {
+		return 0
+	}
+
+	wordTrigrams := trigrams(word)
+	shared := 0
+	for t := range termTrigrams {
+		if wordTrigrams[t] {
+			shared++
+		}
+	}
+
+	return float64(shared) / float64(len(termTrigrams))
+}
+
+// trigrams pads each word of s the way pg_trgm does, two spaces before and
+// one after, and collects its three letter sequences.
+func trigrams(s string) map[string]bool {
+	set := make(map[string]bool)
+	for _, w := range splitWords([]rune(s)) {
+		padded := append([]rune("  "+string([]rune(s)[w[0]:w[1]])), ' ')
+		for i := 0; i+3 <= len(padded); i++ {
+			set[string(padded[i:i+3])] = true
+		}
+	}
+	return set
+}
@@ -0,0 +1,52 @@
+package store
+
+import "testing"
+
+func TestHighlight(t *testing.T) {
+	tests := []struct {
+		name string
+		text string
+		q    string
+		want string
+	}{
+		{"word", "Dell Latitude 5420", "latitude", "Dell <mark>Latitude</mark> 5420"},
+		{"partial tag", "Laptop LAP-0012 SN1", "lap-00", "Laptop <mark>LAP-00</mark>12 SN1"},
+		{"typo", "Lenovo ThinkPad", "lenvo", "<mark>Lenovo</mark> ThinkPad"},
+		{"escapes text", `<img src=x onerror=alert(1)> laptop`, "laptop", "&lt;img src=x onerror=alert(1)&gt; <mark>laptop</mark>"},
+		{"escapes match", "a <b> c", "<b>", "a <mark>&lt;b&gt;</mark> c"},
+		{"excluded term", "red blue", "red -blue", "<mark>red</mark> blue"},
+		{"no match", "Dell Latitude", "xyz", "Dell Latitude"},
+		{
+			"fragments",
+			"one two three four five six seven eight nine ten eleven twelve thirteen fourteen fifteen sixteen seventeen eighteen nineteen twenty",
+			"twenty",
+			"twelve thirteen fourteen fifteen sixteen seventeen eighteen nineteen <mark>twenty</mark>",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := highlight(tt.text, searchTerms(tt.q)); got != tt.want {
+				t.Errorf("highlight(%q, %q) = %q, want %q", tt.text, tt.q, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestEscapeLike(t *testing.T) {
+	tests := []struct {
+		in, want string
+	}{
+		{"LAP-0012", "LAP-0012"},
+		{"50%", `50\%`},
+		{"_", `\_`},
+		{`C:\temp`, `C:\\temp`},
+		{`a\%_b`, `a\\\%\_b`},
+	}
+
+	for _, tt := range tests {
+		if got := escapeLike(tt.in); got != tt.want {
+			t.Errorf("escapeLike(%q) = %q, want %q", tt.in, got, tt.want)
+		}
+	}
+}
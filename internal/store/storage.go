@@ -5,6 +5,7 @@ import (
 	"errors"
 	"time"
 
+	"github.com/jackc/pgx/v5/pgconn"
 	"gorm.io/gorm"
 )
 
@@ -22,14 +23,47 @@ type Storage struct {
 	AssetAssignment AssetAssignmentStore
 	AssetLoan       AssetLoanStore
 	AssetLog        AssetLogStore
+	AuditLog        AuditLogStore
 	Model           ModelStore
 	Department      DepartmentStore
 	Supplier        SupplierStore
+	Search          SearchStore
+	CustomField     CustomFieldStore
+	Maintenance     MaintenanceStore
+	MaintenancePlan MaintenancePlanStore
+	Notification    NotificationStore
+	Warranty        WarrantyStore
+	PurchaseOrder   PurchaseOrderStore
+	Contact         ContactStore
+	Consumable      ConsumableStore
+	License         LicenseStore
+	Accessory       AccessoryStore
+	Component       ComponentStore
+	AssetRelation   AssetRelationStore
+	Kit             KitStore
+	Reservation     ReservationStore
+	ApprovalStep    ApprovalStepStore
+	AssetRequest    AssetRequestStore
+	Attachment      AttachmentStore
+	Offboarding     OffboardingStore
+	Disposal        DisposalStore
+	Webhook         WebhookStore
+	Outbox          OutboxStore
 	Roles           interface {
 		GetByName(context.Context, string) (*Role, error)
 	}
 }
 
+func isUniqueViolation(err error) bool {
+	var pgErr *pgconn.PgError
+	return errors.As(err, &pgErr) && pgErr.Code == "23505"
+}
+
+func isExclusionViolation(err error) bool {
+	var pgErr *pgconn.PgError
+	return errors.As(err, &pgErr) && pgErr.Code == "23P01"
+}
+
 func NewStorage(db *gorm.DB, auditService AuditService) Storage {
 	return Storage{
 		Users:           UsersStore{db},
@@ -39,9 +73,32 @@ func NewStorage(db *gorm.DB, auditService AuditService) Storage {
 		AssetAssignment: AssetAssignmentStore{db},
 		AssetLoan:       AssetLoanStore{db},
 		AssetLog:        AssetLogStore{db},
+		AuditLog:        AuditLogStore{db},
 		Model:           ModelStore{db},
 		Department:      DepartmentStore{db},
 		Supplier:        SupplierStore{db},
+		Search:          SearchStore{db},
+		CustomField:     CustomFieldStore{db},
+		Maintenance:     MaintenanceStore{db},
+		MaintenancePlan: MaintenancePlanStore{db},
+		Notification:    NotificationStore{db},
+		Warranty:        WarrantyStore{db},
+		PurchaseOrder:   PurchaseOrderStore{db},
+		Contact:         ContactStore{db},
+		Consumable:      ConsumableStore{db},
+		License:         LicenseStore{db},
+		Accessory:       AccessoryStore{db},
+		Component:       ComponentStore{db},
+		AssetRelation:   AssetRelationStore{db},
+		Kit:             KitStore{db},
+		Reservation:     ReservationStore{db},
+		ApprovalStep:    ApprovalStepStore{db},
+		AssetRequest:    AssetRequestStore{db},
+		Attachment:      AttachmentStore{db},
+		Offboarding:     OffboardingStore{db},
+		Disposal:        DisposalStore{db},
+		Webhook:         WebhookStore{db},
+		Outbox:          OutboxStore{db},
 		Roles:           &RoleStore{db},
 	}
 }
@@ -0,0 +1,32 @@
+package store
+
+import (
+	"context"
+
+	"gorm.io/gorm"
+)
+
+// streamRows walks the result of query with a database cursor and hands each
+// row to fn, so large exports never hold the full result set in memory.
+func streamRows[T any](ctx context.Context, query *gorm.DB, fn func(*T) error) error {
+	tx := query.WithContext(ctx)
+
+	rows, err := tx.Rows()
+	if err != nil {
+		return err
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var item T
+		if err := tx.ScanRows(rows, &item); err != nil {
+			return err
+		}
+
+		if err := fn(&item); err != nil {
+			return err
+		}
+	}
+
+	return rows.Err()
+}
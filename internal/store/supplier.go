@@ -2,6 +2,7 @@ package store
 
 import (
 	"context"
+	"errors"
 	"time"
 
 	"gorm.io/gorm"
@@ -11,14 +12,37 @@ type Supplier struct {
 	ID   int64  `gorm:"primaryKey"`
 	Name string `gorm:"size:100;uniqueIndex;not null"`
 
+	VendorDetails
+	Contacts []Contact `gorm:"polymorphic:Owner;polymorphicValue:suppliers"`
+
 	CreatedAt time.Time
 	UpdatedAt time.Time
 }
 
+// SupplierStats sums up what was bought from and spent with a supplier.
+// Order and invoice totals are kept per currency.
+type SupplierStats struct {
+	PurchaseOrderCount int64
+	AssetCount         int64
+	Ordered            map[string]float64
+	Invoiced           map[string]float64
+	MaintenanceCount   int64
+	MaintenanceCost    float64
+	WarrantyCount      int64
+	WarrantyCost       float64
+}
+
 type SupplierStore struct {
 	db *gorm.DB
 }
 
+var supplierQueryFields = QueryFields{
+	"id":        {"suppliers.id", IntField},
+	"name":      {"suppliers.name", StringField},
+	"createdAt": {"suppliers.created_at", TimeField},
+	"updatedAt": {"suppliers.updated_at", TimeField},
+}
+
 func (s *SupplierStore) GetAll(ctx context.Context) ([]Supplier, error) {
 	var categories []Supplier
 
@@ -30,31 +54,49 @@ func (s *SupplierStore) GetAll(ctx context.Context) ([]Supplier, error) {
 	return categories, nil
 }
 
+func (s *SupplierStore) List(ctx context.Context, spec QuerySpec) (*Pagination, error) {
+	query := s.db.WithContext(ctx).Model(&Supplier{})
+
+	return paginate[Supplier](query, spec, supplierQueryFields, "suppliers.id")
+}
+
 func (s *SupplierStore) GetByID(ctx context.Context, id int64) (*Supplier, error) {
-	var Supplier Supplier
+	var supplier Supplier
 
 	err := s.db.WithContext(ctx).
-		First(&Supplier, id).
+		Preload("Contacts", func(db *gorm.DB) *gorm.DB { return db.Order("name, id") }).
+		First(&supplier, id).
 		Error
 	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, ErrNotFound
+		}
 		return nil, err
 	}
 
-	return &Supplier, nil
+	return &supplier, nil
 }
 
 func (s SupplierStore) Create(ctx context.Context, supplier *Supplier) error {
-	return s.db.WithContext(ctx).Create(supplier).Error
+	err := s.db.WithContext(ctx).Omit("Contacts").Create(supplier).Error
+	if isUniqueViolation(err) {
+		return ErrConflict
+	}
+	return err
 }
 
 func (s *SupplierStore) Update(ctx context.Context, supplier *Supplier) error {
+	updates := vendorDetailsUpdates(supplier.VendorDetails)
+	updates["name"] = supplier.Name
+
 	result := s.db.WithContext(ctx).
 		Model(&Supplier{}).
 		Where("id = ?", supplier.ID).
-		Updates(map[string]interface{}{
-			"name": supplier.Name,
-		})
+		Updates(updates)
 
+	if isUniqueViolation(result.Error) {
+		return ErrConflict
+	}
 	if result.Error != nil {
 		return result.Error
 	}
@@ -67,16 +109,102 @@ func (s *SupplierStore) Update(ctx context.Context, supplier *Supplier) error {
 }
 
 func (s *SupplierStore) Delete(ctx context.Context, id int64) error {
-	result := s.db.WithContext(ctx).
-		Delete(&Supplier{}, id)
+	return s.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		result := tx.Delete(&Supplier{}, id)
+		if result.Error != nil {
+			return result.Error
+		}
+		if result.RowsAffected == 0 {
+			return ErrNotFound
+		}
+
+		return deleteContacts(tx, ContactOwnerSupplier, id)
+	})
+}
 
-	if result.Error != nil {
-		return result.Error
+func (s *SupplierStore) Stats(ctx context.Context, id int64) (*SupplierStats, error) {
+	ctx, cancel := context.WithTimeout(ctx, QueryTimeoutDuration)
+	defer cancel()
+
+	db := s.db.WithContext(ctx)
+	stats := &SupplierStats{
+		Ordered:  map[string]float64{},
+		Invoiced: map[string]float64{},
 	}
 
-	if result.RowsAffected == 0 {
-		return ErrNotFound
+	err := db.Model(&PurchaseOrder{}).
+		Where("supplier_id = ? AND status <> ?", id, PurchaseOrderCancelled).
+		Count(&stats.PurchaseOrderCount).Error
+	if err != nil {
+		return nil, err
 	}
 
-	return nil
+	err = db.Model(&Asset{}).
+		Joins("JOIN purchase_orders ON purchase_orders.id = assets.purchase_order_id").
+		Where("purchase_orders.supplier_id = ?", id).
+		Count(&stats.AssetCount).Error
+	if err != nil {
+		return nil, err
+	}
+
+	type currencyTotal struct {
+		Currency string
+		Total    float64
+	}
+
+	var ordered []currencyTotal
+	err = db.Model(&PurchaseOrderLine{}).
+		Select("purchase_order_lines.currency, SUM(purchase_order_lines.quantity * purchase_order_lines.unit_cost) AS total").
+		Joins("JOIN purchase_orders ON purchase_orders.id = purchase_order_lines.purchase_order_id").
+		Where("purchase_orders.supplier_id = ? AND purchase_orders.status <> ?", id, PurchaseOrderCancelled).
+		Group("purchase_order_lines.currency").
+		Scan(&ordered).Error
+	if err != nil {
+		return nil, err
+	}
+	for _, t := range ordered {
+		stats.Ordered[t.Currency] = t.Total
+	}
+
+	var invoiced []currencyTotal
+	err = db.Model(&Invoice{}).
+		Select("invoices.currency, SUM(invoices.amount) AS total").
+		Joins("JOIN purchase_orders ON purchase_orders.id = invoices.purchase_order_id").
+		Where("purchase_orders.supplier_id = ?", id).
+		Group("invoices.currency").
+		Scan(&invoiced).Error
+	if err != nil {
+		return nil, err
+	}
+	for _, t := range invoiced {
+		stats.Invoiced[t.Currency] = t.Total
+	}
+
+	var maintenance struct {
+		Count int64
+		Cost  float64
+	}
+	err = db.Model(&Maintenance{}).
+		Select("COUNT(*) AS count, COALESCE(SUM(cost), 0) AS cost").
+		Where("supplier_id = ? AND status <> ?", id, MaintenanceCancelled).
+		Scan(&maintenance).Error
+	if err != nil {
+		return nil, err
+	}
+	stats.MaintenanceCount, stats.MaintenanceCost = maintenance.Count, maintenance.Cost
+
+	var warranty struct {
+		Count int64
+		Cost  float64
+	}
+	err = db.Model(&Warranty{}).
+		Select("COUNT(*) AS count, COALESCE(SUM(cost), 0) AS cost").
+		Where("supplier_id = ?", id).
+		Scan(&warranty).Error
+	if err != nil {
+		return nil, err
+	}
+	stats.WarrantyCount, stats.WarrantyCost = warranty.Count, warranty.Cost
+
+	return stats, nil
 }
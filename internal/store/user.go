@@ -25,6 +25,7 @@ type User struct {
 	IsActive     bool           `json:"is_active"`
 	RoleID       int64          `json:"role_id"`
 	Role         Role           `json:"role"`
+	DepartmentID *int64         `gorm:"index" json:"department_id"`
 }
 
 type password struct {
@@ -50,6 +51,23 @@ type UsersStore struct {
 	db *gorm.DB
 }
 
+var userQueryFields = QueryFields{
+	"id":        {"users.id", IntField},
+	"username":  {"users.username", StringField},
+	"email":     {"users.email", StringField},
+	"isActive":  {"users.is_active", BoolField},
+	"roleId":    {"users.role_id", IntField},
+	"createdAt": {"users.created_at", TimeField},
+}
+
+var userActivationMigrations = []string{
+	// users used to be registered inactive; only a completed offboarding
+	// deactivates one now that inactive users are refused
+	`UPDATE users SET is_active = true
+		WHERE NOT is_active
+			AND id NOT IN (SELECT user_id FROM offboardings WHERE status = 'COMPLETED')`,
+}
+
 func (s UsersStore) Create(ctx context.Context, user *User) error {
 	return s.db.WithContext(ctx).Create(user).Error
 }
@@ -61,6 +79,9 @@ func (s *UsersStore) GetByID(ctx context.Context, id int64) (*User, error) {
 		First(&user, id).
 		Error
 	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, ErrNotFound
+		}
 		return nil, err
 	}
 
@@ -102,6 +123,25 @@ func (s *UsersStore) Update(ctx context.Context, user *User) error {
 	return nil
 }
 
+// SetDepartment moves the user to departmentID, nil takes them out of their
+// department.
+func (s *UsersStore) SetDepartment(ctx context.Context, id int64, departmentID *int64) error {
+	result := s.db.WithContext(ctx).
+		Model(&User{}).
+		Where("id = ?", id).
+		Update("department_id", departmentID)
+
+	if result.Error != nil {
+		return result.Error
+	}
+
+	if result.RowsAffected == 0 {
+		return ErrNotFound
+	}
+
+	return nil
+}
+
 func (s *UsersStore) GetAll(ctx context.Context) ([]User, error) {
 	var users []User
 
@@ -112,3 +152,32 @@ func (s *UsersStore) GetAll(ctx context.Context) ([]User, error) {
 
 	return users, nil
 }
+
+func (s *UsersStore) List(ctx context.Context, spec QuerySpec) (*Pagination, error) {
+	query := s.db.WithContext(ctx).Model(&User{})
+
+	return paginate[User](query, spec, userQueryFields, "users.id")
+}
+
+func (s *UsersStore) Stream(ctx context.Context, spec QuerySpec, fn func(*User) error) error {
+	query := s.db.Model(&User{})
+
+	return streamQuery(ctx, query, spec, userQueryFields, "users.id", fn)
+}
+
+// GetIDsByRole returns the active users holding the named role.
+func (s *UsersStore) GetIDsByRole(ctx context.Context, role string) ([]int64, error) {
+	var ids []int64
+
+	err := s.db.WithContext(ctx).
+		Model(&User{}).
+		Joins("JOIN roles ON roles.id = users.role_id").
+		Where("roles.name = ? AND users.is_active", role).
+		Order("users.id").
+		Pluck("users.id", &ids).Error
+	if err != nil {
+		return nil, err
+	}
+
+	return ids, nil
+}
@@ -0,0 +1,257 @@
+package store
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+type WarrantyKind string
+
+const (
+	WarrantyManufacturer WarrantyKind = "WARRANTY"
+	WarrantySupport      WarrantyKind = "SUPPORT"
+)
+
+type WarrantyStatus string
+
+const (
+	WarrantyNone     WarrantyStatus = "NONE"
+	WarrantyPending  WarrantyStatus = "PENDING"
+	WarrantyActive   WarrantyStatus = "ACTIVE"
+	WarrantyExpiring WarrantyStatus = "EXPIRING"
+	WarrantyExpired  WarrantyStatus = "EXPIRED"
+)
+
+// coverage ending within this window is reported as expiring, and is when
+// the scheduler sends its alert
+const WarrantyExpiringWindow = 30 * 24 * time.Hour
+
+// Warranty is a manufacturer warranty or a support contract covering a single
+// asset.
+type Warranty struct {
+	ID   int64        `gorm:"primaryKey"`
+	Kind WarrantyKind `gorm:"type:varchar(20);not null;default:'WARRANTY'"`
+
+	AssetID int64 `gorm:"not null;index"`
+	Asset   Asset `gorm:"constraint:OnDelete:CASCADE;"`
+
+	SupplierID *int64    `gorm:"index"`
+	Supplier   *Supplier `gorm:"constraint:OnDelete:SET NULL;"`
+
+	ManufacturerID *int64        `gorm:"index"`
+	Manufacturer   *Manufacturer `gorm:"constraint:OnDelete:SET NULL;"`
+
+	ContractNumber string    `gorm:"size:100"`
+	StartDate      time.Time `gorm:"not null"`
+	EndDate        time.Time `gorm:"not null;index"`
+	SLATerms       string    `gorm:"column:sla_terms;size:1000"`
+	Cost           float64   `gorm:"not null;default:0"`
+	Notes          string    `gorm:"size:1000"`
+
+	// set once the expiry alert went out, cleared when the end date moves
+	ExpiryNotifiedAt *time.Time
+
+	CreatedAt time.Time
+	UpdatedAt time.Time
+}
+
+type WarrantyStore struct {
+	db *gorm.DB
+}
+
+var warrantyQueryFields = QueryFields{
+	"id":             {"warranties.id", IntField},
+	"kind":           {"warranties.kind", StringField},
+	"assetId":        {"warranties.asset_id", IntField},
+	"supplierId":     {"warranties.supplier_id", IntField},
+	"manufacturerId": {"warranties.manufacturer_id", IntField},
+	"contractNumber": {"warranties.contract_number", StringField},
+	"startDate":      {"warranties.start_date", TimeField},
+	"endDate":        {"warranties.end_date", TimeField},
+	"cost":           {"warranties.cost", FloatField},
+	"createdAt":      {"warranties.created_at", TimeField},
+}
+
+// WarrantyStatusOf summarises the coverage of an asset at now. Of the
+// warranties in force the one running longest wins; without any, the most
+// recently expired one is reported.
+func WarrantyStatusOf(warranties []Warranty, now time.Time) (WarrantyStatus, *Warranty) {
+	var current, last *Warranty
+
+	for i := range warranties {
+		w := &warranties[i]
+
+		if w.StartDate.After(now) {
+			continue
+		}
+
+		if w.EndDate.Before(now) {
+			if last == nil || w.EndDate.After(last.EndDate) {
+				last = w
+			}
+			continue
+		}
+
+		if current == nil || w.EndDate.After(current.EndDate) {
+			current = w
+		}
+	}
+
+	switch {
+	case current != nil && current.EndDate.Sub(now) <= WarrantyExpiringWindow:
+		return WarrantyExpiring, current
+	case current != nil:
+		return WarrantyActive, current
+	case last != nil:
+		return WarrantyExpired, last
+	}
+
+	return WarrantyNone, nil
+}
+
+func (s *WarrantyStore) List(ctx context.Context, spec QuerySpec) (*Pagination, error) {
+	query := s.db.WithContext(ctx).Model(&Warranty{}).
+		Joins("Asset").
+		Joins("Supplier").
+		Joins("Manufacturer")
+
+	return paginate[Warranty](query, spec, warrantyQueryFields, "warranties.id")
+}
+
+func (s *WarrantyStore) GetByID(ctx context.Context, id int64) (*Warranty, error) {
+	var warranty Warranty
+
+	err := s.db.WithContext(ctx).
+		Joins("Asset").
+		Joins("Supplier").
+		Joins("Manufacturer").
+		First(&warranty, "warranties.id = ?", id).Error
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, ErrNotFound
+		}
+		return nil, err
+	}
+
+	return &warranty, nil
+}
+
+func (s *WarrantyStore) Create(ctx context.Context, warranty *Warranty) error {
+	return s.db.WithContext(ctx).Omit("Asset", "Supplier", "Manufacturer").Create(warranty).Error
+}
+
+func (s *WarrantyStore) Update(ctx context.Context, warranty *Warranty) error {
+	result := s.db.WithContext(ctx).
+		Model(&Warranty{}).
+		Where("id = ?", warranty.ID).
+		Updates(map[string]interface{}{
+			"kind":               warranty.Kind,
+			"supplier_id":        warranty.SupplierID,
+			"manufacturer_id":    warranty.ManufacturerID,
+			"contract_number":    warranty.ContractNumber,
+			"start_date":         warranty.StartDate,
+			"end_date":           warranty.EndDate,
+			"sla_terms":          warranty.SLATerms,
+			"cost":               warranty.Cost,
+			"notes":              warranty.Notes,
+			"expiry_notified_at": warranty.ExpiryNotifiedAt,
+		})
+
+	if result.Error != nil {
+		return result.Error
+	}
+
+	if result.RowsAffected == 0 {
+		return ErrNotFound
+	}
+
+	return nil
+}
+
+func (s *WarrantyStore) Delete(ctx context.Context, id int64) error {
+	result := s.db.WithContext(ctx).
+		Delete(&Warranty{}, id)
+
+	if result.Error != nil {
+		return result.Error
+	}
+
+	if result.RowsAffected == 0 {
+		return ErrNotFound
+	}
+
+	return nil
+}
+
+// GetExpiring returns the warranties still in force that end before until,
+// soonest first.
+func (s *WarrantyStore) GetExpiring(ctx context.Context, until time.Time) ([]Warranty, error) {
+	ctx, cancel := context.WithTimeout(ctx, QueryTimeoutDuration)
+	defer cancel()
+
+	warranties := []Warranty{}
+
+	// inner joined so the warranties of deleted assets are left out
+	err := s.db.WithContext(ctx).
+		InnerJoins("Asset").
+		Joins("Supplier").
+		Joins("Manufacturer").
+		Where("warranties.end_date >= ? AND warranties.end_date < ?", time.Now(), until).
+		Order("warranties.end_date, warranties.id").
+		Find(&warranties).Error
+	if err != nil {
+		return nil, err
+	}
+
+	return warranties, nil
+}
+
+// GetUnnotifiedExpiring returns the expiring warranties no alert went out for
+// yet.
+func (s *WarrantyStore) GetUnnotifiedExpiring(ctx context.Context) ([]Warranty, error) {
+	var warranties []Warranty
+
+	err := s.db.WithContext(ctx).
+		InnerJoins("Asset").
+		Where("warranties.expiry_notified_at IS NULL").
+		Where("warranties.end_date >= ? AND warranties.end_date < ?", time.Now(), time.Now().Add(WarrantyExpiringWindow)).
+		Order("warranties.end_date, warranties.id").
+		Find(&warranties).Error
+	if err != nil {
+		return nil, err
+	}
+
+	return warranties, nil
+}
+
+// MarkNotified records the expiry alert for a warranty together with the
+// notifications carrying it. It reports false when another run got there
+// first.
+func (s *WarrantyStore) MarkNotified(ctx context.Context, id int64, notifications []Notification) (bool, error) {
+	marked := false
+
+	err := s.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		result := tx.Model(&Warranty{}).
+			Where("id = ? AND expiry_notified_at IS NULL", id).
+			Update("expiry_notified_at", time.Now())
+		if result.Error != nil {
+			return result.Error
+		}
+		if result.RowsAffected == 0 {
+			return nil
+		}
+
+		marked = true
+
+		if len(notifications) == 0 {
+			return nil
+		}
+
+		return tx.Create(&notifications).Error
+	})
+
+	return marked, err
+}
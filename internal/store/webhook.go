@@ -0,0 +1,299 @@
+package store
+
+import (
+	"context"
+	"errors"
+	"slices"
+	"time"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// Webhook posts the events it subscribes to to an outside URL.
+type Webhook struct {
+	ID   int64  `gorm:"primaryKey"`
+	Name string `gorm:"size:100;not null"`
+	URL  string `gorm:"size:500;not null"`
+
+	// sealed key the payloads are signed with
+	Secret string   `gorm:"size:255;not null"`
+	Events []string `gorm:"serializer:json;type:text;not null"`
+	Active bool     `gorm:"not null;default:true"`
+
+	CreatedByID *int64
+	CreatedBy   *User `gorm:"constraint:OnDelete:SET NULL;"`
+
+	CreatedAt time.Time
+	UpdatedAt time.Time
+}
+
+// Subscribes reports whether the webhook wants events of eventType.
+func (w *Webhook) Subscribes(eventType string) bool {
+	return w.Active && slices.Contains(w.Events, eventType)
+}
+
+type WebhookDeliveryStatus string
+
+const (
+	DeliveryPending   WebhookDeliveryStatus = "PENDING"
+	DeliverySucceeded WebhookDeliveryStatus = "SUCCEEDED"
+	// given up on after the last retry
+	DeliveryDead WebhookDeliveryStatus = "DEAD"
+)
+
+// WebhookDelivery is one event sent, or to be sent, to one webhook. It keeps
+// the outcome of the latest attempt.
+type WebhookDelivery struct {
+	ID int64 `gorm:"primaryKey"`
+
+	WebhookID int64   `gorm:"not null;index"`
+	Webhook   Webhook `gorm:"constraint:OnDelete:CASCADE;"`
+
+	EventID int64       `gorm:"not null;index"`
+	Event   OutboxEvent `gorm:"constraint:OnDelete:CASCADE;"`
+
+	Status        WebhookDeliveryStatus `gorm:"type:varchar(20);not null;default:'PENDING'"`
+	Attempts      int                   `gorm:"not null;default:0"`
+	NextAttemptAt time.Time             `gorm:"not null"`
+	LastAttemptAt *time.Time
+	ResponseCode  *int
+	ResponseBody  string `gorm:"type:text"`
+	Error         string `gorm:"size:500"`
+	DeliveredAt   *time.Time
+
+	// the delivery this one was manually sent again for
+	RedeliveryOfID *int64
+	RedeliveryOf   *WebhookDelivery `gorm:"constraint:OnDelete:SET NULL;"`
+
+	CreatedAt time.Time
+}
+
+type WebhookStore struct {
+	db *gorm.DB
+}
+
+var webhookQueryFields = QueryFields{
+	"id":        {"webhooks.id", IntField},
+	"name":      {"webhooks.name", StringField},
+	"url":       {"webhooks.url", StringField},
+	"active":    {"webhooks.active", BoolField},
+	"createdAt": {"webhooks.created_at", TimeField},
+}
+
+var webhookDeliveryQueryFields = QueryFields{
+	"id":            {"webhook_deliveries.id", IntField},
+	"eventId":       {"webhook_deliveries.event_id", IntField},
+	"eventType":     {`"Event"."type"`, StringField},
+	"status":        {"webhook_deliveries.status", StringField},
+	"attempts":      {"webhook_deliveries.attempts", IntField},
+	"responseCode":  {"webhook_deliveries.response_code", IntField},
+	"lastAttemptAt": {"webhook_deliveries.last_attempt_at", TimeField},
+	"createdAt":     {"webhook_deliveries.created_at", TimeField},
+}
+
+// The dispatcher polls for unpublished events and due deliveries; partial
+// indexes keep those scans small as the history grows.
+var webhookMigrations = []string{
+	`CREATE INDEX IF NOT EXISTS idx_outbox_events_unpublished
+		ON outbox_events (id) WHERE published_at IS NULL`,
+	`CREATE INDEX IF NOT EXISTS idx_webhook_deliveries_due
+		ON webhook_deliveries (next_attempt_at) WHERE status = 'PENDING'`,
+}
+
+// Events can be dispatched more than once; a webhook still gets one delivery
+// of each, not counting redeliveries.
+var webhookDeliveryMigrations = []string{
+	`CREATE UNIQUE INDEX IF NOT EXISTS idx_webhook_deliveries_event
+		ON webhook_deliveries (webhook_id, event_id) WHERE redelivery_of_id IS NULL`,
+}
+
+func (s *WebhookStore) List(ctx context.Context, spec QuerySpec) (*Pagination, error) {
+	query := s.db.WithContext(ctx).Model(&Webhook{}).
+		Preload("CreatedBy")
+
+	return paginate[Webhook](query, spec, webhookQueryFields, "webhooks.id")
+}
+
+func (s *WebhookStore) GetByID(ctx context.Context, id int64) (*Webhook, error) {
+	var webhook Webhook
+
+	err := s.db.WithContext(ctx).
+		Preload("CreatedBy").
+		First(&webhook, id).Error
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, ErrNotFound
+		}
+		return nil, err
+	}
+
+	return &webhook, nil
+}
+
+func (s *WebhookStore) Create(ctx context.Context, webhook *Webhook) error {
+	return s.db.WithContext(ctx).Omit(clause.Associations).Create(webhook).Error
+}
+
+// Update saves the webhook's settings. It updates from the struct rather than
+// a map so the events list goes through its serializer.
+func (s *WebhookStore) Update(ctx context.Context, webhook *Webhook) error {
+	result := s.db.WithContext(ctx).
+		Model(&Webhook{ID: webhook.ID}).
+		Select("name", "url", "secret", "events", "active").
+		Updates(webhook)
+
+	if result.Error != nil {
+		return result.Error
+	}
+
+	if result.RowsAffected == 0 {
+		return ErrNotFound
+	}
+
+	return nil
+}
+
+func (s *WebhookStore) Delete(ctx context.Context, id int64) error {
+	result := s.db.WithContext(ctx).Delete(&Webhook{}, id)
+
+	if result.Error != nil {
+		return result.Error
+	}
+
+	if result.RowsAffected == 0 {
+		return ErrNotFound
+	}
+
+	return nil
+}
+
+// Deliveries pages through the delivery log of a webhook.
+func (s *WebhookStore) Deliveries(ctx context.Context, webhookID int64, spec QuerySpec) (*Pagination, error) {
+	query := s.db.WithContext(ctx).Model(&WebhookDelivery{}).
+		Joins("Event").
+		Where("webhook_deliveries.webhook_id = ?", webhookID)
+
+	return paginate[WebhookDelivery](query, spec, webhookDeliveryQueryFields, "webhook_deliveries.id")
+}
+
+func (s *WebhookStore) GetDelivery(ctx context.Context, webhookID, deliveryID int64) (*WebhookDelivery, error) {
+	var delivery WebhookDelivery
+
+	err := s.db.WithContext(ctx).
+		Joins("Event").
+		Where("webhook_deliveries.webhook_id = ?", webhookID).
+		First(&delivery, deliveryID).Error
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, ErrNotFound
+		}
+		return nil, err
+	}
+
+	return &delivery, nil
+}
+
+// Redeliver queues the event of a delivery to be sent again, as a new
+// delivery with fresh retries.
+func (s *WebhookStore) Redeliver(ctx context.Context, delivery *WebhookDelivery) (*WebhookDelivery, error) {
+	redelivery := &WebhookDelivery{
+		WebhookID:      delivery.WebhookID,
+		EventID:        delivery.EventID,
+		Status:         DeliveryPending,
+		NextAttemptAt:  time.Now(),
+		RedeliveryOfID: &delivery.ID,
+	}
+
+	if err := s.db.WithContext(ctx).Omit(clause.Associations).Create(redelivery).Error; err != nil {
+		return nil, err
+	}
+
+	return redelivery, nil
+}
+
+// QueueDeliveries queues a delivery of the event to every active webhook
+// subscribed to it. Queueing an event again adds no second delivery.
+func (s *WebhookStore) QueueDeliveries(ctx context.Context, event *OutboxEvent) error {
+	var webhooks []Webhook
+	if err := s.db.WithContext(ctx).Where("active").Find(&webhooks).Error; err != nil {
+		return err
+	}
+
+	var deliveries []WebhookDelivery
+	for _, webhook := range webhooks {
+		if !webhook.Subscribes(event.Type) {
+			continue
+		}
+
+		deliveries = append(deliveries, WebhookDelivery{
+			WebhookID:     webhook.ID,
+			EventID:       event.ID,
+			Status:        DeliveryPending,
+			NextAttemptAt: time.Now(),
+		})
+	}
+	if len(deliveries) == 0 {
+		return nil
+	}
+
+	return s.db.WithContext(ctx).
+		Omit(clause.Associations).
+		Clauses(clause.OnConflict{
+			Columns:     []clause.Column{{Name: "webhook_id"}, {Name: "event_id"}},
+			TargetWhere: clause.Where{Exprs: []clause.Expression{clause.Expr{SQL: "redelivery_of_id IS NULL"}}},
+			DoNothing:   true,
+		}).
+		Create(&deliveries).Error
+}
+
+// ClaimDue takes up to limit pending deliveries that are due, with their
+// webhook and event, and holds them for lease: the claim pushes their next
+// attempt back so no other replica picks them up meanwhile.
+func (s *WebhookStore) ClaimDue(ctx context.Context, limit int, lease time.Duration) ([]WebhookDelivery, error) {
+	var deliveries []WebhookDelivery
+
+	err := s.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		var ids []int64
+		err := tx.Model(&WebhookDelivery{}).
+			Clauses(clause.Locking{Strength: "UPDATE", Options: "SKIP LOCKED"}).
+			Where("status = ? AND next_attempt_at <= ?", DeliveryPending, time.Now()).
+			Order("next_attempt_at").
+			Limit(limit).
+			Pluck("id", &ids).Error
+		if err != nil || len(ids) == 0 {
+			return err
+		}
+
+		err = tx.Model(&WebhookDelivery{}).
+			Where("id IN ?", ids).
+			Update("next_attempt_at", time.Now().Add(lease)).Error
+		if err != nil {
+			return err
+		}
+
+		return tx.Preload("Webhook").
+			Preload("Event").
+			Where("id IN ?", ids).
+			Order("id").
+			Find(&deliveries).Error
+	})
+
+	return deliveries, err
+}
+
+// RecordAttempt saves the outcome of an attempt at a delivery.
+func (s *WebhookStore) RecordAttempt(ctx context.Context, delivery *WebhookDelivery) error {
+	return s.db.WithContext(ctx).
+		Model(&WebhookDelivery{ID: delivery.ID}).
+		Updates(map[string]interface{}{
+			"status":          delivery.Status,
+			"attempts":        delivery.Attempts,
+			"next_attempt_at": delivery.NextAttemptAt,
+			"last_attempt_at": delivery.LastAttemptAt,
+			"response_code":   delivery.ResponseCode,
+			"response_body":   delivery.ResponseBody,
+			"error":           delivery.Error,
+			"delivered_at":    delivery.DeliveredAt,
+		}).Error
+}
@@ -3,8 +3,16 @@ import { HttpClient } from './http-client'
 
 export function crudFactory<Type, QueryParams, InputType>(endpoint: string) {
   return {
-    all(params: QueryParams) {
-      return HttpClient.get<Type[]>(endpoint, params)
+    // walks every page, the API serves at most 100 rows per request
+    async all(params: QueryParams) {
+      const rows: Type[] = []
+      for (let page = 1; ; page++) {
+        const result = await HttpClient.get<PaginatorInfo<Type>>(endpoint, { ...params, limit: 100, page })
+        rows.push(...result.rows)
+        if (page >= result.total_pages) {
+          return rows
+        }
+      }
     },
     paginated(params: QueryParams) {
       return HttpClient.get<PaginatorInfo<Type>>(endpoint, params)